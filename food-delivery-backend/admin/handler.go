@@ -256,7 +256,8 @@ func (h *Handler) AssignRider(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.AssignRider(uint(orderID), req.RiderID); err != nil {
+	adminID := c.GetUint("user_id")
+	if err := h.service.AssignRider(adminID, uint(orderID), req.RiderID); err != nil {
 		pkg.SendError(c, http.StatusBadRequest, "Failed to assign rider", err.Error())
 		return
 	}
@@ -264,6 +265,95 @@ func (h *Handler) AssignRider(c *gin.Context) {
 	pkg.SendSuccess(c, http.StatusOK, "Rider assigned successfully", nil)
 }
 
+// GetPricing returns the delivery pricing rule
+// @Summary Get pricing rule
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.PricingRule}
+// @Router /admin/pricing [get]
+func (h *Handler) GetPricing(c *gin.Context) {
+	rule, err := h.service.GetPricing()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get pricing", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Pricing retrieved successfully", rule)
+}
+
+// UpdatePricing edits the delivery pricing rule
+// @Summary Update pricing rule
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdatePricingRequest true "Pricing fields to change"
+// @Success 200 {object} pkg.Response{data=database.PricingRule}
+// @Router /admin/pricing [put]
+func (h *Handler) UpdatePricing(c *gin.Context) {
+	var req UpdatePricingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	rule, err := h.service.UpdatePricing(&req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to update pricing", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Pricing updated successfully", rule)
+}
+
+// AddPeakHour adds a peak-hour delivery fee multiplier
+// @Summary Add peak hour
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PeakHourRequest true "Peak hour window"
+// @Success 201 {object} pkg.Response{data=database.PeakHour}
+// @Router /admin/pricing/peak-hours [post]
+func (h *Handler) AddPeakHour(c *gin.Context) {
+	var req PeakHourRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	peak, err := h.service.AddPeakHour(&req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to add peak hour", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Peak hour added successfully", peak)
+}
+
+// DeletePeakHour removes a peak-hour multiplier
+// @Summary Delete peak hour
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Peak hour ID"
+// @Success 200 {object} pkg.Response
+// @Router /admin/pricing/peak-hours/{id} [delete]
+func (h *Handler) DeletePeakHour(c *gin.Context) {
+	peakHourID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid peak hour ID", nil)
+		return
+	}
+
+	if err := h.service.DeletePeakHour(uint(peakHourID)); err != nil {
+		pkg.SendError(c, http.StatusNotFound, "Failed to delete peak hour", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Peak hour deleted successfully", nil)
+}
+
 // GetRevenueReport returns revenue report
 // @Summary Get revenue report
 // @Tags Admin
@@ -1,5 +1,7 @@
 package admin
 
+import "food-delivery-backend/pkg"
+
 type StatusCount struct {
 	Status string `json:"status"`
 	Count  int64  `json:"count"`
@@ -48,6 +50,25 @@ type AssignRiderRequest struct {
 	RiderID uint `json:"rider_id" binding:"required"`
 }
 
+// UpdatePricingRequest edits the pricing rule; omitted fields are unchanged
+type UpdatePricingRequest struct {
+	BaseFare          *pkg.Money `json:"base_fare"`
+	IncludedKm        *float64   `json:"included_km"`
+	PerKmRate         *pkg.Money `json:"per_km_rate"`
+	MinDeliveryFee    *pkg.Money `json:"min_delivery_fee"`
+	MaxDeliveryFee    *pkg.Money `json:"max_delivery_fee"`
+	ServiceFeeRate    *float64   `json:"service_fee_rate"`
+	RiderEarningsRate *float64   `json:"rider_earnings_rate"`
+}
+
+type PeakHourRequest struct {
+	Name       string  `json:"name" binding:"required"`
+	DayOfWeek  *int    `json:"day_of_week"`
+	StartTime  string  `json:"start_time" binding:"required"`
+	EndTime    string  `json:"end_time" binding:"required"`
+	Multiplier float64 `json:"multiplier" binding:"required,gt=0"`
+}
+
 type OrderFilters struct {
 	Status    string
 	VendorID  uint
@@ -62,12 +83,12 @@ type RevenueReport struct {
 		EndDate   string `json:"end_date"`
 	} `json:"period"`
 	Summary struct {
-		TotalOrders       int     `json:"total_orders"`
-		TotalRevenue      float64 `json:"total_revenue"`
-		AverageOrderValue float64 `json:"average_order_value"`
-		TotalCommission   float64 `json:"total_commission"`
-		PlatformFee       float64 `json:"platform_fee"`
-		DeliveryFees      float64 `json:"delivery_fees"`
+		TotalOrders       int       `json:"total_orders"`
+		TotalRevenue      pkg.Money `json:"total_revenue"`
+		AverageOrderValue pkg.Money `json:"average_order_value"`
+		TotalCommission   pkg.Money `json:"total_commission"`
+		PlatformFee       pkg.Money `json:"platform_fee"`
+		DeliveryFees      pkg.Money `json:"delivery_fees"`
 	} `json:"summary"`
 	ByVendor []VendorRevenue `json:"by_vendor"`
 	ByDay    []DailyRevenue  `json:"daily_breakdown"`
@@ -91,55 +112,55 @@ type OrdersByStatus struct {
 }
 
 type DailyOrders struct {
-	Date    string  `json:"date"`
-	Orders  int64   `json:"orders"`
-	Revenue float64 `json:"revenue"`
+	Date    string    `json:"date"`
+	Orders  int64     `json:"orders"`
+	Revenue pkg.Money `json:"revenue"`
 }
 
 type RecentOrder struct {
-	ID          uint    `json:"id"`
-	OrderNumber string  `json:"order_number"`
-	Status      string  `json:"status"`
-	TotalAmount float64 `json:"total_amount"`
-	CreatedAt   string  `json:"created_at"`
-	VendorName  string  `json:"vendor_name"`
+	ID          uint      `json:"id"`
+	OrderNumber string    `json:"order_number"`
+	Status      string    `json:"status"`
+	TotalAmount pkg.Money `json:"total_amount"`
+	CreatedAt   string    `json:"created_at"`
+	VendorName  string    `json:"vendor_name"`
 }
 
 type VendorRevenue struct {
-	VendorID     uint    `json:"vendor_id"`
-	BusinessName string  `json:"business_name"`
-	Orders       int     `json:"orders"`
-	Revenue      float64 `json:"revenue"`
-	Commission   float64 `json:"commission"`
-	Earnings     float64 `json:"earnings"`
+	VendorID     uint      `json:"vendor_id"`
+	BusinessName string    `json:"business_name"`
+	Orders       int       `json:"orders"`
+	Revenue      pkg.Money `json:"revenue"`
+	Commission   pkg.Money `json:"commission"`
+	Earnings     pkg.Money `json:"earnings"`
 }
 
 type DailyRevenue struct {
-	Date    string  `json:"date"`
-	Orders  int     `json:"orders"`
-	Revenue float64 `json:"revenue"`
+	Date    string    `json:"date"`
+	Orders  int       `json:"orders"`
+	Revenue pkg.Money `json:"revenue"`
 }
 
 type VendorPerformance struct {
-	VendorID          uint    `json:"vendor_id"`
-	BusinessName      string  `json:"business_name"`
-	TotalOrders       int     `json:"total_orders"`
-	TotalRevenue      float64 `json:"total_revenue"`
-	AverageOrderValue float64 `json:"average_order_value"`
-	Rating            float64 `json:"rating"`
-	ReviewCount       int     `json:"review_count"`
-	AcceptanceRate    float64 `json:"acceptance_rate"`
-	CompletionRate    float64 `json:"completion_rate"`
-	AvgPrepTime       float64 `json:"avg_prep_time"`
+	VendorID          uint      `json:"vendor_id"`
+	BusinessName      string    `json:"business_name"`
+	TotalOrders       int       `json:"total_orders"`
+	TotalRevenue      pkg.Money `json:"total_revenue"`
+	AverageOrderValue pkg.Money `json:"average_order_value"`
+	Rating            float64   `json:"rating"`
+	ReviewCount       int       `json:"review_count"`
+	AcceptanceRate    float64   `json:"acceptance_rate"`
+	CompletionRate    float64   `json:"completion_rate"`
+	AvgPrepTime       float64   `json:"avg_prep_time"`
 }
 
 type RiderPerformance struct {
-	RiderID         uint    `json:"rider_id"`
-	Name            string  `json:"name"`
-	TotalDeliveries int     `json:"total_deliveries"`
-	TotalEarnings   float64 `json:"total_earnings"`
-	Rating          float64 `json:"rating"`
-	ReviewCount     int     `json:"review_count"`
-	AcceptanceRate  float64 `json:"acceptance_rate"`
-	AvgDeliveryTime float64 `json:"avg_delivery_time"`
+	RiderID         uint      `json:"rider_id"`
+	Name            string    `json:"name"`
+	TotalDeliveries int       `json:"total_deliveries"`
+	TotalEarnings   pkg.Money `json:"total_earnings"`
+	Rating          float64   `json:"rating"`
+	ReviewCount     int       `json:"review_count"`
+	AcceptanceRate  float64   `json:"acceptance_rate"`
+	AvgDeliveryTime float64   `json:"avg_delivery_time"`
 }
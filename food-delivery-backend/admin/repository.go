@@ -2,6 +2,7 @@ package admin
 
 import (
 	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
 	"time"
 
 	"gorm.io/gorm"
@@ -83,7 +84,8 @@ func (r *Repository) GetOrders(filters *OrderFilters, offset, limit int) ([]data
         Preload("Student.User").
         Preload("Vendor.User").
         Preload("AssignedRider.User").
-        Preload("OrderItems.MenuItem")
+        Preload("OrderItems.MenuItem").
+        Preload("OrderItems.Options")
 
     if filters.Status != "" {
         query = query.Where("status = ?", filters.Status)
@@ -103,14 +105,6 @@ func (r *Repository) GetOrders(filters *OrderFilters, offset, limit int) ([]data
     return orders, total, err
 }
 
-func (r *Repository) AssignRider(orderID, riderID uint) error {
-    return r.db.Model(&database.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
-        "assigned_rider_id": riderID,
-        "status": database.OrderStatusConfirmed,
-        "confirmed_at": time.Now(),
-    }).Error
-}
-
 func (r *Repository) GetRevenueReport(startDate, endDate time.Time) ([]database.Order, error) {
     var orders []database.Order
     err := r.db.Where("status = ? AND created_at BETWEEN ? AND ?", 
@@ -136,13 +130,13 @@ func (r *Repository) GetVendorStats(vendorID uint) (*VendorPerformance, error) {
 
     // Get order stats
     var totalOrders int64
-    var totalRevenue float64
+    var totalRevenue pkg.Money
     r.db.Model(&database.Order{}).Where("vendor_id = ? AND status = ?", vendorID, database.OrderStatusDelivered).
         Count(&totalOrders).Select("SUM(subtotal)").Scan(&totalRevenue)
     stats.TotalOrders = int(totalOrders)
     stats.TotalRevenue = totalRevenue
     if totalOrders > 0 {
-        stats.AverageOrderValue = totalRevenue / float64(totalOrders)
+        stats.AverageOrderValue = totalRevenue.Div(totalOrders)
     }
 
     // Get acceptance rate
@@ -204,10 +198,26 @@ func (r *Repository) GetOrderByID(orderID uint) (*database.Order, error) {
         Preload("Vendor.User").
         Preload("AssignedRider.User").
         Preload("OrderItems.MenuItem").
+        Preload("OrderItems.Options").
         Preload("Payment").
+        Preload("Refunds.Items").
         First(&order, orderID).Error
     if err != nil {
         return nil, err
     }
     return &order, nil
-}
\ No newline at end of file
+}
+
+// SavePricingRule creates or updates the pricing rule without touching its peak hours
+func (r *Repository) SavePricingRule(rule *database.PricingRule) error {
+    return r.db.Omit("PeakHours").Save(rule).Error
+}
+
+func (r *Repository) CreatePeakHour(peak *database.PeakHour) error {
+    return r.db.Create(peak).Error
+}
+
+func (r *Repository) DeletePeakHour(peakHourID uint) (bool, error) {
+    res := r.db.Delete(&database.PeakHour{}, peakHourID)
+    return res.RowsAffected > 0, res.Error
+}
@@ -1,7 +1,6 @@
 package admin
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"regexp"
@@ -9,6 +8,8 @@ import (
 	"time"
 
 	"food-delivery-backend/database"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/orders"
 	"food-delivery-backend/pkg"
 	"food-delivery-backend/redis"
 
@@ -28,6 +29,8 @@ func makePlaceholderEmail(rolePrefix, phone string) string {
 
 type Service struct {
 	repo        *Repository
+	orderFlow   *orders.StateMachine
+	pricing     *orders.RulePricing
 	redisClient *redis.RedisClient
 	logger      *zap.Logger
 }
@@ -129,9 +132,11 @@ func (s *Service) GetStatusSummaryReport(period string) (*StatusSummaryReport, e
 	return report, nil
 }
 
-func NewService(repo *Repository, redisClient *redis.RedisClient, logger *zap.Logger) *Service {
+func NewService(repo *Repository, orderFlow *orders.StateMachine, pricing *orders.RulePricing, redisClient *redis.RedisClient, logger *zap.Logger) *Service {
 	return &Service{
 		repo:        repo,
+		orderFlow:   orderFlow,
+		pricing:     pricing,
 		redisClient: redisClient,
 		logger:      logger,
 	}
@@ -305,30 +310,20 @@ func (s *Service) GetOrder(orderID uint) (*database.Order, error) {
 	return s.repo.GetOrderByID(orderID)
 }
 
-func (s *Service) AssignRider(orderID, riderID uint) error {
-	var order database.Order
-	if err := s.repo.db.First(&order, orderID).Error; err != nil {
-		return errors.New("order not found")
-	}
-
-	var rider database.Rider
-	if err := s.repo.db.First(&rider, riderID).Error; err != nil {
-		return errors.New("rider not found")
-	}
-	if !rider.IsAvailable {
-		return errors.New("rider is not available")
-	}
-
-	if err := s.repo.AssignRider(orderID, riderID); err != nil {
+// AssignRider puts the order on the rider, joining any batch they are
+// already gathering; the state machine checks the rider exists and enforces
+// batch size and cash limits
+func (s *Service) AssignRider(adminID, orderID, riderID uint) error {
+	if _, err := s.orderFlow.AssignRider(orders.Assignment{
+		OrderID:   orderID,
+		RiderID:   riderID,
+		ActorID:   adminID,
+		ActorRole: "admin",
+	}); err != nil {
 		s.logger.Error("Failed to assign rider", zap.Error(err))
-		return errors.New("failed to assign rider")
+		return err
 	}
 
-	s.repo.db.Model(&rider).Update("is_available", false)
-
-	ctx := context.Background()
-	s.redisClient.SetRiderUnavailable(ctx, riderID)
-
 	return nil
 }
 
@@ -358,7 +353,7 @@ func (s *Service) GetRevenueReport(startDateStr, endDateStr string) (*RevenueRep
 	dayMap := make(map[string]*DailyRevenue)
 
 	var totalOrders int
-	var totalRevenue, totalCommission, totalDeliveryFees float64
+	var totalRevenue, totalCommission, totalDeliveryFees pkg.Money
 
 	for _, order := range orders {
 		totalOrders++
@@ -390,7 +385,7 @@ func (s *Service) GetRevenueReport(startDateStr, endDateStr string) (*RevenueRep
 	report.Summary.TotalOrders = totalOrders
 	report.Summary.TotalRevenue = totalRevenue
 	if totalOrders > 0 {
-		report.Summary.AverageOrderValue = totalRevenue / float64(totalOrders)
+		report.Summary.AverageOrderValue = totalRevenue.Div(int64(totalOrders))
 	}
 	report.Summary.TotalCommission = totalCommission
 	report.Summary.DeliveryFees = totalDeliveryFees
@@ -455,7 +450,7 @@ func (s *Service) GetDashboard(days int) (*DashboardResponse, error) {
 		type row struct {
 			Date    time.Time
 			Orders  int64
-			Revenue float64
+			Revenue pkg.Money
 		}
 		var rows []row
 		err := s.repo.db.Model(&database.Order{}).
@@ -518,3 +513,116 @@ func (s *Service) GetVendorPerformance(vendorID uint) (*VendorPerformance, error
 func (s *Service) GetRiderPerformance(riderID uint) (*RiderPerformance, error) {
 	return s.repo.GetRiderStats(riderID)
 }
+
+// GetPricing returns the pricing rule currently applied to new orders
+func (s *Service) GetPricing() (*database.PricingRule, error) {
+	return s.pricing.CurrentRule()
+}
+
+// UpdatePricing edits the pricing rule, saving the configured defaults as
+// the starting point the first time it is called
+func (s *Service) UpdatePricing(req *UpdatePricingRequest) (*database.PricingRule, error) {
+	rule, err := s.pricing.CurrentRule()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.BaseFare != nil {
+		rule.BaseFare = *req.BaseFare
+	}
+	if req.IncludedKm != nil {
+		rule.IncludedKm = *req.IncludedKm
+	}
+	if req.PerKmRate != nil {
+		rule.PerKmRate = *req.PerKmRate
+	}
+	if req.MinDeliveryFee != nil {
+		rule.MinDeliveryFee = *req.MinDeliveryFee
+	}
+	if req.MaxDeliveryFee != nil {
+		rule.MaxDeliveryFee = *req.MaxDeliveryFee
+	}
+	if req.ServiceFeeRate != nil {
+		rule.ServiceFeeRate = *req.ServiceFeeRate
+	}
+	if req.RiderEarningsRate != nil {
+		rule.RiderEarningsRate = *req.RiderEarningsRate
+	}
+
+	if rule.BaseFare < 0 || rule.IncludedKm < 0 || rule.PerKmRate < 0 ||
+		rule.MinDeliveryFee < 0 || rule.MaxDeliveryFee < 0 {
+		return nil, errors.New("fares and distances cannot be negative")
+	}
+	if rule.MaxDeliveryFee > 0 && rule.MaxDeliveryFee < rule.MinDeliveryFee {
+		return nil, errors.New("max delivery fee must be at least the min delivery fee")
+	}
+	if rule.ServiceFeeRate < 0 || rule.ServiceFeeRate > 1 {
+		return nil, errors.New("service fee rate must be between 0 and 1")
+	}
+	if rule.RiderEarningsRate < 0 || rule.RiderEarningsRate > 1 {
+		return nil, errors.New("rider earnings rate must be between 0 and 1")
+	}
+
+	if err := s.repo.SavePricingRule(rule); err != nil {
+		s.logger.Error("Failed to save pricing rule", zap.Error(err))
+		return nil, errors.New("failed to save pricing rule")
+	}
+	return s.pricing.CurrentRule()
+}
+
+// AddPeakHour adds a delivery fee multiplier window to the pricing rule
+func (s *Service) AddPeakHour(req *PeakHourRequest) (*database.PeakHour, error) {
+	start, err := hours.ParseClock(req.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := hours.ParseClock(req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	if start == 24*60 {
+		return nil, errors.New("start time must be before 24:00")
+	}
+	if start == end {
+		return nil, errors.New("start and end time must differ")
+	}
+	if req.DayOfWeek != nil && (*req.DayOfWeek < 0 || *req.DayOfWeek > 6) {
+		return nil, errors.New("day of week must be between 0 (Sunday) and 6")
+	}
+
+	rule, err := s.pricing.CurrentRule()
+	if err != nil {
+		return nil, err
+	}
+	if rule.ID == 0 {
+		if err := s.repo.SavePricingRule(rule); err != nil {
+			s.logger.Error("Failed to save pricing rule", zap.Error(err))
+			return nil, errors.New("failed to save pricing rule")
+		}
+	}
+
+	peak := &database.PeakHour{
+		PricingRuleID: rule.ID,
+		Name:          req.Name,
+		DayOfWeek:     req.DayOfWeek,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		Multiplier:    req.Multiplier,
+	}
+	if err := s.repo.CreatePeakHour(peak); err != nil {
+		s.logger.Error("Failed to create peak hour", zap.Error(err))
+		return nil, errors.New("failed to create peak hour")
+	}
+	return peak, nil
+}
+
+func (s *Service) DeletePeakHour(peakHourID uint) error {
+	deleted, err := s.repo.DeletePeakHour(peakHourID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("peak hour not found")
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package cash
+
+import (
+	"errors"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRiderNotFound), errors.Is(err, ErrVendorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrExceedsCashHeld):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// GetMyCash returns the cash the rider is holding against their limit
+// @Summary Get cash in hand
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=Summary}
+// @Router /riders/cash [get]
+func (h *Handler) GetMyCash(c *gin.Context) {
+	summary, err := h.service.GetSummary(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get cash in hand", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Cash in hand retrieved successfully", summary)
+}
+
+// RecordHandover records cash a rider handed over
+// @Summary Record cash handover
+// @Tags Vendors, Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body HandoverRequest true "Handover"
+// @Success 201 {object} pkg.Response{data=database.CashHandover}
+// @Failure 422 {object} pkg.Response "More than the rider is holding"
+// @Router /vendors/cash-handovers [post]
+// @Router /admin/cash-handovers [post]
+func (h *Handler) RecordHandover(c *gin.Context) {
+	var req HandoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	handover, err := h.service.RecordHandover(c.GetUint("user_id"), c.GetString("user_role"), &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to record handover", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Handover recorded successfully", handover)
+}
+
+// GetVendorHandovers lists the cash handovers the vendor received
+// @Summary List received cash handovers
+// @Tags Vendors
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /vendors/cash-handovers [get]
+func (h *Handler) GetVendorHandovers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	handovers, total, err := h.service.GetVendorHandovers(c.GetUint("user_id"), page, limit)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get handovers", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Handovers retrieved successfully", handovers, page, limit, total)
+}
+
+// GetHandovers lists cash handovers
+// @Summary List cash handovers
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param rider_id query int false "Rider ID"
+// @Param vendor_id query int false "Vendor ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/cash-handovers [get]
+func (h *Handler) GetHandovers(c *gin.Context) {
+	var filters HandoverFilters
+	if riderID, err := strconv.ParseUint(c.Query("rider_id"), 10, 32); err == nil {
+		id := uint(riderID)
+		filters.RiderID = &id
+	}
+	if vendorID, err := strconv.ParseUint(c.Query("vendor_id"), 10, 32); err == nil {
+		id := uint(vendorID)
+		filters.VendorID = &id
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	handovers, total, err := h.service.GetHandovers(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get handovers", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Handovers retrieved successfully", handovers, page, limit, total)
+}
+
+// SetLimit sets a rider's cash limit
+// @Summary Set rider cash limit
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Rider ID"
+// @Param request body SetLimitRequest true "Limit, null for the platform default"
+// @Success 200 {object} pkg.Response{data=database.Rider}
+// @Router /admin/riders/{id}/cash-limit [put]
+func (h *Handler) SetLimit(c *gin.Context) {
+	riderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid rider ID", nil)
+		return
+	}
+
+	var req SetLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	rider, err := h.service.SetLimit(uint(riderID), &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to set cash limit", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Cash limit updated successfully", rider)
+}
+
+// GetDailyReport reconciles the cash each rider collected and handed over on a day
+// @Summary Daily cash reconciliation
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param date query string false "Day (YYYY-MM-DD), default today"
+// @Success 200 {object} pkg.Response{data=DailyReport}
+// @Router /admin/reports/cash [get]
+func (h *Handler) GetDailyReport(c *gin.Context) {
+	report, err := h.service.GetDailyReport(c.Query("date"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to generate cash report", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Cash report generated successfully", report)
+}
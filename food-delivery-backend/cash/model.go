@@ -0,0 +1,58 @@
+package cash
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+)
+
+type HandoverRequest struct {
+	RiderID uint      `json:"rider_id" binding:"required"`
+	Amount  pkg.Money `json:"amount" binding:"required,gt=0"`
+	Note    string    `json:"note"`
+}
+
+// SetLimitRequest sets a rider's own cash limit; a null limit goes back to
+// the platform default
+type SetLimitRequest struct {
+	CashLimit *pkg.Money `json:"cash_limit" binding:"omitempty,gte=0"`
+}
+
+type HandoverFilters struct {
+	RiderID  *uint
+	VendorID *uint
+}
+
+// Summary is the cash a rider is holding against their limit. CashLimit is
+// nil when the rider has no limit.
+type Summary struct {
+	RiderID    uint                    `json:"rider_id"`
+	CashInHand pkg.Money               `json:"cash_in_hand"`
+	CashLimit  *pkg.Money              `json:"cash_limit"`
+	OverLimit  bool                    `json:"over_limit"` // no new orders until cash is handed over
+	Handovers  []database.CashHandover `json:"recent_handovers"`
+}
+
+// RiderCashDay is one rider's cash movements over a day
+type RiderCashDay struct {
+	RiderID        uint       `json:"rider_id"`
+	UserID         uint       `json:"user_id"`
+	Name           string     `json:"name"`
+	OpeningBalance pkg.Money  `json:"opening_balance"`
+	Collected      pkg.Money  `json:"collected"`
+	CashOrders     int        `json:"cash_orders"`
+	HandedOver     pkg.Money  `json:"handed_over"`
+	Handovers      int        `json:"handovers"`
+	ClosingBalance pkg.Money  `json:"closing_balance"`
+	CashLimit      *pkg.Money `json:"cash_limit"`
+	OverLimit      bool       `json:"over_limit"`
+}
+
+// DailyReport reconciles the cash riders collected on a day with what they
+// handed over
+type DailyReport struct {
+	Date        string         `json:"date"`
+	Riders      []RiderCashDay `json:"riders"`
+	Collected   pkg.Money      `json:"collected"`
+	HandedOver  pkg.Money      `json:"handed_over"`
+	Outstanding pkg.Money      `json:"outstanding"` // held by riders at the end of the day
+}
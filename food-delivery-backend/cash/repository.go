@@ -0,0 +1,124 @@
+package cash
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// cashMovement sums a rider's rider_cash lines of one type
+type cashMovement struct {
+	UserID uint
+	Type   string
+	Amount pkg.Money
+	Lines  int
+}
+
+func (r *Repository) GetRiderByUserID(userID uint) (*database.Rider, error) {
+	var rider database.Rider
+	err := r.db.Where("user_id = ?", userID).First(&rider).Error
+	return &rider, err
+}
+
+func (r *Repository) GetRider(riderID uint) (*database.Rider, error) {
+	var rider database.Rider
+	err := r.db.Preload("User").First(&rider, riderID).Error
+	return &rider, err
+}
+
+func (r *Repository) GetVendorByUserID(userID uint) (*database.Vendor, error) {
+	var vendor database.Vendor
+	err := r.db.Where("user_id = ?", userID).First(&vendor).Error
+	return &vendor, err
+}
+
+func (r *Repository) SetCashLimit(riderID uint, limit *pkg.Money) error {
+	return r.db.Model(&database.Rider{}).Where("id = ?", riderID).Update("cash_limit", limit).Error
+}
+
+func (r *Repository) GetHandovers(filters *HandoverFilters, offset, limit int) ([]database.CashHandover, int64, error) {
+	var handovers []database.CashHandover
+	var total int64
+
+	query := r.db.Model(&database.CashHandover{})
+	if filters.RiderID != nil {
+		query = query.Where("rider_id = ?", *filters.RiderID)
+	}
+	if filters.VendorID != nil {
+		query = query.Where("vendor_id = ?", *filters.VendorID)
+	}
+
+	query.Count(&total)
+	err := query.Preload("Rider.User").
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&handovers).Error
+	return handovers, total, err
+}
+
+func (r *Repository) GetRecentHandovers(riderID uint, limit int) ([]database.CashHandover, error) {
+	var handovers []database.CashHandover
+	err := r.db.Where("rider_id = ?", riderID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&handovers).Error
+	return handovers, err
+}
+
+// GetCashBefore returns the cash each rider held at the given time, keyed by user id
+func (r *Repository) GetCashBefore(at time.Time) (map[uint]pkg.Money, error) {
+	var rows []struct {
+		UserID  uint
+		Balance pkg.Money
+	}
+	err := r.db.Model(&database.Transaction{}).
+		Select("user_id, COALESCE(SUM(amount), 0) AS balance").
+		Where("account = ? AND created_at < ?", database.LedgerAccountRiderCash, at).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Held cash is a debit in the ledger
+	held := make(map[uint]pkg.Money, len(rows))
+	for _, row := range rows {
+		held[row.UserID] = -row.Balance
+	}
+	return held, nil
+}
+
+func (r *Repository) GetCashMovements(start, end time.Time) ([]cashMovement, error) {
+	var rows []cashMovement
+	err := r.db.Model(&database.Transaction{}).
+		Select("user_id, type, COALESCE(SUM(amount), 0) AS amount, COUNT(*) AS lines").
+		Where("account = ? AND created_at >= ? AND created_at < ?", database.LedgerAccountRiderCash, start, end).
+		Where("type IN ?", []string{ledger.TypeCashCollected, ledger.TypeCashHandover}).
+		Group("user_id, type").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetRidersByUserID loads riders with their users, keyed by user id
+func (r *Repository) GetRidersByUserID(userIDs []uint) (map[uint]database.Rider, error) {
+	var riders []database.Rider
+	if err := r.db.Preload("User").Where("user_id IN ?", userIDs).Find(&riders).Error; err != nil {
+		return nil, err
+	}
+	byUser := make(map[uint]database.Rider, len(riders))
+	for _, rider := range riders {
+		byUser[rider.UserID] = rider
+	}
+	return byUser, nil
+}
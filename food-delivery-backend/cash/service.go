@@ -0,0 +1,265 @@
+package cash
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/pkg"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRiderNotFound   = errors.New("rider not found")
+	ErrVendorNotFound  = errors.New("vendor not found")
+	ErrLimitExceeded   = errors.New("rider is holding more cash than their limit and must hand it over before taking new orders")
+	ErrExceedsCashHeld = errors.New("handover is more than the cash the rider is holding")
+)
+
+// recentHandovers is how many handovers a rider's summary shows
+const recentHandovers = 10
+
+// Limit returns the cash the rider may hold before new assignments stop. ok
+// is false when the rider has no limit.
+func Limit(rider *database.Rider, cfg *config.Config) (limit pkg.Money, ok bool) {
+	if rider.CashLimit != nil {
+		return *rider.CashLimit, true
+	}
+	if cfg.RiderCashLimit <= 0 {
+		return 0, false
+	}
+	return pkg.NewMoney(cfg.RiderCashLimit), true
+}
+
+// OverLimit reports whether the rider holds more cash than their limit
+func OverLimit(rider *database.Rider, cfg *config.Config) bool {
+	limit, ok := Limit(rider, cfg)
+	return ok && rider.CashInHand > limit
+}
+
+type Service struct {
+	repo     *Repository
+	db       *gorm.DB
+	notifier *notifications.Service
+	cfg      *config.Config
+	logger   *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, notifier *notifications.Service, cfg *config.Config, logger *zap.Logger) *Service {
+	return &Service{
+		repo:     repo,
+		db:       db,
+		notifier: notifier,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+// GetSummary returns the cash the rider is holding. riderUserID is the user id.
+func (s *Service) GetSummary(riderUserID uint) (*Summary, error) {
+	rider, err := s.repo.GetRiderByUserID(riderUserID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+	handovers, err := s.repo.GetRecentHandovers(rider.ID, recentHandovers)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		RiderID:    rider.ID,
+		CashInHand: rider.CashInHand,
+		OverLimit:  OverLimit(rider, s.cfg),
+		Handovers:  handovers,
+	}
+	if limit, ok := Limit(rider, s.cfg); ok {
+		summary.CashLimit = &limit
+	}
+	return summary, nil
+}
+
+// RecordHandover records cash a rider handed to an admin, or to a vendor
+// taking it on the platform's behalf
+func (s *Service) RecordHandover(receiverID uint, role string, req *HandoverRequest) (*database.CashHandover, error) {
+	rider, err := s.repo.GetRider(req.RiderID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+
+	handover := &database.CashHandover{
+		RiderID:        rider.ID,
+		Amount:         req.Amount,
+		ReceivedByID:   receiverID,
+		ReceivedByRole: role,
+		Note:           req.Note,
+	}
+	posting := ledger.Handover{
+		RiderUserID: rider.UserID,
+		Amount:      req.Amount,
+		ReferenceID: fmt.Sprintf("cash_handover:%d:%s", rider.ID, pkg.GenerateTransactionID()),
+		Description: fmt.Sprintf("Cash handed over by rider #%d", rider.ID),
+		CreatedByID: &receiverID,
+	}
+	if role == "vendor" {
+		vendor, err := s.repo.GetVendorByUserID(receiverID)
+		if err != nil {
+			return nil, ErrVendorNotFound
+		}
+		handover.VendorID = &vendor.ID
+		posting.VendorUserID = &vendor.UserID
+		posting.Description += " to " + vendor.BusinessName
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		held, err := ledger.LockBalance(tx, database.LedgerAccountRiderCash, rider.UserID)
+		if err != nil {
+			return err
+		}
+		if req.Amount > held {
+			return fmt.Errorf("%w: %s held", ErrExceedsCashHeld, held)
+		}
+
+		entry, err := ledger.PostCashHandover(tx, posting)
+		if err != nil {
+			return err
+		}
+		handover.LedgerEntryID = entry.ID
+		if err := tx.Create(handover).Error; err != nil {
+			return err
+		}
+		rider.CashInHand = held - req.Amount
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrExceedsCashHeld) {
+			s.logger.Error("Failed to record cash handover", zap.Uint("rider_id", rider.ID), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	s.notifier.NotifyRider(rider.UserID, "Cash Handover Recorded",
+		fmt.Sprintf("Your handover of %s was recorded. You are holding %s.", req.Amount, rider.CashInHand),
+		"cash_handover", fmt.Sprintf("%d", handover.ID))
+	return handover, nil
+}
+
+// GetVendorHandovers lists the handovers a vendor received
+func (s *Service) GetVendorHandovers(vendorUserID uint, page, limit int) ([]database.CashHandover, int64, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorUserID)
+	if err != nil {
+		return nil, 0, ErrVendorNotFound
+	}
+	return s.GetHandovers(&HandoverFilters{VendorID: &vendor.ID}, page, limit)
+}
+
+// ================ ADMIN ================
+
+func (s *Service) GetHandovers(filters *HandoverFilters, page, limit int) ([]database.CashHandover, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetHandovers(filters, offset, limit)
+}
+
+// SetLimit overrides a rider's cash limit, or resets it to the platform
+// default when limit is nil
+func (s *Service) SetLimit(riderID uint, req *SetLimitRequest) (*database.Rider, error) {
+	rider, err := s.repo.GetRider(riderID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+	if err := s.repo.SetCashLimit(rider.ID, req.CashLimit); err != nil {
+		s.logger.Error("Failed to set rider cash limit", zap.Uint("rider_id", rider.ID), zap.Error(err))
+		return nil, errors.New("failed to set cash limit")
+	}
+	rider.CashLimit = req.CashLimit
+	return rider, nil
+}
+
+// GetDailyReport reconciles each rider's cash on dateStr (YYYY-MM-DD,
+// default today): what they held at the start of the day, collected,
+// handed over and still held at the end. Riders with no cash and no
+// movements are left out.
+func (s *Service) GetDailyReport(dateStr string) (*DailyReport, error) {
+	date := time.Now()
+	if dateStr != "" {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", dateStr, time.Local); err != nil {
+			return nil, errors.New("invalid date format")
+		}
+	}
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+
+	opening, err := s.repo.GetCashBefore(start)
+	if err != nil {
+		s.logger.Error("Failed to get opening cash balances", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+	movements, err := s.repo.GetCashMovements(start, end)
+	if err != nil {
+		s.logger.Error("Failed to get cash movements", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+
+	days := make(map[uint]*RiderCashDay)
+	day := func(userID uint) *RiderCashDay {
+		if d, ok := days[userID]; ok {
+			return d
+		}
+		d := &RiderCashDay{UserID: userID, OpeningBalance: opening[userID]}
+		days[userID] = d
+		return d
+	}
+	for userID, held := range opening {
+		if held != 0 {
+			day(userID)
+		}
+	}
+	for _, m := range movements {
+		d := day(m.UserID)
+		switch m.Type {
+		case ledger.TypeCashCollected:
+			d.Collected += -m.Amount
+			d.CashOrders += m.Lines
+		case ledger.TypeCashHandover:
+			d.HandedOver += m.Amount
+			d.Handovers += m.Lines
+		}
+	}
+
+	userIDs := make([]uint, 0, len(days))
+	for userID := range days {
+		userIDs = append(userIDs, userID)
+	}
+	riders, err := s.repo.GetRidersByUserID(userIDs)
+	if err != nil {
+		s.logger.Error("Failed to load riders for cash report", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+
+	report := &DailyReport{Date: start.Format("2006-01-02"), Riders: []RiderCashDay{}}
+	for _, d := range days {
+		d.ClosingBalance = d.OpeningBalance + d.Collected - d.HandedOver
+		if rider, ok := riders[d.UserID]; ok {
+			d.RiderID = rider.ID
+			d.Name = rider.User.FirstName + " " + rider.User.LastName
+			if limit, ok := Limit(&rider, s.cfg); ok {
+				d.CashLimit = &limit
+				d.OverLimit = d.ClosingBalance > limit
+			}
+		}
+		report.Collected += d.Collected
+		report.HandedOver += d.HandedOver
+		report.Outstanding += d.ClosingBalance
+		report.Riders = append(report.Riders, *d)
+	}
+	sort.Slice(report.Riders, func(i, j int) bool {
+		return report.Riders[i].ClosingBalance > report.Riders[j].ClosingBalance
+	})
+	return report, nil
+}
@@ -38,8 +38,63 @@ type Config struct {
     ServiceFeeRate       float64
     RiderEarningsRate    float64
     OrderTimeoutMinutes  int
+    PaymentTimeoutMinutes int    // card orders never authorized are cancelled after this
+    OrderExpiryAction    string // cancel or reject
+    OrderExpiryInterval  int    // seconds between expiry sweeps
+    VendorHoursInterval  int    // seconds between opening hours checks
     MaxOrderItems        int
     MaxOrderQuantity     int
+    MinVendorWithdrawal  float64
+    MinRiderWithdrawal   float64
+    RiderCashLimit       float64 // cash a rider may hold before new assignments stop, 0 for no limit
+
+    // Scheduled orders
+    ScheduledLeadMinutes    int // how soon a scheduled order may be for
+    ScheduledMaxDays        int // how far ahead orders may be scheduled
+    ScheduledReleaseMinutes int // how long before its slot a scheduled order reaches the vendor
+    ScheduledSlotMinutes    int
+    ScheduledSlotCapacity   int // scheduled orders per vendor per slot unless the vendor sets one, 0 for no limit
+    ScheduledOrderInterval  int // seconds between scheduler runs
+
+    // Delivery batches
+    RiderMaxBatchSize   int     // orders a rider may carry at once
+    BatchVendorRadiusKm float64 // how close vendors must be for their orders to share a batch
+
+    // Rider dispatch
+    DispatchOfferSeconds int     // how long a rider has to answer an offer
+    DispatchCandidates   int     // riders offered an order in turn each round
+    DispatchMaxRounds    int     // rounds before an order is escalated to admin
+    DispatchRadiusKm     float64 // how far from the vendor riders are searched
+    DispatchInterval     int     // seconds between dispatch sweeps
+
+    // Rider presence
+    RiderStaleSeconds     int // riders not heard from for this long are taken offline
+    PresenceSweepInterval int // seconds between presence sweeps
+
+    // Rider shifts
+    ShiftCheckInEarlyMinutes int     // how long before a shift riders may check in
+    ShiftNoShowMinutes       int     // how late riders may check in before it counts as a no-show
+    ShiftCancelCutoffMinutes int     // bookings can't be cancelled closer than this to the start
+    ShiftInterval            int     // seconds between shift sweeps
+    ForecastWeeks            int     // past weeks averaged for the demand forecast
+    RiderOrdersPerHour       float64 // orders one rider can deliver in an hour
+
+    // Rider location history
+    LocationSampleSeconds int // minimum seconds between stored samples of a rider
+    LocationRetentionDays int // days location samples are kept
+    LocationPruneInterval int // seconds between retention sweeps
+
+    // Handoff codes
+    DeliveryCodeRequired bool // riders must enter the student's code to deliver
+    PickupCodeRequired   bool // riders must enter the vendor's code to pick up
+    HandoffMaxAttempts   int  // wrong codes allowed before the order is locked
+    HandoffLockMinutes   int  // how long a locked order refuses codes
+
+    // Payments
+    PaymentProvider      string // mock
+    PaymentWebhookSecret string
+    MinWalletTopUp       float64
+    MaxWalletTopUp       float64
 
     // File Upload
     MaxUploadSize      int64
@@ -112,8 +167,63 @@ func LoadConfig() *Config {
         ServiceFeeRate:       getEnvAsFloat("SERVICE_FEE_RATE", 0.05),
         RiderEarningsRate:    getEnvAsFloat("RIDER_EARNINGS_RATE", 0.80),
         OrderTimeoutMinutes:  getEnvAsInt("ORDER_TIMEOUT_MINUTES", 30),
+        PaymentTimeoutMinutes: getEnvAsInt("PAYMENT_TIMEOUT_MINUTES", 15),
+        OrderExpiryAction:    getEnv("ORDER_EXPIRY_ACTION", "cancel"),
+        OrderExpiryInterval:  getEnvAsInt("ORDER_EXPIRY_INTERVAL_SECONDS", 60),
+        VendorHoursInterval:  getEnvAsInt("VENDOR_HOURS_INTERVAL_SECONDS", 60),
         MaxOrderItems:        getEnvAsInt("MAX_ORDER_ITEMS", 50),
         MaxOrderQuantity:     getEnvAsInt("MAX_ORDER_QUANTITY_PER_ITEM", 10),
+        MinVendorWithdrawal:  getEnvAsFloat("MIN_VENDOR_WITHDRAWAL", 20),
+        MinRiderWithdrawal:   getEnvAsFloat("MIN_RIDER_WITHDRAWAL", 10),
+        RiderCashLimit:       getEnvAsFloat("RIDER_CASH_LIMIT", 150),
+
+        // Scheduled orders
+        ScheduledLeadMinutes:    getEnvAsInt("SCHEDULED_ORDER_LEAD_MINUTES", 60),
+        ScheduledMaxDays:        getEnvAsInt("SCHEDULED_ORDER_MAX_DAYS", 7),
+        ScheduledReleaseMinutes: getEnvAsInt("SCHEDULED_ORDER_RELEASE_MINUTES", 45),
+        ScheduledSlotMinutes:    getEnvAsInt("SCHEDULED_SLOT_MINUTES", 30),
+        ScheduledSlotCapacity:   getEnvAsInt("SCHEDULED_SLOT_CAPACITY", 10),
+        ScheduledOrderInterval:  getEnvAsInt("SCHEDULED_ORDER_INTERVAL_SECONDS", 60),
+
+        // Delivery batches
+        RiderMaxBatchSize:   getEnvAsInt("RIDER_MAX_BATCH_SIZE", 3),
+        BatchVendorRadiusKm: getEnvAsFloat("BATCH_VENDOR_RADIUS_KM", 0.3),
+
+        // Rider dispatch
+        DispatchOfferSeconds: getEnvAsInt("DISPATCH_OFFER_SECONDS", 30),
+        DispatchCandidates:   getEnvAsInt("DISPATCH_CANDIDATES_PER_ROUND", 3),
+        DispatchMaxRounds:    getEnvAsInt("DISPATCH_MAX_ROUNDS", 3),
+        DispatchRadiusKm:     getEnvAsFloat("DISPATCH_RADIUS_KM", 5),
+        DispatchInterval:     getEnvAsInt("DISPATCH_INTERVAL_SECONDS", 5),
+
+        // Rider presence
+        RiderStaleSeconds:     getEnvAsInt("RIDER_STALE_SECONDS", 120),
+        PresenceSweepInterval: getEnvAsInt("PRESENCE_SWEEP_INTERVAL_SECONDS", 30),
+
+        // Rider shifts
+        ShiftCheckInEarlyMinutes: getEnvAsInt("SHIFT_CHECK_IN_EARLY_MINUTES", 15),
+        ShiftNoShowMinutes:       getEnvAsInt("SHIFT_NO_SHOW_MINUTES", 15),
+        ShiftCancelCutoffMinutes: getEnvAsInt("SHIFT_CANCEL_CUTOFF_MINUTES", 120),
+        ShiftInterval:            getEnvAsInt("SHIFT_INTERVAL_SECONDS", 60),
+        ForecastWeeks:            getEnvAsInt("FORECAST_WEEKS", 4),
+        RiderOrdersPerHour:       getEnvAsFloat("RIDER_ORDERS_PER_HOUR", 3),
+
+        // Rider location history
+        LocationSampleSeconds: getEnvAsInt("LOCATION_SAMPLE_SECONDS", 10),
+        LocationRetentionDays: getEnvAsInt("LOCATION_RETENTION_DAYS", 30),
+        LocationPruneInterval: getEnvAsInt("LOCATION_PRUNE_INTERVAL_SECONDS", 3600),
+
+        // Handoff codes
+        DeliveryCodeRequired: getEnvAsBool("DELIVERY_CODE_REQUIRED", true),
+        PickupCodeRequired:   getEnvAsBool("PICKUP_CODE_REQUIRED", false),
+        HandoffMaxAttempts:   getEnvAsInt("HANDOFF_MAX_ATTEMPTS", 5),
+        HandoffLockMinutes:   getEnvAsInt("HANDOFF_LOCK_MINUTES", 15),
+
+        // Payments
+        PaymentProvider:      getEnv("PAYMENT_PROVIDER", "mock"),
+        PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", "mock-webhook-secret"),
+        MinWalletTopUp:       getEnvAsFloat("MIN_WALLET_TOP_UP", 5),
+        MaxWalletTopUp:       getEnvAsFloat("MAX_WALLET_TOP_UP", 200),
 
         // File Upload
         MaxUploadSize:      getEnvAsInt64("MAX_UPLOAD_SIZE", 5) * 1024 * 1024, // Convert MB to bytes
@@ -0,0 +1,222 @@
+package coupons
+
+import (
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateCoupon creates a promo code
+// @Summary Create coupon
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateCouponRequest true "Coupon details"
+// @Success 201 {object} pkg.Response{data=database.Coupon}
+// @Router /admin/coupons [post]
+func (h *Handler) CreateCoupon(c *gin.Context) {
+	var req CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	coupon, err := h.service.CreateCoupon(&req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to create coupon", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Coupon created successfully", coupon)
+}
+
+// GetCoupons lists coupons
+// @Summary List coupons
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Filter by vendor"
+// @Param active query bool false "Filter by active flag"
+// @Param search query string false "Search by code"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/coupons [get]
+func (h *Handler) GetCoupons(c *gin.Context) {
+	var filters CouponFilters
+	if v := c.Query("vendor_id"); v != "" {
+		vendorID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid vendor ID", nil)
+			return
+		}
+		id := uint(vendorID)
+		filters.VendorID = &id
+	}
+	if v := c.Query("active"); v != "" {
+		active := v == "true"
+		filters.Active = &active
+	}
+	filters.Search = c.Query("search")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	coupons, total, err := h.service.GetCoupons(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get coupons", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Coupons retrieved successfully", coupons, page, limit, total)
+}
+
+// GetCoupon returns a coupon
+// @Summary Get coupon
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.Coupon}
+// @Router /admin/coupons/{id} [get]
+func (h *Handler) GetCoupon(c *gin.Context) {
+	couponID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid coupon ID", nil)
+		return
+	}
+
+	coupon, err := h.service.GetCoupon(uint(couponID))
+	if err != nil {
+		pkg.SendError(c, http.StatusNotFound, "Coupon not found", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Coupon retrieved successfully", coupon)
+}
+
+// UpdateCoupon edits a coupon
+// @Summary Update coupon
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Accept json
+// @Produce json
+// @Param request body UpdateCouponRequest true "Fields to change"
+// @Success 200 {object} pkg.Response{data=database.Coupon}
+// @Router /admin/coupons/{id} [put]
+func (h *Handler) UpdateCoupon(c *gin.Context) {
+	couponID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid coupon ID", nil)
+		return
+	}
+
+	var req UpdateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	coupon, err := h.service.UpdateCoupon(uint(couponID), &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to update coupon", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Coupon updated successfully", coupon)
+}
+
+// DeleteCoupon removes a coupon; past redemptions are kept
+// @Summary Delete coupon
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} pkg.Response
+// @Router /admin/coupons/{id} [delete]
+func (h *Handler) DeleteCoupon(c *gin.Context) {
+	couponID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid coupon ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteCoupon(uint(couponID)); err != nil {
+		pkg.SendError(c, http.StatusNotFound, "Failed to delete coupon", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Coupon deleted successfully", nil)
+}
+
+// GetCouponRedemptions lists the orders a coupon was used on
+// @Summary Get coupon redemptions
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Produce json
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/coupons/{id}/redemptions [get]
+func (h *Handler) GetCouponRedemptions(c *gin.Context) {
+	couponID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid coupon ID", nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	redemptions, total, err := h.service.GetRedemptions(uint(couponID), page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get redemptions", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Redemptions retrieved successfully", redemptions, page, limit, total)
+}
+
+// GetRedemptionReport summarises coupon usage
+// @Summary Get coupon redemption report
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} pkg.Response{data=RedemptionReport}
+// @Router /admin/reports/coupons [get]
+func (h *Handler) GetRedemptionReport(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+
+	if startDate == "" || endDate == "" {
+		pkg.SendError(c, http.StatusBadRequest, "Start date and end date are required", nil)
+		return
+	}
+
+	report, err := h.service.GetRedemptionReport(startDate, endDate)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to generate report", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Coupon report generated", report)
+}
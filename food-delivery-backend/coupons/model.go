@@ -0,0 +1,68 @@
+package coupons
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"time"
+)
+
+type CreateCouponRequest struct {
+	Code           string              `json:"code" binding:"required,min=3,max=32"`
+	Description    string              `json:"description"`
+	Type           database.CouponType `json:"type" binding:"required,oneof=percentage fixed free_delivery"`
+	Percent        float64             `json:"percent" binding:"min=0,max=100"`
+	Amount         pkg.Money           `json:"amount" binding:"min=0"`
+	MaxDiscount    pkg.Money           `json:"max_discount" binding:"min=0"`
+	VendorID       *uint               `json:"vendor_id"`
+	MinSubtotal    pkg.Money           `json:"min_subtotal" binding:"min=0"`
+	StartsAt       *time.Time          `json:"starts_at"`
+	EndsAt         *time.Time          `json:"ends_at"`
+	UsageLimit     int                 `json:"usage_limit" binding:"min=0"`
+	PerUserLimit   int                 `json:"per_user_limit" binding:"min=0"`
+	FirstOrderOnly bool                `json:"first_order_only"`
+	IsActive       *bool               `json:"is_active"`
+}
+
+// UpdateCouponRequest edits a coupon; omitted fields are unchanged. The code,
+// type and vendor cannot change once a coupon exists.
+type UpdateCouponRequest struct {
+	Description    *string    `json:"description"`
+	Percent        *float64   `json:"percent" binding:"omitempty,min=0,max=100"`
+	Amount         *pkg.Money `json:"amount" binding:"omitempty,min=0"`
+	MaxDiscount    *pkg.Money `json:"max_discount" binding:"omitempty,min=0"`
+	MinSubtotal    *pkg.Money `json:"min_subtotal" binding:"omitempty,min=0"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	UsageLimit     *int       `json:"usage_limit" binding:"omitempty,min=0"`
+	PerUserLimit   *int       `json:"per_user_limit" binding:"omitempty,min=0"`
+	FirstOrderOnly *bool      `json:"first_order_only"`
+	IsActive       *bool      `json:"is_active"`
+}
+
+type CouponFilters struct {
+	VendorID *uint
+	Active   *bool
+	Search   string
+}
+
+// CouponUsage summarises redemptions of one coupon over a period
+type CouponUsage struct {
+	CouponID      uint      `json:"coupon_id"`
+	Code          string    `json:"code"`
+	Type          string    `json:"type"`
+	VendorID      *uint     `json:"vendor_id"`
+	Redemptions   int64     `json:"redemptions"`
+	UniqueUsers   int64     `json:"unique_users"`
+	TotalDiscount pkg.Money `json:"total_discount"`
+	OrderRevenue  pkg.Money `json:"order_revenue"` // total paid on the orders that used it
+}
+
+type RedemptionReport struct {
+	StartDate        string        `json:"start_date"`
+	EndDate          string        `json:"end_date"`
+	TotalDiscount    pkg.Money     `json:"total_discount"`
+	PlatformFunded   pkg.Money     `json:"platform_funded"`
+	VendorFunded     pkg.Money     `json:"vendor_funded"`
+	TotalRedemptions int64         `json:"total_redemptions"`
+	Coupons          []CouponUsage `json:"coupons"`
+}
@@ -0,0 +1,126 @@
+package coupons
+
+import (
+	"food-delivery-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) CreateCoupon(coupon *database.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *Repository) UpdateCoupon(coupon *database.Coupon) error {
+	return r.db.Omit("Vendor").Save(coupon).Error
+}
+
+func (r *Repository) DeleteCoupon(couponID uint) (bool, error) {
+	res := r.db.Delete(&database.Coupon{}, couponID)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *Repository) GetCouponByID(couponID uint) (*database.Coupon, error) {
+	var coupon database.Coupon
+	err := r.db.Preload("Vendor").First(&coupon, couponID).Error
+	return &coupon, err
+}
+
+func (r *Repository) GetCouponByCode(code string) (*database.Coupon, error) {
+	var coupon database.Coupon
+	err := r.db.Where("code = ?", code).First(&coupon).Error
+	return &coupon, err
+}
+
+func (r *Repository) CodeExists(code string) bool {
+	var count int64
+	r.db.Unscoped().Model(&database.Coupon{}).Where("code = ?", code).Count(&count)
+	return count > 0
+}
+
+func (r *Repository) VendorExists(vendorID uint) bool {
+	var count int64
+	r.db.Model(&database.Vendor{}).Where("id = ?", vendorID).Count(&count)
+	return count > 0
+}
+
+func (r *Repository) GetCoupons(filters *CouponFilters, offset, limit int) ([]database.Coupon, int64, error) {
+	var coupons []database.Coupon
+	var total int64
+
+	query := r.db.Model(&database.Coupon{}).Preload("Vendor")
+	if filters.VendorID != nil {
+		query = query.Where("vendor_id = ?", *filters.VendorID)
+	}
+	if filters.Active != nil {
+		query = query.Where("is_active = ?", *filters.Active)
+	}
+	if filters.Search != "" {
+		query = query.Where("code ILIKE ?", "%"+filters.Search+"%")
+	}
+
+	query.Count(&total)
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&coupons).Error
+	return coupons, total, err
+}
+
+// CountUserRedemptions counts the student's unreleased uses of a coupon
+func (r *Repository) CountUserRedemptions(db *gorm.DB, couponID, studentID uint) int64 {
+	var count int64
+	db.Model(&database.CouponRedemption{}).
+		Where("coupon_id = ? AND student_id = ? AND released_at IS NULL", couponID, studentID).
+		Count(&count)
+	return count
+}
+
+// CountPlacedOrders counts the student's orders that were not cancelled or
+// rejected, leaving out exceptOrderID
+func (r *Repository) CountPlacedOrders(db *gorm.DB, studentID, exceptOrderID uint) int64 {
+	var count int64
+	db.Model(&database.Order{}).
+		Where("student_id = ? AND id <> ? AND status NOT IN ?", studentID, exceptOrderID,
+			[]database.OrderStatus{database.OrderStatusCancelled, database.OrderStatusRejected}).
+		Count(&count)
+	return count
+}
+
+// LockStudent takes a row lock on the student for the rest of tx
+func (r *Repository) LockStudent(tx *gorm.DB, studentID uint) error {
+	var student database.Student
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&student, studentID).Error
+}
+
+func (r *Repository) GetRedemptions(couponID uint, offset, limit int) ([]database.CouponRedemption, int64, error) {
+	var redemptions []database.CouponRedemption
+	var total int64
+
+	query := r.db.Model(&database.CouponRedemption{}).Where("coupon_id = ?", couponID)
+	query.Count(&total)
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&redemptions).Error
+	return redemptions, total, err
+}
+
+func (r *Repository) GetUsage(startDate, endDate time.Time) ([]CouponUsage, error) {
+	var usage []CouponUsage
+	err := r.db.Raw(`SELECT c.id AS coupon_id, c.code, c.type, c.vendor_id,
+			COUNT(cr.id) AS redemptions,
+			COUNT(DISTINCT cr.student_id) AS unique_users,
+			COALESCE(SUM(cr.discount_amount), 0) AS total_discount,
+			COALESCE(SUM(o.total_amount), 0) AS order_revenue
+		FROM coupon_redemptions cr
+		JOIN coupons c ON c.id = cr.coupon_id
+		JOIN orders o ON o.id = cr.order_id
+		WHERE cr.released_at IS NULL AND cr.created_at BETWEEN ? AND ?
+		GROUP BY c.id, c.code, c.type, c.vendor_id
+		ORDER BY total_discount DESC`, startDate, endDate).Scan(&usage).Error
+	return usage, err
+}
@@ -0,0 +1,355 @@
+package coupons
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CodeCouponNotApplicable is the API error code for coupons that cannot be used
+const CodeCouponNotApplicable = "COUPON_NOT_APPLICABLE"
+
+var ErrCouponNotApplicable = errors.New("coupon cannot be applied")
+
+type Service struct {
+	repo   *Repository
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		db:     db,
+		logger: logger,
+	}
+}
+
+// NormalizeCode makes codes case-insensitive
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ApplyInput describes the order a coupon is being applied to. StudentID is
+// the Student.ID, or 0 when the caller is not a student (per-user and
+// first-order rules are then skipped).
+type ApplyInput struct {
+	Code        string
+	VendorID    uint
+	StudentID   uint
+	Subtotal    pkg.Money
+	DeliveryFee pkg.Money
+	At          time.Time
+}
+
+// Discount is the effect of a valid coupon on an order
+type Discount struct {
+	Coupon           *database.Coupon
+	ItemDiscount     pkg.Money // taken off the subtotal
+	DeliveryDiscount pkg.Money // taken off the delivery fee
+}
+
+func (d *Discount) Total() pkg.Money {
+	return d.ItemDiscount + d.DeliveryDiscount
+}
+
+// VendorFunded reports whether the vendor bears the cost of the discount
+func (d *Discount) VendorFunded() bool {
+	return d.Coupon.VendorID != nil
+}
+
+func notApplicable(reason string) error {
+	return fmt.Errorf("%w: %s", ErrCouponNotApplicable, reason)
+}
+
+// Evaluate checks a coupon against an order and computes the discount. It
+// only reads; the usage is recorded by Redeem.
+func (s *Service) Evaluate(in ApplyInput) (*Discount, error) {
+	coupon, err := s.repo.GetCouponByCode(NormalizeCode(in.Code))
+	if err != nil {
+		return nil, notApplicable("coupon not found")
+	}
+
+	if !coupon.IsActive {
+		return nil, notApplicable("coupon is no longer active")
+	}
+	if coupon.StartsAt != nil && in.At.Before(*coupon.StartsAt) {
+		return nil, notApplicable("coupon is not valid yet")
+	}
+	if coupon.EndsAt != nil && in.At.After(*coupon.EndsAt) {
+		return nil, notApplicable("coupon has expired")
+	}
+	if coupon.VendorID != nil && *coupon.VendorID != in.VendorID {
+		return nil, notApplicable("coupon is not valid for this vendor")
+	}
+	if in.Subtotal < coupon.MinSubtotal {
+		return nil, notApplicable(fmt.Sprintf("requires a subtotal of at least %s", coupon.MinSubtotal))
+	}
+	if coupon.UsageLimit > 0 && coupon.TimesRedeemed >= coupon.UsageLimit {
+		return nil, notApplicable("coupon has been fully redeemed")
+	}
+	if in.StudentID != 0 {
+		if coupon.PerUserLimit > 0 && s.repo.CountUserRedemptions(s.db, coupon.ID, in.StudentID) >= int64(coupon.PerUserLimit) {
+			return nil, notApplicable("you have already used this coupon")
+		}
+		if coupon.FirstOrderOnly && s.repo.CountPlacedOrders(s.db, in.StudentID, 0) > 0 {
+			return nil, notApplicable("coupon is only valid on your first order")
+		}
+	}
+
+	d := &Discount{Coupon: coupon}
+	switch coupon.Type {
+	case database.CouponTypePercentage:
+		d.ItemDiscount = in.Subtotal.MulRate(coupon.Percent / 100)
+		if coupon.MaxDiscount > 0 {
+			d.ItemDiscount = d.ItemDiscount.Min(coupon.MaxDiscount)
+		}
+	case database.CouponTypeFixed:
+		d.ItemDiscount = coupon.Amount.Min(in.Subtotal)
+	case database.CouponTypeFreeDelivery:
+		d.DeliveryDiscount = in.DeliveryFee
+	}
+
+	return d, nil
+}
+
+// Redeem records the coupon against a newly created order inside the order's
+// transaction. Limits are checked again under a row lock so concurrent
+// checkouts cannot overshoot them; first-order coupons also lock the student
+// so two first orders can't both use one.
+func (s *Service) Redeem(tx *gorm.DB, d *Discount, order *database.Order) error {
+	var coupon database.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, d.Coupon.ID).Error; err != nil {
+		return notApplicable("coupon not found")
+	}
+	if coupon.UsageLimit > 0 && coupon.TimesRedeemed >= coupon.UsageLimit {
+		return notApplicable("coupon has been fully redeemed")
+	}
+	if coupon.PerUserLimit > 0 && s.repo.CountUserRedemptions(tx, coupon.ID, order.StudentID) >= int64(coupon.PerUserLimit) {
+		return notApplicable("you have already used this coupon")
+	}
+	if coupon.FirstOrderOnly {
+		if err := s.repo.LockStudent(tx, order.StudentID); err != nil {
+			return err
+		}
+		if s.repo.CountPlacedOrders(tx, order.StudentID, order.ID) > 0 {
+			return notApplicable("coupon is only valid on your first order")
+		}
+	}
+
+	if err := tx.Model(&database.Coupon{}).Where("id = ?", coupon.ID).
+		Update("times_redeemed", gorm.Expr("times_redeemed + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Create(&database.CouponRedemption{
+		CouponID:       coupon.ID,
+		OrderID:        order.ID,
+		StudentID:      order.StudentID,
+		VendorID:       order.VendorID,
+		DiscountAmount: d.Total(),
+	}).Error
+}
+
+// ReleaseForOrder gives back the coupon use of an order that was cancelled
+// or rejected. It is a no-op when the order used no coupon.
+func ReleaseForOrder(tx *gorm.DB, orderID uint) error {
+	var redemption database.CouponRedemption
+	err := tx.Where("order_id = ? AND released_at IS NULL", orderID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&redemption).Update("released_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return tx.Model(&database.Coupon{}).
+		Where("id = ? AND times_redeemed > 0", redemption.CouponID).
+		Update("times_redeemed", gorm.Expr("times_redeemed - 1")).Error
+}
+
+// ================ ADMIN ================
+
+func validateCoupon(coupon *database.Coupon) error {
+	switch coupon.Type {
+	case database.CouponTypePercentage:
+		if coupon.Percent <= 0 || coupon.Percent > 100 {
+			return errors.New("percent must be between 0 and 100")
+		}
+	case database.CouponTypeFixed:
+		if coupon.Amount <= 0 {
+			return errors.New("fixed discount amount must be greater than 0")
+		}
+	}
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+func (s *Service) CreateCoupon(req *CreateCouponRequest) (*database.Coupon, error) {
+	code := NormalizeCode(req.Code)
+	if s.repo.CodeExists(code) {
+		return nil, errors.New("coupon code already exists")
+	}
+	if req.VendorID != nil && !s.repo.VendorExists(*req.VendorID) {
+		return nil, errors.New("vendor not found")
+	}
+
+	coupon := &database.Coupon{
+		Code:           code,
+		Description:    req.Description,
+		Type:           req.Type,
+		Percent:        req.Percent,
+		Amount:         req.Amount,
+		MaxDiscount:    req.MaxDiscount,
+		VendorID:       req.VendorID,
+		MinSubtotal:    req.MinSubtotal,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		UsageLimit:     req.UsageLimit,
+		PerUserLimit:   req.PerUserLimit,
+		FirstOrderOnly: req.FirstOrderOnly,
+		IsActive:       true,
+	}
+	if req.IsActive != nil {
+		coupon.IsActive = *req.IsActive
+	}
+	if err := validateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCoupon(coupon); err != nil {
+		s.logger.Error("Failed to create coupon", zap.Error(err))
+		return nil, errors.New("failed to create coupon")
+	}
+	// A false IsActive is skipped by Create because of the column default
+	if !coupon.IsActive {
+		s.db.Model(coupon).Update("is_active", false)
+	}
+	return coupon, nil
+}
+
+func (s *Service) UpdateCoupon(couponID uint, req *UpdateCouponRequest) (*database.Coupon, error) {
+	coupon, err := s.repo.GetCouponByID(couponID)
+	if err != nil {
+		return nil, errors.New("coupon not found")
+	}
+
+	if req.Description != nil {
+		coupon.Description = *req.Description
+	}
+	if req.Percent != nil {
+		coupon.Percent = *req.Percent
+	}
+	if req.Amount != nil {
+		coupon.Amount = *req.Amount
+	}
+	if req.MaxDiscount != nil {
+		coupon.MaxDiscount = *req.MaxDiscount
+	}
+	if req.MinSubtotal != nil {
+		coupon.MinSubtotal = *req.MinSubtotal
+	}
+	if req.StartsAt != nil {
+		coupon.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		coupon.EndsAt = req.EndsAt
+	}
+	if req.UsageLimit != nil {
+		coupon.UsageLimit = *req.UsageLimit
+	}
+	if req.PerUserLimit != nil {
+		coupon.PerUserLimit = *req.PerUserLimit
+	}
+	if req.FirstOrderOnly != nil {
+		coupon.FirstOrderOnly = *req.FirstOrderOnly
+	}
+	if req.IsActive != nil {
+		coupon.IsActive = *req.IsActive
+	}
+	if err := validateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateCoupon(coupon); err != nil {
+		s.logger.Error("Failed to update coupon", zap.Error(err))
+		return nil, errors.New("failed to update coupon")
+	}
+	return coupon, nil
+}
+
+func (s *Service) DeleteCoupon(couponID uint) error {
+	deleted, err := s.repo.DeleteCoupon(couponID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("coupon not found")
+	}
+	return nil
+}
+
+func (s *Service) GetCoupon(couponID uint) (*database.Coupon, error) {
+	coupon, err := s.repo.GetCouponByID(couponID)
+	if err != nil {
+		return nil, errors.New("coupon not found")
+	}
+	return coupon, nil
+}
+
+func (s *Service) GetCoupons(filters *CouponFilters, page, limit int) ([]database.Coupon, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetCoupons(filters, offset, limit)
+}
+
+func (s *Service) GetRedemptions(couponID uint, page, limit int) ([]database.CouponRedemption, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetRedemptions(couponID, offset, limit)
+}
+
+// GetRedemptionReport summarises coupon usage between two dates (YYYY-MM-DD)
+func (s *Service) GetRedemptionReport(startDateStr, endDateStr string) (*RedemptionReport, error) {
+	startDate, err := time.Parse("2006-01-02", startDateStr)
+	if err != nil {
+		return nil, errors.New("invalid start date format")
+	}
+
+	endDate, err := time.Parse("2006-01-02", endDateStr)
+	if err != nil {
+		return nil, errors.New("invalid end date format")
+	}
+	endDate = endDate.Add(24*time.Hour - time.Second)
+
+	usage, err := s.repo.GetUsage(startDate, endDate)
+	if err != nil {
+		s.logger.Error("Failed to get coupon usage", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+
+	report := &RedemptionReport{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Coupons:   usage,
+	}
+	for _, u := range usage {
+		report.TotalDiscount += u.TotalDiscount
+		report.TotalRedemptions += u.Redemptions
+		if u.VendorID != nil {
+			report.VendorFunded += u.TotalDiscount
+		} else {
+			report.PlatformFunded += u.TotalDiscount
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// moneyColumns are the columns that used to hold float major units and now
+// hold integer minor units (see pkg.Money)
+var moneyColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"students", []string{"total_spent"}},
+	{"vendors", []string{"minimum_order", "total_revenue", "total_earnings", "current_balance"}},
+	{"riders", []string{"total_earnings", "current_balance"}},
+	{"menu_items", []string{"price", "discount_price"}},
+	{"orders", []string{
+		"subtotal", "delivery_fee", "service_fee", "total_amount", "commission_amount",
+		"vendor_earnings", "rider_earnings", "discount_amount",
+		"pricing_base_fare", "pricing_distance_fare",
+	}},
+	{"order_items", []string{"unit_price", "subtotal"}},
+	{"payments", []string{"amount"}},
+	{"transactions", []string{"amount"}},
+	{"pricing_rules", []string{"base_fare", "per_km_rate", "min_delivery_fee", "max_delivery_fee"}},
+	{"coupons", []string{"max_discount", "min_subtotal"}},
+	{"coupon_redemptions", []string{"discount_amount"}},
+}
+
+// migrateMoneyColumns converts float money columns to bigint cents. It runs
+// before AutoMigrate, which would otherwise cast 12.34 to 12. Columns that
+// are already integers are skipped, so it is safe to run on every start.
+func migrateMoneyColumns(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range moneyColumns {
+			for _, col := range t.columns {
+				var dataType string
+				if err := tx.Raw(`SELECT data_type FROM information_schema.columns
+					WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+					t.table, col).Scan(&dataType).Error; err != nil {
+					return err
+				}
+				if dataType != "double precision" && dataType != "numeric" && dataType != "real" {
+					continue
+				}
+
+				// The old default can't be cast; AutoMigrate puts it back
+				stmt := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q DROP DEFAULT,
+					ALTER COLUMN %q TYPE bigint USING ROUND(%q * 100)::bigint`, t.table, col, col, col)
+				if err := tx.Exec(stmt).Error; err != nil {
+					return fmt.Errorf("converting %s.%s to cents: %w", t.table, col, err)
+				}
+				log.Printf("Converted %s.%s to minor units", t.table, col)
+			}
+		}
+		return nil
+	})
+}
+
+// migrateCouponValue splits the old coupons.value column, which held either
+// a percentage or a fixed amount, into percent and amount. It runs after
+// AutoMigrate has added the new columns.
+func migrateCouponValue(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("coupons", "value") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE coupons SET percent = value WHERE type = ?", CouponTypePercentage).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE coupons SET amount = ROUND(value * 100)::bigint WHERE type = ?", CouponTypeFixed).Error; err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE coupons DROP COLUMN value").Error
+	})
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"food-delivery-backend/pkg"
 	"time"
 
 	"gorm.io/gorm"
@@ -31,10 +32,15 @@ const (
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusCompleted PaymentStatus = "completed"
-	PaymentStatusFailed    PaymentStatus = "failed"
-	PaymentStatusRefunded  PaymentStatus = "refunded"
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusAuthorized PaymentStatus = "authorized" // card held, not yet captured
+	PaymentStatusCompleted  PaymentStatus = "completed"
+	PaymentStatusFailed     PaymentStatus = "failed"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+	// PaymentStatusRefundPending marks a payment owed back to the customer
+	PaymentStatusRefundPending PaymentStatus = "refund_pending"
+	// PaymentStatusPartiallyRefunded marks a payment with part of it refunded
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
 type PaymentMethod string
@@ -45,6 +51,12 @@ const (
 	PaymentMethodWallet PaymentMethod = "wallet"
 )
 
+// What happens to new orders once a vendor's kitchen is at capacity
+const (
+	CapacityActionReject = "reject"
+	CapacityActionExtend = "extend"
+)
+
 type User struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time      `json:"created_at"`
@@ -75,14 +87,15 @@ type Student struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	UserID           uint    `gorm:"uniqueIndex;not null" json:"user_id"`
-	User             User    `json:"user"`
-	StudentID        string  `gorm:"uniqueIndex;not null" json:"student_id"` // Add this field
-	DefaultAddress   string  `json:"default_address"`
-	DefaultLatitude  float64 `json:"default_latitude"`
-	DefaultLongitude float64 `json:"default_longitude"`
-	TotalOrders      int     `gorm:"default:0" json:"total_orders"`
-	TotalSpent       float64 `gorm:"default:0" json:"total_spent"`
+	UserID           uint      `gorm:"uniqueIndex;not null" json:"user_id"`
+	User             User      `json:"user"`
+	StudentID        string    `gorm:"uniqueIndex;not null" json:"student_id"` // Add this field
+	DefaultAddress   string    `json:"default_address"`
+	DefaultLatitude  float64   `json:"default_latitude"`
+	DefaultLongitude float64   `json:"default_longitude"`
+	TotalOrders      int       `gorm:"default:0" json:"total_orders"`
+	TotalSpent       pkg.Money `gorm:"default:0" json:"total_spent"`
+	WalletBalance    pkg.Money `gorm:"default:0" json:"wallet_balance"` // cached sum of the student's wallet ledger lines
 
 	Orders []Order `json:"orders,omitempty"`
 }
@@ -92,30 +105,61 @@ type Vendor struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	UserID          uint    `gorm:"uniqueIndex;not null" json:"user_id"`
-	User            User    `json:"user"`
-	BusinessName    string  `gorm:"not null" json:"business_name"`
-	BusinessAddress string  `gorm:"not null" json:"business_address"`
-	Latitude        float64 `json:"latitude"`
-	Longitude       float64 `json:"longitude"`
-	Phone           string  `json:"phone"`
-	Description     string  `json:"description"`
-	LogoURL         string  `json:"logo_url"`
-	CoverImageURL   string  `json:"cover_image_url"`
-	IsOpen          bool    `gorm:"default:false" json:"is_open"`
-	CommissionRate  float64 `gorm:"default:0.15" json:"commission_rate"`
-	DeliveryRadius  float64 `gorm:"default:5.0" json:"delivery_radius"`  // in km
-	AveragePrepTime int     `gorm:"default:15" json:"average_prep_time"` // in minutes
-	MinimumOrder    float64 `gorm:"default:0" json:"minimum_order"`
-	TotalOrders     int     `gorm:"default:0" json:"total_orders"`
-	TotalRevenue    float64 `gorm:"default:0" json:"total_revenue"`
-	TotalEarnings   float64 `gorm:"default:0" json:"total_earnings"`
-	CurrentBalance  float64 `gorm:"default:0" json:"current_balance"`
-	Rating          float64 `gorm:"default:0" json:"rating"`
-	ReviewCount     int     `gorm:"default:0" json:"review_count"`
-
-	MenuItems []MenuItem `json:"menu_items,omitempty"`
-	Orders    []Order    `json:"orders,omitempty"`
+	UserID          uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	User            User       `json:"user"`
+	BusinessName    string     `gorm:"not null" json:"business_name"`
+	BusinessAddress string     `gorm:"not null" json:"business_address"`
+	Latitude        float64    `json:"latitude"`
+	Longitude       float64    `json:"longitude"`
+	Phone           string     `json:"phone"`
+	Description     string     `json:"description"`
+	LogoURL         string     `json:"logo_url"`
+	CoverImageURL   string     `json:"cover_image_url"`
+	IsOpen          bool       `gorm:"default:false" json:"is_open"`
+	CommissionRate  float64    `gorm:"default:0.15" json:"commission_rate"`
+	DeliveryRadius  float64    `gorm:"default:5.0" json:"delivery_radius"`  // in km
+	AveragePrepTime int        `gorm:"default:15" json:"average_prep_time"` // in minutes
+	MinimumOrder    pkg.Money  `gorm:"default:0" json:"minimum_order"`
+	TotalOrders     int        `gorm:"default:0" json:"total_orders"`
+	TotalRevenue    pkg.Money  `gorm:"default:0" json:"total_revenue"`
+	TotalEarnings   pkg.Money  `gorm:"default:0" json:"total_earnings"`
+	CurrentBalance  pkg.Money  `gorm:"default:0" json:"current_balance"`
+	Rating          float64    `gorm:"default:0" json:"rating"`
+	ReviewCount     int        `gorm:"default:0" json:"review_count"`
+	Timezone        string     `gorm:"default:'UTC'" json:"timezone"`           // IANA name the opening hours are in
+	ScheduledOpen   *bool      `json:"-"`                                       // last state the opening hours job applied
+	SlotCapacity    int        `gorm:"default:0" json:"slot_capacity"`          // scheduled orders per slot, 0 for the platform default
+	MaxActiveOrders int        `gorm:"default:0" json:"max_active_orders"`      // confirmed and preparing orders at once, 0 for no limit
+	CapacityAction  string     `gorm:"default:'extend'" json:"capacity_action"` // reject or extend once MaxActiveOrders is reached
+	BusyMinutes     int        `gorm:"default:0" json:"busy_minutes"`           // added to waits while busy mode is on
+	BusyUntil       *time.Time `json:"busy_until,omitempty"`                    // busy mode ends then, or when turned off if nil
+
+	OpeningHours []OpeningHours  `json:"opening_hours,omitempty"`
+	Holidays     []VendorHoliday `json:"holidays,omitempty"`
+	MenuItems    []MenuItem      `json:"menu_items,omitempty"`
+	Orders       []Order         `json:"orders,omitempty"`
+}
+
+// OpeningHours is one range a vendor is open on a weekday, in the vendor's
+// timezone. A range that closes at or before it opens runs past midnight.
+type OpeningHours struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	VendorID uint   `gorm:"not null;index" json:"vendor_id"`
+	Weekday  int    `gorm:"not null" json:"weekday"`   // 0 is Sunday
+	OpensAt  string `gorm:"not null" json:"opens_at"`  // HH:MM
+	ClosesAt string `gorm:"not null" json:"closes_at"` // HH:MM, 24:00 for midnight
+}
+
+// VendorHoliday closes a vendor for a whole day regardless of opening hours
+type VendorHoliday struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	VendorID uint   `gorm:"not null;uniqueIndex:idx_vendor_holiday" json:"vendor_id"`
+	Date     string `gorm:"not null;uniqueIndex:idx_vendor_holiday" json:"date"` // YYYY-MM-DD in the vendor's timezone
+	Reason   string `json:"reason,omitempty"`
 }
 
 type Rider struct {
@@ -133,10 +177,14 @@ type Rider struct {
 	CurrentLongitude   float64    `json:"current_longitude"`
 	LastLocationUpdate *time.Time `json:"last_location_update"`
 	TotalDeliveries    int        `gorm:"default:0" json:"total_deliveries"`
-	TotalEarnings      float64    `gorm:"default:0" json:"total_earnings"`
-	CurrentBalance     float64    `gorm:"default:0" json:"current_balance"`
+	TotalEarnings      pkg.Money  `gorm:"default:0" json:"total_earnings"`
+	CurrentBalance     pkg.Money  `gorm:"default:0" json:"current_balance"`
+	CashInHand         pkg.Money  `gorm:"default:0" json:"cash_in_hand"` // cash collected on deliveries and not yet handed over
+	CashLimit          *pkg.Money `json:"cash_limit"`                    // nil uses the platform default
 	Rating             float64    `gorm:"default:0" json:"rating"`
 	ReviewCount        int        `gorm:"default:0" json:"review_count"`
+	NoShowCount        int        `gorm:"default:0" json:"no_show_count"` // booked shifts the rider never checked in to
+	OffShift           bool       `gorm:"default:false" json:"off_shift"` // checked out of a shift; finishing a delivery doesn't make them available
 
 	Orders []Order `gorm:"foreignKey:AssignedRiderID" json:"orders,omitempty"`
 }
@@ -147,22 +195,56 @@ type MenuItem struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	VendorID        uint     `gorm:"not null;index" json:"vendor_id"`
-	Vendor          Vendor   `json:"vendor"`
-	Name            string   `gorm:"not null" json:"name"`
-	Description     string   `json:"description"`
-	Category        string   `gorm:"index" json:"category"`
-	Price           float64  `gorm:"not null" json:"price"`
-	DiscountPrice   *float64 `json:"discount_price,omitempty"`
-	ImageURL        string   `json:"image_url"`
-	IsAvailable     bool     `gorm:"default:true;index" json:"is_available"`
-	PreparationTime int      `json:"preparation_time"` // in minutes
-	Calories        int      `json:"calories"`
-	IsVegetarian    bool     `gorm:"default:false" json:"is_vegetarian"`
-	IsSpicy         bool     `gorm:"default:false" json:"is_spicy"`
-	SortOrder       int      `gorm:"default:0" json:"sort_order"`
-
-	OrderItems []OrderItem `json:"order_items,omitempty"`
+	VendorID        uint       `gorm:"not null;index" json:"vendor_id"`
+	Vendor          Vendor     `json:"vendor"`
+	Name            string     `gorm:"not null" json:"name"`
+	Description     string     `json:"description"`
+	Category        string     `gorm:"index" json:"category"`
+	Price           pkg.Money  `gorm:"not null" json:"price"`
+	DiscountPrice   *pkg.Money `json:"discount_price,omitempty"`
+	ImageURL        string     `json:"image_url"`
+	IsAvailable     bool       `gorm:"default:true;index" json:"is_available"`
+	PreparationTime int        `json:"preparation_time"` // in minutes
+	Calories        int        `json:"calories"`
+	IsVegetarian    bool       `gorm:"default:false" json:"is_vegetarian"`
+	IsSpicy         bool       `gorm:"default:false" json:"is_spicy"`
+	SortOrder       int        `gorm:"default:0" json:"sort_order"`
+
+	OptionGroups []MenuOptionGroup `json:"option_groups,omitempty"`
+	OrderItems   []OrderItem       `json:"order_items,omitempty"`
+}
+
+// MenuOptionGroup is a choice a student makes when ordering a menu item,
+// such as size or add-ons. Required groups need at least one selection.
+type MenuOptionGroup struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	MenuItemID    uint   `gorm:"not null;index" json:"menu_item_id"`
+	Name          string `gorm:"not null" json:"name"`
+	MinSelections int    `gorm:"default:0" json:"min_selections"`
+	MaxSelections int    `gorm:"default:0" json:"max_selections"` // 0 means no maximum
+	Required      bool   `gorm:"default:false" json:"required"`
+	SortOrder     int    `gorm:"default:0" json:"sort_order"`
+
+	Options []MenuOption `gorm:"foreignKey:GroupID" json:"options"`
+}
+
+// MenuOption is one choice in a group. PriceDelta is added to the item's
+// price for each unit ordered.
+type MenuOption struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	GroupID     uint      `gorm:"not null;index" json:"group_id"`
+	Name        string    `gorm:"not null" json:"name"`
+	PriceDelta  pkg.Money `gorm:"default:0" json:"price_delta"`
+	IsAvailable bool      `gorm:"default:true" json:"is_available"`
+	SortOrder   int       `gorm:"default:0" json:"sort_order"`
 }
 type Order struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -177,15 +259,25 @@ type Order struct {
 	Vendor          Vendor      `json:"vendor"`
 	AssignedRiderID *uint       `gorm:"index" json:"assigned_rider_id"`
 	AssignedRider   *Rider      `json:"assigned_rider,omitempty"`
+	BatchID         *uint       `gorm:"index" json:"batch_id,omitempty"` // the rider's delivery batch the order travels in
+	DeliveryCode    string      `json:"-"`                               // shown to the student, given to the rider at the door
+	PickupCode      string      `json:"-"`                               // shown to the vendor, given to the rider at the counter
 	Status          OrderStatus `gorm:"not null;default:'pending';index" json:"status"`
 
-	Subtotal         float64 `gorm:"not null" json:"subtotal"`
-	DeliveryFee      float64 `gorm:"not null" json:"delivery_fee"`
-	ServiceFee       float64 `gorm:"not null" json:"service_fee"`
-	TotalAmount      float64 `gorm:"not null" json:"total_amount"`
-	CommissionAmount float64 `gorm:"not null" json:"commission_amount"`
-	VendorEarnings   float64 `gorm:"not null" json:"vendor_earnings"`
-	RiderEarnings    float64 `gorm:"not null" json:"rider_earnings"`
+	Subtotal         pkg.Money `gorm:"not null" json:"subtotal"`
+	DeliveryFee      pkg.Money `gorm:"not null" json:"delivery_fee"`
+	ServiceFee       pkg.Money `gorm:"not null" json:"service_fee"`
+	TotalAmount      pkg.Money `gorm:"not null" json:"total_amount"`
+	CommissionAmount pkg.Money `gorm:"not null" json:"commission_amount"`
+	VendorEarnings   pkg.Money `gorm:"not null" json:"vendor_earnings"`
+	RiderEarnings    pkg.Money `gorm:"not null" json:"rider_earnings"`
+
+	Pricing PricingBreakdown `gorm:"embedded;embeddedPrefix:pricing_" json:"pricing"`
+
+	CouponID       *uint     `gorm:"index" json:"coupon_id,omitempty"`
+	CouponCode     string    `json:"coupon_code,omitempty"`
+	DiscountAmount pkg.Money `gorm:"default:0" json:"discount_amount"` // already taken off TotalAmount
+	RefundedAmount pkg.Money `gorm:"default:0" json:"refunded_amount"` // sum of refunds issued, pending or completed
 
 	DeliveryAddress     string  `gorm:"not null" json:"delivery_address"`
 	DeliveryLat         float64 `json:"delivery_lat"`
@@ -197,6 +289,9 @@ type Order struct {
 	SpecialInstructions string  `json:"special_instructions"`
 
 	EstimatedDeliveryTime *time.Time `json:"estimated_delivery_time"`
+	KitchenWaitMinutes    int        `json:"kitchen_wait_minutes"`                 // expected wait at the vendor when the order was placed
+	ScheduledFor          *time.Time `gorm:"index" json:"scheduled_for,omitempty"` // requested delivery time of a pre-order
+	ReleasedAt            *time.Time `gorm:"index" json:"released_at"`             // shown to the vendor; nil while awaiting payment authorization
 	ConfirmedAt           *time.Time `json:"confirmed_at"`
 	PreparedAt            *time.Time `json:"prepared_at"`
 	ReadyAt               *time.Time `json:"ready_at"` // Add this missing field
@@ -205,8 +300,23 @@ type Order struct {
 	CancelledAt           *time.Time `json:"cancelled_at"`
 	CancellationReason    string     `json:"cancellation_reason,omitempty"`
 
+	DispatchRound       int        `gorm:"default:0" json:"dispatch_round"`              // offer rounds sent to riders so far
+	DispatchRoundAt     *time.Time `json:"dispatch_round_at,omitempty"`                  // when the current round started
+	DispatchEscalatedAt *time.Time `gorm:"index" json:"dispatch_escalated_at,omitempty"` // handed to admin after every round failed
+
 	OrderItems []OrderItem `json:"order_items"`
 	Payment    *Payment    `json:"payment,omitempty"`
+	Refunds    []Refund    `json:"refunds,omitempty"`
+}
+
+// PricingBreakdown records how an order's delivery fee was calculated
+type PricingBreakdown struct {
+	RuleID         uint      `json:"rule_id"`
+	DistanceKm     float64   `json:"distance_km"`
+	BaseFare       pkg.Money `json:"base_fare"`
+	DistanceFare   pkg.Money `json:"distance_fare"`
+	PeakMultiplier float64   `gorm:"default:1" json:"peak_multiplier"`
+	PeakName       string    `json:"peak_name,omitempty"`
 }
 
 type OrderItem struct {
@@ -214,14 +324,61 @@ type OrderItem struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
-	OrderID             uint     `gorm:"not null;index" json:"order_id"`
-	Order               Order    `json:"-"`
-	MenuItemID          uint     `gorm:"not null" json:"menu_item_id"`
-	MenuItem            MenuItem `json:"menu_item"`
-	Quantity            int      `gorm:"not null" json:"quantity"`
-	UnitPrice           float64  `gorm:"not null" json:"unit_price"`
-	Subtotal            float64  `gorm:"not null" json:"subtotal"`
-	SpecialInstructions string   `json:"special_instructions"`
+	OrderID             uint      `gorm:"not null;index" json:"order_id"`
+	Order               Order     `json:"-"`
+	MenuItemID          uint      `gorm:"not null" json:"menu_item_id"`
+	MenuItem            MenuItem  `json:"menu_item"`
+	Quantity            int       `gorm:"not null" json:"quantity"`
+	UnitPrice           pkg.Money `gorm:"not null" json:"unit_price"`
+	Subtotal            pkg.Money `gorm:"not null" json:"subtotal"`
+	SpecialInstructions string    `json:"special_instructions"`
+	RefundedQuantity    int       `gorm:"default:0" json:"refunded_quantity"`
+
+	Options []OrderItemOption `json:"options,omitempty"`
+}
+
+// OrderItemOption is an option chosen for an order item, copied from the
+// menu so later menu changes don't alter the order. UnitPrice on the item
+// already includes PriceDelta.
+type OrderItemOption struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrderItemID uint      `gorm:"not null;index" json:"order_item_id"`
+	OptionID    uint      `gorm:"not null" json:"option_id"`
+	GroupName   string    `gorm:"not null" json:"group_name"`
+	Name        string    `gorm:"not null" json:"name"`
+	PriceDelta  pkg.Money `gorm:"default:0" json:"price_delta"`
+}
+
+// OrderEventType identifies what kind of change an OrderEvent records
+type OrderEventType string
+
+const (
+	OrderEventPlaced          OrderEventType = "order_placed"
+	OrderEventStatusChanged   OrderEventType = "status_changed"
+	OrderEventRiderAssigned   OrderEventType = "rider_assigned"
+	OrderEventRiderUnassigned OrderEventType = "rider_unassigned"
+	OrderEventReleased        OrderEventType = "released_to_vendor"
+	OrderEventRefunded        OrderEventType = "refunded"
+)
+
+// OrderEvent is an append-only log entry written in the same transaction as
+// the order change it describes.
+type OrderEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrderID    uint           `gorm:"not null;index" json:"order_id"`
+	Order      Order          `json:"-"`
+	Type       OrderEventType `gorm:"not null;index" json:"type"`
+	FromStatus OrderStatus    `json:"from_status,omitempty"`
+	ToStatus   OrderStatus    `json:"to_status,omitempty"`
+	ActorID    *uint          `gorm:"index" json:"actor_id"` // user who made the change, nil for system
+	Actor      *User          `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	ActorRole  string         `gorm:"not null" json:"actor_role"` // student, vendor, rider, admin, system
+	RiderID    *uint          `json:"rider_id,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
 }
 
 type Payment struct {
@@ -231,27 +388,387 @@ type Payment struct {
 
 	OrderID       uint       `gorm:"not null;index" json:"order_id"`
 	Order         Order      `json:"-"`
-	Amount        float64    `gorm:"not null" json:"amount"`
+	Amount        pkg.Money  `gorm:"not null" json:"amount"`
 	PaymentMethod string     `gorm:"not null" json:"payment_method"` // cash, card, wallet
 	PaymentStatus string     `gorm:"not null;default:'pending'" json:"payment_status"`
-	TransactionID string     `gorm:"uniqueIndex" json:"transaction_id"`
+	TransactionID string     `gorm:"uniqueIndex" json:"transaction_id"` // provider intent id for card payments
+	Provider      string     `json:"provider,omitempty"`
+	AuthorizedAt  *time.Time `json:"authorized_at"`
 	PaidAt        *time.Time `json:"paid_at"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+}
+
+// PaymentEvent is a webhook received from the payment provider. The unique
+// ProviderEventID makes redelivered webhooks a no-op.
+type PaymentEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentID       *uint  `gorm:"index" json:"payment_id"` // nil for intents that are not order payments
+	IntentID        string `gorm:"index" json:"intent_id"`
+	ProviderEventID string `gorm:"uniqueIndex;not null" json:"provider_event_id"`
+	Type            string `gorm:"not null" json:"type"`
+	Payload         string `gorm:"type:text" json:"payload"`
 }
 
+// LedgerAccount names an account in the double-entry ledger. Vendor, rider
+// and wallet accounts are held per user; the others are platform-wide.
+type LedgerAccount string
+
+const (
+	LedgerAccountCollections  LedgerAccount = "collections" // money received from customers
+	LedgerAccountVendor       LedgerAccount = "vendor"
+	LedgerAccountRider        LedgerAccount = "rider"
+	LedgerAccountCommission   LedgerAccount = "platform_commission"
+	LedgerAccountServiceFees  LedgerAccount = "platform_service_fees"
+	LedgerAccountDeliveryFees LedgerAccount = "platform_delivery_fees"
+	LedgerAccountDiscounts    LedgerAccount = "platform_discounts"
+	LedgerAccountRefunds      LedgerAccount = "platform_refunds"
+	LedgerAccountAdjustments  LedgerAccount = "platform_adjustments"
+	LedgerAccountPayouts      LedgerAccount = "payouts"          // money paid out to vendors and riders
+	LedgerAccountWithdrawals  LedgerAccount = "withdrawal_holds" // requested withdrawals not yet paid
+	LedgerAccountWallet       LedgerAccount = "wallet"           // student prepaid balances
+	LedgerAccountRiderCash    LedgerAccount = "rider_cash"       // cash riders collected and have not handed over
+)
+
+type LedgerEntryType string
+
+const (
+	LedgerEntryOrderDelivered LedgerEntryType = "order_delivered"
+	LedgerEntryRefund         LedgerEntryType = "refund"
+	LedgerEntryAdjustment     LedgerEntryType = "adjustment"
+	LedgerEntryPayout         LedgerEntryType = "payout"
+	LedgerEntryOpeningBalance LedgerEntryType = "opening_balance"
+	LedgerEntryWithdrawal     LedgerEntryType = "withdrawal"
+	LedgerEntryWalletTopUp    LedgerEntryType = "wallet_top_up"
+	LedgerEntryWalletPayment  LedgerEntryType = "wallet_payment"
+	LedgerEntryCashCollected  LedgerEntryType = "cash_collected"
+	LedgerEntryCashHandover   LedgerEntryType = "cash_handover"
+)
+
+// LedgerEntry groups the transactions of one financial event. Its
+// transactions always sum to zero. ReferenceID is unique so an event can
+// only be posted once.
+type LedgerEntry struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Type         LedgerEntryType `gorm:"not null;index" json:"type"`
+	OrderID      *uint           `gorm:"index" json:"order_id"`
+	ReferenceID  string          `gorm:"uniqueIndex;not null" json:"reference_id"`
+	Description  string          `json:"description"`
+	CreatedByID  *uint           `json:"created_by_id"` // admin who posted it, nil for system postings
+	Transactions []Transaction   `gorm:"foreignKey:EntryID" json:"transactions,omitempty"`
+}
+
+// Transaction is one line of a LedgerEntry. Amount is signed: credits are
+// positive and debits negative, so the balance of a vendor or rider account
+// is what the platform owes them.
 type Transaction struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
-	UserID      uint    `gorm:"not null;index" json:"user_id"`
-	User        User    `json:"user"`
-	OrderID     *uint   `gorm:"index" json:"order_id"`
-	Order       *Order  `json:"order,omitempty"`
-	Amount      float64 `gorm:"not null" json:"amount"`
-	Type        string  `gorm:"not null" json:"type"` // earning, withdrawal, refund
-	Status      string  `gorm:"not null" json:"status"`
-	Description string  `json:"description"`
-	ReferenceID string  `gorm:"index" json:"reference_id"`
+	EntryID     uint          `gorm:"not null;index" json:"entry_id"`
+	Account     LedgerAccount `gorm:"not null;index" json:"account"`
+	UserID      *uint         `gorm:"index" json:"user_id"` // holder of vendor and rider accounts
+	User        *User         `json:"user,omitempty"`
+	OrderID     *uint         `gorm:"index" json:"order_id"`
+	Order       *Order        `json:"order,omitempty"`
+	Amount      pkg.Money     `gorm:"not null" json:"amount"`
+	Type        string        `gorm:"not null" json:"type"` // earning, commission, fee, payment, refund, adjustment, payout, withdrawal
+	Status      string        `gorm:"not null" json:"status"`
+	Description string        `json:"description"`
+	ReferenceID string        `gorm:"index" json:"reference_id"`
+}
+
+type WithdrawalStatus string
+
+const (
+	WithdrawalStatusPending   WithdrawalStatus = "pending"
+	WithdrawalStatusApproved  WithdrawalStatus = "approved"
+	WithdrawalStatusRejected  WithdrawalStatus = "rejected"
+	WithdrawalStatusPaid      WithdrawalStatus = "paid"
+	WithdrawalStatusCancelled WithdrawalStatus = "cancelled"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending    RefundStatus = "pending"    // waiting to be sent to the payment provider
+	RefundStatusProcessing RefundStatus = "processing" // being sent to the payment provider
+	RefundStatusCompleted  RefundStatus = "completed"
+	RefundStatusFailed     RefundStatus = "failed"
+)
+
+// Refund types
+const (
+	RefundTypeFull     = "full"
+	RefundTypePartial  = "partial"
+	RefundTypeItems    = "items"
+	RefundTypeGoodwill = "goodwill"
+)
+
+// Refund returns money to a student through the order's original payment
+// method: the card provider, the wallet, or cash handed back in person.
+// VendorShare and RiderShare are recovered from their balances and the
+// platform bears the rest.
+type Refund struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	OrderID       uint         `gorm:"not null;index" json:"order_id"`
+	PaymentID     uint         `gorm:"not null;index" json:"payment_id"`
+	Type          string       `gorm:"not null" json:"type"`
+	Method        string       `gorm:"not null" json:"method"` // card, wallet or cash
+	Amount        pkg.Money    `gorm:"not null" json:"amount"`
+	VendorShare   pkg.Money    `gorm:"default:0" json:"vendor_share"`
+	RiderShare    pkg.Money    `gorm:"default:0" json:"rider_share"`
+	Status        RefundStatus `gorm:"not null;default:'pending';index" json:"status"`
+	Reason        string       `gorm:"not null" json:"reason"`
+	Note          string       `json:"note,omitempty"`
+	IssuedByID    *uint        `json:"issued_by_id,omitempty"` // nil for automatic refunds
+	LedgerEntryID *uint        `json:"ledger_entry_id,omitempty"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+	CompletedAt   *time.Time   `json:"completed_at"`
+
+	Items []RefundItem `json:"items,omitempty"`
+}
+
+type RefundItem struct {
+	ID uint `gorm:"primarykey" json:"id"`
+
+	RefundID    uint      `gorm:"not null;index" json:"refund_id"`
+	OrderItemID uint      `gorm:"not null;index" json:"order_item_id"`
+	Quantity    int       `gorm:"not null" json:"quantity"`
+	Amount      pkg.Money `gorm:"not null" json:"amount"`
+}
+
+type WalletTopUpStatus string
+
+const (
+	WalletTopUpStatusPending   WalletTopUpStatus = "pending"
+	WalletTopUpStatusCompleted WalletTopUpStatus = "completed"
+	WalletTopUpStatusFailed    WalletTopUpStatus = "failed"
+)
+
+// WalletTopUp is money a student adds to their wallet by card. The wallet
+// is credited when the payment is captured.
+type WalletTopUp struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID        uint              `gorm:"not null;index" json:"user_id"`
+	Amount        pkg.Money         `gorm:"not null" json:"amount"`
+	Status        WalletTopUpStatus `gorm:"not null;default:'pending';index" json:"status"`
+	Provider      string            `json:"provider"`
+	IntentID      string            `gorm:"uniqueIndex;not null" json:"intent_id"`
+	FailureReason string            `json:"failure_reason,omitempty"`
+	CompletedAt   *time.Time        `json:"completed_at"`
+}
+
+// Withdrawal is a vendor or rider's request to cash out their balance. The
+// amount is held in the ledger from the request until it is paid, or
+// released back to the balance if the request is rejected or cancelled.
+type Withdrawal struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID           uint             `gorm:"not null;index" json:"user_id"`
+	User             *User            `json:"user,omitempty"`
+	Account          LedgerAccount    `gorm:"not null" json:"account"` // vendor or rider
+	Amount           pkg.Money        `gorm:"not null" json:"amount"`
+	Status           WithdrawalStatus `gorm:"not null;default:'pending';index" json:"status"`
+	PayoutDetails    string           `gorm:"not null" json:"payout_details"` // account the money is sent to
+	Note             string           `json:"note,omitempty"`
+	ReviewedByID     *uint            `json:"reviewed_by_id"`
+	ReviewedAt       *time.Time       `json:"reviewed_at"`
+	RejectionReason  string           `json:"rejection_reason,omitempty"`
+	PaymentReference string           `json:"payment_reference,omitempty"`
+	PaidAt           *time.Time       `json:"paid_at"`
+}
+
+// CashHandover records cash a rider handed to an admin or to a vendor
+// collecting on the platform's behalf
+type CashHandover struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RiderID        uint      `gorm:"not null;index" json:"rider_id"`
+	Rider          *Rider    `json:"rider,omitempty"`
+	Amount         pkg.Money `gorm:"not null" json:"amount"`
+	ReceivedByID   uint      `gorm:"not null;index" json:"received_by_id"` // user id of the admin or vendor
+	ReceivedByRole string    `gorm:"not null" json:"received_by_role"`     // admin or vendor
+	VendorID       *uint     `gorm:"index" json:"vendor_id,omitempty"`
+	LedgerEntryID  uint      `gorm:"not null" json:"ledger_entry_id"`
+	Note           string    `json:"note,omitempty"`
+}
+
+// DeliveryBatchStatus is whether a rider is still working through a batch
+type DeliveryBatchStatus string
+
+const (
+	DeliveryBatchActive    DeliveryBatchStatus = "active"
+	DeliveryBatchCompleted DeliveryBatchStatus = "completed"
+)
+
+// DeliveryBatch is the set of orders a rider carries at once. A rider has at
+// most one active batch and becomes available again once every order in it
+// is delivered, cancelled or handed back.
+type DeliveryBatch struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	RiderID     uint                `gorm:"not null;index" json:"rider_id"`
+	Rider       *Rider              `json:"rider,omitempty"`
+	Status      DeliveryBatchStatus `gorm:"not null;default:'active';index" json:"status"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+
+	Orders []Order `gorm:"foreignKey:BatchID" json:"orders,omitempty"`
+}
+
+// DeliveryZone is an area riders are scheduled to cover. Orders count
+// towards the zone their vendor is in.
+type DeliveryZone struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name      string  `gorm:"uniqueIndex;not null" json:"name"`
+	Latitude  float64 `gorm:"not null" json:"latitude"`
+	Longitude float64 `gorm:"not null" json:"longitude"`
+	RadiusKm  float64 `gorm:"not null" json:"radius_km"`
+	Timezone  string  `gorm:"not null;default:'UTC'" json:"timezone"` // coverage days and hours are laid out in this
+	IsActive  bool    `gorm:"default:true" json:"is_active"`
+}
+
+// ShiftSlot is a stretch of time admins open for riders to book in a zone
+type ShiftSlot struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ZoneID   uint          `gorm:"not null;index" json:"zone_id"`
+	Zone     *DeliveryZone `json:"zone,omitempty"`
+	StartsAt time.Time     `gorm:"not null;index" json:"starts_at"`
+	EndsAt   time.Time     `gorm:"not null" json:"ends_at"`
+	Capacity int           `gorm:"not null" json:"capacity"`
+	Booked   int           `gorm:"default:0" json:"booked"` // bookings not cancelled, kept under the slot's row lock
+}
+
+// ShiftBookingStatus is where a rider's booking of a shift stands
+type ShiftBookingStatus string
+
+const (
+	ShiftBookingBooked    ShiftBookingStatus = "booked"
+	ShiftBookingCheckedIn ShiftBookingStatus = "checked_in"
+	ShiftBookingCompleted ShiftBookingStatus = "completed"
+	ShiftBookingCancelled ShiftBookingStatus = "cancelled"
+	ShiftBookingNoShow    ShiftBookingStatus = "no_show" // never checked in
+)
+
+// ShiftBooking is a rider's place on a shift slot
+type ShiftBooking struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	SlotID       uint               `gorm:"not null;index" json:"slot_id"`
+	Slot         *ShiftSlot         `json:"slot,omitempty"`
+	RiderID      uint               `gorm:"not null;index" json:"rider_id"`
+	Rider        *Rider             `json:"rider,omitempty"`
+	Status       ShiftBookingStatus `gorm:"not null;default:'booked';index" json:"status"`
+	CheckedInAt  *time.Time         `json:"checked_in_at,omitempty"`
+	CheckedOutAt *time.Time         `json:"checked_out_at,omitempty"`
+	CancelledAt  *time.Time         `json:"cancelled_at,omitempty"`
+}
+
+// RiderLocation is a position a rider reported while an order was under way.
+// A rider carrying several orders leaves one sample per order, so each
+// order's route can be read on its own.
+type RiderLocation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	RiderID   uint        `gorm:"not null;index" json:"rider_id"`
+	OrderID   uint        `gorm:"not null;index" json:"order_id"`
+	Status    OrderStatus `gorm:"not null" json:"status"` // order status when the sample was taken
+	Latitude  float64     `gorm:"not null" json:"latitude"`
+	Longitude float64     `gorm:"not null" json:"longitude"`
+}
+
+// HandoffStage is the point at which an order changes hands
+type HandoffStage string
+
+const (
+	HandoffPickup   HandoffStage = "pickup"
+	HandoffDelivery HandoffStage = "delivery"
+)
+
+// HandoffMethod is how a handoff was confirmed
+type HandoffMethod string
+
+const (
+	HandoffMethodCode       HandoffMethod = "code"           // the rider entered the order's handoff code
+	HandoffMethodUnverified HandoffMethod = "unverified"     // no code was required
+	HandoffMethodOverride   HandoffMethod = "admin_override" // an admin confirmed it without the code
+)
+
+// HandoffProof records how an order was collected from the vendor or handed
+// to the student, for settling disputes
+type HandoffProof struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrderID    uint          `gorm:"not null;index" json:"order_id"`
+	Stage      HandoffStage  `gorm:"not null" json:"stage"`
+	Method     HandoffMethod `gorm:"not null" json:"method"`
+	RiderID    *uint         `gorm:"index" json:"rider_id,omitempty"`
+	ActorID    *uint         `json:"actor_id,omitempty"`
+	ActorRole  string        `json:"actor_role"`
+	Attempts   int           `json:"attempts"` // wrong codes entered before this one
+	PhotoURL   string        `json:"photo_url,omitempty"`
+	Latitude   *float64      `json:"latitude,omitempty"`
+	Longitude  *float64      `json:"longitude,omitempty"`
+	DistanceKm *float64      `json:"distance_km,omitempty"` // from the vendor at pickup, from the drop point at delivery
+	Reason     string        `json:"reason,omitempty"`
+}
+
+// DispatchOfferStatus is where a delivery offer to a rider stands
+type DispatchOfferStatus string
+
+const (
+	DispatchOfferPending   DispatchOfferStatus = "pending"
+	DispatchOfferAccepted  DispatchOfferStatus = "accepted"
+	DispatchOfferDeclined  DispatchOfferStatus = "declined"
+	DispatchOfferExpired   DispatchOfferStatus = "expired"
+	DispatchOfferCancelled DispatchOfferStatus = "cancelled" // the order was assigned some other way or left ready
+	DispatchOfferReleased  DispatchOfferStatus = "released"  // accepted, then handed back by the rider before pickup
+)
+
+// DispatchOffer is a time-limited offer of a ready order to one rider. Offers
+// are kept after they close so dispatch can be analysed.
+type DispatchOffer struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	OrderID      uint                `gorm:"not null;index" json:"order_id"`
+	Order        *Order              `json:"order,omitempty"`
+	RiderID      uint                `gorm:"not null;index" json:"rider_id"`
+	Rider        *Rider              `json:"rider,omitempty"`
+	Round        int                 `gorm:"not null" json:"round"`
+	Score        float64             `json:"score"`
+	DistanceKm   float64             `json:"distance_km"`   // rider to vendor when offered
+	ActiveOrders int                 `json:"active_orders"` // rider's load when offered
+	Status       DispatchOfferStatus `gorm:"not null;default:'pending';index" json:"status"`
+	ExpiresAt    time.Time           `gorm:"not null;index" json:"expires_at"`
+	RespondedAt  *time.Time          `json:"responded_at,omitempty"`
+	Reason       string              `json:"reason,omitempty"`
 }
 
 type Notification struct {
@@ -308,3 +825,85 @@ type Address struct {
 	IsDefault    bool    `gorm:"default:false" json:"is_default"`
 	AddressType  string  `json:"address_type"` // home, work, other
 }
+
+// PricingRule holds the admin-editable fee settings. Only the first row is
+// used; when the table is empty the configured flat fees apply.
+type PricingRule struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	BaseFare          pkg.Money `gorm:"not null" json:"base_fare"`
+	IncludedKm        float64   `gorm:"default:0" json:"included_km"` // distance covered by the base fare
+	PerKmRate         pkg.Money `gorm:"default:0" json:"per_km_rate"`
+	MinDeliveryFee    pkg.Money `gorm:"default:0" json:"min_delivery_fee"`
+	MaxDeliveryFee    pkg.Money `gorm:"default:0" json:"max_delivery_fee"` // 0 means no cap
+	ServiceFeeRate    float64   `gorm:"not null" json:"service_fee_rate"`
+	RiderEarningsRate float64   `gorm:"not null" json:"rider_earnings_rate"` // share of the delivery fee
+
+	PeakHours []PeakHour `json:"peak_hours"`
+}
+
+// PeakHour multiplies the delivery fee inside a daily time window
+type PeakHour struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PricingRuleID uint    `gorm:"not null;index" json:"pricing_rule_id"`
+	Name          string  `gorm:"not null" json:"name"`
+	DayOfWeek     *int    `json:"day_of_week"`                // 0 = Sunday, nil = every day
+	StartTime     string  `gorm:"not null" json:"start_time"` // HH:MM in the vendor's timezone
+	EndTime       string  `gorm:"not null" json:"end_time"`   // HH:MM, 24:00 for midnight
+	Multiplier    float64 `gorm:"not null" json:"multiplier"`
+}
+
+type CouponType string
+
+const (
+	CouponTypePercentage   CouponType = "percentage"
+	CouponTypeFixed        CouponType = "fixed"
+	CouponTypeFreeDelivery CouponType = "free_delivery"
+)
+
+// Coupon is a promo code. Vendor-scoped coupons are funded by the vendor;
+// platform-wide coupons (VendorID nil) are funded by the platform.
+type Coupon struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Code           string     `gorm:"uniqueIndex;not null" json:"code"`
+	Description    string     `json:"description"`
+	Type           CouponType `gorm:"not null" json:"type"`
+	Percent        float64    `gorm:"default:0" json:"percent"`      // percentage coupons, 0-100
+	Amount         pkg.Money  `gorm:"default:0" json:"amount"`       // fixed coupons
+	MaxDiscount    pkg.Money  `gorm:"default:0" json:"max_discount"` // cap for percentage coupons, 0 means none
+	VendorID       *uint      `gorm:"index" json:"vendor_id"`
+	Vendor         *Vendor    `json:"vendor,omitempty"`
+	MinSubtotal    pkg.Money  `gorm:"default:0" json:"min_subtotal"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	UsageLimit     int        `gorm:"default:0" json:"usage_limit"`    // 0 means unlimited
+	PerUserLimit   int        `gorm:"default:0" json:"per_user_limit"` // 0 means unlimited
+	FirstOrderOnly bool       `gorm:"default:false" json:"first_order_only"`
+	IsActive       bool       `gorm:"default:true" json:"is_active"`
+	TimesRedeemed  int        `gorm:"default:0" json:"times_redeemed"`
+}
+
+// CouponRedemption records a coupon used on an order. ReleasedAt is set when
+// the order is cancelled or rejected and the use no longer counts.
+type CouponRedemption struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	CouponID       uint       `gorm:"not null;index" json:"coupon_id"`
+	Coupon         Coupon     `json:"-"`
+	OrderID        uint       `gorm:"uniqueIndex;not null" json:"order_id"`
+	Order          Order      `json:"-"`
+	StudentID      uint       `gorm:"not null;index" json:"student_id"`
+	VendorID       uint       `gorm:"not null;index" json:"vendor_id"`
+	DiscountAmount pkg.Money  `gorm:"not null" json:"discount_amount"`
+	ReleasedAt     *time.Time `json:"released_at"`
+}
@@ -43,24 +43,64 @@ func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
     sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
     sqlDB.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLifetime) * time.Second)
 
+    // Move float money columns to integer cents before AutoMigrate touches them
+    if err := migrateMoneyColumns(db); err != nil {
+        return nil, fmt.Errorf("failed to migrate money columns: %w", err)
+    }
+
+    // Orders placed before vendor release existed were all visible
+    backfillRelease := !db.Migrator().HasColumn(&Order{}, "released_at")
+
     // Auto migrate schemas
     err = db.AutoMigrate(
         &User{},
         &Student{},
         &Vendor{},
+        &OpeningHours{},
+        &VendorHoliday{},
         &Rider{},
         &MenuItem{},
+        &MenuOptionGroup{},
+        &MenuOption{},
         &Order{},
         &OrderItem{},
+        &OrderItemOption{},
+        &OrderEvent{},
         &Payment{},
+        &PaymentEvent{},
+        &LedgerEntry{},
         &Transaction{},
+        &Withdrawal{},
+        &CashHandover{},
+        &DeliveryBatch{},
+        &DispatchOffer{},
+        &HandoffProof{},
+        &RiderLocation{},
+        &DeliveryZone{},
+        &ShiftSlot{},
+        &ShiftBooking{},
+        &WalletTopUp{},
+        &Refund{},
+        &RefundItem{},
         &Notification{},
         &Review{},
         &Address{},
+        &PricingRule{},
+        &PeakHour{},
+        &Coupon{},
+        &CouponRedemption{},
     )
     if err != nil {
         return nil, fmt.Errorf("failed to migrate database: %w", err)
     }
+    if err := migrateCouponValue(db); err != nil {
+        return nil, fmt.Errorf("failed to migrate coupons: %w", err)
+    }
+    if backfillRelease {
+        if err := db.Exec("UPDATE orders SET released_at = created_at WHERE released_at IS NULL").Error; err != nil {
+            return nil, fmt.Errorf("failed to backfill order release: %w", err)
+        }
+    }
 
     // Create indexes for performance
     createIndexes(db)
@@ -80,6 +120,9 @@ func createIndexes(db *gorm.DB) {
     db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_student_created ON orders(student_id, created_at DESC)")
     db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_order_number ON orders(order_number)")
 
+    // Order events index
+    db.Exec("CREATE INDEX IF NOT EXISTS idx_order_events_order_created ON order_events(order_id, created_at)")
+
     // Menu items indexes
     db.Exec("CREATE INDEX IF NOT EXISTS idx_menu_items_vendor_available ON menu_items(vendor_id, is_available)")
     db.Exec("CREATE INDEX IF NOT EXISTS idx_menu_items_category ON menu_items(category)")
@@ -104,6 +147,7 @@ func createIndexes(db *gorm.DB) {
     // Transactions index
     db.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_user_created ON transactions(user_id, created_at DESC)")
     db.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_reference ON transactions(reference_id)")
+    db.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_account_user ON transactions(account, user_id)")
 
     log.Println("Database indexes created successfully")
 }
@@ -159,15 +203,39 @@ func HealthCheck(db *gorm.DB) error {
 // TruncateTables truncates all tables (useful for testing only)
 func TruncateTables(db *gorm.DB) error {
     tables := []string{
+        "coupon_redemptions",
+        "coupons",
+        "peak_hours",
+        "pricing_rules",
         "reviews",
         "notifications",
+        "wallet_top_ups",
+        "refund_items",
+        "refunds",
+        "shift_bookings",
+        "shift_slots",
+        "delivery_zones",
+        "rider_locations",
+        "handoff_proofs",
+        "dispatch_offers",
+        "delivery_batches",
+        "cash_handovers",
+        "withdrawals",
         "transactions",
+        "ledger_entries",
+        "payment_events",
         "payments",
+        "order_events",
+        "order_item_options",
         "order_items",
         "orders",
+        "menu_options",
+        "menu_option_groups",
         "menu_items",
         "addresses",
         "riders",
+        "vendor_holidays",
+        "opening_hours",
         "vendors",
         "students",
         "users",
@@ -0,0 +1,229 @@
+package dispatch
+
+import (
+	"errors"
+	"food-delivery-backend/cash"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRiderNotFound), errors.Is(err, ErrOrderNotFound), errors.Is(err, ErrOfferNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotAssigned):
+		return http.StatusForbidden
+	case errors.Is(err, ErrOfferClosed), errors.Is(err, ErrOfferExpired),
+		errors.Is(err, ErrOrderUnavailable), errors.Is(err, ErrNotDispatchable):
+		return http.StatusConflict
+	case errors.Is(err, cash.ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// GetMyOffers returns the delivery offers the rider can still accept
+// @Summary Get open delivery offers
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]Offer}
+// @Router /riders/offers [get]
+func (h *Handler) GetMyOffers(c *gin.Context) {
+	offers, err := h.service.GetOpenOffers(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get offers", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Offers retrieved successfully", offers)
+}
+
+// AcceptOffer takes the offered delivery
+// @Summary Accept a delivery offer
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Offer ID"
+// @Success 200 {object} pkg.Response{data=database.Order}
+// @Router /riders/offers/{id}/accept [post]
+func (h *Handler) AcceptOffer(c *gin.Context) {
+	offerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid offer ID", nil)
+		return
+	}
+
+	order, err := h.service.AcceptOffer(c.GetUint("user_id"), uint(offerID))
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to accept offer", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Offer accepted", order)
+}
+
+// DeclineOffer turns the offered delivery down so it goes to another rider
+// @Summary Decline a delivery offer
+// @Tags Riders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Offer ID"
+// @Param request body DeclineRequest false "Reason"
+// @Success 200 {object} pkg.Response
+// @Router /riders/offers/{id}/decline [post]
+func (h *Handler) DeclineOffer(c *gin.Context) {
+	offerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid offer ID", nil)
+		return
+	}
+
+	var req DeclineRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	if err := h.service.DeclineOffer(c.GetUint("user_id"), uint(offerID), req.Reason); err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to decline offer", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Offer declined", nil)
+}
+
+// ReleaseOrder hands back an assigned order the rider has not picked up
+// @Summary Hand back an order
+// @Tags Riders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body ReleaseRequest true "Reason"
+// @Success 200 {object} pkg.Response
+// @Router /riders/orders/{id}/release [post]
+func (h *Handler) ReleaseOrder(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	var req ReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	if err := h.service.ReleaseOrder(c.GetUint("user_id"), uint(orderID), req.Reason); err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to release order", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Order released", nil)
+}
+
+// GetOffers lists delivery offers and how riders answered them
+// @Summary List delivery offers
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param order_id query int false "Order ID"
+// @Param rider_id query int false "Rider ID"
+// @Param status query string false "pending, accepted, declined, expired, cancelled or released"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/dispatch/offers [get]
+func (h *Handler) GetOffers(c *gin.Context) {
+	filters := OfferFilters{Status: database.DispatchOfferStatus(c.Query("status"))}
+	if orderID, err := strconv.ParseUint(c.Query("order_id"), 10, 32); err == nil {
+		id := uint(orderID)
+		filters.OrderID = &id
+	}
+	if riderID, err := strconv.ParseUint(c.Query("rider_id"), 10, 32); err == nil {
+		id := uint(riderID)
+		filters.RiderID = &id
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	offers, total, err := h.service.GetOffers(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get offers", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Offers retrieved successfully", offers, page, limit, total)
+}
+
+// Redispatch starts offering a ready order to riders again
+// @Summary Restart dispatch for an order
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} pkg.Response
+// @Router /admin/orders/{id}/redispatch [post]
+func (h *Handler) Redispatch(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	if err := h.service.Redispatch(uint(orderID)); err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to restart dispatch", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Dispatch restarted", nil)
+}
+
+// GetReport summarises how riders answered delivery offers
+// @Summary Get dispatch report
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} pkg.Response{data=Report}
+// @Router /admin/reports/dispatch [get]
+func (h *Handler) GetReport(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+
+	if startDate == "" || endDate == "" {
+		pkg.SendError(c, http.StatusBadRequest, "Start date and end date are required", nil)
+		return
+	}
+
+	report, err := h.service.GetReport(startDate, endDate)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to generate report", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Dispatch report generated", report)
+}
@@ -0,0 +1,78 @@
+package dispatch
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"time"
+)
+
+type DeclineRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReleaseRequest hands an accepted order back so it is offered to others
+type ReleaseRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type OfferFilters struct {
+	OrderID *uint
+	RiderID *uint
+	Status  database.DispatchOfferStatus
+}
+
+// Offer is an open offer as riders see it
+type Offer struct {
+	ID             uint      `json:"id"`
+	OrderID        uint      `json:"order_id"`
+	OrderNumber    string    `json:"order_number"`
+	VendorName     string    `json:"vendor_name"`
+	VendorAddress  string    `json:"vendor_address"`
+	VendorLat      float64   `json:"vendor_lat"`
+	VendorLng      float64   `json:"vendor_lng"`
+	DeliveryBlock  string    `json:"delivery_block"`
+	DeliveryDorm   string    `json:"delivery_dorm"`
+	DistanceKm     float64   `json:"distance_km"`
+	DeliveryKm     float64   `json:"delivery_km"` // vendor to student
+	RiderEarnings  pkg.Money `json:"rider_earnings"`
+	PaymentMethod  string    `json:"payment_method,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	SecondsToReply int       `json:"seconds_to_reply"`
+}
+
+// Candidate is a rider ranked for an order. Each part of the score is
+// between 0 and 1 before weighting.
+type Candidate struct {
+	Rider        database.Rider `json:"-"`
+	RiderID      uint           `json:"rider_id"`
+	DistanceKm   float64        `json:"distance_km"`
+	ActiveOrders int            `json:"active_orders"`
+	IdleMinutes  float64        `json:"idle_minutes"`
+	Score        float64        `json:"score"`
+}
+
+// Report summarises how offers were answered over a period
+type Report struct {
+	StartDate          string         `json:"start_date"`
+	EndDate            string         `json:"end_date"`
+	Offers             int64          `json:"offers"`
+	ByStatus           map[string]int `json:"by_status"`
+	AcceptanceRate     float64        `json:"acceptance_rate"` // percent of offers answered or left to expire
+	AvgResponseSeconds float64        `json:"avg_response_seconds"`
+	OrdersDispatched   int64          `json:"orders_dispatched"`
+	OrdersEscalated    int64          `json:"orders_escalated"`
+	AvgRoundsToAccept  float64        `json:"avg_rounds_to_accept"`
+	Riders             []RiderReport  `json:"riders"`
+}
+
+// RiderReport is how one rider answered their offers
+type RiderReport struct {
+	RiderID            uint    `json:"rider_id"`
+	Name               string  `json:"name"`
+	Offers             int     `json:"offers"`
+	Accepted           int     `json:"accepted"`
+	Declined           int     `json:"declined"`
+	Expired            int     `json:"expired"`
+	Released           int     `json:"released"`
+	AvgResponseSeconds float64 `json:"avg_response_seconds"`
+}
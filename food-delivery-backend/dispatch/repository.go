@@ -0,0 +1,335 @@
+package dispatch
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/orders"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// riderLoad is a rider's active orders and when they last finished one
+type riderLoad struct {
+	RiderID       uint
+	ActiveOrders  int
+	LastDelivered *time.Time
+}
+
+// offerCounts is how a rider answered offers over a period
+type offerCounts struct {
+	RiderID            uint
+	Status             database.DispatchOfferStatus
+	Offers             int
+	AvgResponseSeconds float64
+}
+
+func (r *Repository) GetRiderByUserID(userID uint) (*database.Rider, error) {
+	var rider database.Rider
+	err := r.db.Where("user_id = ?", userID).First(&rider).Error
+	return &rider, err
+}
+
+// GetRiders loads riders by id, keyed by id
+func (r *Repository) GetRiders(ids []uint) (map[uint]database.Rider, error) {
+	var riders []database.Rider
+	if err := r.db.Preload("User").Where("id IN ?", ids).Find(&riders).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]database.Rider, len(riders))
+	for _, rider := range riders {
+		byID[rider.ID] = rider
+	}
+	return byID, nil
+}
+
+// GetRiderLoads returns the riders' active orders and last delivery, keyed by
+// rider id
+func (r *Repository) GetRiderLoads(ids []uint) (map[uint]riderLoad, error) {
+	var loads []riderLoad
+	err := r.db.Model(&database.Order{}).
+		Select("assigned_rider_id AS rider_id, "+
+			"COUNT(*) FILTER (WHERE status IN ?) AS active_orders, "+
+			"MAX(delivered_at) AS last_delivered",
+			[]database.OrderStatus{
+				database.OrderStatusConfirmed,
+				database.OrderStatusPreparing,
+				database.OrderStatusReady,
+				database.OrderStatusPickedUp,
+			}).
+		Where("assigned_rider_id IN ?", ids).
+		Group("assigned_rider_id").
+		Scan(&loads).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]riderLoad, len(loads))
+	for _, l := range loads {
+		byID[l.RiderID] = l
+	}
+	return byID, nil
+}
+
+// GetGatheringBatches returns active delivery batches whose rider has not
+// collected any of the orders yet, with their riders and open orders
+func (r *Repository) GetGatheringBatches() ([]database.DeliveryBatch, error) {
+	var batches []database.DeliveryBatch
+	err := r.db.Where("status = ?", database.DeliveryBatchActive).
+		Where("NOT EXISTS (SELECT 1 FROM orders o WHERE o.batch_id = delivery_batches.id AND o.status = ?)",
+			database.OrderStatusPickedUp).
+		Preload("Rider").
+		Preload("Orders", "status NOT IN ?", orders.TerminalStatuses()).
+		Preload("Orders.Vendor").
+		Find(&batches).Error
+	return batches, err
+}
+
+// GetBusyRiders returns the riders holding an open offer for any order
+func (r *Repository) GetBusyRiders(ids []uint, now time.Time) (map[uint]bool, error) {
+	var busy []uint
+	err := r.db.Model(&database.DispatchOffer{}).
+		Where("rider_id IN ? AND status = ? AND expires_at > ?", ids, database.DispatchOfferPending, now).
+		Distinct().Pluck("rider_id", &busy).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]bool, len(busy))
+	for _, id := range busy {
+		byID[id] = true
+	}
+	return byID, nil
+}
+
+// GetExcludedRiders returns the riders who must not be offered the order
+// again in the round that started at roundStart: everyone offered it this
+// round, and anyone who ever turned it down or handed it back
+func (r *Repository) GetExcludedRiders(orderID uint, roundStart time.Time) (map[uint]bool, error) {
+	var excluded []uint
+	err := r.db.Model(&database.DispatchOffer{}).
+		Where("order_id = ? AND (created_at >= ? OR status IN ?)", orderID, roundStart,
+			[]database.DispatchOfferStatus{database.DispatchOfferDeclined, database.DispatchOfferReleased}).
+		Distinct().Pluck("rider_id", &excluded).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		byID[id] = true
+	}
+	return byID, nil
+}
+
+// CountRoundOffers counts the offers of the order made since its round
+// started, leaving out offers voided without the rider's doing
+func (r *Repository) CountRoundOffers(orderID uint, roundStart time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.DispatchOffer{}).
+		Where("order_id = ? AND created_at >= ? AND status <> ?", orderID, roundStart, database.DispatchOfferCancelled).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *Repository) GetPendingOffer(orderID uint) (*database.DispatchOffer, error) {
+	var offer database.DispatchOffer
+	err := r.db.Preload("Rider").
+		Where("order_id = ? AND status = ?", orderID, database.DispatchOfferPending).
+		Order("created_at DESC").
+		First(&offer).Error
+	return &offer, err
+}
+
+func (r *Repository) GetOrder(orderID uint) (*database.Order, error) {
+	var order database.Order
+	err := r.db.Preload("Vendor").Preload("Payment").First(&order, orderID).Error
+	return &order, err
+}
+
+// GetWaitingOrders returns ready orders without a rider that dispatch has
+// not given up on and that have no open offer
+func (r *Repository) GetWaitingOrders(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&database.Order{}).
+		Where("status = ? AND assigned_rider_id IS NULL AND dispatch_escalated_at IS NULL", database.OrderStatusReady).
+		Where("NOT EXISTS (SELECT 1 FROM dispatch_offers o WHERE o.order_id = orders.id AND o.status = ? AND o.expires_at > ?)",
+			database.DispatchOfferPending, now).
+		Order("ready_at").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetLapsedOrders returns orders whose pending offer has run out of time
+func (r *Repository) GetLapsedOrders(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&database.DispatchOffer{}).
+		Where("status = ? AND expires_at <= ?", database.DispatchOfferPending, now).
+		Distinct().Pluck("order_id", &ids).Error
+	return ids, err
+}
+
+// GetSettledOrders returns orders that still have a pending offer but no
+// longer need a rider
+func (r *Repository) GetSettledOrders() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&database.DispatchOffer{}).
+		Joins("JOIN orders ON orders.id = dispatch_offers.order_id").
+		Where("dispatch_offers.status = ?", database.DispatchOfferPending).
+		Where("orders.status <> ? OR orders.assigned_rider_id IS NOT NULL", database.OrderStatusReady).
+		Distinct().Pluck("dispatch_offers.order_id", &ids).Error
+	return ids, err
+}
+
+// GetOpenOffers returns a rider's offers that can still be accepted
+func (r *Repository) GetOpenOffers(riderID uint, now time.Time) ([]database.DispatchOffer, error) {
+	var offers []database.DispatchOffer
+	err := r.db.Preload("Order.Vendor").Preload("Order.Payment").
+		Where("rider_id = ? AND status = ? AND expires_at > ?", riderID, database.DispatchOfferPending, now).
+		Order("expires_at").
+		Find(&offers).Error
+	return offers, err
+}
+
+func (r *Repository) CreateOffer(offer *database.DispatchOffer) error {
+	return r.db.Create(offer).Error
+}
+
+// LockOffer takes a row lock on the offer for the rest of tx
+func (r *Repository) LockOffer(tx *gorm.DB, offerID uint) (*database.DispatchOffer, error) {
+	var offer database.DispatchOffer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&offer, offerID).Error
+	return &offer, err
+}
+
+// CloseOffer sets the outcome of an offer that is still pending. It reports
+// whether the offer was still pending.
+func (r *Repository) CloseOffer(db *gorm.DB, offerID uint, status database.DispatchOfferStatus, reason string, at time.Time) (bool, error) {
+	updates := map[string]interface{}{
+		"status": status,
+		"reason": reason,
+	}
+	// Expiry and cancellation are not answers from the rider
+	if status == database.DispatchOfferAccepted || status == database.DispatchOfferDeclined {
+		updates["responded_at"] = at
+	}
+	result := db.Model(&database.DispatchOffer{}).
+		Where("id = ? AND status = ?", offerID, database.DispatchOfferPending).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// VoidOffer cancels an offer whatever its status
+func (r *Repository) VoidOffer(offerID uint, reason string) error {
+	return r.db.Model(&database.DispatchOffer{}).Where("id = ?", offerID).
+		Updates(map[string]interface{}{
+			"status": database.DispatchOfferCancelled,
+			"reason": reason,
+		}).Error
+}
+
+// ReleaseAcceptedOffer marks the rider's accepted offer for the order as
+// handed back
+func (r *Repository) ReleaseAcceptedOffer(orderID, riderID uint, reason string) error {
+	return r.db.Model(&database.DispatchOffer{}).
+		Where("order_id = ? AND rider_id = ? AND status = ?", orderID, riderID, database.DispatchOfferAccepted).
+		Updates(map[string]interface{}{
+			"status": database.DispatchOfferReleased,
+			"reason": reason,
+		}).Error
+}
+
+// StartRound moves the order on to its next round of offers
+func (r *Repository) StartRound(orderID uint, round int, at time.Time) error {
+	return r.db.Model(&database.Order{}).Where("id = ?", orderID).
+		Updates(map[string]interface{}{
+			"dispatch_round":    round,
+			"dispatch_round_at": at,
+		}).Error
+}
+
+// Escalate marks the order as left for an admin to assign. It reports whether
+// the order was not already escalated.
+func (r *Repository) Escalate(orderID uint, at time.Time) (bool, error) {
+	result := r.db.Model(&database.Order{}).
+		Where("id = ? AND dispatch_escalated_at IS NULL", orderID).
+		Update("dispatch_escalated_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ResetDispatch starts dispatch over for an order
+func (r *Repository) ResetDispatch(orderID uint) error {
+	return r.db.Model(&database.Order{}).Where("id = ?", orderID).
+		Updates(map[string]interface{}{
+			"dispatch_round":        0,
+			"dispatch_round_at":     nil,
+			"dispatch_escalated_at": nil,
+		}).Error
+}
+
+func (r *Repository) GetOffers(filters *OfferFilters, offset, limit int) ([]database.DispatchOffer, int64, error) {
+	var offers []database.DispatchOffer
+	var total int64
+
+	query := r.db.Model(&database.DispatchOffer{})
+	if filters.OrderID != nil {
+		query = query.Where("order_id = ?", *filters.OrderID)
+	}
+	if filters.RiderID != nil {
+		query = query.Where("rider_id = ?", *filters.RiderID)
+	}
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+
+	query.Count(&total)
+	err := query.Preload("Rider.User").
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&offers).Error
+	return offers, total, err
+}
+
+// GetOfferCounts groups the offers made between two times by rider and status
+func (r *Repository) GetOfferCounts(start, end time.Time) ([]offerCounts, error) {
+	var counts []offerCounts
+	err := r.db.Model(&database.DispatchOffer{}).
+		Select("rider_id, status, COUNT(*) AS offers, "+
+			"COALESCE(AVG(EXTRACT(EPOCH FROM responded_at - created_at)), 0) AS avg_response_seconds").
+		Where("created_at BETWEEN ? AND ?", start, end).
+		Group("rider_id, status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// CountDispatchedOrders counts the orders offered to riders between two
+// times, and of those the ones escalated to admin
+func (r *Repository) CountDispatchedOrders(start, end time.Time) (dispatched, escalated int64, err error) {
+	offered := r.db.Model(&database.DispatchOffer{}).
+		Select("order_id").
+		Where("created_at BETWEEN ? AND ?", start, end)
+	if err = r.db.Model(&database.Order{}).Where("id IN (?)", offered).Count(&dispatched).Error; err != nil {
+		return
+	}
+	err = r.db.Model(&database.Order{}).
+		Where("id IN (?) AND dispatch_escalated_at IS NOT NULL", offered).
+		Count(&escalated).Error
+	return
+}
+
+// AvgRoundsToAccept is the mean round in which offers made between two
+// times were accepted
+func (r *Repository) AvgRoundsToAccept(start, end time.Time) (float64, error) {
+	var avg float64
+	err := r.db.Model(&database.DispatchOffer{}).
+		Select("COALESCE(AVG(round), 0)").
+		Where("created_at BETWEEN ? AND ? AND status IN ?", start, end,
+			[]database.DispatchOfferStatus{database.DispatchOfferAccepted, database.DispatchOfferReleased}).
+		Scan(&avg).Error
+	return avg, err
+}
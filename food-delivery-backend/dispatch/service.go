@@ -0,0 +1,666 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"food-delivery-backend/cash"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/orders"
+	"food-delivery-backend/pkg"
+	"food-delivery-backend/redis"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// How much each part of a rider's score counts; the weights add up to 1
+const (
+	weightDistance = 0.4
+	weightLoad     = 0.2
+	weightRating   = 0.2
+	weightFairness = 0.2
+)
+
+const (
+	// Riders who have waited this long since their last delivery get the
+	// whole fairness score
+	fairnessIdleMinutes = 60.0
+	// Rating assumed for riders nobody has reviewed yet
+	unratedRating = 3.5
+	// How long one dispatcher holds an order while it sends the next offer
+	orderLockTTL = 10 * time.Second
+)
+
+var (
+	ErrRiderNotFound    = errors.New("rider not found")
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrOfferNotFound    = errors.New("offer not found")
+	ErrOfferClosed      = errors.New("offer is no longer open")
+	ErrOfferExpired     = errors.New("offer has expired")
+	ErrOrderUnavailable = errors.New("order is no longer available")
+	ErrNotAssigned      = errors.New("order is not assigned to you")
+	ErrNotDispatchable  = errors.New("only ready orders without a rider can be dispatched")
+)
+
+// Service offers ready orders to riders one at a time, best scored first.
+// An order goes through rounds of up to DispatchCandidates offers; riders who
+// let an offer expire may be asked again next round, riders who decline are
+// not. After DispatchMaxRounds rounds the order is escalated to admin.
+type Service struct {
+	repo        *Repository
+	db          *gorm.DB
+	flow        *orders.StateMachine
+	notifier    *notifications.Service
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, flow *orders.StateMachine, notifier *notifications.Service, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *Service {
+	s := &Service{
+		repo:        repo,
+		db:          db,
+		flow:        flow,
+		notifier:    notifier,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+
+	// Orders are offered to riders as soon as the food is ready
+	flow.OnEnter(orders.TransitionHook{
+		Name: "dispatch",
+		AfterCommit: func(t *orders.Transition) {
+			if t.Order.AssignedRiderID == nil {
+				s.Dispatch(t.Order.ID)
+			}
+		},
+	}, database.OrderStatusReady)
+
+	return s
+}
+
+func (s *Service) offerWindow() time.Duration {
+	if s.cfg.DispatchOfferSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.cfg.DispatchOfferSeconds) * time.Second
+}
+
+func (s *Service) candidatesPerRound() int64 {
+	if s.cfg.DispatchCandidates <= 0 {
+		return 1
+	}
+	return int64(s.cfg.DispatchCandidates)
+}
+
+func (s *Service) maxRounds() int {
+	if s.cfg.DispatchMaxRounds <= 0 {
+		return 1
+	}
+	return s.cfg.DispatchMaxRounds
+}
+
+// Dispatch moves an order's dispatch along: it expires a lapsed offer, sends
+// the next one, starts a new round or escalates, whichever is due. It does
+// nothing while the current offer is open or another dispatcher holds the
+// order.
+func (s *Service) Dispatch(orderID uint) {
+	ctx := context.Background()
+	lockName := fmt.Sprintf("dispatch-order-%d", orderID)
+	token, ok, err := s.redisClient.AcquireLock(ctx, lockName, orderLockTTL)
+	if err != nil {
+		s.logger.Error("Failed to acquire dispatch lock", zap.Uint("order_id", orderID), zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := s.redisClient.ReleaseLock(context.Background(), lockName, token); err != nil {
+			s.logger.Warn("Failed to release dispatch lock", zap.Uint("order_id", orderID), zap.Error(err))
+		}
+	}()
+
+	if err := s.advance(orderID, time.Now()); err != nil {
+		s.logger.Error("Failed to dispatch order", zap.Uint("order_id", orderID), zap.Error(err))
+	}
+}
+
+func (s *Service) advance(orderID uint, now time.Time) error {
+	order, err := s.repo.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+
+	if pending, err := s.repo.GetPendingOffer(order.ID); err == nil {
+		switch {
+		case order.Status != database.OrderStatusReady || order.AssignedRiderID != nil:
+			return s.closeOffer(pending, database.DispatchOfferCancelled, "order no longer needs a rider", now)
+		case now.Before(pending.ExpiresAt):
+			// Still waiting for the rider to answer
+			return nil
+		}
+		if err := s.closeOffer(pending, database.DispatchOfferExpired, "", now); err != nil {
+			return err
+		}
+	}
+	if order.Status != database.OrderStatusReady || order.AssignedRiderID != nil || order.DispatchEscalatedAt != nil {
+		return nil
+	}
+
+	if order.DispatchRound == 0 || order.DispatchRoundAt == nil {
+		return s.nextRound(order, now)
+	}
+	offered, err := s.repo.CountRoundOffers(order.ID, *order.DispatchRoundAt)
+	if err != nil {
+		return err
+	}
+	if offered >= s.candidatesPerRound() {
+		return s.nextRound(order, now)
+	}
+
+	candidates, err := s.rank(order, *order.DispatchRoundAt, now)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		// A round lasts at least one offer window so that orders with no
+		// riders nearby are not escalated straight away
+		if now.Sub(*order.DispatchRoundAt) < s.offerWindow() {
+			return nil
+		}
+		return s.nextRound(order, now)
+	}
+	return s.offer(order, &candidates[0], now)
+}
+
+// nextRound starts the order's next round of offers, or escalates it once
+// every round has failed
+func (s *Service) nextRound(order *database.Order, now time.Time) error {
+	round := order.DispatchRound + 1
+	if round > s.maxRounds() {
+		return s.escalate(order, now)
+	}
+	if err := s.repo.StartRound(order.ID, round, now); err != nil {
+		return err
+	}
+	order.DispatchRound = round
+	order.DispatchRoundAt = &now
+
+	candidates, err := s.rank(order, now, now)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return s.offer(order, &candidates[0], now)
+}
+
+func (s *Service) escalate(order *database.Order, now time.Time) error {
+	escalated, err := s.repo.Escalate(order.ID, now)
+	if err != nil || !escalated {
+		return err
+	}
+	s.notifier.NotifyAdmin("Order Needs a Rider",
+		fmt.Sprintf("No rider accepted order #%s after %d rounds of offers. Please assign a rider.",
+			order.OrderNumber, order.DispatchRound))
+	s.logger.Warn("Order escalated to admin after dispatch failed",
+		zap.Uint("order_id", order.ID),
+		zap.Int("rounds", order.DispatchRound))
+	return nil
+}
+
+// rank scores the riders who can be offered the order in the round that
+// started at roundStart, best first. Candidates are available riders near
+// the vendor and riders still collecting a batch the order would fit.
+func (s *Service) rank(order *database.Order, roundStart, now time.Time) ([]Candidate, error) {
+	nearby, err := s.redisClient.FindNearestRiders(context.Background(),
+		order.Vendor.Latitude, order.Vendor.Longitude, s.cfg.DispatchRadiusKm)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(nearby))
+	distances := make(map[uint]float64, len(nearby))
+	for _, loc := range nearby {
+		id, err := strconv.ParseUint(loc.Name, 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+		distances[uint(id)] = loc.Dist
+	}
+
+	batching, err := s.batchingRiders(order)
+	if err != nil {
+		return nil, err
+	}
+	for id, km := range batching {
+		if _, ok := distances[id]; !ok {
+			ids = append(ids, id)
+		}
+		distances[id] = km
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	riders, err := s.repo.GetRiders(ids)
+	if err != nil {
+		return nil, err
+	}
+	loads, err := s.repo.GetRiderLoads(ids)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := s.repo.GetBusyRiders(ids, now)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := s.repo.GetExcludedRiders(order.ID, roundStart)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for _, id := range ids {
+		rider, ok := riders[id]
+		if _, batching := batching[id]; !ok || !(rider.IsAvailable || batching) ||
+			!rider.User.IsActive || busy[id] || excluded[id] {
+			continue
+		}
+		if cash.OverLimit(&rider, s.cfg) {
+			continue
+		}
+		load := loads[id]
+		c := Candidate{
+			Rider:        rider,
+			RiderID:      id,
+			DistanceKm:   distances[id],
+			ActiveOrders: load.ActiveOrders,
+			IdleMinutes:  fairnessIdleMinutes,
+		}
+		if load.LastDelivered != nil {
+			c.IdleMinutes = now.Sub(*load.LastDelivered).Minutes()
+		}
+		c.Score = score(&c, s.cfg.DispatchRadiusKm)
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// batchingRiders returns the riders whose batch has room for the order and
+// goes its way, with their distance from the vendor
+func (s *Service) batchingRiders(order *database.Order) (map[uint]float64, error) {
+	batches, err := s.repo.GetGatheringBatches()
+	if err != nil {
+		return nil, err
+	}
+
+	riders := make(map[uint]float64)
+	for _, b := range batches {
+		if b.Rider == nil || len(b.Orders) == 0 || len(b.Orders) >= orders.MaxBatchSize(s.cfg) ||
+			!orders.FitsBatch(order, b.Orders, s.cfg) {
+			continue
+		}
+		km := pkg.CalculateDistance(b.Rider.CurrentLatitude, b.Rider.CurrentLongitude,
+			order.Vendor.Latitude, order.Vendor.Longitude)
+		if km <= s.cfg.DispatchRadiusKm {
+			riders[b.RiderID] = km
+		}
+	}
+	return riders, nil
+}
+
+// score rates a candidate between 0 and 1. Closer riders, riders with fewer
+// orders on hand, better rated riders and riders who have waited longer for
+// work score higher.
+func score(c *Candidate, radiusKm float64) float64 {
+	distance := 1.0
+	if radiusKm > 0 {
+		distance = math.Max(0, 1-c.DistanceKm/radiusKm)
+	}
+	load := 1 / float64(1+c.ActiveOrders)
+	rating := c.Rider.Rating
+	if c.Rider.ReviewCount == 0 {
+		rating = unratedRating
+	}
+	fairness := math.Min(math.Max(c.IdleMinutes, 0), fairnessIdleMinutes) / fairnessIdleMinutes
+
+	total := weightDistance*distance + weightLoad*load + weightRating*rating/5 + weightFairness*fairness
+	return math.Round(total*1000) / 1000
+}
+
+func (s *Service) offer(order *database.Order, c *Candidate, now time.Time) error {
+	offer := &database.DispatchOffer{
+		OrderID:      order.ID,
+		RiderID:      c.RiderID,
+		Round:        order.DispatchRound,
+		Score:        c.Score,
+		DistanceKm:   math.Round(c.DistanceKm*100) / 100,
+		ActiveOrders: c.ActiveOrders,
+		Status:       database.DispatchOfferPending,
+		ExpiresAt:    now.Add(s.offerWindow()),
+	}
+	if err := s.repo.CreateOffer(offer); err != nil {
+		return err
+	}
+
+	s.notifier.PushDeliveryOffer(c.Rider.UserID, offer.ID, order.OrderNumber, offerView(offer, order, now))
+	s.logger.Info("Delivery offered to rider",
+		zap.Uint("order_id", order.ID),
+		zap.Uint("rider_id", c.RiderID),
+		zap.Int("round", offer.Round),
+		zap.Float64("score", c.Score))
+	return nil
+}
+
+// closeOffer ends a pending offer without an answer from the rider and takes
+// it off their screen
+func (s *Service) closeOffer(offer *database.DispatchOffer, status database.DispatchOfferStatus, reason string, now time.Time) error {
+	closed, err := s.repo.CloseOffer(s.db, offer.ID, status, reason, now)
+	if err != nil || !closed {
+		return err
+	}
+	if offer.Rider != nil {
+		s.notifier.PushOfferClosed(offer.Rider.UserID, offer.ID, string(status))
+	}
+	return nil
+}
+
+func offerView(offer *database.DispatchOffer, order *database.Order, now time.Time) Offer {
+	view := Offer{
+		ID:             offer.ID,
+		OrderID:        order.ID,
+		OrderNumber:    order.OrderNumber,
+		VendorName:     order.Vendor.BusinessName,
+		VendorAddress:  order.Vendor.BusinessAddress,
+		VendorLat:      order.Vendor.Latitude,
+		VendorLng:      order.Vendor.Longitude,
+		DeliveryBlock:  order.DeliveryBlock,
+		DeliveryDorm:   order.DeliveryDorm,
+		DistanceKm:     offer.DistanceKm,
+		DeliveryKm:     order.Pricing.DistanceKm,
+		RiderEarnings:  order.RiderEarnings,
+		ExpiresAt:      offer.ExpiresAt,
+		SecondsToReply: int(math.Max(0, offer.ExpiresAt.Sub(now).Seconds())),
+	}
+	if order.Payment != nil {
+		view.PaymentMethod = order.Payment.PaymentMethod
+	}
+	return view
+}
+
+// GetOpenOffers returns the offers a rider can still accept
+func (s *Service) GetOpenOffers(riderUserID uint) ([]Offer, error) {
+	rider, err := s.repo.GetRiderByUserID(riderUserID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+
+	now := time.Now()
+	offers, err := s.repo.GetOpenOffers(rider.ID, now)
+	if err != nil {
+		s.logger.Error("Failed to get delivery offers", zap.Error(err))
+		return nil, errors.New("failed to get offers")
+	}
+
+	views := make([]Offer, 0, len(offers))
+	for i := range offers {
+		if offers[i].Order == nil {
+			continue
+		}
+		views = append(views, offerView(&offers[i], offers[i].Order, now))
+	}
+	return views, nil
+}
+
+// respond records a rider's answer to one of their pending offers
+func (s *Service) respond(riderUserID, offerID uint, status database.DispatchOfferStatus, reason string) (*database.DispatchOffer, *database.Rider, error) {
+	rider, err := s.repo.GetRiderByUserID(riderUserID)
+	if err != nil {
+		return nil, nil, ErrRiderNotFound
+	}
+
+	now := time.Now()
+	var offer *database.DispatchOffer
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		offer, err = s.repo.LockOffer(tx, offerID)
+		if err != nil || offer.RiderID != rider.ID {
+			return ErrOfferNotFound
+		}
+		if offer.Status != database.DispatchOfferPending {
+			return ErrOfferClosed
+		}
+		if !now.Before(offer.ExpiresAt) {
+			return ErrOfferExpired
+		}
+		_, err = s.repo.CloseOffer(tx, offer.ID, status, reason, now)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return offer, rider, nil
+}
+
+// AcceptOffer assigns the offered order to the rider
+func (s *Service) AcceptOffer(riderUserID, offerID uint) (*database.Order, error) {
+	offer, rider, err := s.respond(riderUserID, offerID, database.DispatchOfferAccepted, "")
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.flow.AssignRider(orders.Assignment{
+		OrderID:          offer.OrderID,
+		RiderID:          rider.ID,
+		ActorID:          riderUserID,
+		ActorRole:        "rider",
+		Reason:           fmt.Sprintf("accepted dispatch offer #%d", offer.ID),
+		OnlyIfUnassigned: true,
+	})
+	if err != nil {
+		// The order was taken some other way or this rider can't have it,
+		// so the acceptance doesn't stand and the order moves on
+		if verr := s.repo.VoidOffer(offer.ID, err.Error()); verr != nil {
+			s.logger.Error("Failed to void dispatch offer", zap.Uint("offer_id", offer.ID), zap.Error(verr))
+		}
+		s.Dispatch(offer.OrderID)
+		if errors.Is(err, cash.ErrLimitExceeded) {
+			return nil, err
+		}
+		s.logger.Warn("Accepted offer could not be assigned",
+			zap.Uint("offer_id", offer.ID), zap.Error(err))
+		return nil, ErrOrderUnavailable
+	}
+
+	return order, nil
+}
+
+// DeclineOffer records that the rider turned the order down and offers it to
+// the next candidate
+func (s *Service) DeclineOffer(riderUserID, offerID uint, reason string) error {
+	offer, _, err := s.respond(riderUserID, offerID, database.DispatchOfferDeclined, reason)
+	if err != nil {
+		return err
+	}
+	s.Dispatch(offer.OrderID)
+	return nil
+}
+
+// ReleaseOrder lets a rider hand back an order they have not picked up. The
+// order is offered to other riders again if it is ready.
+func (s *Service) ReleaseOrder(riderUserID, orderID uint, reason string) error {
+	rider, err := s.repo.GetRiderByUserID(riderUserID)
+	if err != nil {
+		return ErrRiderNotFound
+	}
+	order, err := s.repo.GetOrder(orderID)
+	if err != nil {
+		return ErrOrderNotFound
+	}
+	if order.AssignedRiderID == nil || *order.AssignedRiderID != rider.ID {
+		return ErrNotAssigned
+	}
+
+	if _, err := s.flow.UnassignRider(orderID, riderUserID, "rider", reason); err != nil {
+		return err
+	}
+	if err := s.repo.ReleaseAcceptedOffer(orderID, rider.ID, reason); err != nil {
+		s.logger.Error("Failed to record released offer", zap.Uint("order_id", orderID), zap.Error(err))
+	}
+	// Someone has to be found again, even if dispatch had given up before
+	if err := s.repo.ResetDispatch(orderID); err != nil {
+		s.logger.Error("Failed to reset dispatch", zap.Uint("order_id", orderID), zap.Error(err))
+	}
+
+	s.notifier.NotifyAdmin("Rider Released Order",
+		fmt.Sprintf("Rider #%d handed back order #%s: %s", rider.ID, order.OrderNumber, reason))
+	if order.Status == database.OrderStatusReady {
+		s.Dispatch(orderID)
+	}
+	return nil
+}
+
+// Redispatch starts dispatch over for a ready order without a rider, such as
+// one escalated to admin. Riders who declined it are still left out.
+func (s *Service) Redispatch(orderID uint) error {
+	order, err := s.repo.GetOrder(orderID)
+	if err != nil {
+		return ErrOrderNotFound
+	}
+	if order.Status != database.OrderStatusReady || order.AssignedRiderID != nil {
+		return ErrNotDispatchable
+	}
+	if err := s.repo.ResetDispatch(orderID); err != nil {
+		s.logger.Error("Failed to reset dispatch", zap.Uint("order_id", orderID), zap.Error(err))
+		return errors.New("failed to restart dispatch")
+	}
+	s.Dispatch(orderID)
+	return nil
+}
+
+func (s *Service) GetOffers(filters *OfferFilters, page, limit int) ([]database.DispatchOffer, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetOffers(filters, offset, limit)
+}
+
+// GetReport summarises dispatch between two dates (YYYY-MM-DD)
+func (s *Service) GetReport(startDateStr, endDateStr string) (*Report, error) {
+	startDate, err := time.Parse("2006-01-02", startDateStr)
+	if err != nil {
+		return nil, errors.New("invalid start date format")
+	}
+
+	endDate, err := time.Parse("2006-01-02", endDateStr)
+	if err != nil {
+		return nil, errors.New("invalid end date format")
+	}
+	endDate = endDate.Add(24*time.Hour - time.Second)
+
+	counts, err := s.repo.GetOfferCounts(startDate, endDate)
+	if err != nil {
+		s.logger.Error("Failed to get dispatch offers", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+	dispatched, escalated, err := s.repo.CountDispatchedOrders(startDate, endDate)
+	if err != nil {
+		s.logger.Error("Failed to count dispatched orders", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+	avgRounds, err := s.repo.AvgRoundsToAccept(startDate, endDate)
+	if err != nil {
+		s.logger.Error("Failed to average dispatch rounds", zap.Error(err))
+		return nil, errors.New("failed to generate report")
+	}
+
+	report := &Report{
+		StartDate:         startDate.Format("2006-01-02"),
+		EndDate:           endDate.Format("2006-01-02"),
+		ByStatus:          make(map[string]int),
+		OrdersDispatched:  dispatched,
+		OrdersEscalated:   escalated,
+		AvgRoundsToAccept: math.Round(avgRounds*100) / 100,
+	}
+
+	byRider := make(map[uint]*RiderReport)
+	var riderIDs []uint
+	var answered, accepted int
+	var responseTotal float64
+	var responses int
+	for _, c := range counts {
+		report.Offers += int64(c.Offers)
+		report.ByStatus[string(c.Status)] += c.Offers
+
+		r, ok := byRider[c.RiderID]
+		if !ok {
+			r = &RiderReport{RiderID: c.RiderID}
+			byRider[c.RiderID] = r
+			riderIDs = append(riderIDs, c.RiderID)
+		}
+		r.Offers += c.Offers
+
+		switch c.Status {
+		case database.DispatchOfferAccepted, database.DispatchOfferReleased:
+			// Released offers were accepted first
+			if c.Status == database.DispatchOfferReleased {
+				r.Released += c.Offers
+			}
+			r.Accepted += c.Offers
+			accepted += c.Offers
+		case database.DispatchOfferDeclined:
+			r.Declined += c.Offers
+		case database.DispatchOfferExpired:
+			r.Expired += c.Offers
+			answered += c.Offers
+			continue
+		default:
+			continue
+		}
+		// Accepted and declined offers were answered by the rider
+		answered += c.Offers
+		responseTotal += c.AvgResponseSeconds * float64(c.Offers)
+		responses += c.Offers
+		r.AvgResponseSeconds += c.AvgResponseSeconds * float64(c.Offers)
+	}
+	if answered > 0 {
+		report.AcceptanceRate = math.Round(float64(accepted)/float64(answered)*10000) / 100
+	}
+	if responses > 0 {
+		report.AvgResponseSeconds = math.Round(responseTotal/float64(responses)*10) / 10
+	}
+
+	riders, err := s.repo.GetRiders(riderIDs)
+	if err != nil {
+		s.logger.Warn("Failed to load riders for dispatch report", zap.Error(err))
+	}
+	for _, id := range riderIDs {
+		r := byRider[id]
+		if replied := r.Accepted + r.Declined; replied > 0 {
+			r.AvgResponseSeconds = math.Round(r.AvgResponseSeconds/float64(replied)*10) / 10
+		}
+		if rider, ok := riders[id]; ok {
+			r.Name = rider.User.FirstName + " " + rider.User.LastName
+		}
+		report.Riders = append(report.Riders, *r)
+	}
+	sort.Slice(report.Riders, func(i, j int) bool {
+		return report.Riders[i].Offers > report.Riders[j].Offers
+	})
+
+	return report, nil
+}
@@ -0,0 +1,99 @@
+package dispatch
+
+import (
+	"context"
+	"food-delivery-backend/config"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const workerLockName = "rider-dispatch"
+
+// Worker expires offers riders did not answer and moves their orders on to
+// the next candidate. It also picks up ready orders that have no offer out,
+// such as ones a rider handed back.
+type Worker struct {
+	service     *Service
+	repo        *Repository
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewWorker(service *Service, repo *Repository, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *Worker {
+	return &Worker{
+		service:     service,
+		repo:        repo,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run sweeps on every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.DispatchInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx, interval)
+		}
+	}
+}
+
+func (w *Worker) sweep(ctx context.Context, lockTTL time.Duration) {
+	token, ok, err := w.redisClient.AcquireLock(ctx, workerLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire dispatch lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), workerLockName, token); err != nil {
+			w.logger.Warn("Failed to release dispatch lock", zap.Error(err))
+		}
+	}()
+
+	now := time.Now()
+	lapsed, err := w.repo.GetLapsedOrders(now)
+	if err != nil {
+		w.logger.Error("Failed to load lapsed offers", zap.Error(err))
+		return
+	}
+	settled, err := w.repo.GetSettledOrders()
+	if err != nil {
+		w.logger.Error("Failed to load settled offers", zap.Error(err))
+		return
+	}
+	waiting, err := w.repo.GetWaitingOrders(now)
+	if err != nil {
+		w.logger.Error("Failed to load orders waiting for a rider", zap.Error(err))
+		return
+	}
+
+	seen := make(map[uint]bool)
+	for _, ids := range [][]uint{lapsed, settled, waiting} {
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			w.service.Dispatch(id)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package hours
+
+import (
+	"errors"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrVendorNotFound), errors.Is(err, ErrHolidayNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrHolidayExists), errors.Is(err, ErrNoOpeningHours):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// GetHours returns the vendor's opening hours and upcoming holidays
+// @Summary Get opening hours
+// @Tags Vendors
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=Schedule}
+// @Router /vendors/hours [get]
+func (h *Handler) GetHours(c *gin.Context) {
+	schedule, err := h.service.GetSchedule(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get opening hours", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Opening hours retrieved successfully", schedule)
+}
+
+// UpdateHours replaces the vendor's weekly opening hours. The shop is opened
+// and closed automatically at the boundaries; an empty list turns this off.
+// @Summary Update opening hours
+// @Tags Vendors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateHoursRequest true "Opening hours"
+// @Success 200 {object} pkg.Response{data=Schedule}
+// @Router /vendors/hours [put]
+func (h *Handler) UpdateHours(c *gin.Context) {
+	var req UpdateHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	schedule, err := h.service.UpdateHours(c.GetUint("user_id"), &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to update opening hours", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Opening hours updated successfully", schedule)
+}
+
+// AddHoliday closes the shop for a day
+// @Summary Add holiday
+// @Tags Vendors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body HolidayRequest true "Holiday"
+// @Success 201 {object} pkg.Response{data=database.VendorHoliday}
+// @Failure 409 {object} pkg.Response "Already a holiday or no opening hours"
+// @Router /vendors/holidays [post]
+func (h *Handler) AddHoliday(c *gin.Context) {
+	var req HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	holiday, err := h.service.AddHoliday(c.GetUint("user_id"), &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to add holiday", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Holiday added successfully", holiday)
+}
+
+// DeleteHoliday removes a holiday
+// @Summary Delete holiday
+// @Tags Vendors
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Holiday ID"
+// @Success 200 {object} pkg.Response
+// @Router /vendors/holidays/{id} [delete]
+func (h *Handler) DeleteHoliday(c *gin.Context) {
+	holidayID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid holiday ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteHoliday(c.GetUint("user_id"), uint(holidayID)); err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to delete holiday", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Holiday deleted successfully", nil)
+}
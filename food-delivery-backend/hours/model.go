@@ -0,0 +1,33 @@
+package hours
+
+import (
+	"food-delivery-backend/database"
+	"time"
+)
+
+type HoursRange struct {
+	Weekday  int    `json:"weekday" binding:"min=0,max=6"` // 0 is Sunday
+	OpensAt  string `json:"opens_at" binding:"required"`   // HH:MM
+	ClosesAt string `json:"closes_at" binding:"required"`  // HH:MM, before OpensAt to close after midnight
+}
+
+// UpdateHoursRequest replaces the vendor's weekly opening hours. An empty
+// list turns the schedule off.
+type UpdateHoursRequest struct {
+	Timezone string       `json:"timezone" binding:"required"` // IANA name, e.g. Africa/Nairobi
+	Hours    []HoursRange `json:"hours" binding:"dive"`
+}
+
+type HolidayRequest struct {
+	Date   string `json:"date" binding:"required"` // YYYY-MM-DD
+	Reason string `json:"reason"`
+}
+
+// Schedule is a vendor's opening hours and upcoming holidays
+type Schedule struct {
+	Timezone string                   `json:"timezone"`
+	OpenNow  bool                     `json:"open_now"` // within opening hours, whatever the manual toggle says
+	NextOpen *time.Time               `json:"next_open,omitempty"`
+	Hours    []database.OpeningHours  `json:"hours"`
+	Holidays []database.VendorHoliday `json:"holidays"`
+}
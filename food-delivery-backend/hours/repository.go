@@ -0,0 +1,74 @@
+package hours
+
+import (
+	"food-delivery-backend/database"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetVendorByUserID(userID uint) (*database.Vendor, error) {
+	var vendor database.Vendor
+	err := r.db.Scopes(WithSchedule).Where("user_id = ?", userID).First(&vendor).Error
+	return &vendor, err
+}
+
+// ReplaceHours swaps the vendor's weekly hours and timezone and clears the
+// last state the worker applied, so the new schedule takes effect on its
+// next tick
+func (r *Repository) ReplaceHours(vendorID uint, timezone string, hours []database.OpeningHours) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("vendor_id = ?", vendorID).Delete(&database.OpeningHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) > 0 {
+			if err := tx.Create(&hours).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&database.Vendor{}).Where("id = ?", vendorID).Updates(map[string]interface{}{
+			"timezone":       timezone,
+			"scheduled_open": nil,
+		}).Error
+	})
+}
+
+func (r *Repository) CreateHoliday(holiday *database.VendorHoliday) error {
+	return r.db.Create(holiday).Error
+}
+
+func (r *Repository) HolidayExists(vendorID uint, date string) bool {
+	var count int64
+	r.db.Model(&database.VendorHoliday{}).Where("vendor_id = ? AND date = ?", vendorID, date).Count(&count)
+	return count > 0
+}
+
+// DeleteHoliday deletes the vendor's holiday and reports whether it existed
+func (r *Repository) DeleteHoliday(vendorID, holidayID uint) (bool, error) {
+	result := r.db.Where("id = ? AND vendor_id = ?", holidayID, vendorID).Delete(&database.VendorHoliday{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetScheduledVendors returns every vendor with opening hours
+func (r *Repository) GetScheduledVendors() ([]database.Vendor, error) {
+	var vendors []database.Vendor
+	err := r.db.Scopes(WithSchedule).
+		Where("EXISTS (SELECT 1 FROM opening_hours WHERE opening_hours.vendor_id = vendors.id)").
+		Find(&vendors).Error
+	return vendors, err
+}
+
+// ApplySchedule sets the vendor open or closed as the schedule says
+func (r *Repository) ApplySchedule(vendorID uint, open bool) error {
+	return r.db.Model(&database.Vendor{}).Where("id = ?", vendorID).Updates(map[string]interface{}{
+		"is_open":        open,
+		"scheduled_open": open,
+	}).Error
+}
@@ -0,0 +1,148 @@
+package hours
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrOutsideOpeningHours = errors.New("vendor is closed at this time")
+
+// DateLayout is the format of holiday dates
+const DateLayout = "2006-01-02"
+
+// WithSchedule preloads what OpenAt needs: the vendor's opening hours and
+// holidays from yesterday on (UTC, so no vendor timezone misses today)
+func WithSchedule(db *gorm.DB) *gorm.DB {
+	since := time.Now().UTC().AddDate(0, 0, -1).Format(DateLayout)
+	return db.Preload("OpeningHours", func(db *gorm.DB) *gorm.DB {
+		return db.Order("weekday, opens_at")
+	}).Preload("Holidays", func(db *gorm.DB) *gorm.DB {
+		return db.Where("date >= ?", since).Order("date")
+	})
+}
+
+// Location returns the vendor's timezone, falling back to UTC
+func Location(vendor *database.Vendor) *time.Location {
+	if vendor.Timezone != "" {
+		if loc, err := time.LoadLocation(vendor.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// HasSchedule reports whether the vendor has opening hours. Vendors without
+// them are opened and closed by hand only.
+func HasSchedule(vendor *database.Vendor) bool {
+	return len(vendor.OpeningHours) > 0
+}
+
+// OpenAt reports whether at falls inside the vendor's opening hours. The
+// vendor must be loaded WithSchedule.
+func OpenAt(vendor *database.Vendor, at time.Time) bool {
+	if !HasSchedule(vendor) {
+		return true
+	}
+	today := midnight(at.In(Location(vendor)))
+	// Yesterday's ranges may run past midnight into today
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		for _, s := range spans(vendor, day) {
+			if !at.Before(s.start) && at.Before(s.end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NextOpen returns when the vendor next opens after at, looking a week ahead
+func NextOpen(vendor *database.Vendor, at time.Time) (time.Time, bool) {
+	today := midnight(at.In(Location(vendor)))
+	var next time.Time
+	for i := 0; i <= 7; i++ {
+		for _, s := range spans(vendor, today.AddDate(0, 0, i)) {
+			if s.start.After(at) && (next.IsZero() || s.start.Before(next)) {
+				next = s.start
+			}
+		}
+		if !next.IsZero() {
+			return next, true
+		}
+	}
+	return next, false
+}
+
+// CheckOpen returns ErrOutsideOpeningHours, saying when the vendor opens
+// next, if at is outside the vendor's opening hours
+func CheckOpen(vendor *database.Vendor, at time.Time) error {
+	if OpenAt(vendor, at) {
+		return nil
+	}
+	if next, ok := NextOpen(vendor, at); ok {
+		return fmt.Errorf("%w, opens %s", ErrOutsideOpeningHours, next.Format("Mon 15:04 MST"))
+	}
+	return ErrOutsideOpeningHours
+}
+
+// ParseClock converts HH:MM to minutes after midnight. 24:00 is allowed so
+// a range can close at midnight.
+func ParseClock(clock string) (int, error) {
+	parts := strings.Split(clock, ":")
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", clock)
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", clock)
+	}
+	return h*60 + m, nil
+}
+
+type span struct {
+	start, end time.Time
+}
+
+// spans returns the ranges opening on day (local midnight), none on holidays
+func spans(vendor *database.Vendor, day time.Time) []span {
+	date := day.Format(DateLayout)
+	for _, h := range vendor.Holidays {
+		if h.Date == date {
+			return nil
+		}
+	}
+
+	var out []span
+	for _, h := range vendor.OpeningHours {
+		if h.Weekday != int(day.Weekday()) {
+			continue
+		}
+		opens, err := ParseClock(h.OpensAt)
+		if err != nil {
+			continue
+		}
+		closes, err := ParseClock(h.ClosesAt)
+		if err != nil {
+			continue
+		}
+		if closes <= opens {
+			closes += 24 * 60
+		}
+		// time.Date normalises the minutes, which keeps DST changes right
+		out = append(out, span{
+			start: time.Date(day.Year(), day.Month(), day.Day(), 0, opens, 0, 0, day.Location()),
+			end:   time.Date(day.Year(), day.Month(), day.Day(), 0, closes, 0, 0, day.Location()),
+		})
+	}
+	return out
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
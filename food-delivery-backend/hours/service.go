@@ -0,0 +1,155 @@
+package hours
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrVendorNotFound   = errors.New("vendor not found")
+	ErrHolidayNotFound  = errors.New("holiday not found")
+	ErrHolidayExists    = errors.New("a holiday is already set for this date")
+	ErrNoOpeningHours   = errors.New("set opening hours before adding holidays")
+	ErrHolidayInPast    = errors.New("holiday date is in the past")
+	ErrInvalidTimezone  = errors.New("invalid timezone")
+	ErrInvalidHoliday   = errors.New("invalid holiday date, use YYYY-MM-DD")
+	ErrEmptyOpeningSpan = errors.New("opening and closing times must differ")
+)
+
+type Service struct {
+	repo   *Repository
+	logger *zap.Logger
+}
+
+func NewService(repo *Repository, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// GetSchedule returns the vendor's hours and upcoming holidays.
+// vendorUserID is the user id.
+func (s *Service) GetSchedule(vendorUserID uint) (*Schedule, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorUserID)
+	if err != nil {
+		return nil, ErrVendorNotFound
+	}
+	return schedule(vendor, time.Now()), nil
+}
+
+// UpdateHours replaces the vendor's weekly opening hours
+func (s *Service) UpdateHours(vendorUserID uint, req *UpdateHoursRequest) (*Schedule, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorUserID)
+	if err != nil {
+		return nil, ErrVendorNotFound
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, req.Timezone)
+	}
+
+	hours := make([]database.OpeningHours, 0, len(req.Hours))
+	for _, h := range req.Hours {
+		opens, err := ParseClock(h.OpensAt)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := ParseClock(h.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+		if opens%(24*60) == closes%(24*60) {
+			return nil, fmt.Errorf("%w: %s-%s", ErrEmptyOpeningSpan, h.OpensAt, h.ClosesAt)
+		}
+		hours = append(hours, database.OpeningHours{
+			VendorID: vendor.ID,
+			Weekday:  h.Weekday,
+			OpensAt:  h.OpensAt,
+			ClosesAt: h.ClosesAt,
+		})
+	}
+
+	if err := s.repo.ReplaceHours(vendor.ID, req.Timezone, hours); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Vendor opening hours updated",
+		zap.Uint("vendor_id", vendor.ID),
+		zap.Int("ranges", len(hours)))
+
+	return s.GetSchedule(vendorUserID)
+}
+
+// AddHoliday closes the vendor for a whole day. Ranges that open on the
+// holiday are skipped, including ones that would run past midnight.
+func (s *Service) AddHoliday(vendorUserID uint, req *HolidayRequest) (*database.VendorHoliday, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorUserID)
+	if err != nil {
+		return nil, ErrVendorNotFound
+	}
+	if !HasSchedule(vendor) {
+		return nil, ErrNoOpeningHours
+	}
+
+	loc := Location(vendor)
+	date, err := time.ParseInLocation(DateLayout, req.Date, loc)
+	if err != nil {
+		return nil, ErrInvalidHoliday
+	}
+	if date.Before(midnight(time.Now().In(loc))) {
+		return nil, ErrHolidayInPast
+	}
+	if s.repo.HolidayExists(vendor.ID, req.Date) {
+		return nil, ErrHolidayExists
+	}
+
+	holiday := &database.VendorHoliday{
+		VendorID: vendor.ID,
+		Date:     date.Format(DateLayout),
+		Reason:   req.Reason,
+	}
+	if err := s.repo.CreateHoliday(holiday); err != nil {
+		return nil, err
+	}
+	return holiday, nil
+}
+
+func (s *Service) DeleteHoliday(vendorUserID, holidayID uint) error {
+	vendor, err := s.repo.GetVendorByUserID(vendorUserID)
+	if err != nil {
+		return ErrVendorNotFound
+	}
+	deleted, err := s.repo.DeleteHoliday(vendor.ID, holidayID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrHolidayNotFound
+	}
+	return nil
+}
+
+func schedule(vendor *database.Vendor, now time.Time) *Schedule {
+	sched := &Schedule{
+		Timezone: Location(vendor).String(),
+		OpenNow:  OpenAt(vendor, now),
+		Hours:    vendor.OpeningHours,
+		Holidays: vendor.Holidays,
+	}
+	if sched.Hours == nil {
+		sched.Hours = []database.OpeningHours{}
+	}
+	if sched.Holidays == nil {
+		sched.Holidays = []database.VendorHoliday{}
+	}
+	if HasSchedule(vendor) && !sched.OpenNow {
+		if next, ok := NextOpen(vendor, now); ok {
+			sched.NextOpen = &next
+		}
+	}
+	return sched
+}
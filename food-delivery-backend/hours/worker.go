@@ -0,0 +1,113 @@
+package hours
+
+import (
+	"context"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const workerLockName = "vendor-hours"
+
+// Worker opens and closes vendors at their opening hours boundaries. It only
+// acts when the schedule's answer changes, so a vendor who toggles their shop
+// by hand keeps that state until the next boundary.
+type Worker struct {
+	repo        *Repository
+	notifier    *notifications.Service
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewWorker(repo *Repository, notifier *notifications.Service, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *Worker {
+	return &Worker{
+		repo:        repo,
+		notifier:    notifier,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run syncs vendors on every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.VendorHoursInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sync(ctx, interval)
+		}
+	}
+}
+
+func (w *Worker) sync(ctx context.Context, lockTTL time.Duration) {
+	token, ok, err := w.redisClient.AcquireLock(ctx, workerLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire vendor hours lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), workerLockName, token); err != nil {
+			w.logger.Warn("Failed to release vendor hours lock", zap.Error(err))
+		}
+	}()
+
+	vendors, err := w.repo.GetScheduledVendors()
+	if err != nil {
+		w.logger.Error("Failed to load scheduled vendors", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for i := range vendors {
+		if ctx.Err() != nil {
+			return
+		}
+		w.apply(ctx, &vendors[i], now)
+	}
+}
+
+func (w *Worker) apply(ctx context.Context, vendor *database.Vendor, now time.Time) {
+	open := OpenAt(vendor, now)
+	if vendor.ScheduledOpen != nil && *vendor.ScheduledOpen == open {
+		return
+	}
+
+	if err := w.repo.ApplySchedule(vendor.ID, open); err != nil {
+		w.logger.Error("Failed to apply opening hours", zap.Uint("vendor_id", vendor.ID), zap.Error(err))
+		return
+	}
+	if err := w.redisClient.SetVendorStatus(ctx, vendor.ID, open); err != nil {
+		w.logger.Warn("Failed to cache vendor status", zap.Uint("vendor_id", vendor.ID), zap.Error(err))
+	}
+	if vendor.IsOpen == open {
+		return
+	}
+
+	title, message := "Shop Closed", "Your shop was closed automatically by your opening hours"
+	if open {
+		title, message = "Shop Opened", "Your shop was opened automatically by your opening hours"
+	}
+	w.notifier.NotifyVendor(vendor.UserID, title, message, "vendor_hours", fmt.Sprintf("%d", vendor.ID))
+
+	w.logger.Info("Vendor opened or closed by opening hours",
+		zap.Uint("vendor_id", vendor.ID),
+		zap.Bool("is_open", open))
+}
@@ -0,0 +1,251 @@
+package ledger
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetBalances returns the balance of every ledger account
+// @Summary Ledger account balances
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]AccountBalance}
+// @Router /admin/ledger/balances [get]
+func (h *Handler) GetBalances(c *gin.Context) {
+	balances, err := h.service.GetAccountBalances()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get balances", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Balances retrieved successfully", balances)
+}
+
+// GetEntries lists ledger entries with their transactions
+// @Summary List ledger entries
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "Entry type"
+// @Param order_id query int false "Filter by order"
+// @Param user_id query int false "Filter by vendor or rider user"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/ledger/entries [get]
+func (h *Handler) GetEntries(c *gin.Context) {
+	filters := EntryFilters{Type: c.Query("type")}
+	if v := c.Query("order_id"); v != "" {
+		orderID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+			return
+		}
+		id := uint(orderID)
+		filters.OrderID = &id
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid user ID", nil)
+			return
+		}
+		id := uint(userID)
+		filters.UserID = &id
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	entries, total, err := h.service.GetEntries(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get ledger entries", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Ledger entries retrieved successfully", entries, page, limit, total)
+}
+
+// GetEntry returns a ledger entry with its transactions
+// @Summary Get ledger entry
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.LedgerEntry}
+// @Router /admin/ledger/entries/{id} [get]
+func (h *Handler) GetEntry(c *gin.Context) {
+	entryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid entry ID", nil)
+		return
+	}
+
+	entry, err := h.service.GetEntry(uint(entryID))
+	if err != nil {
+		pkg.SendError(c, http.StatusNotFound, "Ledger entry not found", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Ledger entry retrieved successfully", entry)
+}
+
+// Reconcile checks cached vendor, rider and wallet balances against the ledger
+// @Summary Reconcile balances
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=ReconciliationReport}
+// @Router /admin/ledger/reconcile [get]
+func (h *Handler) Reconcile(c *gin.Context) {
+	report, err := h.service.Reconcile(false)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to reconcile balances", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Reconciliation completed", report)
+}
+
+// FixBalances resets cached vendor, rider and wallet balances to the ledger
+// @Summary Fix balances from the ledger
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=ReconciliationReport}
+// @Router /admin/ledger/reconcile [post]
+func (h *Handler) FixBalances(c *gin.Context) {
+	report, err := h.service.Reconcile(true)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to reconcile balances", err.Error())
+		return
+	}
+
+	h.logger.Info("Balances reset from ledger",
+		zap.Uint("admin_id", c.GetUint("user_id")),
+		zap.Int("fixed", len(report.Mismatches)))
+	pkg.SendSuccess(c, http.StatusOK, "Balances reconciled", report)
+}
+
+// CreateAdjustment credits or debits a vendor or rider balance
+// @Summary Post balance adjustment
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body AdjustmentRequest true "Adjustment"
+// @Success 201 {object} pkg.Response{data=database.LedgerEntry}
+// @Router /admin/ledger/adjustments [post]
+func (h *Handler) CreateAdjustment(c *gin.Context) {
+	var req AdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	entry, err := h.service.CreateAdjustment(c.GetUint("user_id"), &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to post adjustment", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Adjustment posted successfully", entry)
+}
+
+// RecordPayout records a payout made outside the platform
+// @Summary Record payout
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PayoutRequest true "Payout"
+// @Success 201 {object} pkg.Response{data=database.LedgerEntry}
+// @Router /admin/ledger/payouts [post]
+func (h *Handler) RecordPayout(c *gin.Context) {
+	var req PayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	entry, err := h.service.RecordPayout(c.GetUint("user_id"), &req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrAlreadyPosted) {
+			status = http.StatusConflict
+		}
+		pkg.SendError(c, status, "Failed to record payout", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Payout recorded successfully", entry)
+}
+
+// GetMyStatement returns the caller's settlement statement
+// @Summary Get settlement statement
+// @Tags Vendors, Riders
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "week (default) or month"
+// @Param date query string false "Any date in the period (YYYY-MM-DD), default today"
+// @Success 200 {object} pkg.Response{data=Statement}
+// @Router /vendors/statement [get]
+// @Router /riders/statement [get]
+func (h *Handler) GetMyStatement(c *gin.Context) {
+	account := database.LedgerAccount(c.GetString("user_role"))
+
+	statement, err := h.service.GetStatement(account, c.GetUint("user_id"), c.Query("period"), c.Query("date"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to get statement", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Statement retrieved successfully", statement)
+}
+
+// GetStatement returns a vendor or rider's settlement statement
+// @Summary Get settlement statement for a vendor or rider
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param account query string true "vendor or rider"
+// @Param user_id query int true "User ID of the vendor or rider"
+// @Param period query string false "week (default) or month"
+// @Param date query string false "Any date in the period (YYYY-MM-DD), default today"
+// @Success 200 {object} pkg.Response{data=Statement}
+// @Router /admin/ledger/statements [get]
+func (h *Handler) GetStatement(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+	account := database.LedgerAccount(c.Query("account"))
+
+	statement, err := h.service.GetStatement(account, uint(userID), c.Query("period"), c.Query("date"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to get statement", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Statement retrieved successfully", statement)
+}
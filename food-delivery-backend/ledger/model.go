@@ -0,0 +1,82 @@
+package ledger
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"time"
+)
+
+type AdjustmentRequest struct {
+	Account database.LedgerAccount `json:"account" binding:"required,oneof=vendor rider"`
+	UserID  uint                   `json:"user_id" binding:"required"`
+	Amount  pkg.Money              `json:"amount" binding:"required"` // negative to debit
+	Reason  string                 `json:"reason" binding:"required"`
+}
+
+// PayoutRequest records a payout made outside the withdrawal flow
+type PayoutRequest struct {
+	Account   database.LedgerAccount `json:"account" binding:"required,oneof=vendor rider"`
+	UserID    uint                   `json:"user_id" binding:"required"`
+	Amount    pkg.Money              `json:"amount" binding:"required,gt=0"`
+	Reference string                 `json:"reference" binding:"required"` // bank or transfer reference
+	Note      string                 `json:"note"`
+}
+
+type EntryFilters struct {
+	Type    string
+	OrderID *uint
+	UserID  *uint
+}
+
+// AccountBalance is the total of one ledger account. Vendor and rider
+// balances are summed over all holders.
+type AccountBalance struct {
+	Account      database.LedgerAccount `json:"account"`
+	Balance      pkg.Money              `json:"balance"`
+	Transactions int64                  `json:"transactions"`
+}
+
+// BalanceMismatch is a vendor, rider or wallet whose cached balance differs from
+// their ledger balance
+type BalanceMismatch struct {
+	Account    database.LedgerAccount `json:"account"`
+	UserID     uint                   `json:"user_id"`
+	Name       string                 `json:"name"`
+	Cached     pkg.Money              `json:"cached"`
+	Ledger     pkg.Money              `json:"ledger"`
+	Difference pkg.Money              `json:"difference"`
+}
+
+type ReconciliationReport struct {
+	CheckedAt         time.Time         `json:"checked_at"`
+	AccountsChecked   int               `json:"accounts_checked"`
+	UnbalancedEntries []uint            `json:"unbalanced_entries"`
+	Mismatches        []BalanceMismatch `json:"mismatches"`
+	Fixed             bool              `json:"fixed"`
+}
+
+// Statement is a vendor or rider's settlement statement for one period,
+// built from their ledger lines
+type Statement struct {
+	Account        database.LedgerAccount `json:"account"`
+	UserID         uint                   `json:"user_id"`
+	PeriodStart    string                 `json:"period_start"`
+	PeriodEnd      string                 `json:"period_end"`
+	OpeningBalance pkg.Money              `json:"opening_balance"`
+	Earnings       pkg.Money              `json:"earnings"`
+	Refunds        pkg.Money              `json:"refunds"`
+	Adjustments    pkg.Money              `json:"adjustments"`
+	Withdrawals    pkg.Money              `json:"withdrawals"`
+	ClosingBalance pkg.Money              `json:"closing_balance"`
+	Lines          []StatementLine        `json:"lines"`
+}
+
+type StatementLine struct {
+	Date        time.Time `json:"date"`
+	EntryID     uint      `json:"entry_id"`
+	OrderID     *uint     `json:"order_id,omitempty"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Amount      pkg.Money `json:"amount"`
+	Balance     pkg.Money `json:"balance"`
+}
@@ -0,0 +1,501 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+
+	"gorm.io/gorm"
+)
+
+// StatusPosted is the status of every ledger transaction; entries are never
+// edited, mistakes are corrected with an adjustment
+const StatusPosted = "posted"
+
+// Transaction types
+const (
+	TypePayment       = "payment"
+	TypeEarning       = "earning"
+	TypeCommission    = "commission"
+	TypeFee           = "fee"
+	TypeDiscount      = "discount"
+	TypeRefund        = "refund"
+	TypeAdjustment    = "adjustment"
+	TypePayout        = "payout"
+	TypeCashCollected = "cash_collected"
+	TypeCashHandover  = "cash_handover"
+)
+
+var (
+	ErrUnbalanced    = errors.New("ledger entry does not balance")
+	ErrAlreadyPosted = errors.New("ledger entry has already been posted")
+)
+
+// Line is one side of an entry. UserID is required for vendor, rider and
+// wallet accounts and must be empty for platform accounts.
+type Line struct {
+	Account     database.LedgerAccount
+	UserID      *uint
+	Amount      pkg.Money
+	Type        string
+	Description string
+}
+
+// Entry is a set of lines that must sum to zero
+type Entry struct {
+	Type        database.LedgerEntryType
+	OrderID     *uint
+	ReferenceID string
+	Description string
+	CreatedByID *uint
+	Lines       []Line
+}
+
+// IsHolderAccount reports whether the account belongs to a vendor or rider
+func IsHolderAccount(account database.LedgerAccount) bool {
+	return account == database.LedgerAccountVendor || account == database.LedgerAccountRider
+}
+
+// Post records an entry inside tx and applies its per-user lines to the
+// cached balance columns, so balances only ever move together with the
+// ledger. Zero lines are dropped.
+func Post(tx *gorm.DB, e Entry) (*database.LedgerEntry, error) {
+	if e.ReferenceID == "" {
+		return nil, errors.New("ledger entry needs a reference")
+	}
+
+	var sum pkg.Money
+	lines := make([]Line, 0, len(e.Lines))
+	for _, l := range e.Lines {
+		if l.Amount == 0 {
+			continue
+		}
+		if (holderModel(l.Account) != nil) != (l.UserID != nil) {
+			return nil, fmt.Errorf("ledger line for %s has the wrong holder", l.Account)
+		}
+		sum += l.Amount
+		lines = append(lines, l)
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("%w: %s is off by %s", ErrUnbalanced, e.ReferenceID, sum)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("ledger entry %s has no amounts", e.ReferenceID)
+	}
+
+	var existing int64
+	if err := tx.Model(&database.LedgerEntry{}).Where("reference_id = ?", e.ReferenceID).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyPosted
+	}
+
+	entry := &database.LedgerEntry{
+		Type:        e.Type,
+		OrderID:     e.OrderID,
+		ReferenceID: e.ReferenceID,
+		Description: e.Description,
+		CreatedByID: e.CreatedByID,
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, err
+	}
+
+	for _, l := range lines {
+		txn := database.Transaction{
+			EntryID:     entry.ID,
+			Account:     l.Account,
+			UserID:      l.UserID,
+			OrderID:     e.OrderID,
+			Amount:      l.Amount,
+			Type:        l.Type,
+			Status:      StatusPosted,
+			Description: l.Description,
+			ReferenceID: e.ReferenceID,
+		}
+		if err := tx.Create(&txn).Error; err != nil {
+			return nil, err
+		}
+		if err := applyToBalance(tx, l); err != nil {
+			return nil, err
+		}
+		entry.Transactions = append(entry.Transactions, txn)
+	}
+	return entry, nil
+}
+
+// holderModel returns the model that caches a holder account's balance, or
+// nil for platform accounts
+func holderModel(account database.LedgerAccount) interface{} {
+	switch account {
+	case database.LedgerAccountVendor:
+		return &database.Vendor{}
+	case database.LedgerAccountRider:
+		return &database.Rider{}
+	case database.LedgerAccountWallet:
+		return &database.Student{}
+	case database.LedgerAccountRiderCash:
+		return &database.Rider{}
+	}
+	return nil
+}
+
+// balanceColumn is the column of holderModel that caches the balance
+func balanceColumn(account database.LedgerAccount) string {
+	switch account {
+	case database.LedgerAccountWallet:
+		return "wallet_balance"
+	case database.LedgerAccountRiderCash:
+		return "cash_in_hand"
+	}
+	return "current_balance"
+}
+
+// cachedAmount converts a ledger amount to its effect on the cached balance.
+// Cash a rider holds is owed to the platform, so rider_cash lines are debits
+// while cash_in_hand is kept positive.
+func cachedAmount(account database.LedgerAccount, amount pkg.Money) pkg.Money {
+	if account == database.LedgerAccountRiderCash {
+		return -amount
+	}
+	return amount
+}
+
+func applyToBalance(tx *gorm.DB, l Line) error {
+	model := holderModel(l.Account)
+	if model == nil {
+		return nil
+	}
+	column := balanceColumn(l.Account)
+	res := tx.Model(model).Where("user_id = ?", *l.UserID).
+		Update(column, gorm.Expr(column+" + ?", cachedAmount(l.Account, l.Amount)))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("no %s account for user %d", l.Account, *l.UserID)
+	}
+	return nil
+}
+
+// PostOrderDelivered splits what the customer paid for a delivered order
+// between the vendor, the rider and the platform. order must be loaded with
+// its Vendor, Student and AssignedRider.
+func PostOrderDelivered(tx *gorm.DB, order *database.Order) (*database.LedgerEntry, error) {
+	// Platform-funded coupons are a platform expense; vendor-funded ones
+	// were already taken out of VendorEarnings
+	var platformDiscount pkg.Money
+	if order.CouponID != nil && order.DiscountAmount > 0 {
+		var coupon database.Coupon
+		if err := tx.Unscoped().Select("id", "vendor_id").First(&coupon, *order.CouponID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load coupon for order %d: %w", order.ID, err)
+		}
+		if coupon.VendorID == nil {
+			platformDiscount = order.DiscountAmount
+		}
+	}
+
+	var riderShare pkg.Money
+	var riderUserID *uint
+	if order.AssignedRider != nil {
+		riderShare = order.RiderEarnings
+		riderUserID = &order.AssignedRider.UserID
+	}
+
+	method := "unknown"
+	if order.Payment != nil {
+		method = order.Payment.PaymentMethod
+	}
+
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryOrderDelivered,
+		OrderID:     &order.ID,
+		ReferenceID: fmt.Sprintf("order:%d:delivered", order.ID),
+		Description: "Order #" + order.OrderNumber + " delivered",
+		Lines: []Line{
+			{Account: database.LedgerAccountCollections, Amount: -order.TotalAmount, Type: TypePayment,
+				Description: "Paid by customer (" + method + ")"},
+			{Account: database.LedgerAccountVendor, UserID: &order.Vendor.UserID, Amount: order.VendorEarnings, Type: TypeEarning,
+				Description: "Vendor share"},
+			{Account: database.LedgerAccountRider, UserID: riderUserID, Amount: riderShare, Type: TypeEarning,
+				Description: "Rider payout"},
+			{Account: database.LedgerAccountCommission, Amount: order.CommissionAmount, Type: TypeCommission,
+				Description: "Commission"},
+			{Account: database.LedgerAccountServiceFees, Amount: order.ServiceFee, Type: TypeFee,
+				Description: "Service fee"},
+			{Account: database.LedgerAccountDeliveryFees, Amount: order.DeliveryFee - riderShare, Type: TypeFee,
+				Description: "Delivery fee after rider payout"},
+			{Account: database.LedgerAccountDiscounts, Amount: -platformDiscount, Type: TypeDiscount,
+				Description: "Platform-funded coupon " + order.CouponCode},
+		},
+	})
+}
+
+// Refund returns money to a customer. VendorShare and RiderShare are taken
+// back from their balances; the platform bears the rest. ToWallet credits
+// the student's wallet instead of paying the money out.
+type Refund struct {
+	Order       *database.Order
+	Amount      pkg.Money
+	VendorShare pkg.Money
+	RiderShare  pkg.Money
+	ToWallet    bool
+	Reason      string
+	ReferenceID string
+	CreatedByID *uint
+}
+
+func PostRefund(tx *gorm.DB, r Refund) (*database.LedgerEntry, error) {
+	if r.Amount <= 0 {
+		return nil, errors.New("refund amount must be greater than 0")
+	}
+	if r.VendorShare < 0 || r.RiderShare < 0 || r.VendorShare+r.RiderShare > r.Amount {
+		return nil, errors.New("refund shares must be between 0 and the refund amount")
+	}
+	if r.RiderShare > 0 && r.Order.AssignedRider == nil {
+		return nil, errors.New("order has no rider to recover the refund from")
+	}
+
+	var riderUserID *uint
+	if r.Order.AssignedRider != nil {
+		riderUserID = &r.Order.AssignedRider.UserID
+	}
+
+	customer := Line{Account: database.LedgerAccountCollections, Amount: r.Amount, Type: TypeRefund,
+		Description: "Refunded to customer"}
+	if r.ToWallet {
+		customer = Line{Account: database.LedgerAccountWallet, UserID: &r.Order.Student.UserID, Amount: r.Amount, Type: TypeRefund,
+			Description: "Refund on order #" + r.Order.OrderNumber}
+	}
+
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryRefund,
+		OrderID:     &r.Order.ID,
+		ReferenceID: r.ReferenceID,
+		Description: "Refund on order #" + r.Order.OrderNumber + ": " + r.Reason,
+		CreatedByID: r.CreatedByID,
+		Lines: []Line{
+			customer,
+			{Account: database.LedgerAccountVendor, UserID: &r.Order.Vendor.UserID, Amount: -r.VendorShare, Type: TypeRefund,
+				Description: "Recovered from vendor"},
+			{Account: database.LedgerAccountRider, UserID: riderUserID, Amount: -r.RiderShare, Type: TypeRefund,
+				Description: "Recovered from rider"},
+			{Account: database.LedgerAccountRefunds, Amount: -(r.Amount - r.VendorShare - r.RiderShare), Type: TypeRefund,
+				Description: "Borne by platform"},
+		},
+	})
+}
+
+// Adjustment credits (positive Amount) or debits a vendor, rider or wallet
+// balance against the platform
+type Adjustment struct {
+	Account     database.LedgerAccount
+	UserID      uint
+	Amount      pkg.Money
+	Reason      string
+	ReferenceID string
+	CreatedByID *uint
+}
+
+func PostAdjustment(tx *gorm.DB, a Adjustment) (*database.LedgerEntry, error) {
+	if holderModel(a.Account) == nil || a.Account == database.LedgerAccountRiderCash {
+		return nil, errors.New("adjustments can only be made to vendor, rider or wallet accounts")
+	}
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryAdjustment,
+		ReferenceID: a.ReferenceID,
+		Description: a.Reason,
+		CreatedByID: a.CreatedByID,
+		Lines: []Line{
+			{Account: a.Account, UserID: &a.UserID, Amount: a.Amount, Type: TypeAdjustment, Description: a.Reason},
+			{Account: database.LedgerAccountAdjustments, Amount: -a.Amount, Type: TypeAdjustment, Description: a.Reason},
+		},
+	})
+}
+
+// Payout records money paid out of a vendor or rider balance
+type Payout struct {
+	Account     database.LedgerAccount
+	UserID      uint
+	Amount      pkg.Money
+	Type        string // defaults to TypePayout
+	Description string
+	ReferenceID string
+	CreatedByID *uint
+}
+
+func PostPayout(tx *gorm.DB, p Payout) (*database.LedgerEntry, error) {
+	if !IsHolderAccount(p.Account) {
+		return nil, errors.New("payouts can only be made from vendor or rider accounts")
+	}
+	if p.Amount <= 0 {
+		return nil, errors.New("payout amount must be greater than 0")
+	}
+	txnType := p.Type
+	if txnType == "" {
+		txnType = TypePayout
+	}
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryPayout,
+		ReferenceID: p.ReferenceID,
+		Description: p.Description,
+		CreatedByID: p.CreatedByID,
+		Lines: []Line{
+			{Account: p.Account, UserID: &p.UserID, Amount: -p.Amount, Type: txnType, Description: p.Description},
+			{Account: database.LedgerAccountPayouts, Amount: p.Amount, Type: txnType, Description: p.Description},
+		},
+	})
+}
+
+// TypeWithdrawal marks the transactions of a withdrawal request
+const TypeWithdrawal = "withdrawal"
+
+// PostWithdrawalHold moves a requested withdrawal out of the holder's
+// available balance into the holds account
+func PostWithdrawalHold(tx *gorm.DB, w *database.Withdrawal) error {
+	return postWithdrawal(tx, w, "hold", "Withdrawal requested",
+		Line{Account: w.Account, UserID: &w.UserID, Amount: -w.Amount},
+		Line{Account: database.LedgerAccountWithdrawals, Amount: w.Amount})
+}
+
+// PostWithdrawalRelease returns a held withdrawal to the holder's balance
+func PostWithdrawalRelease(tx *gorm.DB, w *database.Withdrawal) error {
+	return postWithdrawal(tx, w, "release", "Withdrawal "+string(w.Status)+", amount released",
+		Line{Account: database.LedgerAccountWithdrawals, Amount: -w.Amount},
+		Line{Account: w.Account, UserID: &w.UserID, Amount: w.Amount})
+}
+
+// PostWithdrawalPaid records that a held withdrawal has been paid out
+func PostWithdrawalPaid(tx *gorm.DB, w *database.Withdrawal) error {
+	return postWithdrawal(tx, w, "paid", "Withdrawal paid ("+w.PaymentReference+")",
+		Line{Account: database.LedgerAccountWithdrawals, Amount: -w.Amount},
+		Line{Account: database.LedgerAccountPayouts, Amount: w.Amount})
+}
+
+func postWithdrawal(tx *gorm.DB, w *database.Withdrawal, step, description string, lines ...Line) error {
+	for i := range lines {
+		lines[i].Type = TypeWithdrawal
+		lines[i].Description = description
+	}
+	_, err := Post(tx, Entry{
+		Type:        database.LedgerEntryWithdrawal,
+		ReferenceID: fmt.Sprintf("withdrawal:%d:%s", w.ID, step),
+		Description: fmt.Sprintf("Withdrawal #%d: %s", w.ID, description),
+		CreatedByID: w.ReviewedByID,
+		Lines:       lines,
+	})
+	return err
+}
+
+// TypeTopUp marks money added to a student wallet
+const TypeTopUp = "top_up"
+
+// PostWalletTopUp credits a student's wallet with a captured card payment
+func PostWalletTopUp(tx *gorm.DB, topUp *database.WalletTopUp) error {
+	_, err := Post(tx, Entry{
+		Type:        database.LedgerEntryWalletTopUp,
+		ReferenceID: fmt.Sprintf("topup:%d", topUp.ID),
+		Description: fmt.Sprintf("Wallet top-up #%d", topUp.ID),
+		Lines: []Line{
+			{Account: database.LedgerAccountCollections, Amount: -topUp.Amount, Type: TypePayment,
+				Description: "Paid by customer (card)"},
+			{Account: database.LedgerAccountWallet, UserID: &topUp.UserID, Amount: topUp.Amount, Type: TypeTopUp,
+				Description: "Wallet top-up"},
+		},
+	})
+	return err
+}
+
+// PostWalletPayment pays for an order from the student's wallet. The money
+// joins the order's collections and is split when the order is delivered.
+// order must be loaded with its Student.
+func PostWalletPayment(tx *gorm.DB, order *database.Order) error {
+	_, err := Post(tx, Entry{
+		Type:        database.LedgerEntryWalletPayment,
+		OrderID:     &order.ID,
+		ReferenceID: fmt.Sprintf("order:%d:wallet_payment", order.ID),
+		Description: "Order #" + order.OrderNumber + " paid from wallet",
+		Lines: []Line{
+			{Account: database.LedgerAccountWallet, UserID: &order.Student.UserID, Amount: -order.TotalAmount, Type: TypePayment,
+				Description: "Order #" + order.OrderNumber},
+			{Account: database.LedgerAccountCollections, Amount: order.TotalAmount, Type: TypePayment,
+				Description: "Paid from wallet"},
+		},
+	})
+	return err
+}
+
+// PostWalletRefund returns a wallet payment for an order that was cancelled
+// or rejected before delivery
+func PostWalletRefund(tx *gorm.DB, order *database.Order) (*database.LedgerEntry, error) {
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryRefund,
+		OrderID:     &order.ID,
+		ReferenceID: fmt.Sprintf("order:%d:wallet_refund", order.ID),
+		Description: "Order #" + order.OrderNumber + " " + string(order.Status) + ", wallet payment returned",
+		Lines: []Line{
+			{Account: database.LedgerAccountCollections, Amount: -order.TotalAmount, Type: TypeRefund,
+				Description: "Returned to wallet"},
+			{Account: database.LedgerAccountWallet, UserID: &order.Student.UserID, Amount: order.TotalAmount, Type: TypeRefund,
+				Description: "Order #" + order.OrderNumber + " refunded"},
+		},
+	})
+}
+
+// PostCashCollected moves a cash order's payment from collections to the
+// rider who took the cash, who owes it to the platform until it is handed
+// over. order must be loaded with its AssignedRider.
+func PostCashCollected(tx *gorm.DB, order *database.Order) (*database.LedgerEntry, error) {
+	if order.AssignedRider == nil {
+		return nil, errors.New("order has no rider holding the cash")
+	}
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryCashCollected,
+		OrderID:     &order.ID,
+		ReferenceID: fmt.Sprintf("order:%d:cash_collected", order.ID),
+		Description: "Cash collected for order #" + order.OrderNumber,
+		Lines: []Line{
+			{Account: database.LedgerAccountRiderCash, UserID: &order.AssignedRider.UserID, Amount: -order.TotalAmount, Type: TypeCashCollected,
+				Description: "Cash collected for order #" + order.OrderNumber},
+			{Account: database.LedgerAccountCollections, Amount: order.TotalAmount, Type: TypeCashCollected,
+				Description: "Held by rider"},
+		},
+	})
+}
+
+// Handover is cash a rider hands over. With VendorUserID set the vendor took
+// the cash for the platform and it comes out of their balance; otherwise the
+// platform received it.
+type Handover struct {
+	RiderUserID  uint
+	VendorUserID *uint
+	Amount       pkg.Money
+	ReferenceID  string
+	Description  string
+	CreatedByID  *uint
+}
+
+func PostCashHandover(tx *gorm.DB, h Handover) (*database.LedgerEntry, error) {
+	if h.Amount <= 0 {
+		return nil, errors.New("handover amount must be greater than 0")
+	}
+	receiver := Line{Account: database.LedgerAccountCollections, Amount: -h.Amount, Type: TypeCashHandover,
+		Description: "Cash received from rider"}
+	if h.VendorUserID != nil {
+		receiver = Line{Account: database.LedgerAccountVendor, UserID: h.VendorUserID, Amount: -h.Amount, Type: TypeCashHandover,
+			Description: "Cash received from rider for the platform"}
+	}
+	return Post(tx, Entry{
+		Type:        database.LedgerEntryCashHandover,
+		ReferenceID: h.ReferenceID,
+		Description: h.Description,
+		CreatedByID: h.CreatedByID,
+		Lines: []Line{
+			{Account: database.LedgerAccountRiderCash, UserID: &h.RiderUserID, Amount: h.Amount, Type: TypeCashHandover,
+				Description: "Cash handed over"},
+			receiver,
+		},
+	})
+}
@@ -0,0 +1,148 @@
+package ledger
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// holderBalance is a vendor, rider or wallet's cached balance
+type holderBalance struct {
+	UserID  uint
+	Name    string
+	Balance pkg.Money
+}
+
+func (r *Repository) GetEntries(filters *EntryFilters, offset, limit int) ([]database.LedgerEntry, int64, error) {
+	var entries []database.LedgerEntry
+	var total int64
+
+	query := r.db.Model(&database.LedgerEntry{})
+	if filters.Type != "" {
+		query = query.Where("type = ?", filters.Type)
+	}
+	if filters.OrderID != nil {
+		query = query.Where("order_id = ?", *filters.OrderID)
+	}
+	if filters.UserID != nil {
+		query = query.Where("id IN (?)", r.db.Model(&database.Transaction{}).
+			Select("entry_id").Where("user_id = ?", *filters.UserID))
+	}
+
+	query.Count(&total)
+	err := query.Preload("Transactions").
+		Order("created_at DESC, id DESC").
+		Offset(offset).Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *Repository) GetEntry(entryID uint) (*database.LedgerEntry, error) {
+	var entry database.LedgerEntry
+	err := r.db.Preload("Transactions").First(&entry, entryID).Error
+	return &entry, err
+}
+
+func (r *Repository) GetAccountBalances() ([]AccountBalance, error) {
+	var balances []AccountBalance
+	err := r.db.Model(&database.Transaction{}).
+		Select("account, COALESCE(SUM(amount), 0) AS balance, COUNT(*) AS transactions").
+		Group("account").
+		Order("account").
+		Scan(&balances).Error
+	return balances, err
+}
+
+// GetLedgerBalances sums a holder account per user
+func (r *Repository) GetLedgerBalances(account database.LedgerAccount) (map[uint]pkg.Money, error) {
+	var rows []struct {
+		UserID  uint
+		Balance pkg.Money
+	}
+	err := r.db.Model(&database.Transaction{}).
+		Select("user_id, COALESCE(SUM(amount), 0) AS balance").
+		Where("account = ?", account).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]pkg.Money, len(rows))
+	for _, row := range rows {
+		balances[row.UserID] = row.Balance
+	}
+	return balances, nil
+}
+
+func (r *Repository) GetCachedBalances(account database.LedgerAccount) ([]holderBalance, error) {
+	var rows []holderBalance
+	var err error
+	switch account {
+	case database.LedgerAccountVendor:
+		err = r.db.Model(&database.Vendor{}).
+			Select("user_id, business_name AS name, current_balance AS balance").
+			Scan(&rows).Error
+	case database.LedgerAccountRider:
+		err = r.db.Model(&database.Rider{}).
+			Joins("JOIN users ON users.id = riders.user_id").
+			Select("riders.user_id, users.first_name || ' ' || users.last_name AS name, riders.current_balance AS balance").
+			Scan(&rows).Error
+	case database.LedgerAccountWallet:
+		err = r.db.Model(&database.Student{}).
+			Joins("JOIN users ON users.id = students.user_id").
+			Select("students.user_id, users.first_name || ' ' || users.last_name AS name, students.wallet_balance AS balance").
+			Scan(&rows).Error
+	case database.LedgerAccountRiderCash:
+		// Held cash is a debit in the ledger, see cachedAmount
+		err = r.db.Model(&database.Rider{}).
+			Joins("JOIN users ON users.id = riders.user_id").
+			Select("riders.user_id, users.first_name || ' ' || users.last_name AS name, -riders.cash_in_hand AS balance").
+			Scan(&rows).Error
+	}
+	return rows, err
+}
+
+// GetUnbalancedEntries returns the ids of entries whose lines do not sum to zero
+func (r *Repository) GetUnbalancedEntries() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&database.Transaction{}).
+		Select("entry_id").
+		Group("entry_id").
+		Having("SUM(amount) <> 0").
+		Order("entry_id").
+		Scan(&ids).Error
+	return ids, err
+}
+
+func (r *Repository) SetCachedBalance(tx *gorm.DB, account database.LedgerAccount, userID uint, balance pkg.Money) error {
+	return tx.Model(holderModel(account)).Where("user_id = ?", userID).Update(balanceColumn(account), cachedAmount(account, balance)).Error
+}
+
+// GetBalanceBefore sums a holder's lines posted before at
+func (r *Repository) GetBalanceBefore(account database.LedgerAccount, userID uint, at time.Time) (pkg.Money, error) {
+	var balance pkg.Money
+	err := r.db.Model(&database.Transaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("account = ? AND user_id = ? AND created_at < ?", account, userID, at).
+		Scan(&balance).Error
+	return balance, err
+}
+
+func (r *Repository) GetTransactions(account database.LedgerAccount, userID uint, start, end time.Time) ([]database.Transaction, error) {
+	var txns []database.Transaction
+	err := r.db.Where("account = ? AND user_id = ? AND created_at >= ? AND created_at < ?", account, userID, start, end).
+		Order("created_at ASC, id ASC").
+		Find(&txns).Error
+	return txns, err
+}
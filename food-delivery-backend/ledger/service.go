@@ -0,0 +1,312 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+type Service struct {
+	repo   *Repository
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		db:     db,
+		logger: logger,
+	}
+}
+
+var holderAccounts = []database.LedgerAccount{
+	database.LedgerAccountVendor,
+	database.LedgerAccountRider,
+	database.LedgerAccountWallet,
+	database.LedgerAccountRiderCash,
+}
+
+// OpenBalances gives vendors and riders whose balance predates the ledger an
+// opening entry for it, so the ledger accounts for every cent they are owed.
+// Holders that already have ledger lines are left alone.
+func (s *Service) OpenBalances() (int, error) {
+	opened := 0
+	for _, account := range holderAccounts {
+		ledgerBalances, err := s.repo.GetLedgerBalances(account)
+		if err != nil {
+			return opened, err
+		}
+		cached, err := s.repo.GetCachedBalances(account)
+		if err != nil {
+			return opened, err
+		}
+
+		for _, h := range cached {
+			if _, ok := ledgerBalances[h.UserID]; ok || h.Balance == 0 {
+				continue
+			}
+			userID := h.UserID
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				// Posting adds the amount back onto the cached balance
+				if err := s.repo.SetCachedBalance(tx, account, userID, 0); err != nil {
+					return err
+				}
+				_, err := Post(tx, Entry{
+					Type:        database.LedgerEntryOpeningBalance,
+					ReferenceID: fmt.Sprintf("opening:%s:%d", account, userID),
+					Description: "Balance carried over from before the ledger",
+					Lines: []Line{
+						{Account: account, UserID: &userID, Amount: h.Balance, Type: TypeAdjustment},
+						{Account: database.LedgerAccountAdjustments, Amount: -h.Balance, Type: TypeAdjustment},
+					},
+				})
+				return err
+			})
+			if err != nil {
+				return opened, err
+			}
+			opened++
+		}
+	}
+	return opened, nil
+}
+
+// Reconcile compares the cached vendor, rider, wallet and rider cash
+// balances with the sum of their ledger lines and checks that every entry
+// balances. With fix set the cached balances are reset to the ledger.
+func (s *Service) Reconcile(fix bool) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{CheckedAt: time.Now(), Mismatches: []BalanceMismatch{}}
+
+	unbalanced, err := s.repo.GetUnbalancedEntries()
+	if err != nil {
+		return nil, err
+	}
+	report.UnbalancedEntries = unbalanced
+
+	for _, account := range holderAccounts {
+		ledgerBalances, err := s.repo.GetLedgerBalances(account)
+		if err != nil {
+			return nil, err
+		}
+		cached, err := s.repo.GetCachedBalances(account)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range cached {
+			report.AccountsChecked++
+			expected := ledgerBalances[h.UserID]
+			if h.Balance == expected {
+				continue
+			}
+			report.Mismatches = append(report.Mismatches, BalanceMismatch{
+				Account:    account,
+				UserID:     h.UserID,
+				Name:       h.Name,
+				Cached:     h.Balance,
+				Ledger:     expected,
+				Difference: h.Balance - expected,
+			})
+		}
+	}
+
+	if len(report.Mismatches) > 0 {
+		s.logger.Warn("Ledger reconciliation found balance mismatches", zap.Int("count", len(report.Mismatches)))
+	}
+	if fix && len(report.Mismatches) > 0 {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			for _, m := range report.Mismatches {
+				if err := s.repo.SetCachedBalance(tx, m.Account, m.UserID, m.Ledger); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("Failed to fix cached balances", zap.Error(err))
+			return nil, errors.New("failed to fix balances")
+		}
+		report.Fixed = true
+	}
+	return report, nil
+}
+
+func (s *Service) GetAccountBalances() ([]AccountBalance, error) {
+	return s.repo.GetAccountBalances()
+}
+
+func (s *Service) GetEntries(filters *EntryFilters, page, limit int) ([]database.LedgerEntry, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetEntries(filters, offset, limit)
+}
+
+func (s *Service) GetEntry(entryID uint) (*database.LedgerEntry, error) {
+	entry, err := s.repo.GetEntry(entryID)
+	if err != nil {
+		return nil, errors.New("ledger entry not found")
+	}
+	return entry, nil
+}
+
+func (s *Service) CreateAdjustment(adminID uint, req *AdjustmentRequest) (*database.LedgerEntry, error) {
+	var entry *database.LedgerEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = PostAdjustment(tx, Adjustment{
+			Account:     req.Account,
+			UserID:      req.UserID,
+			Amount:      req.Amount,
+			Reason:      req.Reason,
+			ReferenceID: "adjustment:" + pkg.GenerateTransactionID(),
+			CreatedByID: &adminID,
+		})
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to post adjustment", zap.Uint("user_id", req.UserID), zap.Error(err))
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RecordPayout debits a payout made outside the platform, e.g. a manual
+// bank transfer. The reference can only be used once.
+func (s *Service) RecordPayout(adminID uint, req *PayoutRequest) (*database.LedgerEntry, error) {
+	var entry *database.LedgerEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		balance, err := LockBalance(tx, req.Account, req.UserID)
+		if err != nil {
+			return err
+		}
+		if balance < req.Amount {
+			return fmt.Errorf("%w: %s available", ErrInsufficientBalance, balance)
+		}
+
+		description := "Payout " + req.Reference
+		if req.Note != "" {
+			description += ": " + req.Note
+		}
+		entry, err = PostPayout(tx, Payout{
+			Account:     req.Account,
+			UserID:      req.UserID,
+			Amount:      req.Amount,
+			Description: description,
+			ReferenceID: "payout:" + req.Reference,
+			CreatedByID: &adminID,
+		})
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to record payout", zap.Uint("user_id", req.UserID), zap.Error(err))
+		return nil, err
+	}
+	return entry, nil
+}
+
+// LockBalance takes a row lock on a vendor, rider or student for the rest of
+// tx and returns the cached balance of their account
+func LockBalance(tx *gorm.DB, account database.LedgerAccount, userID uint) (pkg.Money, error) {
+	model := holderModel(account)
+	if model == nil {
+		return 0, fmt.Errorf("%s is not a per-user account", account)
+	}
+
+	var balances []pkg.Money
+	if err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).Pluck(balanceColumn(account), &balances).Error; err != nil {
+		return 0, err
+	}
+	if len(balances) == 0 {
+		return 0, fmt.Errorf("no %s account for user %d", account, userID)
+	}
+	return balances[0], nil
+}
+
+// SettlementPeriod returns the settlement period containing date: a week
+// running Monday to Sunday, or a calendar month. end is exclusive.
+func SettlementPeriod(period string, date time.Time) (start, end time.Time, err error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	switch period {
+	case "", "week":
+		offset := (int(day.Weekday()) + 6) % 7
+		start = day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7), nil
+	case "month":
+		start = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return start, end, errors.New("period must be week or month")
+}
+
+// GetStatement builds a holder's settlement statement for the period (week
+// or month) containing dateStr (YYYY-MM-DD, default today)
+func (s *Service) GetStatement(account database.LedgerAccount, userID uint, period, dateStr string) (*Statement, error) {
+	if !IsHolderAccount(account) {
+		return nil, errors.New("statements are only available for vendor and rider accounts")
+	}
+	date := time.Now()
+	if dateStr != "" {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", dateStr, time.Local); err != nil {
+			return nil, errors.New("invalid date format")
+		}
+	}
+	start, end, err := SettlementPeriod(period, date)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := s.repo.GetBalanceBefore(account, userID, start)
+	if err != nil {
+		s.logger.Error("Failed to get opening balance", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, errors.New("failed to generate statement")
+	}
+	txns, err := s.repo.GetTransactions(account, userID, start, end)
+	if err != nil {
+		s.logger.Error("Failed to get statement lines", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, errors.New("failed to generate statement")
+	}
+
+	statement := &Statement{
+		Account:        account,
+		UserID:         userID,
+		PeriodStart:    start.Format("2006-01-02"),
+		PeriodEnd:      end.AddDate(0, 0, -1).Format("2006-01-02"),
+		OpeningBalance: opening,
+		Lines:          []StatementLine{},
+	}
+	balance := opening
+	for _, txn := range txns {
+		balance += txn.Amount
+		switch txn.Type {
+		case TypeEarning:
+			statement.Earnings += txn.Amount
+		case TypeRefund:
+			statement.Refunds += txn.Amount
+		case TypeWithdrawal, TypePayout:
+			statement.Withdrawals += txn.Amount
+		default:
+			statement.Adjustments += txn.Amount
+		}
+		statement.Lines = append(statement.Lines, StatementLine{
+			Date:        txn.CreatedAt,
+			EntryID:     txn.EntryID,
+			OrderID:     txn.OrderID,
+			Type:        txn.Type,
+			Description: txn.Description,
+			Amount:      txn.Amount,
+			Balance:     balance,
+		})
+	}
+	statement.ClosingBalance = balance
+	return statement, nil
+}
@@ -4,18 +4,28 @@ import (
 	"context"
 	"food-delivery-backend/admin"
 	"food-delivery-backend/auth"
+	"food-delivery-backend/cash"
 	"food-delivery-backend/config"
+	"food-delivery-backend/coupons"
 	"food-delivery-backend/database"
+	"food-delivery-backend/dispatch"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/ledger"
 	"food-delivery-backend/logger"
 	"food-delivery-backend/middleware"
 	"food-delivery-backend/notifications"
 	"food-delivery-backend/orders"
+	"food-delivery-backend/payments"
 	"food-delivery-backend/pkg"
 	"food-delivery-backend/redis"
+	"food-delivery-backend/refunds"
 	"food-delivery-backend/riders"
 	"food-delivery-backend/routes"
+	"food-delivery-backend/shifts"
 	"food-delivery-backend/users"
 	"food-delivery-backend/vendors"
+	"food-delivery-backend/wallet"
+	"food-delivery-backend/withdrawals"
 	"net/http"
 	"os"
 	"os/signal"
@@ -57,6 +67,28 @@ func main() {
 	// Initialize JWT maker
 	jwtMaker := pkg.NewJWTMaker(cfg.JWTSecret)
 
+	// Payments (card authorization, capture and refunds)
+	paymentProvider, err := payments.NewProvider(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize payment provider", zap.Error(err))
+	}
+	paymentsService := payments.NewService(paymentProvider, db, notifier, log)
+	paymentsHandler := payments.NewHandler(paymentsService, log)
+
+	// Refunds (cancellations and admin refunds)
+	refundsRepo := refunds.NewRepository(db)
+	refundsService := refunds.NewService(refundsRepo, db, paymentsService, notifier, log)
+	refundsHandler := refunds.NewHandler(refundsService, log)
+
+	// Cash collected by riders on cash-on-delivery orders
+	cashRepo := cash.NewRepository(db)
+	cashService := cash.NewService(cashRepo, db, notifier, cfg, log)
+	cashHandler := cash.NewHandler(cashService, log)
+
+	// Order state machine (shared by orders, vendors, riders and admin)
+	orderFlow := orders.NewStateMachine(db, notifier, paymentsService, refundsService, redisClient, cfg, log)
+	pricing := orders.NewRulePricing(db, cfg)
+
 	// Initialize repositories and services
 	// Auth Module
 	authRepo := auth.NewRepository(db)
@@ -70,24 +102,90 @@ func main() {
 
 	// Vendors Module
 	vendorsRepo := vendors.NewRepository(db)
-	vendorsService := vendors.NewService(vendorsRepo, notifier, redisClient, log)
+	vendorsService := vendors.NewService(vendorsRepo, orderFlow, notifier, redisClient, log)
 	vendorsHandler := vendors.NewHandler(vendorsService, log)
 
+	// Vendor opening hours
+	hoursRepo := hours.NewRepository(db)
+	hoursService := hours.NewService(hoursRepo, log)
+	hoursHandler := hours.NewHandler(hoursService, log)
+
 	// Riders Module
 	ridersRepo := riders.NewRepository(db)
-	ridersService := riders.NewService(ridersRepo, redisClient, log)
+	ridersService := riders.NewService(ridersRepo, orderFlow, notifier, redisClient, cfg, log)
 	ridersHandler := riders.NewHandler(ridersService, log)
+	wsHub.OnRiderHeartbeat(ridersService.Heartbeat)
+
+	// Rider dispatch (offers ready orders to riders)
+	dispatchRepo := dispatch.NewRepository(db)
+	dispatchService := dispatch.NewService(dispatchRepo, db, orderFlow, notifier, redisClient, cfg, log)
+	dispatchHandler := dispatch.NewHandler(dispatchService, log)
+
+	// Rider shifts (zones, bookable slots and coverage planning)
+	shiftsRepo := shifts.NewRepository(db)
+	shiftsService := shifts.NewService(shiftsRepo, ridersService, notifier, db, cfg, log)
+	shiftsHandler := shifts.NewHandler(shiftsService, log)
+
+	// Ledger Module
+	ledgerRepo := ledger.NewRepository(db)
+	ledgerService := ledger.NewService(ledgerRepo, db, log)
+	ledgerHandler := ledger.NewHandler(ledgerService, log)
+	if opened, err := ledgerService.OpenBalances(); err != nil {
+		log.Fatal("Failed to open ledger balances", zap.Error(err))
+	} else if opened > 0 {
+		log.Info("Opened ledger balances", zap.Int("accounts", opened))
+	}
+
+	// Withdrawals Module
+	withdrawalsRepo := withdrawals.NewRepository(db)
+	withdrawalsService := withdrawals.NewService(withdrawalsRepo, db, notifier, cfg, log)
+	withdrawalsHandler := withdrawals.NewHandler(withdrawalsService, log)
+
+	// Wallet Module
+	walletRepo := wallet.NewRepository(db)
+	walletService := wallet.NewService(walletRepo, db, paymentsService, notifier, cfg, log)
+	walletHandler := wallet.NewHandler(walletService, log)
+
+	// Coupons Module
+	couponsRepo := coupons.NewRepository(db)
+	couponsService := coupons.NewService(couponsRepo, db, log)
+	couponsHandler := coupons.NewHandler(couponsService, log)
 
 	// Orders Module
 	ordersRepo := orders.NewRepository(db)
-	ordersService := orders.NewService(ordersRepo, notifier, redisClient, db, cfg, log)
+	ordersService := orders.NewService(ordersRepo, orderFlow, pricing, couponsService, paymentsService, notifier, redisClient, db, cfg, log)
 	ordersHandler := orders.NewHandler(ordersService, log)
 
 	// Admin Module
 	adminRepo := admin.NewRepository(db)
-	adminService := admin.NewService(adminRepo, redisClient, log)
+	adminService := admin.NewService(adminRepo, orderFlow, pricing, redisClient, log)
 	adminHandler := admin.NewHandler(adminService, log)
 
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	expiryWorker := orders.NewExpiryWorker(db, orderFlow, redisClient, cfg, log)
+	go expiryWorker.Run(jobsCtx)
+
+	schedulerWorker := orders.NewSchedulerWorker(db, orderFlow, notifier, redisClient, cfg, log)
+	go schedulerWorker.Run(jobsCtx)
+
+	hoursWorker := hours.NewWorker(hoursRepo, notifier, redisClient, cfg, log)
+	go hoursWorker.Run(jobsCtx)
+
+	dispatchWorker := dispatch.NewWorker(dispatchService, dispatchRepo, redisClient, cfg, log)
+	go dispatchWorker.Run(jobsCtx)
+
+	locationWorker := riders.NewRetentionWorker(ridersRepo, redisClient, cfg, log)
+	go locationWorker.Run(jobsCtx)
+
+	presenceWorker := riders.NewPresenceWorker(ridersService, ridersRepo, redisClient, cfg, log)
+	go presenceWorker.Run(jobsCtx)
+
+	shiftsWorker := shifts.NewWorker(shiftsService, shiftsRepo, redisClient, cfg, log)
+	go shiftsWorker.Run(jobsCtx)
+
 	// Notifications Module
 	notificationsHandler := notifications.NewHandler(db, log)
 
@@ -109,6 +207,16 @@ func main() {
 		ridersHandler,
 		ordersHandler,
 		adminHandler,
+		couponsHandler,
+		ledgerHandler,
+		withdrawalsHandler,
+		paymentsHandler,
+		walletHandler,
+		refundsHandler,
+		cashHandler,
+		hoursHandler,
+		dispatchHandler,
+		shiftsHandler,
 		notificationsHandler,
 		wsHub,
 		jwtMaker,
@@ -133,6 +241,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
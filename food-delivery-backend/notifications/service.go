@@ -92,6 +92,79 @@ func (s *Service) NotifyRider(riderUserID uint, title, message, notificationType
 	s.sendToUser(riderUserID, msg)
 }
 
+// PushETA sends a student the new estimated delivery time of their order.
+// ETA updates are live only and not stored as notifications.
+func (s *Service) PushETA(studentUserID, orderID uint, eta time.Time) {
+	msg := &NotificationMessage{
+		Type:      "eta_update",
+		Title:     "Delivery Time Updated",
+		Message:   "The estimated delivery time of your order has changed",
+		Reference: fmt.Sprintf("%d", orderID),
+		Data: map[string]interface{}{
+			"order_id":                orderID,
+			"estimated_delivery_time": eta,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+	s.push(studentUserID, msg)
+}
+
+// PushRiderLocation streams the rider's position on an order to someone
+// waiting for it. Positions are live only; the route is kept with the order.
+func (s *Service) PushRiderLocation(userID, orderID uint, lat, lng float64, at time.Time) {
+	s.push(userID, &NotificationMessage{
+		Type:      "rider_location",
+		Reference: fmt.Sprintf("%d", orderID),
+		Data: map[string]interface{}{
+			"order_id":    orderID,
+			"latitude":    lat,
+			"longitude":   lng,
+			"recorded_at": at,
+		},
+		Timestamp: at.Unix(),
+	})
+}
+
+// PushDeliveryOffer offers a rider a delivery they can accept until it
+// expires. Offers are live only; riders who were offline see open offers
+// through the API.
+func (s *Service) PushDeliveryOffer(riderUserID, offerID uint, orderNumber string, data interface{}) {
+	s.push(riderUserID, &NotificationMessage{
+		Type:      "delivery_offer",
+		Title:     "New Delivery Offer",
+		Message:   "Order #" + orderNumber + " is ready for pickup. Accept it before the offer expires",
+		Reference: fmt.Sprintf("%d", offerID),
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// PushOfferClosed tells a rider an offer they have not answered is no longer
+// open, so their app can take it down
+func (s *Service) PushOfferClosed(riderUserID, offerID uint, status string) {
+	s.push(riderUserID, &NotificationMessage{
+		Type:      "delivery_offer_closed",
+		Title:     "Delivery Offer Closed",
+		Message:   "The delivery offer is no longer available",
+		Reference: fmt.Sprintf("%d", offerID),
+		Data: map[string]interface{}{
+			"offer_id": offerID,
+			"status":   status,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// push sends a message over the user's WebSocket without storing it
+func (s *Service) push(userID uint, msg *NotificationMessage) {
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		s.logger.Error("Failed to marshal notification", zap.String("type", msg.Type), zap.Error(err))
+		return
+	}
+	s.hub.BroadcastToUser(userID, jsonMsg)
+}
+
 func (s *Service) NotifyAdmin(title, message string) {
 	msg := &NotificationMessage{
 		Type:      "admin_notification",
@@ -110,17 +183,22 @@ func (s *Service) NotifyAdmin(title, message string) {
 }
 
 func (s *Service) NotifyOrderUpdate(order *database.Order, newStatus database.OrderStatus, reason string) {
+	suffix := ""
+	if reason != "" {
+		suffix = " (" + reason + ")"
+	}
+
 	// Notify student
 	// order.StudentID is Student.ID; send notifications to the underlying user
 	if order.Student.ID != 0 {
 		s.NotifyStudent(order.Student.UserID, "Order Update",
-			"Your order #"+order.OrderNumber+" is now "+string(newStatus),
+			"Your order #"+order.OrderNumber+" is now "+string(newStatus)+suffix,
 			"order_update", fmt.Sprintf("%d", order.ID))
 	}
 
 	// Notify vendor
 	s.NotifyVendor(order.Vendor.UserID, "Order Update",
-		"Order #"+order.OrderNumber+" status: "+string(newStatus),
+		"Order #"+order.OrderNumber+" status: "+string(newStatus)+suffix,
 		"order_update", fmt.Sprintf("%d", order.ID))
 
 	// Notify rider if assigned
@@ -135,7 +213,7 @@ func (s *Service) NotifyOrderUpdate(order *database.Order, newStatus database.Or
 		newStatus == database.OrderStatusCancelled ||
 		newStatus == database.OrderStatusRejected {
 		s.NotifyAdmin("Order "+string(newStatus),
-			"Order #"+order.OrderNumber+" has been "+string(newStatus))
+			"Order #"+order.OrderNumber+" has been "+string(newStatus)+suffix)
 	}
 }
 
@@ -11,6 +11,12 @@ import (
     "food-delivery-backend/database"
 )
 
+const (
+    writeWait  = 10 * time.Second
+    pongWait   = 60 * time.Second    // a connection silent this long is dropped
+    pingPeriod = (pongWait * 9) / 10 // must be shorter than pongWait
+)
+
 var upgrader = websocket.Upgrader{
     ReadBufferSize:  1024,
     WriteBufferSize: 1024,
@@ -40,6 +46,8 @@ type Hub struct {
     mu          sync.RWMutex
     logger      *zap.Logger
     db          *gorm.DB
+
+    riderHeartbeat func(riderID uint)
 }
 
 func NewHub(logger *zap.Logger, db *gorm.DB) *Hub {
@@ -56,6 +64,12 @@ func NewHub(logger *zap.Logger, db *gorm.DB) *Hub {
     }
 }
 
+// OnRiderHeartbeat sets what runs whenever a rider's connection shows it is
+// alive: a ping, a pong or any message. Set it before connections arrive.
+func (h *Hub) OnRiderHeartbeat(fn func(riderID uint)) {
+    h.riderHeartbeat = fn
+}
+
 func (h *Hub) Run() {
     for {
         select {
@@ -187,6 +201,20 @@ func (c *Client) readPump() {
         c.Conn.Close()
     }()
 
+    c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+    c.Conn.SetPongHandler(func(string) error {
+        c.alive()
+        return nil
+    })
+    c.Conn.SetPingHandler(func(data string) error {
+        c.alive()
+        err := c.Conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeWait))
+        if err == websocket.ErrCloseSent {
+            return nil
+        }
+        return err
+    })
+
     for {
         _, _, err := c.Conn.ReadMessage()
         if err != nil {
@@ -195,16 +223,34 @@ func (c *Client) readPump() {
             }
             break
         }
-        // We don't process incoming messages in this implementation
-        // You can add handling for client messages (like ping/pong) here
+        // Incoming messages aren't processed, but they show the client is there
+        c.alive()
+    }
+}
+
+// alive extends the connection's read deadline and, for riders, refreshes
+// their presence
+func (c *Client) alive() {
+    c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+    if c.RiderID != nil && c.Hub.riderHeartbeat != nil {
+        c.Hub.riderHeartbeat(*c.RiderID)
     }
 }
 
 func (c *Client) writePump() {
-    defer c.Conn.Close()
+    ticker := time.NewTicker(pingPeriod)
+    defer func() {
+        ticker.Stop()
+        c.Conn.Close()
+    }()
 
     for {
         select {
+        case <-ticker.C:
+            c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+            if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+                return
+            }
         case message, ok := <-c.Send:
             if !ok {
                 c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
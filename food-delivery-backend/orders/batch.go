@@ -0,0 +1,177 @@
+package orders
+
+import (
+	"errors"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBatchFull    = errors.New("rider is already carrying as many orders as a batch allows")
+	ErrNotBatchable = errors.New("order does not go the same way as the rider's other orders")
+)
+
+// MaxBatchSize is how many orders a rider may carry at once
+func MaxBatchSize(cfg *config.Config) int {
+	if cfg.RiderMaxBatchSize <= 0 {
+		return 1
+	}
+	return cfg.RiderMaxBatchSize
+}
+
+// Batchable reports whether two orders can travel together: they come from
+// the same vendor or vendors close to each other, and go to the same dorm
+// block. Both orders must have their Vendor loaded.
+func Batchable(a, b *database.Order, cfg *config.Config) bool {
+	if !SameDropArea(a, b) {
+		return false
+	}
+	if a.VendorID == b.VendorID {
+		return true
+	}
+	return pkg.CalculateDistance(a.Vendor.Latitude, a.Vendor.Longitude,
+		b.Vendor.Latitude, b.Vendor.Longitude) <= cfg.BatchVendorRadiusKm
+}
+
+// SameDropArea reports whether two orders go to the same dorm block. Orders
+// without a block can't be grouped.
+func SameDropArea(a, b *database.Order) bool {
+	blockA, blockB := normalizePlace(a.DeliveryBlock), normalizePlace(b.DeliveryBlock)
+	return blockA != "" && blockA == blockB
+}
+
+// DropKey identifies where an order is dropped off; orders with the same key
+// are handed over at one stop
+func DropKey(order *database.Order) string {
+	return normalizePlace(order.DeliveryBlock) + "|" + normalizePlace(order.DeliveryDorm)
+}
+
+// FitsBatch reports whether an order can join the given orders
+func FitsBatch(order *database.Order, batch []database.Order, cfg *config.Config) bool {
+	for i := range batch {
+		if batch[i].ID != order.ID && !Batchable(order, &batch[i], cfg) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizePlace(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// ActiveBatch returns the rider's active batch with its orders still under
+// way, or nil when the rider has none
+func ActiveBatch(db *gorm.DB, riderID uint) (*database.DeliveryBatch, error) {
+	var batch database.DeliveryBatch
+	err := db.Where("rider_id = ? AND status = ?", riderID, database.DeliveryBatchActive).
+		Preload("Orders", "status NOT IN ?", TerminalStatuses()).
+		Preload("Orders.Vendor").
+		Order("created_at DESC").
+		First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// TerminalStatuses lists the statuses IsTerminal accepts, for queries
+func TerminalStatuses() []database.OrderStatus {
+	return []database.OrderStatus{
+		database.OrderStatusDelivered,
+		database.OrderStatusCancelled,
+		database.OrderStatusRejected,
+	}
+}
+
+// joinBatch puts the order in the rider's active batch inside tx, opening a
+// batch if the rider has none. The rider row must already be locked. Orders
+// that don't go the same way as the batch are refused unless anyRoute is set.
+func joinBatch(tx *gorm.DB, order *database.Order, riderID uint, anyRoute bool, cfg *config.Config) error {
+	batch, err := ActiveBatch(tx, riderID)
+	if err != nil {
+		return err
+	}
+	if batch == nil {
+		batch = &database.DeliveryBatch{RiderID: riderID, Status: database.DeliveryBatchActive}
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+	} else {
+		var others []database.Order
+		for _, o := range batch.Orders {
+			if o.ID != order.ID {
+				others = append(others, o)
+			}
+		}
+		if len(others) >= MaxBatchSize(cfg) {
+			return ErrBatchFull
+		}
+		if !anyRoute && !FitsBatch(order, others, cfg) {
+			return ErrNotBatchable
+		}
+	}
+
+	order.BatchID = &batch.ID
+	return tx.Model(&database.Order{}).Where("id = ?", order.ID).
+		Update("batch_id", batch.ID).Error
+}
+
+// settleBatch completes the batch once none of its orders are under way and
+// reports whether it did, which frees the rider. Taking the batch row lock
+// makes concurrent deliveries from one batch see each other.
+func settleBatch(tx *gorm.DB, batchID uint, at time.Time) (bool, error) {
+	var batch database.DeliveryBatch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, batchID).Error; err != nil {
+		return false, err
+	}
+	if batch.Status != database.DeliveryBatchActive {
+		return true, nil
+	}
+
+	var remaining int64
+	if err := tx.Model(&database.Order{}).
+		Where("batch_id = ? AND status NOT IN ?", batchID, TerminalStatuses()).
+		Count(&remaining).Error; err != nil {
+		return false, err
+	}
+	if remaining > 0 {
+		return false, nil
+	}
+	return true, tx.Model(&database.DeliveryBatch{}).Where("id = ?", batchID).
+		Updates(map[string]interface{}{
+			"status":       database.DeliveryBatchCompleted,
+			"completed_at": at,
+		}).Error
+}
+
+// leaveBatch takes an order out of its batch inside tx and reports whether
+// that left the batch finished
+func leaveBatch(tx *gorm.DB, order *database.Order, at time.Time) (bool, error) {
+	if order.BatchID == nil {
+		return true, nil
+	}
+	batchID := *order.BatchID
+	if err := tx.Model(&database.Order{}).Where("id = ?", order.ID).
+		Update("batch_id", nil).Error; err != nil {
+		return false, err
+	}
+	order.BatchID = nil
+	return settleBatch(tx, batchID, at)
+}
+
+// freeRider marks the rider available again inside tx, unless they checked
+// out of their shift while the delivery was under way
+func freeRider(tx *gorm.DB, riderID uint) error {
+	return tx.Model(&database.Rider{}).Where("id = ? AND off_shift = ?", riderID, false).
+		Update("is_available", true).Error
+}
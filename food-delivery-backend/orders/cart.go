@@ -0,0 +1,319 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/coupons"
+	"food-delivery-backend/database"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Codes for cart problems reported by quotes
+const (
+	ProblemVendorClosed    = "VENDOR_CLOSED"
+	ProblemOutsideHours    = "OUTSIDE_OPENING_HOURS"
+	ProblemItemUnavailable = "ITEM_UNAVAILABLE"
+	ProblemQuantityLimit   = "QUANTITY_LIMIT"
+	ProblemTooManyItems    = "TOO_MANY_ITEMS"
+	ProblemMinimumOrder    = "MINIMUM_ORDER"
+	ProblemInvalidOptions  = "INVALID_OPTIONS"
+)
+
+var ErrVendorNotFound = errors.New("vendor not found")
+
+// cart is a validated and priced set of order lines. Problems are collected
+// rather than returned so quotes can report all of them at once; CreateOrder
+// fails on the first.
+type cart struct {
+	items    []database.OrderItem
+	lines    []QuoteLine
+	subtotal pkg.Money
+	itemPrep map[uint]int // configured preparation time of each menu item
+	kitchen  Kitchen
+	problems []cartProblem
+}
+
+type cartProblem struct {
+	QuoteProblem
+	err error
+}
+
+func (c *cart) addProblem(code string, menuItemID uint, err error) {
+	c.problems = append(c.problems, cartProblem{
+		QuoteProblem: QuoteProblem{Code: code, Message: err.Error(), MenuItemID: menuItemID},
+		err:          err,
+	})
+}
+
+// buildCart looks up each requested item on the vendor's menu and prices it,
+// preferring the discount price when one is set and adding the price of the
+// chosen options. now is when the order is placed and scheduledFor, if set,
+// when a pre-order is to be delivered; the vendor must be open at whichever
+// applies. It never writes.
+func (s *Service) buildCart(vendor *database.Vendor, items []OrderItemRequest, lat, lng float64, now time.Time, scheduledFor *time.Time) *cart {
+	c := &cart{itemPrep: make(map[uint]int)}
+
+	switch {
+	case scheduledFor != nil:
+		// Pre-orders can be placed while the vendor is closed
+		if err := s.checkSchedule(vendor, *scheduledFor, now); err != nil {
+			c.addProblem(errorCode(err), 0, err)
+		} else if err := s.checkSlot(s.db, vendor, *scheduledFor); err != nil {
+			c.addProblem(CodeSlotFull, 0, err)
+		}
+	case !vendor.IsOpen:
+		c.addProblem(ProblemVendorClosed, 0, errors.New("vendor is currently closed"))
+	default:
+		// Open by hand outside the schedule is still closed for orders
+		if err := hours.CheckOpen(vendor, now); err != nil {
+			c.addProblem(ProblemOutsideHours, 0, err)
+		}
+	}
+	// Pre-orders are cooked later, so only orders for now queue behind the
+	// kitchen's current load
+	if scheduledFor == nil {
+		kitchen, err := s.kitchenLoad(vendor, now)
+		if err != nil {
+			s.logger.Warn("Failed to load kitchen queue", zap.Uint("vendor_id", vendor.ID), zap.Error(err))
+		}
+		c.kitchen = kitchen
+		if kitchen.Rejects(vendor) {
+			c.addProblem(ProblemVendorBusy, 0, ErrVendorBusy)
+		}
+	} else {
+		c.kitchen = Kitchen{WaitMinutes: vendor.AveragePrepTime}
+	}
+	if err := checkDeliveryZone(vendor, lat, lng); err != nil {
+		c.addProblem(errorCode(err), 0, err)
+	}
+	if s.cfg.MaxOrderItems > 0 && len(items) > s.cfg.MaxOrderItems {
+		c.addProblem(ProblemTooManyItems, 0,
+			fmt.Errorf("an order can contain at most %d items", s.cfg.MaxOrderItems))
+	}
+
+	for _, item := range items {
+		menuItem, err := s.repo.GetVendorMenuItem(item.MenuItemID, vendor.ID)
+		if err != nil || !menuItem.IsAvailable {
+			line := QuoteLine{MenuItemID: item.MenuItemID, Quantity: item.Quantity}
+			if err == nil {
+				line.Name = menuItem.Name
+			}
+			c.lines = append(c.lines, line)
+			c.addProblem(ProblemItemUnavailable, item.MenuItemID,
+				fmt.Errorf("menu item %d not available", item.MenuItemID))
+			continue
+		}
+		if s.cfg.MaxOrderQuantity > 0 && item.Quantity > s.cfg.MaxOrderQuantity {
+			c.addProblem(ProblemQuantityLimit, item.MenuItemID,
+				fmt.Errorf("at most %d of %s can be ordered", s.cfg.MaxOrderQuantity, menuItem.Name))
+		}
+
+		options, optionsPrice, err := chooseOptions(menuItem, item.Options)
+		if err != nil {
+			c.addProblem(ProblemInvalidOptions, item.MenuItemID, err)
+		}
+
+		// Use discount price if available
+		price := menuItem.Price
+		if menuItem.DiscountPrice != nil && *menuItem.DiscountPrice > 0 {
+			price = *menuItem.DiscountPrice
+		}
+		price += optionsPrice
+		if price < 0 {
+			price = 0
+		}
+
+		itemSubtotal := price.Mul(item.Quantity)
+		c.subtotal += itemSubtotal
+		c.itemPrep[menuItem.ID] = menuItem.PreparationTime
+
+		c.items = append(c.items, database.OrderItem{
+			MenuItemID:          item.MenuItemID,
+			Quantity:            item.Quantity,
+			UnitPrice:           price,
+			Subtotal:            itemSubtotal,
+			SpecialInstructions: item.SpecialInstructions,
+			Options:             options,
+		})
+		line := QuoteLine{
+			MenuItemID: item.MenuItemID,
+			Name:       menuItem.Name,
+			Quantity:   item.Quantity,
+			UnitPrice:  price,
+			ListPrice:  menuItem.Price + optionsPrice,
+			Subtotal:   itemSubtotal,
+			Available:  true,
+		}
+		for _, opt := range options {
+			line.Options = append(line.Options, opt.Name)
+		}
+		c.lines = append(c.lines, line)
+	}
+
+	// Check minimum order
+	if c.subtotal < vendor.MinimumOrder {
+		c.addProblem(ProblemMinimumOrder, 0,
+			fmt.Errorf("minimum order amount is %s", vendor.MinimumOrder))
+	}
+
+	return c
+}
+
+// chooseOptions checks the options chosen for a menu item against its option
+// groups and returns them ready to store with the order item, together with
+// what they add to the unit price
+func chooseOptions(menuItem *database.MenuItem, selected []uint) ([]database.OrderItemOption, pkg.Money, error) {
+	type choice struct {
+		group  *database.MenuOptionGroup
+		option *database.MenuOption
+	}
+	choices := make(map[uint]choice)
+	for gi := range menuItem.OptionGroups {
+		group := &menuItem.OptionGroups[gi]
+		for oi := range group.Options {
+			choices[group.Options[oi].ID] = choice{group: group, option: &group.Options[oi]}
+		}
+	}
+
+	var chosen []database.OrderItemOption
+	var total pkg.Money
+	perGroup := make(map[uint]int)
+	seen := make(map[uint]bool)
+	for _, id := range selected {
+		ch, ok := choices[id]
+		if !ok || !ch.option.IsAvailable {
+			return nil, 0, fmt.Errorf("option %d is not available for %s", id, menuItem.Name)
+		}
+		if seen[id] {
+			return nil, 0, fmt.Errorf("%s was chosen more than once for %s", ch.option.Name, menuItem.Name)
+		}
+		seen[id] = true
+		perGroup[ch.group.ID]++
+		total += ch.option.PriceDelta
+		chosen = append(chosen, database.OrderItemOption{
+			OptionID:   id,
+			GroupName:  ch.group.Name,
+			Name:       ch.option.Name,
+			PriceDelta: ch.option.PriceDelta,
+		})
+	}
+
+	for _, group := range menuItem.OptionGroups {
+		minimum := group.MinSelections
+		if group.Required && minimum == 0 {
+			minimum = 1
+		}
+		count := perGroup[group.ID]
+		if count < minimum {
+			return nil, 0, fmt.Errorf("choose at least %d from %s for %s", minimum, group.Name, menuItem.Name)
+		}
+		if group.MaxSelections > 0 && count > group.MaxSelections {
+			return nil, 0, fmt.Errorf("choose at most %d from %s for %s", group.MaxSelections, group.Name, menuItem.Name)
+		}
+	}
+	return chosen, total, nil
+}
+
+// orderTotals is what the student pays and how it is split
+type orderTotals struct {
+	Discount       pkg.Money
+	Commission     pkg.Money
+	VendorEarnings pkg.Money
+	Total          pkg.Money
+}
+
+// computeTotals applies a coupon discount (which may be nil). Vendor-funded
+// coupons reduce the vendor's sales, so commission is charged on the
+// discounted subtotal and a free-delivery discount comes out of the vendor's
+// earnings. Platform coupons leave the vendor and rider untouched.
+func computeTotals(vendor *database.Vendor, subtotal pkg.Money, price *Price, discount *coupons.Discount) orderTotals {
+	commissionBase := subtotal
+	var vendorPaidDelivery pkg.Money
+	t := orderTotals{}
+
+	if discount != nil {
+		t.Discount = discount.Total()
+		if discount.VendorFunded() {
+			commissionBase -= discount.ItemDiscount
+			vendorPaidDelivery = discount.DeliveryDiscount
+		}
+	}
+
+	t.Commission = commissionBase.MulRate(vendor.CommissionRate)
+	t.VendorEarnings = commissionBase - t.Commission - vendorPaidDelivery
+	t.Total = subtotal + price.DeliveryFee + price.ServiceFee - t.Discount
+	return t
+}
+
+// QuoteOrder prices a prospective order and reports everything that would
+// stop it from being placed, without creating it
+func (s *Service) QuoteOrder(userID uint, req *QuoteRequest) (*QuoteResponse, error) {
+	vendor, err := s.repo.GetVendorByID(req.VendorID)
+	if err != nil {
+		return nil, ErrVendorNotFound
+	}
+
+	now := time.Now()
+	c := s.buildCart(vendor, req.Items, req.DeliveryLat, req.DeliveryLng, now, req.ScheduledFor)
+	deliverAt := deliveryTime(req.ScheduledFor, now)
+
+	price, err := s.pricing.Price(PricingInput{
+		Vendor:      vendor,
+		Subtotal:    c.subtotal,
+		DeliveryLat: req.DeliveryLat,
+		DeliveryLng: req.DeliveryLng,
+		At:          deliverAt,
+	})
+	if err != nil {
+		s.logger.Error("Failed to price quote", zap.Error(err))
+		return nil, errors.New("failed to calculate fees")
+	}
+	eta := s.eta.estimateNew(vendor, c.kitchen, c.itemPrep, price.Breakdown.DistanceKm, now)
+	if eta.Before(deliverAt) {
+		eta = deliverAt
+	}
+
+	var discount *coupons.Discount
+	if req.CouponCode != "" {
+		// Per-user coupon rules only apply to students
+		var studentID uint
+		if student, err := s.repo.GetStudentByUserID(userID); err == nil {
+			studentID = student.ID
+		}
+		discount, err = s.coupons.Evaluate(coupons.ApplyInput{
+			Code:        req.CouponCode,
+			VendorID:    vendor.ID,
+			StudentID:   studentID,
+			Subtotal:    c.subtotal,
+			DeliveryFee: price.DeliveryFee,
+			At:          now,
+		})
+		if err != nil {
+			c.addProblem(coupons.CodeCouponNotApplicable, 0, err)
+		}
+	}
+	totals := computeTotals(vendor, c.subtotal, price, discount)
+
+	problems := make([]QuoteProblem, len(c.problems))
+	for i, p := range c.problems {
+		problems[i] = p.QuoteProblem
+	}
+
+	return &QuoteResponse{
+		Valid:                 len(problems) == 0,
+		Lines:                 c.lines,
+		Subtotal:              c.subtotal,
+		DeliveryFee:           price.DeliveryFee,
+		ServiceFee:            price.ServiceFee,
+		DiscountAmount:        totals.Discount,
+		TotalAmount:           totals.Total,
+		Pricing:               price.Breakdown,
+		EstimatedDeliveryTime: eta,
+		Kitchen:               c.kitchen,
+		Problems:              problems,
+	}, nil
+}
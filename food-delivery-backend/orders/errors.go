@@ -0,0 +1,38 @@
+package orders
+
+import (
+	"errors"
+	"food-delivery-backend/coupons"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/payments"
+)
+
+// CodeInsufficientFunds is returned when a wallet order costs more than the balance
+const CodeInsufficientFunds = "INSUFFICIENT_FUNDS"
+
+var ErrInsufficientFunds = errors.New("insufficient wallet balance")
+
+// errorCode maps service errors to the codes exposed by the API
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrDeliveryLocationRequired):
+		return CodeDeliveryLocationRequired
+	case errors.Is(err, ErrOutsideDeliveryZone):
+		return CodeOutsideDeliveryZone
+	case errors.Is(err, ErrScheduleTooSoon), errors.Is(err, ErrScheduleTooFar):
+		return CodeInvalidSchedule
+	case errors.Is(err, ErrVendorBusy):
+		return ProblemVendorBusy
+	case errors.Is(err, ErrSlotFull):
+		return CodeSlotFull
+	case errors.Is(err, hours.ErrOutsideOpeningHours):
+		return ProblemOutsideHours
+	case errors.Is(err, coupons.ErrCouponNotApplicable):
+		return coupons.CodeCouponNotApplicable
+	case errors.Is(err, ErrInsufficientFunds):
+		return CodeInsufficientFunds
+	case errors.Is(err, payments.ErrPaymentDeclined):
+		return payments.CodePaymentDeclined
+	}
+	return ""
+}
@@ -0,0 +1,290 @@
+package orders
+
+import (
+	"context"
+	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/pkg"
+	"food-delivery-backend/redis"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	riderSpeedKmh       = 15.0 // average campus riding speed
+	pickupBufferMinutes = 5    // rider assignment and hand-over at the counter
+	handoverMinutes     = 2    // hand-over when the rider's position is known
+
+	prepHistoryDays   = 30 // how far back preparation times are learned from
+	prepMinSamples    = 5  // below this the configured preparation time is used
+	riderSearchRadius = 10.0
+)
+
+// ETAEstimator predicts delivery times from preparation times learned from
+// past orders, the kitchen's queue and rider positions
+type ETAEstimator struct {
+	db          *gorm.DB
+	redisClient *redis.RedisClient
+	logger      *zap.Logger
+}
+
+func NewETAEstimator(db *gorm.DB, redisClient *redis.RedisClient, logger *zap.Logger) *ETAEstimator {
+	return &ETAEstimator{
+		db:          db,
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+type prepSample struct {
+	MenuItemID uint
+	Samples    int
+	Minutes    float64
+}
+
+// VendorPrepMinutes returns the vendor's average confirmed-to-ready time over
+// recent orders. ok is false when there are too few orders to trust it.
+func (e *ETAEstimator) VendorPrepMinutes(vendorID uint) (minutes int, ok bool) {
+	var s prepSample
+	err := e.db.Model(&database.Order{}).
+		Select("COUNT(*) AS samples, COALESCE(AVG(EXTRACT(EPOCH FROM (ready_at - confirmed_at)) / 60), 0) AS minutes").
+		Where("vendor_id = ? AND confirmed_at IS NOT NULL AND ready_at > ?", vendorID, time.Now().AddDate(0, 0, -prepHistoryDays)).
+		Scan(&s).Error
+	if err != nil || s.Samples < prepMinSamples {
+		return 0, false
+	}
+	return int(math.Ceil(s.Minutes)), true
+}
+
+// itemPrepMinutes returns the learned confirmed-to-ready time of orders
+// containing each item, for items with enough history
+func (e *ETAEstimator) itemPrepMinutes(itemIDs []uint) map[uint]int {
+	learned := make(map[uint]int)
+	if len(itemIDs) == 0 {
+		return learned
+	}
+
+	var samples []prepSample
+	err := e.db.Table("order_items").
+		Select("order_items.menu_item_id, COUNT(*) AS samples, AVG(EXTRACT(EPOCH FROM (orders.ready_at - orders.confirmed_at)) / 60) AS minutes").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.menu_item_id IN ? AND orders.confirmed_at IS NOT NULL AND orders.ready_at > ?",
+			itemIDs, time.Now().AddDate(0, 0, -prepHistoryDays)).
+		Group("order_items.menu_item_id").
+		Scan(&samples).Error
+	if err != nil {
+		e.logger.Warn("Failed to learn item preparation times", zap.Error(err))
+		return learned
+	}
+	for _, s := range samples {
+		if s.Samples >= prepMinSamples {
+			learned[s.MenuItemID] = int(math.Ceil(s.Minutes))
+		}
+	}
+	return learned
+}
+
+// prepMinutes is how long the vendor takes to prepare the items: the
+// slowest of the vendor's average and each item's time, learned where there
+// is enough history and as configured otherwise. configured maps menu item
+// ids to their configured preparation time.
+func (e *ETAEstimator) prepMinutes(vendor *database.Vendor, configured map[uint]int) int {
+	prep := vendor.AveragePrepTime
+	ids := make([]uint, 0, len(configured))
+	for id := range configured {
+		ids = append(ids, id)
+	}
+	learned := e.itemPrepMinutes(ids)
+	for id, minutes := range configured {
+		if l, ok := learned[id]; ok {
+			minutes = l
+		}
+		if minutes > prep {
+			prep = minutes
+		}
+	}
+	return prep
+}
+
+// configuredPrep maps the order's menu items to their configured
+// preparation times. The items need their menu item loaded.
+func configuredPrep(items []database.OrderItem) map[uint]int {
+	configured := make(map[uint]int, len(items))
+	for _, item := range items {
+		configured[item.MenuItemID] = item.MenuItem.PreparationTime
+	}
+	return configured
+}
+
+// nearestRiderKm is the distance from the closest available rider in the
+// geo index to the point
+func (e *ETAEstimator) nearestRiderKm(lat, lng float64) (float64, bool) {
+	if lat == 0 && lng == 0 {
+		return 0, false
+	}
+	riders, err := e.redisClient.FindNearestRiders(context.Background(), lat, lng, riderSearchRadius)
+	if err != nil || len(riders) == 0 {
+		return 0, false
+	}
+	return riders[0].Dist, true
+}
+
+func travel(km float64) time.Duration {
+	return time.Duration(km / riderSpeedKmh * float64(time.Hour))
+}
+
+// deliveryLeg returns when food ready at readyAt reaches the customer. The
+// rider sets off for the vendor now from riderKm away; without a known rider
+// position a fixed buffer covers assignment and pickup.
+func deliveryLeg(readyAt time.Time, riderKm *float64, vendorToCustomerKm float64, now time.Time) time.Time {
+	departs := readyAt.Add(pickupBufferMinutes * time.Minute)
+	if riderKm != nil {
+		departs = now.Add(travel(*riderKm))
+		if departs.Before(readyAt) {
+			departs = readyAt
+		}
+		departs = departs.Add(handoverMinutes * time.Minute)
+	}
+	return departs.Add(travel(vendorToCustomerKm))
+}
+
+// estimateNew predicts when an order placed now will arrive: preparation
+// behind the kitchen's queue, the nearest rider reaching the vendor and the
+// ride to the customer
+func (e *ETAEstimator) estimateNew(vendor *database.Vendor, kitchen Kitchen, itemPrep map[uint]int, distanceKm float64, now time.Time) time.Time {
+	readyAt := now.Add(time.Duration(e.prepMinutes(vendor, itemPrep)+kitchen.QueueMinutes) * time.Minute)
+	var riderKm *float64
+	if km, ok := e.nearestRiderKm(vendor.Latitude, vendor.Longitude); ok {
+		riderKm = &km
+	}
+	return deliveryLeg(readyAt, riderKm, distanceKm, now).Truncate(time.Minute)
+}
+
+// Estimate predicts when an order will be delivered from where it is now.
+// The order needs its vendor, rider and items with menu items loaded. ok is
+// false for finished orders and when nothing better than the current
+// estimate is known.
+func (e *ETAEstimator) Estimate(order *database.Order, now time.Time) (time.Time, bool) {
+	vendor := &order.Vendor
+	rider := order.AssignedRider
+	riderKnown := rider != nil && (rider.CurrentLatitude != 0 || rider.CurrentLongitude != 0)
+
+	vendorToCustomerKm := order.Pricing.DistanceKm
+	if vendorToCustomerKm == 0 && HasLocation(vendor) && (order.DeliveryLat != 0 || order.DeliveryLng != 0) {
+		vendorToCustomerKm = DistanceToVendor(vendor, order.DeliveryLat, order.DeliveryLng)
+	}
+
+	var riderKm *float64
+	switch {
+	case riderKnown && HasLocation(vendor):
+		km := pkg.CalculateDistance(rider.CurrentLatitude, rider.CurrentLongitude, vendor.Latitude, vendor.Longitude)
+		riderKm = &km
+	case rider == nil:
+		if km, ok := e.nearestRiderKm(vendor.Latitude, vendor.Longitude); ok {
+			riderKm = &km
+		}
+	}
+
+	var eta time.Time
+	switch order.Status {
+	case database.OrderStatusPending:
+		active, err := e.countKitchen(vendor.ID, nil)
+		if err != nil {
+			return time.Time{}, false
+		}
+		kitchen := KitchenLoad(vendor, active, now)
+		readyAt := now.Add(time.Duration(e.prepMinutes(vendor, configuredPrep(order.OrderItems))+kitchen.QueueMinutes) * time.Minute)
+		eta = deliveryLeg(readyAt, riderKm, vendorToCustomerKm, now)
+
+	case database.OrderStatusConfirmed, database.OrderStatusPreparing:
+		started := now
+		if order.ConfirmedAt != nil {
+			started = *order.ConfirmedAt
+		}
+		// Only orders confirmed earlier are ahead in the queue
+		ahead, err := e.countKitchen(vendor.ID, &started)
+		if err != nil {
+			return time.Time{}, false
+		}
+		prep := time.Duration(e.prepMinutes(vendor, configuredPrep(order.OrderItems))+KitchenLoad(vendor, ahead, now).QueueMinutes) * time.Minute
+		readyAt := started.Add(prep)
+		// Running late: assume it is nearly done
+		if readyAt.Before(now) {
+			readyAt = now.Add(handoverMinutes * time.Minute)
+		}
+		eta = deliveryLeg(readyAt, riderKm, vendorToCustomerKm, now)
+
+	case database.OrderStatusReady:
+		eta = deliveryLeg(now, riderKm, vendorToCustomerKm, now)
+
+	case database.OrderStatusPickedUp:
+		if !riderKnown || (order.DeliveryLat == 0 && order.DeliveryLng == 0) {
+			return time.Time{}, false
+		}
+		eta = now.Add(travel(pkg.CalculateDistance(rider.CurrentLatitude, rider.CurrentLongitude, order.DeliveryLat, order.DeliveryLng)))
+
+	default:
+		return time.Time{}, false
+	}
+
+	if order.ScheduledFor != nil && eta.Before(*order.ScheduledFor) {
+		eta = *order.ScheduledFor
+	}
+	return eta.Truncate(time.Minute), true
+}
+
+// countKitchen counts the vendor's confirmed and preparing orders, only
+// those confirmed before since when given
+func (e *ETAEstimator) countKitchen(vendorID uint, before *time.Time) (int, error) {
+	query := e.db.Model(&database.Order{}).Where("vendor_id = ? AND status IN ?", vendorID, KitchenStatuses)
+	if before != nil {
+		query = query.Where("confirmed_at < ?", *before)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return int(count), err
+}
+
+// Refresh recomputes the order's ETA, stores it and pushes it to the
+// student when it changed
+func (e *ETAEstimator) Refresh(orderID uint, notifier *notifications.Service) {
+	var order database.Order
+	err := e.db.Preload("Vendor").
+		Preload("AssignedRider").
+		Preload("Student").
+		Preload("OrderItems.MenuItem").
+		First(&order, orderID).Error
+	if err != nil {
+		e.logger.Warn("Failed to load order for ETA", zap.Uint("order_id", orderID), zap.Error(err))
+		return
+	}
+
+	eta, ok := e.Estimate(&order, time.Now())
+	if !ok || (order.EstimatedDeliveryTime != nil && order.EstimatedDeliveryTime.Equal(eta)) {
+		return
+	}
+	if err := e.db.Model(&database.Order{}).Where("id = ?", order.ID).
+		Update("estimated_delivery_time", eta).Error; err != nil {
+		e.logger.Warn("Failed to store ETA", zap.Uint("order_id", order.ID), zap.Error(err))
+		return
+	}
+
+	notifier.PushETA(order.Student.UserID, order.ID, eta)
+}
+
+// LearnPrepTime replaces the vendor's average preparation time with the one
+// learned from recent orders, once there are enough of them
+func (e *ETAEstimator) LearnPrepTime(vendorID uint) {
+	minutes, ok := e.VendorPrepMinutes(vendorID)
+	if !ok || minutes <= 0 {
+		return
+	}
+	if err := e.db.Model(&database.Vendor{}).Where("id = ?", vendorID).
+		Update("average_prep_time", minutes).Error; err != nil {
+		e.logger.Warn("Failed to update vendor preparation time",
+			zap.Uint("vendor_id", vendorID), zap.Int("minutes", minutes), zap.Error(err))
+	}
+}
@@ -0,0 +1,166 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	expiryLockName  = "order-expiry"
+	expiryBatchSize = 100
+)
+
+// ExpiryWorker cancels (or rejects) orders that have sat in pending for longer
+// than OrderTimeoutMinutes since the vendor was shown them, and cancels card
+// orders whose payment wasn't authorized within PaymentTimeoutMinutes. Only
+// one instance sweeps at a time across processes; the others skip the tick
+// while the Redis lock is held.
+type ExpiryWorker struct {
+	db          *gorm.DB
+	flow        *StateMachine
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewExpiryWorker(db *gorm.DB, flow *StateMachine, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *ExpiryWorker {
+	return &ExpiryWorker{
+		db:          db,
+		flow:        flow,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run sweeps on every interval until ctx is cancelled
+func (w *ExpiryWorker) Run(ctx context.Context) {
+	if w.cfg.OrderTimeoutMinutes <= 0 && w.cfg.PaymentTimeoutMinutes <= 0 {
+		w.logger.Info("Order expiry disabled")
+		return
+	}
+
+	interval := time.Duration(w.cfg.OrderExpiryInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx, interval)
+		}
+	}
+}
+
+func (w *ExpiryWorker) sweep(ctx context.Context, lockTTL time.Duration) {
+	token, ok, err := w.redisClient.AcquireLock(ctx, expiryLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire order expiry lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), expiryLockName, token); err != nil {
+			w.logger.Warn("Failed to release order expiry lock", zap.Error(err))
+		}
+	}()
+
+	now := time.Now()
+	if w.cfg.OrderTimeoutMinutes > 0 {
+		to := database.OrderStatusCancelled
+		if w.cfg.OrderExpiryAction == "reject" {
+			to = database.OrderStatusRejected
+		}
+		// Orders are timed from when the vendor first saw them
+		w.expire(ctx, expiryPass{
+			query:  w.db.Where("released_at < ?", now.Add(-time.Duration(w.cfg.OrderTimeoutMinutes)*time.Minute)),
+			to:     to,
+			reason: fmt.Sprintf("Vendor did not confirm within %d minutes", w.cfg.OrderTimeoutMinutes),
+			label:  "stale pending orders",
+		})
+	}
+	if w.cfg.PaymentTimeoutMinutes > 0 {
+		// Scheduled orders awaiting authorization are left to the scheduler
+		w.expire(ctx, expiryPass{
+			query: w.db.Where("released_at IS NULL AND scheduled_for IS NULL AND created_at < ?",
+				now.Add(-time.Duration(w.cfg.PaymentTimeoutMinutes)*time.Minute)),
+			to:         database.OrderStatusCancelled,
+			reason:     fmt.Sprintf("Payment was not authorized within %d minutes", w.cfg.PaymentTimeoutMinutes),
+			label:      "orders awaiting payment",
+			unreleased: true,
+		})
+	}
+}
+
+// expiryPass picks out one kind of pending order to expire
+type expiryPass struct {
+	query      *gorm.DB
+	to         database.OrderStatus
+	reason     string
+	label      string
+	unreleased bool // only while still awaiting payment authorization
+}
+
+func (w *ExpiryWorker) expire(ctx context.Context, pass expiryPass) {
+	var lastID uint
+	expired := 0
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		var ids []uint
+		if err := w.db.Model(&database.Order{}).
+			Where("status = ? AND id > ?", database.OrderStatusPending, lastID).
+			Where(pass.query).
+			Order("id ASC").
+			Limit(expiryBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			w.logger.Error("Failed to load "+pass.label, zap.Error(err))
+			return
+		}
+		if len(ids) == 0 {
+			break
+		}
+		lastID = ids[len(ids)-1]
+
+		for _, id := range ids {
+			_, err := w.flow.Apply(TransitionRequest{
+				OrderID:    id,
+				From:       database.OrderStatusPending,
+				To:         pass.to,
+				ActorRole:  ActorSystem,
+				Reason:     pass.reason,
+				Unreleased: pass.unreleased,
+			})
+			if err != nil {
+				// The vendor or the payment provider got to it first
+				if errors.Is(err, ErrStatusChanged) {
+					continue
+				}
+				w.logger.Error("Failed to expire order", zap.Uint("order_id", id), zap.Error(err))
+				continue
+			}
+			expired++
+		}
+	}
+
+	if expired > 0 {
+		w.logger.Info("Expired "+pass.label, zap.Int("count", expired))
+	}
+}
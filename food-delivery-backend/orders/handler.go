@@ -1,6 +1,7 @@
 package orders
 
 import (
+    "errors"
     "net/http"
     "strconv"
     "github.com/gin-gonic/gin"
@@ -29,6 +30,7 @@ func NewHandler(service *Service, logger *zap.Logger) *Handler {
 // @Param request body CreateOrderRequest true "Order details"
 // @Success 201 {object} pkg.Response{data=database.Order}
 // @Failure 400 {object} pkg.Response
+// @Failure 422 {object} pkg.Response "Outside the delivery zone or opening hours, vendor busy, slot full, coupon not applicable, payment declined or insufficient wallet balance"
 // @Router /orders [post]
 func (h *Handler) CreateOrder(c *gin.Context) {
     studentID := c.GetUint("user_id")
@@ -48,6 +50,10 @@ func (h *Handler) CreateOrder(c *gin.Context) {
     order, err := h.service.CreateOrder(studentID, &req)
     if err != nil {
         h.logger.Error("Failed to create order", zap.Error(err))
+        if code := errorCode(err); code != "" {
+            pkg.SendErrorCode(c, http.StatusUnprocessableEntity, code, "Failed to create order", err)
+            return
+        }
         pkg.SendError(c, http.StatusBadRequest, "Failed to create order", err.Error())
         return
     }
@@ -55,6 +61,66 @@ func (h *Handler) CreateOrder(c *gin.Context) {
     pkg.SendSuccess(c, http.StatusCreated, "Order created successfully", order)
 }
 
+// QuoteOrder validates and prices a cart before checkout without creating an order
+// @Summary Quote order
+// @Tags Orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body QuoteRequest true "Vendor, items and delivery location"
+// @Success 200 {object} pkg.Response{data=QuoteResponse}
+// @Failure 400 {object} pkg.Response
+// @Failure 404 {object} pkg.Response
+// @Router /orders/quote [post]
+func (h *Handler) QuoteOrder(c *gin.Context) {
+    var req QuoteRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+        return
+    }
+
+    quote, err := h.service.QuoteOrder(c.GetUint("user_id"), &req)
+    if err != nil {
+        status := http.StatusInternalServerError
+        if errors.Is(err, ErrVendorNotFound) {
+            status = http.StatusNotFound
+        }
+        pkg.SendError(c, status, "Failed to quote order", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Quote calculated successfully", quote)
+}
+
+// GetSlots lists the delivery slots a vendor takes pre-orders for on a day
+// @Summary Get delivery slots
+// @Tags Public
+// @Produce json
+// @Param id path int true "Vendor ID"
+// @Param date query string false "YYYY-MM-DD in the vendor's timezone, today by default"
+// @Success 200 {object} pkg.Response{data=[]Slot}
+// @Failure 404 {object} pkg.Response
+// @Router /public/vendors/{id}/slots [get]
+func (h *Handler) GetSlots(c *gin.Context) {
+    vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid vendor ID", nil)
+        return
+    }
+
+    slots, err := h.service.GetSlots(uint(vendorID), c.Query("date"))
+    if err != nil {
+        status := http.StatusBadRequest
+        if errors.Is(err, ErrVendorNotFound) {
+            status = http.StatusNotFound
+        }
+        pkg.SendError(c, status, "Failed to get delivery slots", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Delivery slots retrieved successfully", slots)
+}
+
 // GetOrder returns order details
 // @Summary Get order details
 // @Tags Orders
@@ -243,6 +309,144 @@ func (h *Handler) TrackOrder(c *gin.Context) {
     pkg.SendSuccess(c, http.StatusOK, "Tracking info retrieved", tracking)
 }
 
+// GetOrderEvents returns the order's full status history
+// @Summary Get order event log
+// @Tags Orders
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]OrderEvent}
+// @Failure 404 {object} pkg.Response
+// @Router /orders/{id}/events [get]
+func (h *Handler) GetOrderEvents(c *gin.Context) {
+    userID := c.GetUint("user_id")
+    userRole := c.GetString("user_role")
+    orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+        return
+    }
+
+    events, err := h.service.GetOrderEvents(userID, userRole, uint(orderID))
+    if err != nil {
+        pkg.SendError(c, http.StatusNotFound, "Order not found", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Order events retrieved", events)
+}
+
+// GetRoute returns the path the rider took with the order
+// @Summary Get delivery route
+// @Tags Orders
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=RouteResponse}
+// @Failure 404 {object} pkg.Response
+// @Router /orders/{id}/route [get]
+func (h *Handler) GetRoute(c *gin.Context) {
+    userID := c.GetUint("user_id")
+    userRole := c.GetString("user_role")
+    orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+        return
+    }
+
+    route, err := h.service.GetRoute(userID, userRole, uint(orderID))
+    if err != nil {
+        pkg.SendError(c, http.StatusNotFound, "Order not found", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Route retrieved", route)
+}
+
+// GetHandoffCode returns the code to give the rider: the delivery code for
+// the student, the pickup code for the vendor
+// @Summary Get handoff code
+// @Tags Orders
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=HandoffCodeResponse}
+// @Failure 404 {object} pkg.Response
+// @Router /orders/{id}/handoff-code [get]
+func (h *Handler) GetHandoffCode(c *gin.Context) {
+    userID := c.GetUint("user_id")
+    userRole := c.GetString("user_role")
+    orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+        return
+    }
+
+    code, err := h.service.GetHandoffCode(userID, userRole, uint(orderID))
+    if err != nil {
+        pkg.SendError(c, http.StatusNotFound, "Handoff code not available", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Handoff code retrieved", code)
+}
+
+// OverrideHandoff confirms a pickup or delivery without its code
+// @Summary Confirm handoff without code
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Accept json
+// @Produce json
+// @Param request body HandoffOverrideRequest true "Stage and reason"
+// @Success 200 {object} pkg.Response{data=database.Order}
+// @Router /admin/orders/{id}/handoff-override [post]
+func (h *Handler) OverrideHandoff(c *gin.Context) {
+    orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+        return
+    }
+
+    var req HandoffOverrideRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+        return
+    }
+
+    order, err := h.service.OverrideHandoff(c.GetUint("user_id"), uint(orderID), &req)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Failed to confirm handoff", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Handoff confirmed", order)
+}
+
+// GetHandoffProofs returns the proof recorded when the order changed hands
+// @Summary Get handoff proof
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]database.HandoffProof}
+// @Router /admin/orders/{id}/handoffs [get]
+func (h *Handler) GetHandoffProofs(c *gin.Context) {
+    orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid order ID", nil)
+        return
+    }
+
+    proofs, err := h.service.GetHandoffProofs(uint(orderID))
+    if err != nil {
+        pkg.SendError(c, http.StatusInternalServerError, "Failed to get handoff proof", err.Error())
+        return
+    }
+
+    pkg.SendSuccess(c, http.StatusOK, "Handoff proof retrieved", proofs)
+}
+
 // RateOrder rates a delivered order
 // @Summary Rate order
 // @Tags Orders
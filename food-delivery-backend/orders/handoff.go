@@ -0,0 +1,184 @@
+package orders
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrHandoffCodeRequired = errors.New("handoff code is required")
+	ErrHandoffCodeInvalid  = errors.New("handoff code is incorrect")
+	ErrHandoffLocked       = errors.New("too many wrong codes for this order, try again later or contact support")
+	ErrOverrideReason      = errors.New("a reason is required to confirm a handoff without its code")
+	ErrInvalidStage        = errors.New("stage must be pickup or delivery")
+)
+
+const handoffCodeDigits = 4
+
+// Handoff is the proof a rider submits when collecting or delivering an order
+type Handoff struct {
+	Code      string
+	PhotoURL  string
+	Latitude  *float64
+	Longitude *float64
+}
+
+// NewHandoffCode returns a random numeric code for handing an order over
+func NewHandoffCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < handoffCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", handoffCodeDigits, n.Int64()), nil
+}
+
+// HandoffStatus maps a handoff stage to the status it moves the order into
+func HandoffStatus(stage database.HandoffStage) (database.OrderStatus, error) {
+	switch stage {
+	case database.HandoffPickup:
+		return database.OrderStatusPickedUp, nil
+	case database.HandoffDelivery:
+		return database.OrderStatusDelivered, nil
+	}
+	return "", ErrInvalidStage
+}
+
+func handoffStage(to database.OrderStatus) (database.HandoffStage, bool) {
+	switch to {
+	case database.OrderStatusPickedUp:
+		return database.HandoffPickup, true
+	case database.OrderStatusDelivered:
+		return database.HandoffDelivery, true
+	}
+	return "", false
+}
+
+// handoffCode returns the order's code for the stage and whether riders must
+// enter it. Orders placed before codes existed have none and aren't checked.
+func (m *StateMachine) handoffCode(order *database.Order, stage database.HandoffStage) (string, bool) {
+	if stage == database.HandoffPickup {
+		return order.PickupCode, m.cfg.PickupCodeRequired && order.PickupCode != ""
+	}
+	return order.DeliveryCode, m.cfg.DeliveryCodeRequired && order.DeliveryCode != ""
+}
+
+// verifyHandoff checks the code for a transition into picked_up or delivered
+// and returns the proof to record with it. Riders must enter the code when
+// one is required; admins may go ahead without it if they give a reason.
+func (m *StateMachine) verifyHandoff(order *database.Order, req TransitionRequest) (*database.HandoffProof, error) {
+	stage, ok := handoffStage(req.To)
+	if !ok {
+		return nil, nil
+	}
+
+	proof := &database.HandoffProof{
+		OrderID:   order.ID,
+		Stage:     stage,
+		Method:    database.HandoffMethodUnverified,
+		RiderID:   order.AssignedRiderID,
+		ActorRole: req.ActorRole,
+		Reason:    req.Reason,
+	}
+	if req.ActorID != 0 {
+		actorID := req.ActorID
+		proof.ActorID = &actorID
+	}
+	if h := req.Handoff; h != nil {
+		proof.PhotoURL = h.PhotoURL
+		if h.Latitude != nil && h.Longitude != nil {
+			proof.Latitude, proof.Longitude = h.Latitude, h.Longitude
+			lat, lng := order.DeliveryLat, order.DeliveryLng
+			if stage == database.HandoffPickup {
+				lat, lng = order.Vendor.Latitude, order.Vendor.Longitude
+			}
+			if lat != 0 || lng != 0 {
+				km := pkg.CalculateDistance(*h.Latitude, *h.Longitude, lat, lng)
+				proof.DistanceKm = &km
+			}
+		}
+	}
+
+	code, required := m.handoffCode(order, stage)
+	if !required {
+		return proof, nil
+	}
+
+	switch req.ActorRole {
+	case "rider":
+		submitted := ""
+		if req.Handoff != nil {
+			submitted = req.Handoff.Code
+		}
+		attempts, err := m.checkCode(order, stage, code, submitted)
+		if err != nil {
+			return nil, err
+		}
+		proof.Method = database.HandoffMethodCode
+		proof.Attempts = attempts
+	case "admin":
+		if req.Reason == "" {
+			return nil, ErrOverrideReason
+		}
+		proof.Method = database.HandoffMethodOverride
+	}
+	return proof, nil
+}
+
+// checkCode compares the submitted code with the order's and counts wrong
+// ones. After HandoffMaxAttempts wrong codes the order refuses codes until
+// the counter expires, HandoffLockMinutes after the first wrong one. It
+// returns how many wrong codes came before the right one.
+func (m *StateMachine) checkCode(order *database.Order, stage database.HandoffStage, want, got string) (int, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("handoff:%d:%s", order.ID, stage)
+	maxAttempts := int64(m.cfg.HandoffMaxAttempts)
+
+	failed, err := m.redisClient.GetAttempts(ctx, key)
+	if err != nil {
+		m.logger.Warn("Failed to read handoff attempts", zap.Uint("order_id", order.ID), zap.Error(err))
+	}
+	if maxAttempts > 0 && failed >= maxAttempts {
+		return 0, ErrHandoffLocked
+	}
+	if got == "" {
+		return 0, ErrHandoffCodeRequired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		window := time.Duration(m.cfg.HandoffLockMinutes) * time.Minute
+		count, err := m.redisClient.CountAttempt(ctx, key, window)
+		if err != nil {
+			m.logger.Warn("Failed to count handoff attempt", zap.Uint("order_id", order.ID), zap.Error(err))
+			return 0, ErrHandoffCodeInvalid
+		}
+		if maxAttempts <= 0 {
+			return 0, ErrHandoffCodeInvalid
+		}
+		if count >= maxAttempts {
+			m.notifier.NotifyAdmin("Handoff Code Locked",
+				fmt.Sprintf("Order #%s was locked after %d wrong %s codes", order.OrderNumber, count, stage))
+			return 0, ErrHandoffLocked
+		}
+		return 0, fmt.Errorf("%w, %d attempts left", ErrHandoffCodeInvalid, maxAttempts-count)
+	}
+
+	if failed > 0 {
+		if err := m.redisClient.ClearAttempts(ctx, key); err != nil {
+			m.logger.Warn("Failed to clear handoff attempts", zap.Uint("order_id", order.ID), zap.Error(err))
+		}
+	}
+	return int(failed), nil
+}
@@ -0,0 +1,65 @@
+package orders
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"time"
+)
+
+// ProblemVendorBusy is reported when the vendor's kitchen is full and the
+// vendor rejects orders rather than extending waits
+const ProblemVendorBusy = "VENDOR_BUSY"
+
+var ErrVendorBusy = errors.New("vendor is too busy to take new orders right now, try again shortly")
+
+// KitchenStatuses are the statuses of orders a kitchen is working on
+var KitchenStatuses = []database.OrderStatus{database.OrderStatusConfirmed, database.OrderStatusPreparing}
+
+// Kitchen is a vendor's live queue and the wait a new order can expect
+type Kitchen struct {
+	ActiveOrders int  `json:"active_orders"`
+	Limit        int  `json:"limit,omitempty"`
+	AtCapacity   bool `json:"at_capacity"`
+	Busy         bool `json:"busy"`
+	QueueMinutes int  `json:"queue_minutes"` // delay on top of preparation from the queue and busy mode
+	WaitMinutes  int  `json:"wait_minutes"`  // until a new order is ready
+}
+
+// IsBusy reports whether the vendor's manual busy mode is on at now
+func IsBusy(vendor *database.Vendor, now time.Time) bool {
+	return vendor.BusyMinutes > 0 && (vendor.BusyUntil == nil || now.Before(*vendor.BusyUntil))
+}
+
+// KitchenLoad works out the wait a new order can expect given the orders
+// the kitchen is working on. Past MaxActiveOrders, every full batch of
+// orders ahead adds one preparation cycle.
+func KitchenLoad(vendor *database.Vendor, active int, now time.Time) Kitchen {
+	k := Kitchen{
+		ActiveOrders: active,
+		Limit:        vendor.MaxActiveOrders,
+		Busy:         IsBusy(vendor, now),
+	}
+	if k.Limit > 0 && active >= k.Limit {
+		k.AtCapacity = true
+		k.QueueMinutes = ((active-k.Limit)/k.Limit + 1) * vendor.AveragePrepTime
+	}
+	if k.Busy {
+		k.QueueMinutes += vendor.BusyMinutes
+	}
+	k.WaitMinutes = vendor.AveragePrepTime + k.QueueMinutes
+	return k
+}
+
+// Rejects reports whether the vendor turns new orders away at this load
+func (k Kitchen) Rejects(vendor *database.Vendor) bool {
+	return k.AtCapacity && vendor.CapacityAction == database.CapacityActionReject
+}
+
+// kitchenLoad loads the vendor's live queue
+func (s *Service) kitchenLoad(vendor *database.Vendor, now time.Time) (Kitchen, error) {
+	active, err := s.repo.CountKitchenOrders(vendor.ID)
+	if err != nil {
+		return Kitchen{}, err
+	}
+	return KitchenLoad(vendor, int(active), now), nil
+}
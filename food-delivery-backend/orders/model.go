@@ -2,6 +2,7 @@ package orders
 
 import (
 	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
 	"time"
 )
 
@@ -17,14 +18,60 @@ type CreateOrderRequest struct {
 	CustomerIDNumber    string             `json:"customer_id_number" binding:"required"`
 	SpecialInstructions string             `json:"special_instructions"`
 	PaymentMethod       string             `json:"payment_method" binding:"required,oneof=cash card wallet"`
+	PaymentToken        string             `json:"payment_token" binding:"required_if=PaymentMethod card"` // card token from the payment provider
+	CouponCode          string             `json:"coupon_code"`
+	ScheduledFor        *time.Time         `json:"scheduled_for"` // deliver at this time instead of as soon as possible
 }
 
 type OrderItemRequest struct {
 	MenuItemID          uint   `json:"menu_item_id" binding:"required"`
 	Quantity            int    `json:"quantity" binding:"required,min=1"`
+	Options             []uint `json:"options"` // ids of the chosen menu options
 	SpecialInstructions string `json:"special_instructions"`
 }
 
+type QuoteRequest struct {
+	VendorID     uint               `json:"vendor_id" binding:"required"`
+	Items        []OrderItemRequest `json:"items" binding:"required,min=1,dive"`
+	DeliveryLat  float64            `json:"delivery_lat"`
+	DeliveryLng  float64            `json:"delivery_lng"`
+	CouponCode   string             `json:"coupon_code"`
+	ScheduledFor *time.Time         `json:"scheduled_for"`
+}
+
+// QuoteResponse is what the order would cost if placed now. Valid is false
+// when Problems lists anything that would make CreateOrder fail.
+type QuoteResponse struct {
+	Valid                 bool                      `json:"valid"`
+	Lines                 []QuoteLine               `json:"lines"`
+	Subtotal              pkg.Money                 `json:"subtotal"`
+	DeliveryFee           pkg.Money                 `json:"delivery_fee"`
+	ServiceFee            pkg.Money                 `json:"service_fee"`
+	DiscountAmount        pkg.Money                 `json:"discount_amount"`
+	TotalAmount           pkg.Money                 `json:"total_amount"`
+	Pricing               database.PricingBreakdown `json:"pricing"`
+	EstimatedDeliveryTime time.Time                 `json:"estimated_delivery_time"`
+	Kitchen               Kitchen                   `json:"kitchen"` // the vendor's current queue and wait
+	Problems              []QuoteProblem            `json:"problems"`
+}
+
+type QuoteLine struct {
+	MenuItemID uint      `json:"menu_item_id"`
+	Name       string    `json:"name"`
+	Quantity   int       `json:"quantity"`
+	UnitPrice  pkg.Money `json:"unit_price"` // price charged, after any discount
+	ListPrice  pkg.Money `json:"list_price"`
+	Subtotal   pkg.Money `json:"subtotal"`
+	Available  bool      `json:"available"`
+	Options    []string  `json:"options,omitempty"`
+}
+
+type QuoteProblem struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	MenuItemID uint   `json:"menu_item_id,omitempty"`
+}
+
 type UpdateOrderStatusRequest struct {
 	Status database.OrderStatus `json:"status" binding:"required"`
 	Reason string               `json:"reason,omitempty"`
@@ -34,6 +81,47 @@ type CancelOrderRequest struct {
 	Reason string `json:"reason" binding:"required"`
 }
 
+// HandoffOverrideRequest confirms a pickup or delivery without its code
+type HandoffOverrideRequest struct {
+	Stage  database.HandoffStage `json:"stage" binding:"required,oneof=pickup delivery"`
+	Reason string                `json:"reason" binding:"required"`
+}
+
+// HandoffCodeResponse is the code a party gives the rider at handoff
+type HandoffCodeResponse struct {
+	Stage    database.HandoffStage `json:"stage"`
+	Code     string                `json:"code"`
+	Required bool                  `json:"required"` // whether the rider must enter it
+}
+
+// RoutePoint is one position of the rider on the way with an order
+type RoutePoint struct {
+	Latitude   float64              `json:"latitude"`
+	Longitude  float64              `json:"longitude"`
+	Status     database.OrderStatus `json:"status"`
+	RecordedAt time.Time            `json:"recorded_at"`
+}
+
+// RouteResponse is the path the rider took with an order, alongside where it
+// was collected and where it was due
+type RouteResponse struct {
+	OrderID     uint                 `json:"order_id"`
+	Status      database.OrderStatus `json:"status"`
+	RiderID     *uint                `json:"rider_id,omitempty"`
+	Pickup      RouteStop            `json:"pickup"`
+	Dropoff     RouteStop            `json:"dropoff"`
+	ReadyAt     *time.Time           `json:"ready_at,omitempty"`
+	PickedUpAt  *time.Time           `json:"picked_up_at,omitempty"`
+	DeliveredAt *time.Time           `json:"delivered_at,omitempty"`
+	DistanceKm  float64              `json:"distance_km"` // along the recorded points
+	Points      []RoutePoint         `json:"points"`
+}
+
+type RouteStop struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
 type RateOrderRequest struct {
 	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
 	Comment string `json:"comment"`
@@ -43,10 +131,10 @@ type OrderResponse struct {
 	ID                    uint                 `json:"id"`
 	OrderNumber           string               `json:"order_number"`
 	Status                database.OrderStatus `json:"status"`
-	Subtotal              float64              `json:"subtotal"`
-	DeliveryFee           float64              `json:"delivery_fee"`
-	ServiceFee            float64              `json:"service_fee"`
-	TotalAmount           float64              `json:"total_amount"`
+	Subtotal              pkg.Money            `json:"subtotal"`
+	DeliveryFee           pkg.Money            `json:"delivery_fee"`
+	ServiceFee            pkg.Money            `json:"service_fee"`
+	TotalAmount           pkg.Money            `json:"total_amount"`
 	DeliveryAddress       string               `json:"delivery_address"`
 	DeliveryBlock         string               `json:"delivery_block,omitempty"`
 	DeliveryDorm          string               `json:"delivery_dorm,omitempty"`
@@ -61,12 +149,12 @@ type OrderResponse struct {
 }
 
 type OrderItemResponse struct {
-	ID         uint    `json:"id"`
-	MenuItemID uint    `json:"menu_item_id"`
-	Name       string  `json:"name"`
-	Quantity   int     `json:"quantity"`
-	UnitPrice  float64 `json:"unit_price"`
-	Subtotal   float64 `json:"subtotal"`
+	ID         uint      `json:"id"`
+	MenuItemID uint      `json:"menu_item_id"`
+	Name       string    `json:"name"`
+	Quantity   int       `json:"quantity"`
+	UnitPrice  pkg.Money `json:"unit_price"`
+	Subtotal   pkg.Money `json:"subtotal"`
 }
 
 type VendorInfo struct {
@@ -86,9 +174,9 @@ type RiderInfo struct {
 }
 
 type PaymentInfo struct {
-	Method string  `json:"method"`
-	Status string  `json:"status"`
-	Amount float64 `json:"amount"`
+	Method string    `json:"method"`
+	Status string    `json:"status"`
+	Amount pkg.Money `json:"amount"`
 }
 
 type TrackingInfo struct {
@@ -99,6 +187,7 @@ type TrackingInfo struct {
 	DeliveryDorm      string               `json:"delivery_dorm,omitempty"`
 	CustomerPhone     string               `json:"customer_phone,omitempty"`
 	CustomerIDNumber  string               `json:"customer_id_number,omitempty"`
+	DeliveryCode      string               `json:"delivery_code,omitempty"` // only for the student
 	Vendor            struct {
 		Name      string  `json:"name"`
 		Latitude  float64 `json:"latitude"`
@@ -113,9 +202,24 @@ type TrackingInfo struct {
 	Timeline []TrackingEvent `json:"timeline"`
 }
 
+// OrderEvent is an entry in an order's event log as the parties to the
+// order see it: who acted is named, but their account is not shared
+type OrderEvent struct {
+	ID         uint                    `json:"id"`
+	Type       database.OrderEventType `json:"type"`
+	FromStatus database.OrderStatus    `json:"from_status,omitempty"`
+	ToStatus   database.OrderStatus    `json:"to_status,omitempty"`
+	ActorRole  string                  `json:"actor_role"`
+	ActorName  string                  `json:"actor_name,omitempty"`
+	Reason     string                  `json:"reason,omitempty"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
 type TrackingEvent struct {
-	Status    database.OrderStatus `json:"status"`
-	Timestamp time.Time            `json:"timestamp"`
-	Location  string               `json:"location,omitempty"`
-	Note      string               `json:"note,omitempty"`
+	Status    database.OrderStatus    `json:"status"`
+	Event     database.OrderEventType `json:"event,omitempty"`
+	Actor     string                  `json:"actor,omitempty"`
+	Timestamp time.Time               `json:"timestamp"`
+	Location  string                  `json:"location,omitempty"`
+	Note      string                  `json:"note,omitempty"`
 }
@@ -0,0 +1,158 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/pkg"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PricingInput is everything the fee calculation depends on
+type PricingInput struct {
+	Vendor      *database.Vendor
+	Subtotal    pkg.Money
+	DeliveryLat float64
+	DeliveryLng float64
+	At          time.Time
+}
+
+// Price is the result of pricing an order
+type Price struct {
+	DeliveryFee   pkg.Money                 `json:"delivery_fee"`
+	ServiceFee    pkg.Money                 `json:"service_fee"`
+	RiderEarnings pkg.Money                 `json:"rider_earnings"`
+	Breakdown     database.PricingBreakdown `json:"breakdown"`
+}
+
+// PricingEngine computes the fees charged on an order
+type PricingEngine interface {
+	Price(in PricingInput) (*Price, error)
+}
+
+// RulePricing prices orders from the PricingRule stored in the database:
+// a base fare covering the first IncludedKm, a per-km rate beyond that and
+// any peak-hour multiplier in effect.
+type RulePricing struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewRulePricing(db *gorm.DB, cfg *config.Config) *RulePricing {
+	return &RulePricing{db: db, cfg: cfg}
+}
+
+// CurrentRule returns the active rule, or one built from the configured flat
+// fees when no rule has been saved yet
+func (p *RulePricing) CurrentRule() (*database.PricingRule, error) {
+	var rule database.PricingRule
+	err := p.db.Preload("PeakHours").Order("id ASC").First(&rule).Error
+	if err == nil {
+		return &rule, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &database.PricingRule{
+		BaseFare:          pkg.NewMoney(p.cfg.DeliveryFee),
+		ServiceFeeRate:    p.cfg.ServiceFeeRate,
+		RiderEarningsRate: p.cfg.RiderEarningsRate,
+	}, nil
+}
+
+func (p *RulePricing) Price(in PricingInput) (*Price, error) {
+	rule, err := p.CurrentRule()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing rule: %w", err)
+	}
+	return PriceWithRule(rule, in), nil
+}
+
+// PriceWithRule applies rule to an order. It is pure so the same numbers come
+// out of quotes and order creation.
+func PriceWithRule(rule *database.PricingRule, in PricingInput) *Price {
+	var distance float64
+	if in.Vendor != nil && HasLocation(in.Vendor) && (in.DeliveryLat != 0 || in.DeliveryLng != 0) {
+		distance = DistanceToVendor(in.Vendor, in.DeliveryLat, in.DeliveryLng)
+	}
+
+	var distanceFare pkg.Money
+	if extra := distance - rule.IncludedKm; extra > 0 {
+		distanceFare = rule.PerKmRate.MulRate(extra)
+	}
+
+	// Peak windows are wall-clock times where the vendor is
+	at := in.At.In(time.UTC)
+	if in.Vendor != nil {
+		at = in.At.In(hours.Location(in.Vendor))
+	}
+	multiplier := 1.0
+	var peakName string
+	if peak := activePeakHour(rule.PeakHours, at); peak != nil {
+		multiplier = peak.Multiplier
+		peakName = peak.Name
+	}
+
+	deliveryFee := (rule.BaseFare + distanceFare).MulRate(multiplier)
+	if deliveryFee < rule.MinDeliveryFee {
+		deliveryFee = rule.MinDeliveryFee
+	}
+	if rule.MaxDeliveryFee > 0 && deliveryFee > rule.MaxDeliveryFee {
+		deliveryFee = rule.MaxDeliveryFee
+	}
+
+	return &Price{
+		DeliveryFee:   deliveryFee,
+		ServiceFee:    in.Subtotal.MulRate(rule.ServiceFeeRate),
+		RiderEarnings: deliveryFee.MulRate(rule.RiderEarningsRate),
+		Breakdown: database.PricingBreakdown{
+			RuleID:         rule.ID,
+			DistanceKm:     math.Round(distance*100) / 100,
+			BaseFare:       rule.BaseFare,
+			DistanceFare:   distanceFare,
+			PeakMultiplier: multiplier,
+			PeakName:       peakName,
+		},
+	}
+}
+
+// activePeakHour returns the peak window covering at, read in at's location,
+// with the highest multiplier. Windows whose end is before their start run
+// past midnight.
+func activePeakHour(peaks []database.PeakHour, at time.Time) *database.PeakHour {
+	minute := at.Hour()*60 + at.Minute()
+	var best *database.PeakHour
+
+	for i := range peaks {
+		peak := &peaks[i]
+		start, err1 := hours.ParseClock(peak.StartTime)
+		end, err2 := hours.ParseClock(peak.EndTime)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		day := int(at.Weekday())
+		var inWindow bool
+		if start <= end {
+			inWindow = minute >= start && minute < end
+		} else if minute >= start {
+			inWindow = true
+		} else if minute < end {
+			// Early-morning tail of a window that started the previous day
+			inWindow = true
+			day = (day + 6) % 7
+		}
+		if !inWindow || (peak.DayOfWeek != nil && *peak.DayOfWeek != day) {
+			continue
+		}
+		if best == nil || peak.Multiplier > best.Multiplier {
+			best = peak
+		}
+	}
+	return best
+}
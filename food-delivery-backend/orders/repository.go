@@ -2,9 +2,11 @@ package orders
 
 import (
 	"food-delivery-backend/database"
+	"food-delivery-backend/hours"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -17,14 +19,78 @@ func NewRepository(db *gorm.DB) *Repository {
 
 func (r *Repository) GetVendorByID(vendorID uint) (*database.Vendor, error) {
 	var vendor database.Vendor
-	err := r.db.Preload("User").First(&vendor, vendorID).Error
+	err := r.db.Scopes(hours.WithSchedule).Preload("User").First(&vendor, vendorID).Error
 	return &vendor, err
 }
 
-func (r *Repository) GetMenuItem(menuItemID, vendorID uint) (*database.MenuItem, error) {
+// CountKitchenOrders counts the vendor's confirmed and preparing orders
+func (r *Repository) CountKitchenOrders(vendorID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.Order{}).
+		Where("vendor_id = ? AND status IN ?", vendorID, KitchenStatuses).
+		Count(&count).Error
+	return count, err
+}
+
+// LockVendor takes a row lock on the vendor for the rest of tx
+func (r *Repository) LockVendor(tx *gorm.DB, vendorID uint) error {
+	var vendor database.Vendor
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&vendor, vendorID).Error
+}
+
+// CountSlotOrders counts the vendor's live scheduled orders for delivery
+// between from and to
+func (r *Repository) CountSlotOrders(db *gorm.DB, vendorID uint, from, to time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&database.Order{}).
+		Where("vendor_id = ? AND scheduled_for >= ? AND scheduled_for < ?", vendorID, from, to).
+		Where("status NOT IN ?", []database.OrderStatus{database.OrderStatusCancelled, database.OrderStatusRejected}).
+		Count(&count).Error
+	return count, err
+}
+
+// GetSlotCounts returns the vendor's live scheduled orders between from and
+// to, counted by the unix time of the slot they fall in
+func (r *Repository) GetSlotCounts(vendorID uint, from, to time.Time, slot time.Duration) (map[int64]int, error) {
+	var times []time.Time
+	err := r.db.Model(&database.Order{}).
+		Where("vendor_id = ? AND scheduled_for >= ? AND scheduled_for < ?", vendorID, from, to).
+		Where("status NOT IN ?", []database.OrderStatus{database.OrderStatusCancelled, database.OrderStatusRejected}).
+		Pluck("scheduled_for", &times).Error
+	counts := make(map[int64]int)
+	for _, t := range times {
+		counts[t.Truncate(slot).Unix()]++
+	}
+	return counts, err
+}
+
+// ReleaseOrder makes a scheduled order visible to its vendor. It reports
+// false if the order was already released or is no longer pending.
+func (r *Repository) ReleaseOrder(tx *gorm.DB, orderID uint, at time.Time) (bool, error) {
+	res := tx.Model(&database.Order{}).
+		Where("id = ? AND released_at IS NULL AND status = ?", orderID, database.OrderStatusPending).
+		Update("released_at", at)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+
+	event := &database.OrderEvent{
+		OrderID:    orderID,
+		Type:       database.OrderEventReleased,
+		FromStatus: database.OrderStatusPending,
+		ToStatus:   database.OrderStatusPending,
+		ActorRole:  ActorSystem,
+		Reason:     "scheduled delivery time approaching",
+	}
+	return true, tx.Create(event).Error
+}
+
+// GetVendorMenuItem returns a menu item belonging to the vendor whether or not
+// it is currently available
+func (r *Repository) GetVendorMenuItem(menuItemID, vendorID uint) (*database.MenuItem, error) {
 	var menuItem database.MenuItem
-	err := r.db.Where("id = ? AND vendor_id = ? AND is_available = ?",
-		menuItemID, vendorID, true).First(&menuItem).Error
+	err := r.db.Preload("OptionGroups.Options").
+		Where("id = ? AND vendor_id = ?", menuItemID, vendorID).First(&menuItem).Error
 	return &menuItem, err
 }
 
@@ -35,10 +101,12 @@ func (r *Repository) CreateOrder(order *database.Order) error {
 func (r *Repository) GetOrderByID(orderID uint) (*database.Order, error) {
 	var order database.Order
 	err := r.db.Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Preload("Vendor.User").
 		Preload("AssignedRider.User").
 		Preload("Student.User").
 		Preload("Payment").
+		Preload("Refunds.Items").
 		First(&order, orderID).Error
 	return &order, err
 }
@@ -54,31 +122,45 @@ func (r *Repository) UpdateOrder(order *database.Order) error {
 	return r.db.Save(order).Error
 }
 
-func (r *Repository) UpdateOrderStatus(orderID uint, status database.OrderStatus, updates map[string]interface{}) error {
-	updates["status"] = status
-	return r.db.Model(&database.Order{}).Where("id = ?", orderID).Updates(updates).Error
+// GetOrderEvents returns the order's event log in chronological order
+func (r *Repository) GetOrderEvents(orderID uint) ([]database.OrderEvent, error) {
+	var events []database.OrderEvent
+	err := r.db.Where("order_id = ?", orderID).
+		Order("created_at ASC, id ASC").
+		Find(&events).Error
+	return events, err
 }
 
-func (r *Repository) AssignRider(orderID, riderID uint) error {
-	return r.db.Model(&database.Order{}).Where("id = ?", orderID).
-		Updates(map[string]interface{}{
-			"assigned_rider_id": riderID,
-			"status":            database.OrderStatusConfirmed,
-			"confirmed_at":      time.Now(),
-		}).Error
+// GetUserFirstNames returns the first name of each of the given users
+func (r *Repository) GetUserFirstNames(userIDs []uint) (map[uint]string, error) {
+	names := make(map[uint]string)
+	if len(userIDs) == 0 {
+		return names, nil
+	}
+	var users []database.User
+	if err := r.db.Select("id, first_name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		names[u.ID] = u.FirstName
+	}
+	return names, nil
 }
 
-func (r *Repository) GetAvailableRider() (*database.Rider, error) {
-	var rider database.Rider
-	err := r.db.Where("is_available = ?", true).
-		Order("RANDOM()").
-		First(&rider).Error
-	return &rider, err
+func (r *Repository) GetRouteSamples(orderID uint) ([]database.RiderLocation, error) {
+	var samples []database.RiderLocation
+	err := r.db.Where("order_id = ?", orderID).
+		Order("created_at ASC, id ASC").
+		Find(&samples).Error
+	return samples, err
 }
 
-func (r *Repository) UpdateRiderAvailability(riderID uint, available bool) error {
-	return r.db.Model(&database.Rider{}).Where("id = ?", riderID).
-		Update("is_available", available).Error
+func (r *Repository) GetHandoffProofs(orderID uint) ([]database.HandoffProof, error) {
+	var proofs []database.HandoffProof
+	err := r.db.Where("order_id = ?", orderID).
+		Order("created_at ASC, id ASC").
+		Find(&proofs).Error
+	return proofs, err
 }
 
 func (r *Repository) GetStudentOrders(studentID uint, offset, limit int) ([]database.Order, int64, error) {
@@ -89,6 +171,7 @@ func (r *Repository) GetStudentOrders(studentID uint, offset, limit int) ([]data
 
 	err := r.db.Where("student_id = ?", studentID).
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Preload("Vendor").
 		Preload("AssignedRider.User").
 		Order("created_at DESC").
@@ -103,7 +186,8 @@ func (r *Repository) GetVendorOrders(vendorID uint, status string, offset, limit
 	var orders []database.Order
 	var total int64
 
-	query := r.db.Model(&database.Order{}).Where("vendor_id = ?", vendorID)
+	// Orders awaiting payment authorization are not shown to the vendor
+	query := r.db.Model(&database.Order{}).Where("vendor_id = ? AND released_at IS NOT NULL", vendorID)
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
@@ -111,6 +195,7 @@ func (r *Repository) GetVendorOrders(vendorID uint, status string, offset, limit
 
 	err := query.Preload("Student.User").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Preload("AssignedRider.User").
 		Order("created_at DESC").
 		Offset(offset).
@@ -129,6 +214,7 @@ func (r *Repository) GetRiderOrders(riderID uint, status string) ([]database.Ord
 	err := query.Preload("Student.User").
 		Preload("Vendor").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Order("created_at DESC").
 		Find(&orders).Error
 	return orders, err
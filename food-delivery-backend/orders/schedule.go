@@ -0,0 +1,139 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/hours"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Error codes returned to clients for scheduled orders
+const (
+	CodeInvalidSchedule = "INVALID_SCHEDULE"
+	CodeSlotFull        = "SLOT_FULL"
+)
+
+var (
+	ErrScheduleTooSoon = errors.New("scheduled time is too soon")
+	ErrScheduleTooFar  = errors.New("scheduled time is too far ahead")
+	ErrSlotFull        = errors.New("delivery slot is full")
+	ErrInvalidDate     = errors.New("invalid date, use YYYY-MM-DD")
+)
+
+// Slot is a delivery window a pre-order can be placed for
+type Slot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Remaining *int      `json:"remaining,omitempty"` // nil when the vendor has no slot limit
+	Available bool      `json:"available"`
+}
+
+// SlotLength is the width of a delivery slot
+func SlotLength(cfg *config.Config) time.Duration {
+	if cfg.ScheduledSlotMinutes <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(cfg.ScheduledSlotMinutes) * time.Minute
+}
+
+// SlotCapacity returns how many scheduled orders the vendor takes per slot,
+// 0 for no limit
+func SlotCapacity(vendor *database.Vendor, cfg *config.Config) int {
+	if vendor.SlotCapacity > 0 {
+		return vendor.SlotCapacity
+	}
+	return cfg.ScheduledSlotCapacity
+}
+
+// releaseTime is when a scheduled order is shown to the vendor
+func releaseTime(scheduledFor time.Time, cfg *config.Config) time.Time {
+	return scheduledFor.Add(-time.Duration(cfg.ScheduledReleaseMinutes) * time.Minute)
+}
+
+// deliveryTime is when the order is wanted: its slot if scheduled, now if not
+func deliveryTime(scheduledFor *time.Time, now time.Time) time.Time {
+	if scheduledFor != nil {
+		return *scheduledFor
+	}
+	return now
+}
+
+// checkSchedule validates the requested delivery time of a pre-order against
+// the lead time rule and the vendor's opening hours
+func (s *Service) checkSchedule(vendor *database.Vendor, at, now time.Time) error {
+	if at.Before(now.Add(time.Duration(s.cfg.ScheduledLeadMinutes) * time.Minute)) {
+		return fmt.Errorf("%w, schedule at least %d minutes ahead", ErrScheduleTooSoon, s.cfg.ScheduledLeadMinutes)
+	}
+	if s.cfg.ScheduledMaxDays > 0 && at.After(now.AddDate(0, 0, s.cfg.ScheduledMaxDays)) {
+		return fmt.Errorf("%w, schedule at most %d days ahead", ErrScheduleTooFar, s.cfg.ScheduledMaxDays)
+	}
+	return hours.CheckOpen(vendor, at)
+}
+
+// checkSlot returns ErrSlotFull when the vendor's slot containing at has no
+// room. CreateOrder runs it in its transaction with the vendor locked so
+// concurrent orders can't overbook the slot.
+func (s *Service) checkSlot(db *gorm.DB, vendor *database.Vendor, at time.Time) error {
+	capacity := SlotCapacity(vendor, s.cfg)
+	if capacity <= 0 {
+		return nil
+	}
+	start := at.Truncate(SlotLength(s.cfg))
+	taken, err := s.repo.CountSlotOrders(db, vendor.ID, start, start.Add(SlotLength(s.cfg)))
+	if err != nil {
+		return err
+	}
+	if taken >= int64(capacity) {
+		return fmt.Errorf("%w for %s, choose another time", ErrSlotFull, start.In(hours.Location(vendor)).Format("Mon 15:04"))
+	}
+	return nil
+}
+
+// GetSlots lists the vendor's delivery slots on date (YYYY-MM-DD in the
+// vendor's timezone, today when empty) that a pre-order could be placed for
+func (s *Service) GetSlots(vendorID uint, date string) ([]Slot, error) {
+	vendor, err := s.repo.GetVendorByID(vendorID)
+	if err != nil {
+		return nil, ErrVendorNotFound
+	}
+
+	loc := hours.Location(vendor)
+	now := time.Now()
+	day := now.In(loc)
+	if date != "" {
+		if day, err = time.ParseInLocation(hours.DateLayout, date, loc); err != nil {
+			return nil, ErrInvalidDate
+		}
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+
+	length := SlotLength(s.cfg)
+	taken, err := s.repo.GetSlotCounts(vendor.ID, from.Add(-length), to, length)
+	if err != nil {
+		return nil, err
+	}
+	capacity := SlotCapacity(vendor, s.cfg)
+
+	slots := []Slot{}
+	for start := from.Truncate(length); start.Before(to); start = start.Add(length) {
+		if start.Before(from) || s.checkSchedule(vendor, start, now) != nil {
+			continue
+		}
+		slot := Slot{Start: start, End: start.Add(length), Available: true}
+		if capacity > 0 {
+			remaining := capacity - taken[start.Unix()]
+			if remaining < 0 {
+				remaining = 0
+			}
+			slot.Remaining = &remaining
+			slot.Available = remaining > 0
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
@@ -0,0 +1,162 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const schedulerLockName = "order-scheduler"
+
+// SchedulerWorker releases scheduled orders to their vendor
+// ScheduledReleaseMinutes before the slot, after which they follow the
+// normal pending flow. Card orders are only released once authorized;
+// those still unauthorized when the slot arrives are cancelled.
+type SchedulerWorker struct {
+	db          *gorm.DB
+	repo        *Repository
+	flow        *StateMachine
+	notifier    *notifications.Service
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewSchedulerWorker(db *gorm.DB, flow *StateMachine, notifier *notifications.Service, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *SchedulerWorker {
+	return &SchedulerWorker{
+		db:          db,
+		repo:        NewRepository(db),
+		flow:        flow,
+		notifier:    notifier,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run releases due orders on every interval until ctx is cancelled
+func (w *SchedulerWorker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.ScheduledOrderInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx, interval)
+		}
+	}
+}
+
+func (w *SchedulerWorker) sweep(ctx context.Context, lockTTL time.Duration) {
+	token, ok, err := w.redisClient.AcquireLock(ctx, schedulerLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire order scheduler lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), schedulerLockName, token); err != nil {
+			w.logger.Warn("Failed to release order scheduler lock", zap.Error(err))
+		}
+	}()
+
+	now := time.Now()
+	w.releaseDue(ctx, now)
+	w.cancelLapsed(ctx, now)
+}
+
+// releaseDue shows the vendor every paid-up scheduled order inside the
+// release window
+func (w *SchedulerWorker) releaseDue(ctx context.Context, now time.Time) {
+	cutoff := now.Add(time.Duration(w.cfg.ScheduledReleaseMinutes) * time.Minute)
+
+	var ids []uint
+	if err := w.db.Model(&database.Order{}).
+		Where("status = ? AND released_at IS NULL AND scheduled_for <= ?", database.OrderStatusPending, cutoff).
+		Where("EXISTS (SELECT 1 FROM payments WHERE payments.order_id = orders.id AND (payments.payment_method <> ? OR payments.payment_status IN ?))",
+			database.PaymentMethodCard, []database.PaymentStatus{database.PaymentStatusAuthorized, database.PaymentStatusCompleted}).
+		Order("scheduled_for ASC").
+		Pluck("id", &ids).Error; err != nil {
+		w.logger.Error("Failed to load due scheduled orders", zap.Error(err))
+		return
+	}
+
+	count := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+
+		var released bool
+		err := w.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			released, err = w.repo.ReleaseOrder(tx, id, now)
+			return err
+		})
+		if err != nil {
+			w.logger.Error("Failed to release scheduled order", zap.Uint("order_id", id), zap.Error(err))
+			continue
+		}
+		if !released {
+			continue
+		}
+		count++
+
+		order, err := w.repo.GetOrderByID(id)
+		if err != nil {
+			continue
+		}
+		w.notifier.NotifyNewOrder(order)
+		w.notifier.NotifyStudent(order.Student.UserID, "Order Sent to Vendor",
+			fmt.Sprintf("Your scheduled order #%s has been sent to the vendor", order.OrderNumber),
+			"order_released", fmt.Sprintf("%d", order.ID))
+	}
+
+	if count > 0 {
+		w.logger.Info("Released scheduled orders", zap.Int("count", count))
+	}
+}
+
+// cancelLapsed cancels scheduled orders whose slot has arrived without the
+// card payment being authorized
+func (w *SchedulerWorker) cancelLapsed(ctx context.Context, now time.Time) {
+	var ids []uint
+	if err := w.db.Model(&database.Order{}).
+		Where("status = ? AND released_at IS NULL AND scheduled_for < ?", database.OrderStatusPending, now).
+		Pluck("id", &ids).Error; err != nil {
+		w.logger.Error("Failed to load lapsed scheduled orders", zap.Error(err))
+		return
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		_, err := w.flow.Apply(TransitionRequest{
+			OrderID:   id,
+			From:      database.OrderStatusPending,
+			To:        database.OrderStatusCancelled,
+			ActorRole: ActorSystem,
+			Reason:    "Payment was not authorized before the scheduled time",
+		})
+		if err != nil && !errors.Is(err, ErrStatusChanged) {
+			w.logger.Error("Failed to cancel lapsed scheduled order", zap.Uint("order_id", id), zap.Error(err))
+		}
+	}
+}
@@ -7,135 +7,30 @@ import (
 	"errors"
 	"fmt"
 	"food-delivery-backend/config"
+	"food-delivery-backend/coupons"
 	"food-delivery-backend/database"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/ledger"
 	"food-delivery-backend/notifications"
+	"food-delivery-backend/payments"
 	"food-delivery-backend/pkg"
 	"food-delivery-backend/redis"
+	"math"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-// ... rest of the file
-// Add this method to orders/service.go
-
-func (s *Service) autoAssignRider(order *database.Order) error {
-	// Find nearest available riders
-	ctx := context.Background()
-	riders, err := s.redisClient.FindNearestRiders(
-		ctx,
-		order.DeliveryLat,
-		order.DeliveryLng,
-		10.0, // 10km radius
-	)
-	if err != nil || len(riders) == 0 {
-		s.logger.Warn("No available riders found for auto-assignment", zap.Uint("order_id", order.ID))
-		return errors.New("no available riders")
-	}
-
-	// Try to assign to first rider
-	for _, riderLoc := range riders {
-		riderIDStr := riderLoc.Name
-		var riderID uint
-		fmt.Sscanf(riderIDStr, "%d", &riderID)
-
-		// Check if rider is available
-		available, err := s.redisClient.IsRiderAvailable(ctx, riderID)
-		if err != nil || !available {
-			continue
-		}
-
-		// Assign rider
-		if err := s.assignRiderToOrder(order.ID, riderID); err != nil {
-			s.logger.Warn("Failed to assign rider", zap.Uint("rider_id", riderID), zap.Error(err))
-			continue
-		}
-
-		s.logger.Info("Auto-assigned rider to order", zap.Uint("order_id", order.ID), zap.Uint("rider_id", riderID))
-		return nil
-	}
-
-	return errors.New("no available riders could be assigned")
-}
-
-func (s *Service) assignRiderToOrder(orderID, riderID uint) error {
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return err
-	}
-
-	// Update order with rider
-	updates := map[string]interface{}{
-		"assigned_rider_id": riderID,
-	}
-
-	if err := s.repo.UpdateOrderStatus(orderID, order.Status, updates); err != nil {
-		return err
-	}
-
-	// Update rider availability
-	s.repo.UpdateRiderAvailability(riderID, false)
-
-	// Reload order with rider info
-	order, _ = s.repo.GetOrderByID(orderID)
-
-	// Send notifications
-	s.notifier.NotifyRiderAssigned(order, riderID)
-	s.notifier.NotifyAdmin("Rider Assigned",
-		fmt.Sprintf("Rider #%d was assigned to order #%s", riderID, order.OrderNumber))
-
-	return nil
-}
-
-// Update MarkOrderReady to auto-assign rider
-func (s *Service) MarkOrderReady(vendorID uint, orderID uint) error {
-	vendor, err := s.repo.GetVendorByID(vendorID)
-	if err != nil {
-		return errors.New("vendor not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.VendorID != vendor.ID {
-		return errors.New("unauthorized to update this order")
-	}
-
-	if order.Status != database.OrderStatusPreparing {
-		return errors.New("order must be in preparing status to mark as ready")
-	}
-
-	now := time.Now()
-	updates := map[string]interface{}{
-		"ready_at": now,
-	}
-	if err := s.repo.UpdateOrderStatus(orderID, database.OrderStatusReady, updates); err != nil {
-		return err
-	}
-
-	// Reload order to get updated status
-	order, _ = s.repo.GetOrderByID(orderID)
-
-	// Auto-assign rider when order is ready
-	if order.AssignedRiderID == nil {
-		if err := s.autoAssignRider(order); err != nil {
-			s.logger.Warn("Failed to auto-assign rider", zap.Error(err))
-			// Notify admin for manual assignment
-			s.notifier.NotifyAdmin("Order Ready - Manual Assignment Required",
-				fmt.Sprintf("Order #%s is ready but no rider could be auto-assigned", order.OrderNumber))
-		}
-	}
-
-	return nil
-}
-
 type Service struct {
 	repo        *Repository
+	flow        *StateMachine
 	notifier    *notifications.Service
 	redisClient *redis.RedisClient
+	pricing     PricingEngine
+	coupons     *coupons.Service
+	payments    *payments.Service
+	eta         *ETAEstimator
 	db          *gorm.DB
 	cfg         *config.Config
 	logger      *zap.Logger
@@ -143,6 +38,10 @@ type Service struct {
 
 func NewService(
 	repo *Repository,
+	flow *StateMachine,
+	pricing PricingEngine,
+	couponService *coupons.Service,
+	paymentService *payments.Service,
 	notifier *notifications.Service,
 	redisClient *redis.RedisClient,
 	db *gorm.DB,
@@ -151,6 +50,11 @@ func NewService(
 ) *Service {
 	return &Service{
 		repo:        repo,
+		flow:        flow,
+		pricing:     pricing,
+		coupons:     couponService,
+		payments:    paymentService,
+		eta:         NewETAEstimator(db, redisClient, logger),
 		notifier:    notifier,
 		redisClient: redisClient,
 		db:          db,
@@ -165,54 +69,48 @@ func (s *Service) CreateOrder(studentID uint, req *CreateOrderRequest) (*databas
 	if err != nil {
 		return nil, errors.New("vendor not found")
 	}
-	if !vendor.IsOpen {
-		return nil, errors.New("vendor is currently closed")
-	}
-
-	// Calculate order totals and validate items
-	var subtotal float64
-	var orderItems []database.OrderItem
-
-	for _, item := range req.Items {
-		menuItem, err := s.repo.GetMenuItem(item.MenuItemID, req.VendorID)
-		if err != nil {
-			return nil, fmt.Errorf("menu item %d not available", item.MenuItemID)
-		}
-
-		// Use discount price if available
-		price := menuItem.Price
-		if menuItem.DiscountPrice != nil && *menuItem.DiscountPrice > 0 {
-			price = *menuItem.DiscountPrice
-		}
-
-		itemSubtotal := price * float64(item.Quantity)
-		subtotal += itemSubtotal
-
-		orderItems = append(orderItems, database.OrderItem{
-			MenuItemID:          item.MenuItemID,
-			Quantity:            item.Quantity,
-			UnitPrice:           price,
-			Subtotal:            itemSubtotal,
-			SpecialInstructions: item.SpecialInstructions,
-		})
-	}
 
-	// Check minimum order
-	if subtotal < vendor.MinimumOrder {
-		return nil, fmt.Errorf("minimum order amount is %.2f", vendor.MinimumOrder)
+	// Validate the cart and calculate order totals
+	now := time.Now()
+	c := s.buildCart(vendor, req.Items, req.DeliveryLat, req.DeliveryLng, now, req.ScheduledFor)
+	if len(c.problems) > 0 {
+		return nil, c.problems[0].err
 	}
+	orderItems, subtotal := c.items, c.subtotal
+	deliverAt := deliveryTime(req.ScheduledFor, now)
 
 	// Calculate fees
-	deliveryFee := s.cfg.DeliveryFee
-	serviceFee := subtotal * s.cfg.ServiceFeeRate
-	commissionAmount := subtotal * vendor.CommissionRate
-	vendorEarnings := subtotal - commissionAmount
-	riderEarnings := deliveryFee * s.cfg.RiderEarningsRate
-	totalAmount := subtotal + deliveryFee + serviceFee
+	price, err := s.pricing.Price(PricingInput{
+		Vendor:      vendor,
+		Subtotal:    subtotal,
+		DeliveryLat: req.DeliveryLat,
+		DeliveryLng: req.DeliveryLng,
+		At:          deliverAt,
+	})
+	if err != nil {
+		s.logger.Error("Failed to price order", zap.Error(err))
+		return nil, errors.New("failed to calculate fees")
+	}
+	eta := s.eta.estimateNew(vendor, c.kitchen, c.itemPrep, price.Breakdown.DistanceKm, now)
+	if eta.Before(deliverAt) {
+		eta = deliverAt
+	}
 
 	// Generate order number
 	orderNumber := pkg.GenerateOrderNumber()
 
+	// Codes the rider must be given to collect and hand over the order
+	deliveryCode, err := NewHandoffCode()
+	if err != nil {
+		s.logger.Error("Failed to generate delivery code", zap.Error(err))
+		return nil, errors.New("failed to create order")
+	}
+	pickupCode, err := NewHandoffCode()
+	if err != nil {
+		s.logger.Error("Failed to generate pickup code", zap.Error(err))
+		return nil, errors.New("failed to create order")
+	}
+
 	// Resolve student record (studentID param is authenticated user ID)
 	student, err := s.repo.GetStudentByUserID(studentID)
 	if err != nil {
@@ -229,53 +127,161 @@ func (s *Service) CreateOrder(studentID uint, req *CreateOrderRequest) (*databas
 		zap.Int64("student_row_count", studentCount),
 	)
 
+	var discount *coupons.Discount
+	if req.CouponCode != "" {
+		discount, err = s.coupons.Evaluate(coupons.ApplyInput{
+			Code:        req.CouponCode,
+			VendorID:    vendor.ID,
+			StudentID:   student.ID,
+			Subtotal:    subtotal,
+			DeliveryFee: price.DeliveryFee,
+			At:          now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	totals := computeTotals(vendor, subtotal, price, discount)
+
+	// Cards are authorized before anything is stored; the vendor only sees
+	// the order once the hold succeeds
+	var intent *payments.Intent
+	payment := &database.Payment{
+		Amount:        totals.Total,
+		PaymentMethod: req.PaymentMethod,
+		PaymentStatus: string(database.PaymentStatusPending),
+		TransactionID: pkg.GenerateTransactionID(),
+	}
+	var releasedAt *time.Time
+	if req.PaymentMethod == string(database.PaymentMethodCard) {
+		intent, err = s.payments.Authorize(orderNumber, totals.Total, req.PaymentToken)
+		if err != nil {
+			return nil, err
+		}
+		payment.Provider = s.payments.ProviderName()
+		payment.TransactionID = intent.ID
+		if intent.Status == payments.IntentAuthorized {
+			payment.PaymentStatus = string(database.PaymentStatusAuthorized)
+			payment.AuthorizedAt = &now
+			releasedAt = &now
+		}
+	} else {
+		releasedAt = &now
+	}
+	// Pre-orders wait for the scheduler unless their slot is already close
+	if req.ScheduledFor != nil && releaseTime(*req.ScheduledFor, s.cfg).After(now) {
+		releasedAt = nil
+	}
+
 	// Create order within transaction
 	tx := s.db.Begin()
 
+	if req.ScheduledFor != nil {
+		err := s.repo.LockVendor(tx, vendor.ID)
+		if err == nil {
+			err = s.checkSlot(tx, vendor, *req.ScheduledFor)
+		}
+		if err != nil {
+			tx.Rollback()
+			s.voidIntent(intent)
+			if errors.Is(err, ErrSlotFull) {
+				return nil, err
+			}
+			s.logger.Error("Failed to check delivery slot", zap.Error(err))
+			return nil, errors.New("failed to create order")
+		}
+	}
+
 	order := &database.Order{
-		OrderNumber:         orderNumber,
-		StudentID:           student.ID,
-		VendorID:            req.VendorID,
-		Status:              database.OrderStatusPending,
-		Subtotal:            subtotal,
-		DeliveryFee:         deliveryFee,
-		ServiceFee:          serviceFee,
-		TotalAmount:         totalAmount,
-		CommissionAmount:    commissionAmount,
-		VendorEarnings:      vendorEarnings,
-		RiderEarnings:       riderEarnings,
-		DeliveryAddress:     req.DeliveryAddress,
-		DeliveryBlock:       req.DeliveryBlock,
-		DeliveryDorm:        req.DeliveryDorm,
-		CustomerPhone:       req.CustomerPhone,
-		CustomerIDNumber:    req.CustomerIDNumber,
-		DeliveryLat:         req.DeliveryLat,
-		DeliveryLng:         req.DeliveryLng,
-		SpecialInstructions: req.SpecialInstructions,
-		OrderItems:          orderItems,
+		OrderNumber:           orderNumber,
+		DeliveryCode:          deliveryCode,
+		PickupCode:            pickupCode,
+		StudentID:             student.ID,
+		VendorID:              req.VendorID,
+		Status:                database.OrderStatusPending,
+		Subtotal:              subtotal,
+		DeliveryFee:           price.DeliveryFee,
+		ServiceFee:            price.ServiceFee,
+		TotalAmount:           totals.Total,
+		CommissionAmount:      totals.Commission,
+		VendorEarnings:        totals.VendorEarnings,
+		RiderEarnings:         price.RiderEarnings,
+		Pricing:               price.Breakdown,
+		DiscountAmount:        totals.Discount,
+		DeliveryAddress:       req.DeliveryAddress,
+		DeliveryBlock:         req.DeliveryBlock,
+		DeliveryDorm:          req.DeliveryDorm,
+		CustomerPhone:         req.CustomerPhone,
+		CustomerIDNumber:      req.CustomerIDNumber,
+		DeliveryLat:           req.DeliveryLat,
+		DeliveryLng:           req.DeliveryLng,
+		SpecialInstructions:   req.SpecialInstructions,
+		EstimatedDeliveryTime: &eta,
+		KitchenWaitMinutes:    c.kitchen.WaitMinutes,
+		ScheduledFor:          req.ScheduledFor,
+		ReleasedAt:            releasedAt,
+		OrderItems:            orderItems,
+	}
+
+	if discount != nil {
+		order.CouponID = &discount.Coupon.ID
+		order.CouponCode = discount.Coupon.Code
 	}
 
 	if err := tx.Create(order).Error; err != nil {
 		tx.Rollback()
+		s.voidIntent(intent)
 		s.logger.Error("Failed to create order", zap.Error(err))
 		return nil, errors.New("failed to create order")
 	}
 
-	// Create payment record
-	payment := &database.Payment{
-		OrderID:       order.ID,
-		Amount:        totalAmount,
-		PaymentMethod: req.PaymentMethod,
-		PaymentStatus: string(database.PaymentStatusPending),
-		TransactionID: pkg.GenerateTransactionID(),
+	if discount != nil {
+		if err := s.coupons.Redeem(tx, discount, order); err != nil {
+			tx.Rollback()
+			s.voidIntent(intent)
+			if errors.Is(err, coupons.ErrCouponNotApplicable) {
+				return nil, err
+			}
+			s.logger.Error("Failed to redeem coupon", zap.Error(err))
+			return nil, errors.New("failed to create order")
+		}
+	}
+
+	placed := &database.OrderEvent{
+		OrderID:   order.ID,
+		Type:      database.OrderEventPlaced,
+		ToStatus:  database.OrderStatusPending,
+		ActorID:   &studentID,
+		ActorRole: "student",
+	}
+	if err := tx.Create(placed).Error; err != nil {
+		tx.Rollback()
+		s.voidIntent(intent)
+		s.logger.Error("Failed to record order event", zap.Error(err))
+		return nil, errors.New("failed to create order")
+	}
+
+	// Wallet orders are paid in full before the vendor sees them
+	if req.PaymentMethod == string(database.PaymentMethodWallet) {
+		order.Student = *student
+		if err := s.payFromWallet(tx, order, payment); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
+
+	// Create payment record
+	payment.OrderID = order.ID
 	if err := tx.Create(payment).Error; err != nil {
 		tx.Rollback()
+		s.voidIntent(intent)
 		s.logger.Error("Failed to create payment", zap.Error(err))
 		return nil, errors.New("failed to create payment record")
 	}
+	order.Payment = payment
 
 	if err := tx.Commit().Error; err != nil {
+		s.voidIntent(intent)
 		return nil, errors.New("failed to complete order creation")
 	}
 
@@ -284,6 +290,19 @@ func (s *Service) CreateOrder(studentID uint, req *CreateOrderRequest) (*databas
 	s.redisClient.CacheActiveOrder(ctx, order.ID, order, 30*time.Minute)
 
 	// Send notifications
+	if order.ReleasedAt == nil && order.ScheduledFor != nil {
+		s.notifier.NotifyStudent(studentID, "Order Scheduled",
+			fmt.Sprintf("Your order #%s is scheduled for %s", order.OrderNumber, order.ScheduledFor.In(hours.Location(vendor)).Format("Mon 15:04")),
+			"order_scheduled", fmt.Sprintf("%d", order.ID))
+		return order, nil
+	}
+	if order.ReleasedAt == nil {
+		s.notifier.NotifyStudent(studentID, "Awaiting Payment",
+			fmt.Sprintf("Your order #%s will be sent to the vendor once your payment is authorized", order.OrderNumber),
+			"payment_pending", fmt.Sprintf("%d", order.ID))
+		return order, nil
+	}
+
 	s.notifier.NotifyVendor(vendor.UserID, "New Order",
 		fmt.Sprintf("New order #%s received", order.OrderNumber),
 		"order_received", fmt.Sprintf("%d", order.ID))
@@ -300,6 +319,35 @@ func (s *Service) CreateOrder(studentID uint, req *CreateOrderRequest) (*databas
 	return order, nil
 }
 
+// payFromWallet debits the order total from the student's wallet inside tx.
+// order must have its Student set.
+func (s *Service) payFromWallet(tx *gorm.DB, order *database.Order, payment *database.Payment) error {
+	balance, err := ledger.LockBalance(tx, database.LedgerAccountWallet, order.Student.UserID)
+	if err != nil {
+		s.logger.Error("Failed to lock wallet", zap.Uint("order_id", order.ID), zap.Error(err))
+		return errors.New("failed to create order")
+	}
+	if balance < order.TotalAmount {
+		return fmt.Errorf("%w: %s available, %s needed", ErrInsufficientFunds, balance, order.TotalAmount)
+	}
+
+	if err := ledger.PostWalletPayment(tx, order); err != nil {
+		s.logger.Error("Failed to debit wallet", zap.Uint("order_id", order.ID), zap.Error(err))
+		return errors.New("failed to create order")
+	}
+	now := time.Now()
+	payment.PaymentStatus = string(database.PaymentStatusCompleted)
+	payment.PaidAt = &now
+	return nil
+}
+
+// voidIntent releases the card hold of an order that could not be created
+func (s *Service) voidIntent(intent *payments.Intent) {
+	if intent != nil {
+		s.payments.Void(intent)
+	}
+}
+
 func (s *Service) GetOrder(userID uint, userRole string, orderID uint) (*database.Order, error) {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
@@ -316,8 +364,13 @@ func (s *Service) GetOrder(userID uint, userRole string, orderID uint) (*databas
 		if order.Vendor.UserID != userID {
 			return nil, errors.New("unauthorized to view this order")
 		}
+		// Orders awaiting payment authorization don't exist for the vendor yet
+		if order.ReleasedAt == nil {
+			return nil, errors.New("order not found")
+		}
 	case "rider":
-		if order.AssignedRiderID == nil || *order.AssignedRiderID != userID {
+		// userID is the rider's user, not their rider record
+		if order.AssignedRider == nil || order.AssignedRider.UserID != userID {
 			return nil, errors.New("unauthorized to view this order")
 		}
 	case "admin":
@@ -350,83 +403,14 @@ func (s *Service) GetRiderOrders(riderID uint, status string) ([]database.Order,
 func (s *Service) UpdateOrderStatus(updaterID uint, role string, orderID uint,
 	status database.OrderStatus, reason string) error {
 
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	// Validate role-based permissions and state transitions
-	if err := s.validateStatusUpdate(order, updaterID, role, status); err != nil {
-		return err
-	}
-
-	// Prepare updates
-	updates := map[string]interface{}{
-		"status": status,
-	}
-
-	now := time.Now()
-	switch status {
-	case database.OrderStatusConfirmed:
-		updates["confirmed_at"] = now
-	case database.OrderStatusPreparing:
-		updates["prepared_at"] = now
-	case database.OrderStatusReady:
-		updates["ready_at"] = now
-	case database.OrderStatusPickedUp:
-		updates["picked_up_at"] = now
-	case database.OrderStatusDelivered:
-		updates["delivered_at"] = now
-	case database.OrderStatusCancelled, database.OrderStatusRejected:
-		updates["cancelled_at"] = now
-		updates["cancellation_reason"] = reason
-	}
-
-	// Update order status
-	if err := s.repo.UpdateOrderStatus(orderID, status, updates); err != nil {
-		s.logger.Error("Failed to update order status", zap.Error(err))
-		return errors.New("failed to update order status")
-	}
-
-	// Handle rider availability and earnings
-	if status == database.OrderStatusDelivered {
-		if order.AssignedRiderID != nil {
-			// Update rider availability
-			s.repo.UpdateRiderAvailability(*order.AssignedRiderID, true)
-
-			// Update vendor stats
-			s.db.Model(&database.Vendor{}).Where("id = ?", order.VendorID).
-				Updates(map[string]interface{}{
-					"total_orders":    gorm.Expr("total_orders + ?", 1),
-					"total_revenue":   gorm.Expr("total_revenue + ?", order.Subtotal),
-					"total_earnings":  gorm.Expr("total_earnings + ?", order.VendorEarnings),
-					"current_balance": gorm.Expr("current_balance + ?", order.VendorEarnings),
-				})
-
-			// Update student stats (order.StudentID is Student.ID)
-			s.db.Model(&database.Student{}).Where("id = ?", order.StudentID).
-				Updates(map[string]interface{}{
-					"total_orders": gorm.Expr("total_orders + ?", 1),
-					"total_spent":  gorm.Expr("total_spent + ?", order.TotalAmount),
-				})
-		}
-	}
-
-	// Handle cancellation/rejection
-	if status == database.OrderStatusCancelled || status == database.OrderStatusRejected {
-		if order.AssignedRiderID != nil {
-			s.repo.UpdateRiderAvailability(*order.AssignedRiderID, true)
-		}
-	}
-
-	// Remove from cache
-	ctx := context.Background()
-	s.redisClient.RemoveActiveOrder(ctx, orderID)
-
-	// Send notifications
-	s.notifier.NotifyOrderUpdate(order, status, reason)
-
-	return nil
+	_, err := s.flow.Apply(TransitionRequest{
+		OrderID:   orderID,
+		To:        status,
+		ActorID:   updaterID,
+		ActorRole: role,
+		Reason:    reason,
+	})
+	return err
 }
 
 func (s *Service) CancelOrder(userID uint, role string, orderID uint, reason string) error {
@@ -490,6 +474,11 @@ func (s *Service) TrackOrder(userID uint, orderID uint) (*TrackingInfo, error) {
 	tracking.CustomerPhone = order.CustomerPhone
 	tracking.CustomerIDNumber = order.CustomerIDNumber
 
+	// The delivery code is the student's to give the rider at the door
+	if order.Student.UserID == userID && !IsTerminal(order.Status) {
+		tracking.DeliveryCode = order.DeliveryCode
+	}
+
 	// Rider info if assigned
 	if order.AssignedRider != nil {
 		tracking.Rider = &struct {
@@ -505,12 +494,165 @@ func (s *Service) TrackOrder(userID uint, orderID uint) (*TrackingInfo, error) {
 		}
 	}
 
-	// Build timeline
-	tracking.Timeline = s.buildTimeline(order)
+	// Build timeline from the event log; orders placed before the log
+	// existed fall back to the timestamp columns
+	events, err := s.repo.GetOrderEvents(orderID)
+	if err != nil {
+		s.logger.Warn("Failed to load order events", zap.Uint("order_id", orderID), zap.Error(err))
+	}
+	if len(events) > 0 {
+		tracking.Timeline = buildEventTimeline(events)
+	} else {
+		tracking.Timeline = s.buildTimeline(order)
+	}
 
 	return tracking, nil
 }
 
+// GetRoute returns the positions the rider reported while the order was
+// under way. Samples older than the retention period are gone.
+func (s *Service) GetRoute(userID uint, userRole string, orderID uint) (*RouteResponse, error) {
+	order, err := s.GetOrder(userID, userRole, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := s.repo.GetRouteSamples(orderID)
+	if err != nil {
+		s.logger.Error("Failed to load route", zap.Uint("order_id", orderID), zap.Error(err))
+		return nil, errors.New("failed to get route")
+	}
+
+	route := &RouteResponse{
+		OrderID:     order.ID,
+		Status:      order.Status,
+		RiderID:     order.AssignedRiderID,
+		Pickup:      RouteStop{Latitude: order.Vendor.Latitude, Longitude: order.Vendor.Longitude},
+		Dropoff:     RouteStop{Latitude: order.DeliveryLat, Longitude: order.DeliveryLng},
+		ReadyAt:     order.ReadyAt,
+		PickedUpAt:  order.PickedUpAt,
+		DeliveredAt: order.DeliveredAt,
+		Points:      make([]RoutePoint, 0, len(samples)),
+	}
+	for i, sample := range samples {
+		if i > 0 {
+			prev := samples[i-1]
+			route.DistanceKm += pkg.CalculateDistance(prev.Latitude, prev.Longitude, sample.Latitude, sample.Longitude)
+		}
+		route.Points = append(route.Points, RoutePoint{
+			Latitude:   sample.Latitude,
+			Longitude:  sample.Longitude,
+			Status:     sample.Status,
+			RecordedAt: sample.CreatedAt,
+		})
+	}
+	route.DistanceKm = math.Round(route.DistanceKm*100) / 100
+	return route, nil
+}
+
+// GetHandoffCode returns the code the user gives the rider: the student's
+// delivery code or the vendor's pickup code
+func (s *Service) GetHandoffCode(userID uint, userRole string, orderID uint) (*HandoffCodeResponse, error) {
+	order, err := s.repo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, errors.New("order not found")
+	}
+
+	var response *HandoffCodeResponse
+	switch {
+	case userRole == "student" && order.Student.UserID == userID:
+		response = &HandoffCodeResponse{
+			Stage:    database.HandoffDelivery,
+			Code:     order.DeliveryCode,
+			Required: s.cfg.DeliveryCodeRequired,
+		}
+	case userRole == "vendor" && order.Vendor.UserID == userID && order.ReleasedAt != nil:
+		response = &HandoffCodeResponse{
+			Stage:    database.HandoffPickup,
+			Code:     order.PickupCode,
+			Required: s.cfg.PickupCodeRequired,
+		}
+	default:
+		return nil, errors.New("order not found")
+	}
+
+	if response.Code == "" || IsTerminal(order.Status) {
+		return nil, errors.New("order has no handoff code")
+	}
+	return response, nil
+}
+
+// OverrideHandoff lets an admin confirm a pickup or delivery the rider could
+// not enter the code for. The reason is kept with the handoff proof.
+func (s *Service) OverrideHandoff(adminID uint, orderID uint, req *HandoffOverrideRequest) (*database.Order, error) {
+	to, err := HandoffStatus(req.Stage)
+	if err != nil {
+		return nil, err
+	}
+	return s.flow.Apply(TransitionRequest{
+		OrderID:   orderID,
+		To:        to,
+		ActorID:   adminID,
+		ActorRole: "admin",
+		Reason:    req.Reason,
+	})
+}
+
+// GetHandoffProofs returns how the order was collected and handed over
+func (s *Service) GetHandoffProofs(orderID uint) ([]database.HandoffProof, error) {
+	return s.repo.GetHandoffProofs(orderID)
+}
+
+// GetOrderEvents returns the full event log for an order the user may view.
+// Actors are shown by role and display name only: the vendor by business
+// name, admins as support and everyone else by first name.
+func (s *Service) GetOrderEvents(userID uint, userRole string, orderID uint) ([]OrderEvent, error) {
+	order, err := s.GetOrder(userID, userRole, orderID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.GetOrderEvents(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var actorIDs []uint
+	for _, ev := range events {
+		if ev.ActorID != nil {
+			actorIDs = append(actorIDs, *ev.ActorID)
+		}
+	}
+	names, err := s.repo.GetUserFirstNames(actorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]OrderEvent, 0, len(events))
+	for _, ev := range events {
+		view := OrderEvent{
+			ID:         ev.ID,
+			Type:       ev.Type,
+			FromStatus: ev.FromStatus,
+			ToStatus:   ev.ToStatus,
+			ActorRole:  ev.ActorRole,
+			Reason:     ev.Reason,
+			CreatedAt:  ev.CreatedAt,
+		}
+		switch ev.ActorRole {
+		case "vendor":
+			view.ActorName = order.Vendor.BusinessName
+		case "admin":
+			view.ActorName = "Support"
+		default:
+			if ev.ActorID != nil {
+				view.ActorName = names[*ev.ActorID]
+			}
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
 func (s *Service) RateOrder(studentID uint, orderID uint, req *RateOrderRequest) error {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
@@ -565,64 +707,6 @@ func (s *Service) RateOrder(studentID uint, orderID uint, req *RateOrderRequest)
 	return tx.Commit().Error
 }
 
-func (s *Service) validateStatusUpdate(order *database.Order, updaterID uint, role string, newStatus database.OrderStatus) error {
-	// Check authorization
-	switch role {
-	case "vendor":
-		if order.Vendor.UserID != updaterID {
-			return errors.New("unauthorized to update this order")
-		}
-		// Vendor allowed transitions
-		allowed := map[database.OrderStatus]bool{
-			database.OrderStatusConfirmed: true,
-			database.OrderStatusPreparing: true,
-			database.OrderStatusReady:     true,
-			database.OrderStatusRejected:  true,
-		}
-		if !allowed[newStatus] {
-			return errors.New("vendor cannot set this status")
-		}
-	case "rider":
-		if order.AssignedRiderID == nil || *order.AssignedRiderID != updaterID {
-			return errors.New("unauthorized to update this order")
-		}
-		// Rider allowed transitions
-		allowed := map[database.OrderStatus]bool{
-			database.OrderStatusPickedUp:  true,
-			database.OrderStatusDelivered: true,
-		}
-		if !allowed[newStatus] {
-			return errors.New("rider cannot set this status")
-		}
-	case "admin":
-		// Admin can set any status
-	default:
-		return errors.New("unauthorized role")
-	}
-
-	// Validate state transition
-	validTransitions := map[database.OrderStatus][]database.OrderStatus{
-		database.OrderStatusPending:   {database.OrderStatusConfirmed, database.OrderStatusRejected, database.OrderStatusCancelled},
-		database.OrderStatusConfirmed: {database.OrderStatusPreparing, database.OrderStatusCancelled},
-		database.OrderStatusPreparing: {database.OrderStatusReady, database.OrderStatusCancelled},
-		database.OrderStatusReady:     {database.OrderStatusPickedUp},
-		database.OrderStatusPickedUp:  {database.OrderStatusDelivered},
-	}
-
-	allowed, ok := validTransitions[order.Status]
-	if !ok {
-		return errors.New("invalid current status")
-	}
-
-	for _, s := range allowed {
-		if s == newStatus {
-			return nil
-		}
-	}
-
-	return errors.New("invalid status transition")
-}
-
 func (s *Service) buildTimeline(order *database.Order) []TrackingEvent {
 	var timeline []TrackingEvent
 
@@ -685,34 +769,42 @@ func (s *Service) buildTimeline(order *database.Order) []TrackingEvent {
 	return timeline
 }
 
-// Add this method to handle rider rejection
-
-func (s *Service) HandleRiderRejection(orderID uint, riderID uint) error {
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.AssignedRiderID == nil || *order.AssignedRiderID != riderID {
-		return errors.New("rider not assigned to this order")
-	}
-
-	// Free up rider
-	s.repo.UpdateRiderAvailability(riderID, true)
+var timelineNotes = map[database.OrderStatus]string{
+	database.OrderStatusPending:   "Order placed",
+	database.OrderStatusConfirmed: "Order confirmed by vendor",
+	database.OrderStatusPreparing: "Food is being prepared",
+	database.OrderStatusReady:     "Order ready for pickup",
+	database.OrderStatusPickedUp:  "Order picked up by rider",
+	database.OrderStatusDelivered: "Order delivered",
+	database.OrderStatusCancelled: "Order cancelled",
+	database.OrderStatusRejected:  "Order rejected by vendor",
+}
 
-	// Track rejection count (you might want to add this to Order model)
-	// For now, try to assign to next rider
-	order.AssignedRiderID = nil
-	if err := s.repo.UpdateOrderStatus(orderID, order.Status, map[string]interface{}{"assigned_rider_id": nil}); err != nil {
-		return err
-	}
+func buildEventTimeline(events []database.OrderEvent) []TrackingEvent {
+	timeline := make([]TrackingEvent, 0, len(events))
+	for _, ev := range events {
+		note := ev.Reason
+		switch ev.Type {
+		case database.OrderEventRiderAssigned:
+			note = "Rider assigned"
+		case database.OrderEventRiderUnassigned:
+			note = "Rider unassigned"
+			if ev.Reason != "" {
+				note += ": " + ev.Reason
+			}
+		default:
+			if note == "" {
+				note = timelineNotes[ev.ToStatus]
+			}
+		}
 
-	// Try to assign to next available rider
-	if err := s.autoAssignRider(order); err != nil {
-		// If second attempt fails, notify admin
-		s.notifier.NotifyAdmin("Order Needs Manual Assignment",
-			fmt.Sprintf("Order #%s has been rejected by riders. Manual assignment required.", order.OrderNumber))
+		timeline = append(timeline, TrackingEvent{
+			Status:    ev.ToStatus,
+			Event:     ev.Type,
+			Actor:     ev.ActorRole,
+			Timestamp: ev.CreatedAt,
+			Note:      note,
+		})
 	}
-
-	return nil
+	return timeline
 }
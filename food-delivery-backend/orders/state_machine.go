@@ -0,0 +1,707 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food-delivery-backend/cash"
+	"food-delivery-backend/config"
+	"food-delivery-backend/coupons"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/payments"
+	"food-delivery-backend/redis"
+	"food-delivery-backend/refunds"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ActorSystem is the role recorded for transitions made by background jobs
+const ActorSystem = "system"
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrUnauthorizedActor = errors.New("unauthorized to update this order")
+	ErrStatusChanged     = errors.New("order status changed, please retry")
+)
+
+// transitionRule declares a permitted status change and the roles allowed to make it
+type transitionRule struct {
+	from  database.OrderStatus
+	to    database.OrderStatus
+	roles []string
+}
+
+var transitionRules = []transitionRule{
+	{database.OrderStatusPending, database.OrderStatusConfirmed, []string{"vendor", "admin"}},
+	{database.OrderStatusPending, database.OrderStatusRejected, []string{"vendor", "admin", ActorSystem}},
+	{database.OrderStatusPending, database.OrderStatusCancelled, []string{"student", "vendor", "admin", ActorSystem}},
+	{database.OrderStatusConfirmed, database.OrderStatusPreparing, []string{"vendor", "admin"}},
+	{database.OrderStatusConfirmed, database.OrderStatusCancelled, []string{"student", "vendor", "admin", ActorSystem}},
+	{database.OrderStatusPreparing, database.OrderStatusReady, []string{"vendor", "admin"}},
+	{database.OrderStatusPreparing, database.OrderStatusCancelled, []string{"vendor", "admin"}},
+	{database.OrderStatusReady, database.OrderStatusPickedUp, []string{"rider", "admin"}},
+	{database.OrderStatusPickedUp, database.OrderStatusDelivered, []string{"rider", "admin"}},
+}
+
+// statusTimestamps maps each status to the order column stamped on entry
+var statusTimestamps = map[database.OrderStatus]string{
+	database.OrderStatusConfirmed: "confirmed_at",
+	database.OrderStatusPreparing: "prepared_at",
+	database.OrderStatusReady:     "ready_at",
+	database.OrderStatusPickedUp:  "picked_up_at",
+	database.OrderStatusDelivered: "delivered_at",
+	database.OrderStatusCancelled: "cancelled_at",
+	database.OrderStatusRejected:  "cancelled_at",
+}
+
+// IsTerminal reports whether no further transitions are possible from status
+func IsTerminal(status database.OrderStatus) bool {
+	return status == database.OrderStatusDelivered ||
+		status == database.OrderStatusCancelled ||
+		status == database.OrderStatusRejected
+}
+
+// CanTransition reports whether role may move an order from one status to another
+func CanTransition(from, to database.OrderStatus, role string) bool {
+	rule := findRule(from, to)
+	if rule == nil {
+		return false
+	}
+	for _, r := range rule.roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func findRule(from, to database.OrderStatus) *transitionRule {
+	for i := range transitionRules {
+		if transitionRules[i].from == from && transitionRules[i].to == to {
+			return &transitionRules[i]
+		}
+	}
+	return nil
+}
+
+// TransitionRequest asks the state machine to move an order to a new status.
+// ActorID is the authenticated user id, or 0 for system actions. When From is
+// set the transition only happens if the order is still in that status.
+type TransitionRequest struct {
+	OrderID   uint
+	From      database.OrderStatus
+	To        database.OrderStatus
+	ActorID   uint
+	ActorRole string
+	Reason    string
+	Handoff   *Handoff // proof submitted by the rider at pickup or delivery
+	// Unreleased only applies the change while the order is still awaiting
+	// payment authorization, failing with ErrStatusChanged otherwise
+	Unreleased bool
+}
+
+// Transition describes a status change that has been validated and applied.
+// Order is loaded with its Vendor, Student and AssignedRider relations.
+type Transition struct {
+	Order     *database.Order
+	From      database.OrderStatus
+	To        database.OrderStatus
+	ActorID   *uint
+	ActorRole string
+	Reason    string
+	At        time.Time
+}
+
+// TransitionHook is a side effect of entering a status. InTx runs inside the
+// transition's transaction and can abort it; AfterCommit runs once the change
+// is durable and must not fail the transition.
+type TransitionHook struct {
+	Name        string
+	InTx        func(tx *gorm.DB, t *Transition) error
+	AfterCommit func(t *Transition)
+}
+
+// StateMachine is the only place order statuses change. Vendors, riders,
+// admin and background jobs all go through Apply so that every path to a
+// status has the same checks and side effects.
+type StateMachine struct {
+	db          *gorm.DB
+	notifier    *notifications.Service
+	payments    *payments.Service
+	refunds     *refunds.Service
+	redisClient *redis.RedisClient
+	eta         *ETAEstimator
+	cfg         *config.Config
+	logger      *zap.Logger
+
+	hooks    map[database.OrderStatus][]TransitionHook
+	anyHooks []TransitionHook
+}
+
+func NewStateMachine(db *gorm.DB, notifier *notifications.Service, paymentService *payments.Service, refundService *refunds.Service, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *StateMachine {
+	m := &StateMachine{
+		db:          db,
+		notifier:    notifier,
+		payments:    paymentService,
+		refunds:     refundService,
+		redisClient: redisClient,
+		eta:         NewETAEstimator(db, redisClient, logger),
+		cfg:         cfg,
+		logger:      logger,
+		hooks:       make(map[database.OrderStatus][]TransitionHook),
+	}
+	m.registerDefaultHooks()
+	return m
+}
+
+// OnEnter registers a hook that runs whenever an order enters one of the
+// given statuses, or on every transition when no status is given. Hooks run
+// in registration order.
+func (m *StateMachine) OnEnter(hook TransitionHook, statuses ...database.OrderStatus) {
+	if len(statuses) == 0 {
+		m.anyHooks = append(m.anyHooks, hook)
+		return
+	}
+	for _, st := range statuses {
+		m.hooks[st] = append(m.hooks[st], hook)
+	}
+}
+
+func (m *StateMachine) hooksFor(status database.OrderStatus) []TransitionHook {
+	hooks := append([]TransitionHook{}, m.hooks[status]...)
+	return append(hooks, m.anyHooks...)
+}
+
+// Apply validates and performs a status change, records it in the order's
+// event log and runs the hooks registered for the new status.
+func (m *StateMachine) Apply(req TransitionRequest) (*database.Order, error) {
+	var t *Transition
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, req.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkActor(order, req.ActorID, req.ActorRole); err != nil {
+			return err
+		}
+		if req.From != "" && order.Status != req.From {
+			return ErrStatusChanged
+		}
+		if req.Unreleased && order.ReleasedAt != nil {
+			return ErrStatusChanged
+		}
+		if findRule(order.Status, req.To) == nil {
+			return fmt.Errorf("cannot move order from %s to %s", order.Status, req.To)
+		}
+		if !CanTransition(order.Status, req.To, req.ActorRole) {
+			return fmt.Errorf("%s cannot set status %s", req.ActorRole, req.To)
+		}
+		if req.To == database.OrderStatusPickedUp && order.AssignedRiderID == nil {
+			return errors.New("order has no assigned rider")
+		}
+		proof, err := m.verifyHandoff(order, req)
+		if err != nil {
+			return err
+		}
+
+		t = &Transition{
+			Order:     order,
+			From:      order.Status,
+			To:        req.To,
+			ActorRole: req.ActorRole,
+			Reason:    req.Reason,
+			At:        time.Now(),
+		}
+		if req.ActorID != 0 {
+			actorID := req.ActorID
+			t.ActorID = &actorID
+		}
+
+		updates := map[string]interface{}{"status": req.To}
+		if col, ok := statusTimestamps[req.To]; ok {
+			updates[col] = t.At
+		}
+		if req.To == database.OrderStatusCancelled || req.To == database.OrderStatusRejected {
+			updates["cancellation_reason"] = req.Reason
+		}
+
+		res := tx.Model(&database.Order{}).
+			Where("id = ? AND status = ?", order.ID, t.From).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrStatusChanged
+		}
+
+		event := &database.OrderEvent{
+			OrderID:    order.ID,
+			Type:       database.OrderEventStatusChanged,
+			FromStatus: t.From,
+			ToStatus:   t.To,
+			ActorID:    t.ActorID,
+			ActorRole:  t.ActorRole,
+			RiderID:    order.AssignedRiderID,
+			Reason:     t.Reason,
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		if proof != nil {
+			if err := tx.Create(proof).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, hook := range m.hooksFor(t.To) {
+			if hook.InTx == nil {
+				continue
+			}
+			if err := hook.InTx(tx, t); err != nil {
+				m.logger.Error("Transition hook failed",
+					zap.String("hook", hook.Name),
+					zap.Uint("order_id", order.ID),
+					zap.String("to", string(t.To)),
+					zap.Error(err))
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t.Order.Status = t.To
+	for _, hook := range m.hooksFor(t.To) {
+		if hook.AfterCommit != nil {
+			hook.AfterCommit(t)
+		}
+	}
+
+	return t.Order, nil
+}
+
+// Assignment attaches a rider to an order. OnlyIfUnassigned makes the
+// assignment fail instead of replacing a rider that is already attached.
+type Assignment struct {
+	OrderID          uint
+	RiderID          uint
+	ActorID          uint
+	ActorRole        string
+	Reason           string
+	OnlyIfUnassigned bool
+}
+
+// AssignRider attaches a rider to an order, adding it to the rider's delivery
+// batch and releasing any previous rider whose batch that empties, and records
+// the change in the order's event log.
+func (m *StateMachine) AssignRider(a Assignment) (*database.Order, error) {
+	var order *database.Order
+	var previous *database.Rider
+	previousFreed := false
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = lockOrder(tx, a.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if IsTerminal(order.Status) || order.Status == database.OrderStatusPickedUp {
+			return errors.New("order cannot be assigned in current status")
+		}
+		if order.AssignedRiderID != nil {
+			if a.OnlyIfUnassigned {
+				return errors.New("order already assigned")
+			}
+			if *order.AssignedRiderID == a.RiderID {
+				return errors.New("rider already assigned to this order")
+			}
+			previous = order.AssignedRider
+		}
+
+		var rider database.Rider
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rider, a.RiderID).Error; err != nil {
+			return errors.New("rider not found")
+		}
+		if cash.OverLimit(&rider, m.cfg) {
+			return cash.ErrLimitExceeded
+		}
+
+		// Admins may put any orders together; riders only ones going the
+		// same way
+		previousBatchID := order.BatchID
+		if err := joinBatch(tx, order, a.RiderID, a.ActorRole == "admin", m.cfg); err != nil {
+			return err
+		}
+		if err := tx.Model(&database.Order{}).Where("id = ?", order.ID).
+			Update("assigned_rider_id", a.RiderID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&database.Rider{}).Where("id = ?", a.RiderID).
+			Update("is_available", false).Error; err != nil {
+			return err
+		}
+		if previous != nil {
+			previousFreed = true
+			if previousBatchID != nil {
+				if previousFreed, err = settleBatch(tx, *previousBatchID, time.Now()); err != nil {
+					return err
+				}
+			}
+			if previousFreed {
+				if err := freeRider(tx, previous.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		riderID := a.RiderID
+		event := &database.OrderEvent{
+			OrderID:    order.ID,
+			Type:       database.OrderEventRiderAssigned,
+			FromStatus: order.Status,
+			ToStatus:   order.Status,
+			ActorRole:  a.ActorRole,
+			RiderID:    &riderID,
+			Reason:     a.Reason,
+		}
+		if a.ActorID != 0 {
+			actorID := a.ActorID
+			event.ActorID = &actorID
+		}
+		if previous != nil && event.Reason == "" {
+			event.Reason = fmt.Sprintf("reassigned from rider #%d", previous.ID)
+		}
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	m.redisClient.SetRiderUnavailable(ctx, a.RiderID)
+	if previous != nil && previousFreed {
+		m.redisClient.SetRiderAvailable(ctx, previous.ID, previous.CurrentLatitude, previous.CurrentLongitude)
+	}
+
+	// Reload so notifications see the new rider
+	var updated database.Order
+	if err := loadOrder(m.db, a.OrderID, &updated); err != nil {
+		return order, nil
+	}
+	m.notifier.NotifyRiderAssigned(&updated, a.RiderID)
+	m.eta.Refresh(updated.ID, m.notifier)
+	m.notifier.NotifyAdmin("Rider Assigned",
+		fmt.Sprintf("Rider #%d was assigned to order #%s", a.RiderID, updated.OrderNumber))
+
+	return &updated, nil
+}
+
+// UnassignRider detaches the current rider from an order that has not been
+// picked up yet and takes it out of the rider's batch. The rider is available
+// again once their batch has nothing else in it.
+func (m *StateMachine) UnassignRider(orderID uint, actorID uint, actorRole, reason string) (*database.Order, error) {
+	var order *database.Order
+	freed := false
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.AssignedRiderID == nil {
+			return errors.New("order has no assigned rider")
+		}
+		if IsTerminal(order.Status) || order.Status == database.OrderStatusPickedUp {
+			return errors.New("rider cannot be unassigned in current status")
+		}
+
+		if err := tx.Model(&database.Order{}).Where("id = ?", order.ID).
+			Update("assigned_rider_id", nil).Error; err != nil {
+			return err
+		}
+		if freed, err = leaveBatch(tx, order, time.Now()); err != nil {
+			return err
+		}
+		if freed {
+			if err := freeRider(tx, *order.AssignedRiderID); err != nil {
+				return err
+			}
+		}
+
+		event := &database.OrderEvent{
+			OrderID:    order.ID,
+			Type:       database.OrderEventRiderUnassigned,
+			FromStatus: order.Status,
+			ToStatus:   order.Status,
+			ActorRole:  actorRole,
+			RiderID:    order.AssignedRiderID,
+			Reason:     reason,
+		}
+		if actorID != 0 {
+			event.ActorID = &actorID
+		}
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rider := order.AssignedRider; rider != nil && freed {
+		m.redisClient.SetRiderAvailable(context.Background(), rider.ID, rider.CurrentLatitude, rider.CurrentLongitude)
+	}
+	order.AssignedRiderID = nil
+	order.AssignedRider = nil
+
+	return order, nil
+}
+
+// lockOrder takes a row lock on the order for the rest of tx and loads it
+// with the relations the guards and hooks need.
+func lockOrder(tx *gorm.DB, orderID uint) (*database.Order, error) {
+	var locked database.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").First(&locked, orderID).Error; err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	var order database.Order
+	if err := loadOrder(tx, orderID, &order); err != nil {
+		return nil, ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func loadOrder(db *gorm.DB, orderID uint, order *database.Order) error {
+	return db.Preload("Vendor.User").
+		Preload("AssignedRider.User").
+		Preload("Student.User").
+		Preload("Payment").
+		First(order, orderID).Error
+}
+
+// checkActor verifies that the actor is a party to the order
+func checkActor(order *database.Order, actorID uint, role string) error {
+	switch role {
+	case "student":
+		if order.Student.UserID != actorID {
+			return ErrUnauthorizedActor
+		}
+	case "vendor":
+		if order.Vendor.UserID != actorID {
+			return ErrUnauthorizedActor
+		}
+		// Vendors can't act on orders still awaiting payment authorization
+		if order.ReleasedAt == nil {
+			return ErrOrderNotFound
+		}
+	case "rider":
+		if order.AssignedRider == nil || order.AssignedRider.UserID != actorID {
+			return ErrUnauthorizedActor
+		}
+	case "admin", ActorSystem:
+		// Admin and background jobs can act on any order
+	default:
+		return errors.New("unauthorized role")
+	}
+	return nil
+}
+
+func (m *StateMachine) registerDefaultHooks() {
+	// Card holds are collected when the vendor commits to the order
+	m.OnEnter(TransitionHook{
+		Name: "capture_payment",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			if t.Order.Payment == nil {
+				return nil
+			}
+			return m.payments.Capture(tx, t.Order.Payment)
+		},
+	}, database.OrderStatusConfirmed)
+
+	m.OnEnter(TransitionHook{
+		Name: "delivery_stats",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			order := t.Order
+			if err := tx.Model(&database.Vendor{}).Where("id = ?", order.VendorID).
+				Updates(map[string]interface{}{
+					"total_orders":   gorm.Expr("total_orders + ?", 1),
+					"total_revenue":  gorm.Expr("total_revenue + ?", order.Subtotal),
+					"total_earnings": gorm.Expr("total_earnings + ?", order.VendorEarnings),
+				}).Error; err != nil {
+				return err
+			}
+
+			// order.StudentID is Student.ID
+			if err := tx.Model(&database.Student{}).Where("id = ?", order.StudentID).
+				Updates(map[string]interface{}{
+					"total_orders": gorm.Expr("total_orders + ?", 1),
+					"total_spent":  gorm.Expr("total_spent + ?", order.TotalAmount),
+				}).Error; err != nil {
+				return err
+			}
+
+			if order.AssignedRiderID == nil {
+				return nil
+			}
+			return tx.Model(&database.Rider{}).Where("id = ?", *order.AssignedRiderID).
+				Updates(map[string]interface{}{
+					"total_deliveries": gorm.Expr("total_deliveries + ?", 1),
+					"total_earnings":   gorm.Expr("total_earnings + ?", order.RiderEarnings),
+				}).Error
+		},
+	}, database.OrderStatusDelivered)
+
+	// Balances are only credited through the ledger
+	m.OnEnter(TransitionHook{
+		Name: "ledger",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			_, err := ledger.PostOrderDelivered(tx, t.Order)
+			return err
+		},
+	}, database.OrderStatusDelivered)
+
+	// Cash orders are paid on delivery; the rider holds the cash until it is
+	// handed over
+	m.OnEnter(TransitionHook{
+		Name: "cash_collected",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			payment := t.Order.Payment
+			if payment == nil || payment.PaymentMethod != string(database.PaymentMethodCash) {
+				return nil
+			}
+			if rider := t.Order.AssignedRider; rider != nil {
+				if _, err := ledger.PostCashCollected(tx, t.Order); err != nil {
+					return err
+				}
+				rider.CashInHand += t.Order.TotalAmount
+			}
+			payment.PaymentStatus = string(database.PaymentStatusCompleted)
+			payment.PaidAt = &t.At
+			return tx.Model(&database.Payment{}).Where("id = ?", payment.ID).
+				Updates(map[string]interface{}{
+					"payment_status": payment.PaymentStatus,
+					"paid_at":        payment.PaidAt,
+				}).Error
+		},
+		AfterCommit: func(t *Transition) {
+			rider := t.Order.AssignedRider
+			if rider == nil || t.Order.Payment == nil ||
+				t.Order.Payment.PaymentMethod != string(database.PaymentMethodCash) || !cash.OverLimit(rider, m.cfg) {
+				return
+			}
+			m.notifier.NotifyRider(rider.UserID, "Cash Limit Reached",
+				fmt.Sprintf("You are holding %s in cash. Hand it over to receive new orders.", rider.CashInHand),
+				"cash_limit", fmt.Sprintf("%d", rider.ID))
+		},
+	}, database.OrderStatusDelivered)
+
+	// A rider carrying a batch stays busy until the last of its orders is
+	// done
+	m.OnEnter(TransitionHook{
+		Name: "release_rider",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			if t.Order.AssignedRiderID == nil {
+				return nil
+			}
+			if t.Order.BatchID != nil {
+				done, err := settleBatch(tx, *t.Order.BatchID, t.At)
+				if err != nil || !done {
+					return err
+				}
+			}
+			return freeRider(tx, *t.Order.AssignedRiderID)
+		},
+		AfterCommit: func(t *Transition) {
+			rider := t.Order.AssignedRider
+			if rider == nil {
+				return
+			}
+			var available bool
+			if err := m.db.Model(&database.Rider{}).Select("is_available").
+				Where("id = ?", rider.ID).Scan(&available).Error; err != nil || !available {
+				return
+			}
+			m.redisClient.SetRiderAvailable(context.Background(), rider.ID, rider.CurrentLatitude, rider.CurrentLongitude)
+		},
+	}, database.OrderStatusDelivered, database.OrderStatusCancelled, database.OrderStatusRejected)
+
+	m.OnEnter(TransitionHook{
+		Name: "release_coupon",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			if t.Order.CouponID == nil {
+				return nil
+			}
+			return coupons.ReleaseForOrder(tx, t.Order.ID)
+		},
+	}, database.OrderStatusCancelled, database.OrderStatusRejected)
+
+	// Whatever the student paid goes back: wallet payments at once, card
+	// payments through the provider once the cancellation has committed
+	m.OnEnter(TransitionHook{
+		Name: "refund",
+		InTx: func(tx *gorm.DB, t *Transition) error {
+			return m.refunds.RefundCancelled(tx, t.Order, t.To, t.Reason)
+		},
+		AfterCommit: func(t *Transition) {
+			m.refunds.SettleCancelled(t.Order)
+		},
+	}, database.OrderStatusCancelled, database.OrderStatusRejected)
+
+	// Each ready order is another sample of how long the vendor takes
+	m.OnEnter(TransitionHook{
+		Name: "learn_prep_time",
+		AfterCommit: func(t *Transition) {
+			m.eta.LearnPrepTime(t.Order.VendorID)
+		},
+	}, database.OrderStatusReady)
+
+	m.OnEnter(TransitionHook{
+		Name: "active_order_cache",
+		AfterCommit: func(t *Transition) {
+			ctx := context.Background()
+			if IsTerminal(t.To) {
+				m.redisClient.RemoveActiveOrder(ctx, t.Order.ID)
+				return
+			}
+			data, err := json.Marshal(t.Order)
+			if err != nil {
+				m.logger.Warn("Failed to marshal order for cache", zap.Uint("order_id", t.Order.ID), zap.Error(err))
+				return
+			}
+			m.redisClient.CacheActiveOrder(ctx, t.Order.ID, data, 30*time.Minute)
+		},
+	})
+
+	m.OnEnter(TransitionHook{
+		Name: "eta",
+		AfterCommit: func(t *Transition) {
+			if !IsTerminal(t.To) {
+				m.eta.Refresh(t.Order.ID, m.notifier)
+			}
+		},
+	})
+
+	m.OnEnter(TransitionHook{
+		Name: "notify",
+		AfterCommit: func(t *Transition) {
+			m.notifier.NotifyOrderUpdate(t.Order, t.To, t.Reason)
+			if t.To == database.OrderStatusConfirmed {
+				m.notifier.NotifyAdmin("Order Accepted",
+					fmt.Sprintf("Order #%s was accepted by the vendor", t.Order.OrderNumber))
+			}
+		},
+	})
+}
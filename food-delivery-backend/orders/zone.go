@@ -0,0 +1,53 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+)
+
+// Error codes returned to clients alongside zone errors
+const (
+	CodeDeliveryLocationRequired = "DELIVERY_LOCATION_REQUIRED"
+	CodeOutsideDeliveryZone      = "OUTSIDE_DELIVERY_ZONE"
+)
+
+var (
+	ErrDeliveryLocationRequired = errors.New("delivery location is required")
+	ErrOutsideDeliveryZone      = errors.New("delivery location is outside the vendor's delivery zone")
+)
+
+// HasLocation reports whether the vendor's coordinates have been set
+func HasLocation(vendor *database.Vendor) bool {
+	return vendor.Latitude != 0 || vendor.Longitude != 0
+}
+
+// DistanceToVendor returns the distance in km between the vendor and a point
+func DistanceToVendor(vendor *database.Vendor, lat, lng float64) float64 {
+	return pkg.CalculateDistance(vendor.Latitude, vendor.Longitude, lat, lng)
+}
+
+// Delivers reports whether the point lies within the vendor's delivery radius.
+// Vendors without coordinates or a radius are not restricted.
+func Delivers(vendor *database.Vendor, lat, lng float64) bool {
+	if !HasLocation(vendor) || vendor.DeliveryRadius <= 0 {
+		return true
+	}
+	return DistanceToVendor(vendor, lat, lng) <= vendor.DeliveryRadius
+}
+
+// checkDeliveryZone rejects orders the vendor cannot deliver to
+func checkDeliveryZone(vendor *database.Vendor, lat, lng float64) error {
+	if !HasLocation(vendor) || vendor.DeliveryRadius <= 0 {
+		return nil
+	}
+	if lat == 0 && lng == 0 {
+		return ErrDeliveryLocationRequired
+	}
+	if distance := DistanceToVendor(vendor, lat, lng); distance > vendor.DeliveryRadius {
+		return fmt.Errorf("%w: %.1f km away, %s delivers within %.1f km",
+			ErrOutsideDeliveryZone, distance, vendor.BusinessName, vendor.DeliveryRadius)
+	}
+	return nil
+}
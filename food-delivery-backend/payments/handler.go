@@ -0,0 +1,60 @@
+package payments
+
+import (
+	"errors"
+	"food-delivery-backend/pkg"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxWebhookBody bounds the webhook payload we are willing to read
+const maxWebhookBody = 64 << 10
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Webhook receives payment status updates from the provider
+// @Summary Payment provider webhook
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Success 200 {object} pkg.Response
+// @Failure 401 {object} pkg.Response "Invalid signature"
+// @Router /payments/webhook [post]
+func (h *Handler) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	if err := h.service.HandleWebhook(c.Request.Header, body); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidSignature):
+			h.logger.Warn("Rejected payment webhook with invalid signature", zap.String("ip", c.ClientIP()))
+			pkg.SendError(c, http.StatusUnauthorized, "Invalid signature", nil)
+		case errors.Is(err, ErrInvalidPayload):
+			pkg.SendError(c, http.StatusBadRequest, "Invalid webhook payload", err.Error())
+		case errors.Is(err, ErrUnknownIntent):
+			pkg.SendError(c, http.StatusNotFound, "Payment not found", err.Error())
+		default:
+			// Non-2xx makes the provider retry later
+			pkg.SendError(c, http.StatusInternalServerError, "Failed to process webhook", err.Error())
+		}
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Webhook processed", nil)
+}
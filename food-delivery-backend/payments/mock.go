@@ -0,0 +1,147 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"sync"
+)
+
+// MockSignatureHeader carries the hex HMAC-SHA256 of the webhook body
+const MockSignatureHeader = "X-Mock-Signature"
+
+// Card tokens with special behaviour in the mock provider. Any other token
+// is authorized immediately.
+const (
+	MockTokenDecline = "tok_decline"
+	MockTokenAsync   = "tok_async" // stays pending until a payment.authorized webhook arrives
+)
+
+// MockProvider is a deterministic in-process gateway for local development.
+// Intent ids are derived from the reference, so the same order always gets
+// the same intent. Intents the mock has not seen (for example after a
+// restart) are treated as authorized.
+type MockProvider struct {
+	secret []byte
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+}
+
+func NewMockProvider(secret string) *MockProvider {
+	return &MockProvider{
+		secret:  []byte(secret),
+		intents: make(map[string]*Intent),
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	sum := sha256.Sum256([]byte(req.Reference))
+	id := "mock_pi_" + hex.EncodeToString(sum[:12])
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if intent, ok := p.intents[id]; ok {
+		snapshot := *intent
+		return &snapshot, nil
+	}
+
+	intent := &Intent{ID: id, Amount: req.Amount, Status: IntentAuthorized}
+	switch req.Token {
+	case MockTokenDecline:
+		intent.Status = IntentFailed
+		intent.FailureReason = "card declined"
+	case MockTokenAsync:
+		intent.Status = IntentPending
+	}
+	p.intents[id] = intent
+
+	snapshot := *intent
+	return &snapshot, nil
+}
+
+func (p *MockProvider) Capture(ctx context.Context, intentID string, amount pkg.Money) (*Intent, error) {
+	return p.move(intentID, amount, IntentCaptured, IntentAuthorized, IntentCaptured)
+}
+
+func (p *MockProvider) Refund(ctx context.Context, intentID string, amount pkg.Money) (*Intent, error) {
+	return p.move(intentID, amount, IntentRefunded, IntentAuthorized, IntentCaptured, IntentRefunded)
+}
+
+// move sets an intent's status if it is currently in one of from
+func (p *MockProvider) move(intentID string, amount pkg.Money, to IntentStatus, from ...IntentStatus) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		intent = &Intent{ID: intentID, Amount: amount, Status: IntentAuthorized}
+		p.intents[intentID] = intent
+	}
+
+	allowed := false
+	for _, st := range from {
+		if intent.Status == st {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("cannot move intent %s from %s to %s", intentID, intent.Status, to)
+	}
+
+	intent.Status = to
+	snapshot := *intent
+	return &snapshot, nil
+}
+
+func (p *MockProvider) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	got, err := hex.DecodeString(header.Get(MockSignatureHeader))
+	if err != nil || !hmac.Equal(got, p.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if event.ID == "" || event.IntentID == "" {
+		return nil, fmt.Errorf("%w: id and intent_id are required", ErrInvalidPayload)
+	}
+
+	// Keep the mock's own view in step so later captures behave
+	p.mu.Lock()
+	if intent, ok := p.intents[event.IntentID]; ok {
+		switch event.Type {
+		case EventAuthorized:
+			intent.Status = IntentAuthorized
+		case EventFailed:
+			intent.Status = IntentFailed
+		}
+	}
+	p.mu.Unlock()
+
+	return &event, nil
+}
+
+// Sign returns the signature header value for a webhook body, for local
+// tools that simulate the provider
+func (p *MockProvider) Sign(body []byte) string {
+	return hex.EncodeToString(p.sign(body))
+}
+
+func (p *MockProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
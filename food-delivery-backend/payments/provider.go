@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/pkg"
+	"net/http"
+)
+
+var (
+	ErrPaymentDeclined  = errors.New("payment was declined")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidPayload   = errors.New("invalid webhook payload")
+	ErrUnknownIntent    = errors.New("unknown payment intent")
+)
+
+// CodePaymentDeclined is the API error code for card payments the provider refused
+const CodePaymentDeclined = "PAYMENT_DECLINED"
+
+// IntentStatus is the provider's view of a payment intent
+type IntentStatus string
+
+const (
+	IntentPending    IntentStatus = "pending" // waiting on the customer or the bank
+	IntentAuthorized IntentStatus = "authorized"
+	IntentCaptured   IntentStatus = "captured"
+	IntentFailed     IntentStatus = "failed"
+	IntentRefunded   IntentStatus = "refunded"
+)
+
+// Webhook event types
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventRefunded   = "payment.refunded"
+)
+
+// IntentRequest asks the provider to hold Amount on the customer's card.
+// Reference is our idempotency key, so retrying with the same reference
+// returns the same intent.
+type IntentRequest struct {
+	Reference string
+	Amount    pkg.Money
+	Token     string // card token from the client
+}
+
+type Intent struct {
+	ID            string
+	Status        IntentStatus
+	Amount        pkg.Money
+	FailureReason string
+}
+
+// WebhookEvent is a verified notification from the provider
+type WebhookEvent struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	IntentID      string    `json:"intent_id"`
+	Amount        pkg.Money `json:"amount"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+}
+
+// PaymentProvider is a card payment gateway. Intents are authorized first
+// and captured once the vendor accepts the order; refunding an intent that
+// was never captured releases the hold.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	Capture(ctx context.Context, intentID string, amount pkg.Money) (*Intent, error)
+	Refund(ctx context.Context, intentID string, amount pkg.Money) (*Intent, error)
+	// VerifyWebhook checks the signature of a webhook request and parses it
+	VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// NewProvider returns the provider selected by PAYMENT_PROVIDER
+func NewProvider(cfg *config.Config) (PaymentProvider, error) {
+	switch cfg.PaymentProvider {
+	case "", "mock":
+		return NewMockProvider(cfg.PaymentWebhookSecret), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", cfg.PaymentProvider)
+}
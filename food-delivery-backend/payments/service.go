@@ -0,0 +1,296 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNotAuthorized = errors.New("payment has not been authorized")
+
+// IntentHandler applies webhooks for intents that are not order payments,
+// such as wallet top-ups. Apply runs inside the webhook's transaction and
+// reports whether the intent was its own; Applied runs after commit.
+type IntentHandler struct {
+	Name    string
+	Apply   func(tx *gorm.DB, event *WebhookEvent) (bool, error)
+	Applied func(event *WebhookEvent)
+}
+
+type Service struct {
+	provider PaymentProvider
+	db       *gorm.DB
+	notifier *notifications.Service
+	logger   *zap.Logger
+
+	intentHandlers []IntentHandler
+}
+
+func NewService(provider PaymentProvider, db *gorm.DB, notifier *notifications.Service, logger *zap.Logger) *Service {
+	return &Service{
+		provider: provider,
+		db:       db,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// HandleIntents registers a handler for webhooks about intents that do not
+// belong to an order payment
+func (s *Service) HandleIntents(h IntentHandler) {
+	s.intentHandlers = append(s.intentHandlers, h)
+}
+
+// ProviderName is recorded on card payments
+func (s *Service) ProviderName() string {
+	return s.provider.Name()
+}
+
+// Authorize creates an intent holding amount on the customer's card. A
+// declined card returns ErrPaymentDeclined; an intent that is still pending
+// is authorized later by webhook.
+func (s *Service) Authorize(reference string, amount pkg.Money, token string) (*Intent, error) {
+	intent, err := s.provider.CreateIntent(context.Background(), IntentRequest{
+		Reference: reference,
+		Amount:    amount,
+		Token:     token,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create payment intent", zap.String("reference", reference), zap.Error(err))
+		return nil, errors.New("payment provider unavailable, please try again")
+	}
+	if intent.Status == IntentFailed {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentDeclined, intent.FailureReason)
+	}
+	return intent, nil
+}
+
+// CaptureIntent collects an authorized intent that has no order payment
+func (s *Service) CaptureIntent(intent *Intent) (*Intent, error) {
+	captured, err := s.provider.Capture(context.Background(), intent.ID, intent.Amount)
+	if err != nil {
+		s.logger.Error("Failed to capture payment intent", zap.String("intent_id", intent.ID), zap.Error(err))
+		return nil, errors.New("failed to capture payment")
+	}
+	return captured, nil
+}
+
+// Void releases or refunds an intent whose purchase could not be completed
+func (s *Service) Void(intent *Intent) {
+	if intent.Status != IntentAuthorized && intent.Status != IntentCaptured {
+		return
+	}
+	if _, err := s.provider.Refund(context.Background(), intent.ID, intent.Amount); err != nil {
+		s.logger.Error("Failed to void payment intent", zap.String("intent_id", intent.ID), zap.Error(err))
+	}
+}
+
+// Capture collects an authorized card payment inside tx. Payments that are
+// not by card, or are already captured, are left alone.
+func (s *Service) Capture(tx *gorm.DB, payment *database.Payment) error {
+	if payment.PaymentMethod != string(database.PaymentMethodCard) ||
+		payment.PaymentStatus == string(database.PaymentStatusCompleted) {
+		return nil
+	}
+	if payment.PaymentStatus != string(database.PaymentStatusAuthorized) {
+		return ErrNotAuthorized
+	}
+
+	if _, err := s.provider.Capture(context.Background(), payment.TransactionID, payment.Amount); err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	now := time.Now()
+	payment.PaymentStatus = string(database.PaymentStatusCompleted)
+	payment.PaidAt = &now
+	return tx.Model(&database.Payment{}).
+		Where("id = ? AND payment_status = ?", payment.ID, database.PaymentStatusAuthorized).
+		Updates(map[string]interface{}{
+			"payment_status": database.PaymentStatusCompleted,
+			"paid_at":        now,
+		}).Error
+}
+
+// Refund returns amount of a card payment to the customer through the
+// provider. Refunding a payment that was only authorized releases the hold.
+func (s *Service) Refund(payment *database.Payment, amount pkg.Money) error {
+	if payment.PaymentMethod != string(database.PaymentMethodCard) || payment.TransactionID == "" {
+		return errors.New("payment was not made by card")
+	}
+	if _, err := s.provider.Refund(context.Background(), payment.TransactionID, amount); err != nil {
+		s.logger.Error("Failed to refund payment", zap.Uint("payment_id", payment.ID), zap.Error(err))
+		return fmt.Errorf("payment provider refused the refund: %w", err)
+	}
+	return nil
+}
+
+// HandleWebhook verifies and applies a provider webhook. Every event is
+// stored once by its provider id and each status change is conditional, so
+// redelivered webhooks change nothing.
+func (s *Service) HandleWebhook(header http.Header, body []byte) error {
+	event, err := s.provider.VerifyWebhook(header, body)
+	if err != nil {
+		return err
+	}
+
+	var payment database.Payment
+	var handler *IntentHandler
+	released := false
+	duplicate := false
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		record := &database.PaymentEvent{
+			IntentID:        event.IntentID,
+			ProviderEventID: event.ID,
+			Type:            event.Type,
+			Payload:         string(body),
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("transaction_id = ?", event.IntentID).
+			First(&payment).Error; err != nil {
+			handler, err = s.applyToOthers(tx, event)
+			return err
+		}
+		if err := tx.Model(record).Update("payment_id", payment.ID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch event.Type {
+		case EventAuthorized:
+			// A late or replayed authorization for a payment that already
+			// failed mustn't release the order
+			moved, err := s.setStatus(tx, &payment, map[string]interface{}{
+				"payment_status": database.PaymentStatusAuthorized,
+				"authorized_at":  now,
+			}, database.PaymentStatusPending)
+			if err != nil || !moved {
+				return err
+			}
+			released, err = s.releaseOrder(tx, payment.OrderID, now)
+			return err
+		case EventCaptured:
+			_, err := s.setStatus(tx, &payment, map[string]interface{}{
+				"payment_status": database.PaymentStatusCompleted,
+				"paid_at":        now,
+			}, database.PaymentStatusPending, database.PaymentStatusAuthorized)
+			return err
+		case EventFailed:
+			_, err := s.setStatus(tx, &payment, map[string]interface{}{
+				"payment_status": database.PaymentStatusFailed,
+				"failure_reason": event.FailureReason,
+			}, database.PaymentStatusPending)
+			return err
+		case EventRefunded:
+			_, err := s.setStatus(tx, &payment, map[string]interface{}{
+				"payment_status": database.PaymentStatusRefunded,
+			}, database.PaymentStatusAuthorized, database.PaymentStatusCompleted, database.PaymentStatusRefundPending)
+			return err
+		}
+		s.logger.Warn("Ignoring unknown payment event", zap.String("type", event.Type), zap.String("event_id", event.ID))
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrUnknownIntent) {
+			s.logger.Error("Failed to apply payment webhook", zap.String("event_id", event.ID), zap.Error(err))
+		}
+		return err
+	}
+	if duplicate {
+		s.logger.Info("Duplicate payment webhook ignored", zap.String("event_id", event.ID))
+		return nil
+	}
+
+	if handler != nil {
+		if handler.Applied != nil {
+			handler.Applied(event)
+		}
+		return nil
+	}
+	s.notifyOutcome(event, payment.OrderID, released)
+	return nil
+}
+
+// applyToOthers offers an event for an unknown intent to the registered
+// intent handlers and returns the one that took it
+func (s *Service) applyToOthers(tx *gorm.DB, event *WebhookEvent) (*IntentHandler, error) {
+	for i := range s.intentHandlers {
+		h := &s.intentHandlers[i]
+		handled, err := h.Apply(tx, event)
+		if err != nil {
+			return nil, err
+		}
+		if handled {
+			return h, nil
+		}
+	}
+	return nil, ErrUnknownIntent
+}
+
+// setStatus applies updates if the payment is still in one of from and
+// reports whether it was
+func (s *Service) setStatus(tx *gorm.DB, payment *database.Payment, updates map[string]interface{}, from ...database.PaymentStatus) (bool, error) {
+	res := tx.Model(&database.Payment{}).
+		Where("id = ? AND payment_status IN ?", payment.ID, from).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// releaseOrder makes an order awaiting authorization visible to its vendor.
+// Scheduled orders are left to the order scheduler.
+func (s *Service) releaseOrder(tx *gorm.DB, orderID uint, at time.Time) (bool, error) {
+	res := tx.Model(&database.Order{}).
+		Where("id = ? AND released_at IS NULL AND scheduled_for IS NULL AND status = ?", orderID, database.OrderStatusPending).
+		Update("released_at", at)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+
+	event := &database.OrderEvent{
+		OrderID:    orderID,
+		Type:       database.OrderEventReleased,
+		FromStatus: database.OrderStatusPending,
+		ToStatus:   database.OrderStatusPending,
+		ActorRole:  "system",
+		Reason:     "payment authorized",
+	}
+	return true, tx.Create(event).Error
+}
+
+func (s *Service) notifyOutcome(event *WebhookEvent, orderID uint, released bool) {
+	var order database.Order
+	if err := s.db.Preload("Vendor").Preload("Student").First(&order, orderID).Error; err != nil {
+		return
+	}
+	reference := fmt.Sprintf("%d", order.ID)
+
+	switch {
+	case released:
+		s.notifier.NotifyNewOrder(&order)
+		s.notifier.NotifyStudent(order.Student.UserID, "Payment Authorized",
+			fmt.Sprintf("Payment for order #%s was authorized and the vendor has your order", order.OrderNumber),
+			"payment_authorized", reference)
+	case event.Type == EventFailed:
+		s.notifier.NotifyStudent(order.Student.UserID, "Payment Failed",
+			fmt.Sprintf("Payment for order #%s failed. Please place the order again", order.OrderNumber),
+			"payment_failed", reference)
+	}
+}
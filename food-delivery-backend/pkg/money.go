@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (cents). All stored and computed amounts
+// use it so sums never drift; it is shown to clients as a decimal with two
+// places, e.g. 12.5 -> 12.50.
+//
+// Rounding: anything that produces fractions of a cent (rates, percentages,
+// averages, conversions from floats) rounds half away from zero.
+type Money int64
+
+// NewMoney converts an amount in major units, rounding to the nearest cent
+func NewMoney(major float64) Money {
+	return Money(math.Round(major * 100))
+}
+
+// Float64 returns the amount in major units, for display and ratios only
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// Mul multiplies by a whole quantity
+func (m Money) Mul(qty int) Money {
+	return m * Money(qty)
+}
+
+// MulRate multiplies by a rate such as a commission or fee percentage
+func (m Money) MulRate(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// Div divides by a whole count, such as the orders an average is taken over
+func (m Money) Div(n int64) Money {
+	if n == 0 {
+		return 0
+	}
+	q, r := int64(m)/n, int64(m)%n
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= abs(n) {
+		if (m < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money(q)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Min returns the smaller of two amounts
+func (m Money) Min(other Money) Money {
+	if other < m {
+		return other
+	}
+	return m
+}
+
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or numeric string in major units
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// ParseMoney parses a decimal amount in major units such as "12.34", with at
+// most one leading sign. Digits beyond the second decimal place are rounded
+// half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		return NewMoney(f), nil
+	}
+
+	input := s
+	neg := strings.HasPrefix(s, "-")
+	if neg || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", input)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	for _, r := range whole {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", input)
+		}
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/100 {
+		return 0, fmt.Errorf("invalid amount %q", input)
+	}
+	for _, r := range frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", input)
+		}
+	}
+
+	frac += "000"
+	cents, _ := strconv.ParseInt(frac[:2], 10, 64)
+	total := units*100 + cents
+	if frac[2] >= '5' {
+		total++
+	}
+	if neg {
+		total = -total
+	}
+	return Money(total), nil
+}
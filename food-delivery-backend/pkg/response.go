@@ -9,6 +9,7 @@ type Response struct {
     Message string      `json:"message,omitempty"`
     Data    interface{} `json:"data,omitempty"`
     Error   string      `json:"error,omitempty"`
+    Code    string      `json:"code,omitempty"`
 }
 
 type PaginatedResponse struct {
@@ -52,6 +53,23 @@ func SendError(c *gin.Context, status int, message string, err interface{}) {
     })
 }
 
+// SendErrorCode is SendError with a stable machine-readable code that clients
+// can branch on instead of parsing the message
+func SendErrorCode(c *gin.Context, status int, code, message string, err interface{}) {
+    var errStr string
+    if e, ok := err.(error); ok {
+        errStr = e.Error()
+    } else if s, ok := err.(string); ok {
+        errStr = s
+    }
+    c.JSON(status, Response{
+        Success: false,
+        Message: message,
+        Error:   errStr,
+        Code:    code,
+    })
+}
+
 func SendPaginated(c *gin.Context, status int, message string, data interface{}, page, limit int, totalRows int64) {
     totalPages := int(totalRows) / limit
     if int(totalRows)%limit != 0 {
@@ -3,6 +3,7 @@ package redis
 import (
     "context"
     "fmt"
+    "strconv"
     "time"
     "food-delivery-backend/config"
     "github.com/go-redis/redis/v8"
@@ -47,15 +48,7 @@ func (r *RedisClient) SetRiderAvailable(ctx context.Context, riderID uint, lat,
     }
     
     // Set rider available flag
-    err = r.Client.Set(ctx, fmt.Sprintf("rider:%d:available", riderID), "true", 0).Err()
-    if err != nil {
-        return err
-    }
-    
-    // Set rider last update
-    err = r.Client.Set(ctx, fmt.Sprintf("rider:%d:last_update", riderID), time.Now().Unix(), 0).Err()
-    
-    return err
+    return r.Client.Set(ctx, fmt.Sprintf("rider:%d:available", riderID), "true", 0).Err()
 }
 
 func (r *RedisClient) SetRiderUnavailable(ctx context.Context, riderID uint) error {
@@ -82,6 +75,85 @@ func (r *RedisClient) FindNearestRiders(ctx context.Context, lat, lng float64, r
     }).Result()
 }
 
+// Rider presence. riders:presence scores each rider by the unix time they
+// were last heard from, through a WebSocket ping or a location post.
+const riderPresenceKey = "riders:presence"
+
+func (r *RedisClient) TouchRiderPresence(ctx context.Context, riderID uint, at time.Time) error {
+    return r.Client.ZAdd(ctx, riderPresenceKey, &redis.Z{
+        Score:  float64(at.Unix()),
+        Member: fmt.Sprintf("%d", riderID),
+    }).Err()
+}
+
+// GetRiderPresence returns when each of the riders was last heard from.
+// Riders never heard from are left out.
+func (r *RedisClient) GetRiderPresence(ctx context.Context, riderIDs []uint) (map[uint]time.Time, error) {
+    pipe := r.Client.Pipeline()
+    cmds := make([]*redis.FloatCmd, len(riderIDs))
+    for i, id := range riderIDs {
+        cmds[i] = pipe.ZScore(ctx, riderPresenceKey, fmt.Sprintf("%d", id))
+    }
+    if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+        return nil, err
+    }
+
+    seen := make(map[uint]time.Time, len(riderIDs))
+    for i, cmd := range cmds {
+        score, err := cmd.Result()
+        if err == redis.Nil {
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        seen[riderIDs[i]] = time.Unix(int64(score), 0)
+    }
+    return seen, nil
+}
+
+// GetStaleRiders returns riders last heard from before cutoff
+func (r *RedisClient) GetStaleRiders(ctx context.Context, cutoff time.Time) ([]uint, error) {
+    members, err := r.Client.ZRangeByScore(ctx, riderPresenceKey, &redis.ZRangeBy{
+        Min: "-inf",
+        Max: fmt.Sprintf("(%d", cutoff.Unix()),
+    }).Result()
+    if err != nil {
+        return nil, err
+    }
+    return parseRiderIDs(members), nil
+}
+
+func (r *RedisClient) RemoveRiderPresence(ctx context.Context, riderID uint) error {
+    return r.Client.ZRem(ctx, riderPresenceKey, fmt.Sprintf("%d", riderID)).Err()
+}
+
+// CountOnlineRiders counts riders heard from since cutoff
+func (r *RedisClient) CountOnlineRiders(ctx context.Context, cutoff time.Time) (int64, error) {
+    return r.Client.ZCount(ctx, riderPresenceKey, fmt.Sprintf("%d", cutoff.Unix()), "+inf").Result()
+}
+
+// GetIndexedRiders returns every rider in the geo index
+func (r *RedisClient) GetIndexedRiders(ctx context.Context) ([]uint, error) {
+    members, err := r.Client.ZRange(ctx, "riders:locations", 0, -1).Result()
+    if err != nil {
+        return nil, err
+    }
+    return parseRiderIDs(members), nil
+}
+
+func parseRiderIDs(members []string) []uint {
+    ids := make([]uint, 0, len(members))
+    for _, m := range members {
+        id, err := strconv.ParseUint(m, 10, 32)
+        if err != nil {
+            continue
+        }
+        ids = append(ids, uint(id))
+    }
+    return ids
+}
+
 func (r *RedisClient) IsRiderAvailable(ctx context.Context, riderID uint) (bool, error) {
     val, err := r.Client.Get(ctx, fmt.Sprintf("rider:%d:available", riderID)).Result()
     if err == redis.Nil {
@@ -131,6 +203,55 @@ func (r *RedisClient) GetVendorStatus(ctx context.Context, vendorID uint) (bool,
     return val == "open", nil
 }
 
+// Distributed locks for background jobs that must run on one instance at a time
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// AcquireLock takes the named lock for ttl. It returns the token needed to
+// release it, or ok=false if another holder has it.
+func (r *RedisClient) AcquireLock(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+    token := fmt.Sprintf("%d", time.Now().UnixNano())
+    ok, err := r.Client.SetNX(ctx, "lock:"+name, token, ttl).Result()
+    if err != nil || !ok {
+        return "", false, err
+    }
+    return token, true, nil
+}
+
+// ReleaseLock releases the named lock if it is still held with token
+func (r *RedisClient) ReleaseLock(ctx context.Context, name, token string) error {
+    return releaseLockScript.Run(ctx, r.Client, []string{"lock:" + name}, token).Err()
+}
+
+// Attempt counters for guessable secrets such as handoff codes. A counter
+// lives for window from the first attempt.
+func (r *RedisClient) CountAttempt(ctx context.Context, key string, window time.Duration) (int64, error) {
+    key = "attempts:" + key
+    count, err := r.Client.Incr(ctx, key).Result()
+    if err != nil {
+        return 0, err
+    }
+    if count == 1 {
+        r.Client.Expire(ctx, key, window)
+    }
+    return count, nil
+}
+
+func (r *RedisClient) GetAttempts(ctx context.Context, key string) (int64, error) {
+    count, err := r.Client.Get(ctx, "attempts:"+key).Int64()
+    if err == redis.Nil {
+        return 0, nil
+    }
+    return count, err
+}
+
+func (r *RedisClient) ClearAttempts(ctx context.Context, key string) error {
+    return r.Client.Del(ctx, "attempts:"+key).Err()
+}
+
 // Rate limiting
 func (r *RedisClient) IncrementRequestCount(ctx context.Context, userID uint, window time.Duration) (int64, error) {
     key := fmt.Sprintf("ratelimit:user:%d", userID)
@@ -0,0 +1,159 @@
+package refunds
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRefundNotFound), errors.Is(err, ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotRefundable), errors.Is(err, ErrNothingToRefund), errors.Is(err, ErrInvalidStatus):
+		return http.StatusConflict
+	case errors.Is(err, ledger.ErrInsufficientBalance):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrProviderFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusBadRequest
+}
+
+func pathID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid "+name+" ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// IssueRefund refunds all or part of a delivered order
+// @Summary Refund order
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body CreateRefundRequest true "Refund"
+// @Success 201 {object} pkg.Response{data=database.Refund}
+// @Failure 409 {object} pkg.Response "Order cannot be refunded"
+// @Router /admin/orders/{id}/refunds [post]
+func (h *Handler) IssueRefund(c *gin.Context) {
+	orderID, ok := pathID(c, "order")
+	if !ok {
+		return
+	}
+
+	var req CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	refund, err := h.service.IssueRefund(c.GetUint("user_id"), orderID, &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to refund order", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Refund issued successfully", refund)
+}
+
+// GetRefunds lists refunds
+// @Summary List refunds
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, completed or failed"
+// @Param method query string false "card, wallet or cash"
+// @Param order_id query int false "Order ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/refunds [get]
+func (h *Handler) GetRefunds(c *gin.Context) {
+	filters := RefundFilters{
+		Status: database.RefundStatus(c.Query("status")),
+		Method: c.Query("method"),
+	}
+	if orderID, err := strconv.ParseUint(c.Query("order_id"), 10, 32); err == nil {
+		id := uint(orderID)
+		filters.OrderID = &id
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	refunds, total, err := h.service.GetRefunds(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get refunds", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Refunds retrieved successfully", refunds, page, limit, total)
+}
+
+// GetRefund returns a refund
+// @Summary Get refund
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Refund ID"
+// @Success 200 {object} pkg.Response{data=database.Refund}
+// @Router /admin/refunds/{id} [get]
+func (h *Handler) GetRefund(c *gin.Context) {
+	refundID, ok := pathID(c, "refund")
+	if !ok {
+		return
+	}
+
+	refund, err := h.service.GetRefund(refundID)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get refund", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Refund retrieved successfully", refund)
+}
+
+// RetryRefund sends a failed card refund back to the payment provider
+// @Summary Retry refund
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Refund ID"
+// @Success 200 {object} pkg.Response{data=database.Refund}
+// @Router /admin/refunds/{id}/retry [post]
+func (h *Handler) RetryRefund(c *gin.Context) {
+	refundID, ok := pathID(c, "refund")
+	if !ok {
+		return
+	}
+
+	refund, err := h.service.RetryRefund(refundID)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to retry refund", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Refund completed successfully", refund)
+}
@@ -0,0 +1,30 @@
+package refunds
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+)
+
+type RefundItemRequest struct {
+	OrderItemID uint `json:"order_item_id" binding:"required"`
+	Quantity    int  `json:"quantity" binding:"required,min=1"`
+}
+
+// CreateRefundRequest refunds part or all of a delivered order. Give Items
+// for an item-level refund, Amount for a partial one, or neither to refund
+// everything not yet refunded. VendorShare and RiderShare default to the
+// vendor's cut of refunded items and nothing otherwise.
+type CreateRefundRequest struct {
+	Items       []RefundItemRequest `json:"items" binding:"omitempty,dive"`
+	Amount      *pkg.Money          `json:"amount"`
+	Reason      string              `json:"reason" binding:"required"`
+	Goodwill    bool                `json:"goodwill"` // the platform bears the whole refund
+	VendorShare *pkg.Money          `json:"vendor_share"`
+	RiderShare  *pkg.Money          `json:"rider_share"`
+}
+
+type RefundFilters struct {
+	Status  database.RefundStatus
+	Method  string
+	OrderID *uint
+}
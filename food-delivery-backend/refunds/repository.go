@@ -0,0 +1,64 @@
+package refunds
+
+import (
+	"food-delivery-backend/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetRefunds(filters *RefundFilters, offset, limit int) ([]database.Refund, int64, error) {
+	var refunds []database.Refund
+	var total int64
+
+	query := r.db.Model(&database.Refund{})
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	if filters.Method != "" {
+		query = query.Where("method = ?", filters.Method)
+	}
+	if filters.OrderID != nil {
+		query = query.Where("order_id = ?", *filters.OrderID)
+	}
+
+	query.Count(&total)
+	err := query.Preload("Items").
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&refunds).Error
+	return refunds, total, err
+}
+
+func (r *Repository) GetRefund(refundID uint) (*database.Refund, error) {
+	var refund database.Refund
+	err := r.db.Preload("Items").First(&refund, refundID).Error
+	return &refund, err
+}
+
+// LockOrder takes a row lock on the order for the rest of tx and loads what
+// a refund needs
+func (r *Repository) LockOrder(tx *gorm.DB, orderID uint) (*database.Order, error) {
+	var locked database.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").First(&locked, orderID).Error; err != nil {
+		return nil, err
+	}
+
+	var order database.Order
+	err := tx.Preload("Vendor.User").
+		Preload("AssignedRider.User").
+		Preload("Student.User").
+		Preload("Payment").
+		Preload("OrderItems").
+		First(&order, orderID).Error
+	return &order, err
+}
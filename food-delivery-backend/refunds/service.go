@@ -0,0 +1,432 @@
+package refunds
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/payments"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefundNotFound  = errors.New("refund not found")
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrNotRefundable   = errors.New("only delivered orders can be refunded; cancelled and rejected orders are refunded automatically")
+	ErrNothingToRefund = errors.New("nothing left to refund on this order")
+	ErrInvalidStatus   = errors.New("refund cannot be retried in its current status")
+	ErrProviderFailed  = errors.New("refund was recorded but the payment provider rejected it; retry it once the problem is fixed")
+)
+
+// cashRefundNote tells support how a cash refund reaches the student
+const cashRefundNote = "Paid in cash: return the amount to the student in person"
+
+type Service struct {
+	repo     *Repository
+	db       *gorm.DB
+	payments *payments.Service
+	notifier *notifications.Service
+	logger   *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, paymentService *payments.Service, notifier *notifications.Service, logger *zap.Logger) *Service {
+	return &Service{
+		repo:     repo,
+		db:       db,
+		payments: paymentService,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// RefundCancelled runs inside the transaction that cancels or rejects an
+// order and gives back everything the student paid. Wallet payments are
+// returned at once; card refunds are opened here and sent to the provider
+// by SettleCancelled after the cancellation commits. Nothing was booked in
+// the ledger for a card order before delivery, so only wallet refunds post
+// an entry. order must be loaded with its Student and Payment; the refunds
+// opened here are appended to order.Refunds for SettleCancelled.
+func (s *Service) RefundCancelled(tx *gorm.DB, order *database.Order, status database.OrderStatus, reason string) error {
+	payment := order.Payment
+	if payment == nil {
+		return nil
+	}
+
+	switch database.PaymentStatus(payment.PaymentStatus) {
+	case database.PaymentStatusPending:
+		// Never authorized or collected, so nothing is owed
+		return setPaymentStatus(tx, payment, database.PaymentStatusFailed)
+	case database.PaymentStatusAuthorized, database.PaymentStatusCompleted:
+	default:
+		return nil
+	}
+	if payment.PaymentMethod == string(database.PaymentMethodCash) {
+		return nil
+	}
+
+	if reason == "" {
+		reason = "Order " + string(status)
+	}
+	refund := &database.Refund{
+		OrderID:   order.ID,
+		PaymentID: payment.ID,
+		Type:      database.RefundTypeFull,
+		Method:    payment.PaymentMethod,
+		Amount:    order.TotalAmount - order.RefundedAmount,
+		Status:    database.RefundStatusPending,
+		Reason:    reason,
+	}
+	paymentStatus := database.PaymentStatusRefundPending
+
+	if payment.PaymentMethod == string(database.PaymentMethodWallet) {
+		cancelled := *order
+		cancelled.Status = status
+		entry, err := ledger.PostWalletRefund(tx, &cancelled)
+		if err != nil {
+			return err
+		}
+		markCompleted(refund, entry)
+		paymentStatus = database.PaymentStatusRefunded
+	}
+
+	if err := tx.Create(refund).Error; err != nil {
+		return err
+	}
+	if err := addRefunded(tx, order, refund.Amount); err != nil {
+		return err
+	}
+	order.Refunds = append(order.Refunds, *refund)
+	return setPaymentStatus(tx, payment, paymentStatus)
+}
+
+// SettleCancelled runs after a cancellation commits. It sends the card
+// refunds RefundCancelled opened to the provider and tells the student about
+// wallet refunds. Refunds the provider rejects are left failed for an admin
+// to retry.
+func (s *Service) SettleCancelled(order *database.Order) {
+	for i := range order.Refunds {
+		refund := &order.Refunds[i]
+		if refund.Status == database.RefundStatusCompleted {
+			s.notifyStudent(order, refund)
+			continue
+		}
+		s.settle(refund, database.RefundStatusPending)
+	}
+}
+
+// IssueRefund refunds all or part of a delivered order through its original
+// payment method and books it in the ledger. Card refunds are committed as
+// pending and sent to the provider afterwards, so money never goes out for
+// a refund that wasn't recorded.
+func (s *Service) IssueRefund(adminID, orderID uint, req *CreateRefundRequest) (*database.Refund, error) {
+	if len(req.Items) > 0 && req.Amount != nil {
+		return nil, errors.New("give either items or an amount, not both")
+	}
+	if req.Goodwill && (req.VendorShare != nil || req.RiderShare != nil) {
+		return nil, errors.New("goodwill refunds are borne by the platform")
+	}
+
+	var refund *database.Refund
+	var order *database.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.repo.LockOrder(tx, orderID)
+		if err != nil {
+			return ErrOrderNotFound
+		}
+		if order.Status != database.OrderStatusDelivered {
+			return ErrNotRefundable
+		}
+		payment := order.Payment
+		if payment == nil {
+			return errors.New("order has no payment to refund")
+		}
+		remaining := order.TotalAmount - order.RefundedAmount
+		if remaining <= 0 || payment.PaymentStatus == string(database.PaymentStatusRefunded) {
+			return ErrNothingToRefund
+		}
+
+		refund = &database.Refund{
+			OrderID:    order.ID,
+			PaymentID:  payment.ID,
+			Type:       database.RefundTypeFull,
+			Method:     payment.PaymentMethod,
+			Amount:     remaining,
+			Reason:     req.Reason,
+			IssuedByID: &adminID,
+		}
+		switch {
+		case len(req.Items) > 0:
+			if err := s.refundItems(tx, order, refund, req.Items); err != nil {
+				return err
+			}
+		case req.Amount != nil:
+			if *req.Amount <= 0 {
+				return errors.New("refund amount must be greater than 0")
+			}
+			refund.Type = database.RefundTypePartial
+			refund.Amount = *req.Amount
+		}
+		if refund.Amount > remaining {
+			return fmt.Errorf("at most %s can still be refunded on this order", remaining)
+		}
+
+		if req.Goodwill {
+			refund.Type = database.RefundTypeGoodwill
+			refund.VendorShare = 0
+		}
+		if req.VendorShare != nil {
+			refund.VendorShare = *req.VendorShare
+		}
+		if req.RiderShare != nil {
+			refund.RiderShare = *req.RiderShare
+		}
+		if refund.Method == string(database.PaymentMethodCash) {
+			refund.Note = cashRefundNote
+		}
+
+		// Save first so the ledger entry can reference the refund
+		refund.Status = database.RefundStatusCompleted
+		if refund.Method == string(database.PaymentMethodCard) {
+			refund.Status = database.RefundStatusPending
+		}
+		if err := tx.Omit("Items").Create(refund).Error; err != nil {
+			return err
+		}
+		for i := range refund.Items {
+			refund.Items[i].RefundID = refund.ID
+		}
+		if len(refund.Items) > 0 {
+			if err := tx.Create(&refund.Items).Error; err != nil {
+				return err
+			}
+		}
+
+		entry, err := ledger.PostRefund(tx, ledger.Refund{
+			Order:       order,
+			Amount:      refund.Amount,
+			VendorShare: refund.VendorShare,
+			RiderShare:  refund.RiderShare,
+			ToWallet:    refund.Method == string(database.PaymentMethodWallet),
+			Reason:      refund.Reason,
+			ReferenceID: fmt.Sprintf("refund:%d", refund.ID),
+			CreatedByID: &adminID,
+		})
+		if err != nil {
+			return err
+		}
+		if refund.Status == database.RefundStatusPending {
+			refund.LedgerEntryID = &entry.ID
+		} else {
+			markCompleted(refund, entry)
+		}
+		if err := tx.Model(refund).Updates(map[string]interface{}{
+			"ledger_entry_id": refund.LedgerEntryID,
+			"completed_at":    refund.CompletedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := addRefunded(tx, order, refund.Amount); err != nil {
+			return err
+		}
+		status := database.PaymentStatusPartiallyRefunded
+		if order.RefundedAmount >= order.TotalAmount {
+			status = database.PaymentStatusRefunded
+		}
+		if err := setPaymentStatus(tx, payment, status); err != nil {
+			return err
+		}
+		return tx.Create(&database.OrderEvent{
+			OrderID:    order.ID,
+			Type:       database.OrderEventRefunded,
+			FromStatus: order.Status,
+			ToStatus:   order.Status,
+			ActorID:    &adminID,
+			ActorRole:  "admin",
+			Reason:     fmt.Sprintf("%s refund of %s: %s", refund.Type, refund.Amount, refund.Reason),
+		}).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOrderNotFound) && !errors.Is(err, ErrNotRefundable) && !errors.Is(err, ErrNothingToRefund) {
+			s.logger.Error("Failed to issue refund", zap.Uint("order_id", orderID), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	if refund.Status == database.RefundStatusPending {
+		if err := s.settle(refund, database.RefundStatusPending); err != nil {
+			return refund, ErrProviderFailed
+		}
+		return refund, nil
+	}
+	s.notifyStudent(order, refund)
+	return refund, nil
+}
+
+// refundItems prices an item-level refund and records the refunded
+// quantities. The vendor gives back their cut of the items by default.
+func (s *Service) refundItems(tx *gorm.DB, order *database.Order, refund *database.Refund, items []RefundItemRequest) error {
+	orderItems := make(map[uint]*database.OrderItem, len(order.OrderItems))
+	for i := range order.OrderItems {
+		orderItems[order.OrderItems[i].ID] = &order.OrderItems[i]
+	}
+
+	var amount pkg.Money
+	for _, req := range items {
+		item, ok := orderItems[req.OrderItemID]
+		if !ok {
+			return fmt.Errorf("item %d is not part of this order", req.OrderItemID)
+		}
+		if item.RefundedQuantity+req.Quantity > item.Quantity {
+			return fmt.Errorf("only %d of item %d can still be refunded", item.Quantity-item.RefundedQuantity, item.ID)
+		}
+		item.RefundedQuantity += req.Quantity
+		if err := tx.Model(&database.OrderItem{}).Where("id = ?", item.ID).
+			Update("refunded_quantity", item.RefundedQuantity).Error; err != nil {
+			return err
+		}
+
+		line := item.UnitPrice.Mul(req.Quantity)
+		amount += line
+		refund.Items = append(refund.Items, database.RefundItem{
+			OrderItemID: item.ID,
+			Quantity:    req.Quantity,
+			Amount:      line,
+		})
+	}
+
+	refund.Type = database.RefundTypeItems
+	refund.Amount = amount
+	if order.Subtotal > 0 {
+		refund.VendorShare = amount.MulRate(float64(order.VendorEarnings) / float64(order.Subtotal)).Min(amount)
+	}
+	return nil
+}
+
+// ================ ADMIN ================
+
+func (s *Service) GetRefunds(filters *RefundFilters, page, limit int) ([]database.Refund, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetRefunds(filters, offset, limit)
+}
+
+func (s *Service) GetRefund(refundID uint) (*database.Refund, error) {
+	refund, err := s.repo.GetRefund(refundID)
+	if err != nil {
+		return nil, ErrRefundNotFound
+	}
+	return refund, nil
+}
+
+// RetryRefund sends a card refund the provider rejected back to it
+func (s *Service) RetryRefund(refundID uint) (*database.Refund, error) {
+	refund, err := s.repo.GetRefund(refundID)
+	if err != nil {
+		return nil, ErrRefundNotFound
+	}
+	if refund.Status != database.RefundStatusFailed {
+		return nil, ErrInvalidStatus
+	}
+	if err := s.settle(refund, database.RefundStatusFailed); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// settle sends an open card refund to the provider and completes it. The
+// refund is first claimed by moving it from the given status to processing,
+// so a refund is never sent twice by concurrent callers.
+func (s *Service) settle(refund *database.Refund, from database.RefundStatus) error {
+	var payment database.Payment
+	if err := s.db.First(&payment, refund.PaymentID).Error; err != nil {
+		return err
+	}
+
+	res := s.db.Model(&database.Refund{}).
+		Where("id = ? AND status = ?", refund.ID, from).
+		Update("status", database.RefundStatusProcessing)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidStatus
+	}
+	refund.Status = database.RefundStatusProcessing
+
+	if err := s.payments.Refund(&payment, refund.Amount); err != nil {
+		refund.Status = database.RefundStatusFailed
+		refund.FailureReason = err.Error()
+		s.db.Model(refund).Updates(map[string]interface{}{
+			"status":         refund.Status,
+			"failure_reason": refund.FailureReason,
+		})
+		s.notifier.NotifyAdmin("Refund Failed",
+			fmt.Sprintf("Refund #%d of %s on order #%d failed: %s", refund.ID, refund.Amount, refund.OrderID, err))
+		return err
+	}
+
+	markCompleted(refund, nil)
+	refund.FailureReason = ""
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(refund).Updates(map[string]interface{}{
+			"status":         refund.Status,
+			"failure_reason": "",
+			"completed_at":   refund.CompletedAt,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&database.Payment{}).
+			Where("id = ? AND payment_status = ?", payment.ID, database.PaymentStatusRefundPending).
+			Update("payment_status", database.PaymentStatusRefunded).Error
+	})
+	if err != nil {
+		s.logger.Error("Failed to complete refund", zap.Uint("refund_id", refund.ID), zap.Error(err))
+		return err
+	}
+
+	var order database.Order
+	if err := s.db.Preload("Student").First(&order, refund.OrderID).Error; err == nil {
+		s.notifyStudent(&order, refund)
+	}
+	return nil
+}
+
+func markCompleted(refund *database.Refund, entry *database.LedgerEntry) {
+	now := time.Now()
+	refund.Status = database.RefundStatusCompleted
+	refund.CompletedAt = &now
+	if entry != nil {
+		refund.LedgerEntryID = &entry.ID
+	}
+}
+
+func addRefunded(tx *gorm.DB, order *database.Order, amount pkg.Money) error {
+	order.RefundedAmount += amount
+	return tx.Model(&database.Order{}).Where("id = ?", order.ID).
+		Update("refunded_amount", order.RefundedAmount).Error
+}
+
+func setPaymentStatus(tx *gorm.DB, payment *database.Payment, status database.PaymentStatus) error {
+	payment.PaymentStatus = string(status)
+	return tx.Model(&database.Payment{}).Where("id = ?", payment.ID).
+		Update("payment_status", status).Error
+}
+
+func (s *Service) notifyStudent(order *database.Order, refund *database.Refund) {
+	var message string
+	switch refund.Method {
+	case string(database.PaymentMethodWallet):
+		message = fmt.Sprintf("%s for order #%s is back in your wallet", refund.Amount, order.OrderNumber)
+	case string(database.PaymentMethodCash):
+		message = fmt.Sprintf("%s for order #%s will be returned to you in cash", refund.Amount, order.OrderNumber)
+	default:
+		message = fmt.Sprintf("%s for order #%s has been refunded to your card", refund.Amount, order.OrderNumber)
+	}
+	s.notifier.NotifyStudent(order.Student.UserID, "Refund Issued", message, "refund", fmt.Sprintf("%d", order.ID))
+}
@@ -0,0 +1,272 @@
+package riders
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/orders"
+	"food-delivery-backend/pkg"
+	"math"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// suggestionPool is how many waiting orders suggestions are drawn from
+const suggestionPool = 100
+
+// GetBatch returns the rider's current batch and the route through it
+func (s *Service) GetBatch(riderID uint) (*BatchResponse, error) {
+	rider, err := s.repo.GetRiderByUserID(riderID)
+	if err != nil {
+		return nil, errors.New("rider not found")
+	}
+
+	batch, err := s.repo.GetActiveBatch(rider.ID)
+	if err != nil {
+		s.logger.Error("Failed to get delivery batch", zap.Error(err))
+		return nil, errors.New("failed to get batch")
+	}
+
+	response := &BatchResponse{Batch: batch, MaxSize: orders.MaxBatchSize(s.cfg), Stops: []BatchStop{}}
+	if batch != nil {
+		response.Stops = buildStops(rider, batch.Orders)
+	}
+	return response, nil
+}
+
+// GetBatchSuggestions returns ready orders the rider could carry together.
+// A rider with a batch gets the orders that fit it, up to the room left; a
+// rider without one gets groups of orders that go the same way.
+func (s *Service) GetBatchSuggestions(riderID uint) ([]BatchSuggestion, error) {
+	rider, err := s.repo.GetRiderByUserID(riderID)
+	if err != nil {
+		return nil, errors.New("rider not found")
+	}
+
+	batch, err := s.repo.GetActiveBatch(rider.ID)
+	if err != nil {
+		s.logger.Error("Failed to get delivery batch", zap.Error(err))
+		return nil, errors.New("failed to get suggestions")
+	}
+	ready, err := s.repo.GetUnassignedReadyOrders(suggestionPool)
+	if err != nil {
+		s.logger.Error("Failed to get ready orders", zap.Error(err))
+		return nil, errors.New("failed to get suggestions")
+	}
+
+	maxSize := orders.MaxBatchSize(s.cfg)
+	suggestions := []BatchSuggestion{}
+
+	if batch != nil {
+		var carrying []database.Order
+		for _, o := range batch.Orders {
+			if !orders.IsTerminal(o.Status) {
+				carrying = append(carrying, o)
+			}
+		}
+		group := carrying
+		var fits []database.Order
+		for i := range ready {
+			if len(group) >= maxSize {
+				break
+			}
+			if orders.FitsBatch(&ready[i], group, s.cfg) {
+				group = append(group, ready[i])
+				fits = append(fits, ready[i])
+			}
+		}
+		if len(fits) > 0 {
+			suggestions = append(suggestions, suggest(rider, fits))
+		}
+		return suggestions, nil
+	}
+
+	// Single orders can just be claimed, so only groups are suggested
+	used := make(map[uint]bool)
+	for i := range ready {
+		if used[ready[i].ID] {
+			continue
+		}
+		group := []database.Order{ready[i]}
+		for j := i + 1; j < len(ready) && len(group) < maxSize; j++ {
+			if !used[ready[j].ID] && orders.FitsBatch(&ready[j], group, s.cfg) {
+				group = append(group, ready[j])
+			}
+		}
+		if len(group) < 2 {
+			continue
+		}
+		for _, o := range group {
+			used[o.ID] = true
+		}
+		suggestions = append(suggestions, suggest(rider, group))
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].DistanceKm < suggestions[j].DistanceKm
+	})
+	return suggestions, nil
+}
+
+func suggest(rider *database.Rider, group []database.Order) BatchSuggestion {
+	suggestion := BatchSuggestion{Orders: group, DeliveryBlock: group[0].DeliveryBlock}
+	seen := make(map[uint]bool)
+	for _, o := range group {
+		suggestion.RiderEarnings += o.RiderEarnings
+		if !seen[o.VendorID] {
+			seen[o.VendorID] = true
+			suggestion.Vendors = append(suggestion.Vendors, o.Vendor.BusinessName)
+		}
+	}
+	pickups := pendingStops(groupPickups(group))
+	if route := nearestFirst(rider.CurrentLatitude, rider.CurrentLongitude, pickups); len(route) > 0 {
+		suggestion.DistanceKm = roundKm(pkg.CalculateDistance(rider.CurrentLatitude, rider.CurrentLongitude,
+			route[0].Latitude, route[0].Longitude))
+	}
+	return suggestion
+}
+
+// ClaimBatch claims several ready orders at once, in the order given. Orders
+// that can't be claimed are reported and the rest are still claimed.
+func (s *Service) ClaimBatch(riderID uint, orderIDs []uint) *ClaimBatchResponse {
+	response := &ClaimBatchResponse{Claimed: []uint{}}
+	for _, orderID := range orderIDs {
+		if err := s.ClaimOrder(riderID, orderID); err != nil {
+			if response.Failed == nil {
+				response.Failed = make(map[uint]string)
+			}
+			response.Failed[orderID] = err.Error()
+			continue
+		}
+		response.Claimed = append(response.Claimed, orderID)
+	}
+	return response
+}
+
+// buildStops lays out the route through a batch: stops already done first,
+// then the pickups left, nearest first from the rider, then the drop-offs,
+// nearest first from the last pickup. Cancelled orders are left out.
+func buildStops(rider *database.Rider, batchOrders []database.Order) []BatchStop {
+	var live []database.Order
+	for _, o := range batchOrders {
+		if o.Status != database.OrderStatusCancelled && o.Status != database.OrderStatusRejected {
+			live = append(live, o)
+		}
+	}
+
+	pickups := groupPickups(live)
+	dropoffs := groupDropoffs(live)
+
+	var stops []BatchStop
+	for _, group := range [][]BatchStop{pickups, dropoffs} {
+		for _, stop := range group {
+			if stop.Done {
+				stops = append(stops, stop)
+			}
+		}
+	}
+
+	lat, lng := rider.CurrentLatitude, rider.CurrentLongitude
+	for _, group := range [][]BatchStop{pickups, dropoffs} {
+		route := nearestFirst(lat, lng, pendingStops(group))
+		if len(route) > 0 {
+			last := route[len(route)-1]
+			lat, lng = last.Latitude, last.Longitude
+		}
+		stops = append(stops, route...)
+	}
+
+	for i := range stops {
+		stops[i].Sequence = i + 1
+	}
+	return stops
+}
+
+// groupPickups makes one pickup stop per vendor. A pickup is done once all
+// of its orders have been collected.
+func groupPickups(batchOrders []database.Order) []BatchStop {
+	var stops []BatchStop
+	index := make(map[uint]int)
+	for _, o := range batchOrders {
+		i, ok := index[o.VendorID]
+		if !ok {
+			i = len(stops)
+			index[o.VendorID] = i
+			stops = append(stops, BatchStop{
+				Type:      StopPickup,
+				Name:      o.Vendor.BusinessName,
+				Address:   o.Vendor.BusinessAddress,
+				Latitude:  o.Vendor.Latitude,
+				Longitude: o.Vendor.Longitude,
+				Done:      true,
+			})
+		}
+		stops[i].OrderIDs = append(stops[i].OrderIDs, o.ID)
+		if o.Status != database.OrderStatusPickedUp && o.Status != database.OrderStatusDelivered {
+			stops[i].Done = false
+		}
+	}
+	return stops
+}
+
+// groupDropoffs makes one drop-off stop per block and dorm. A drop-off is
+// done once all of its orders have been delivered.
+func groupDropoffs(batchOrders []database.Order) []BatchStop {
+	var stops []BatchStop
+	index := make(map[string]int)
+	for _, o := range batchOrders {
+		key := orders.DropKey(&o)
+		i, ok := index[key]
+		if !ok {
+			i = len(stops)
+			index[key] = i
+			name := strings.TrimSpace(strings.Join([]string{o.DeliveryBlock, o.DeliveryDorm}, " "))
+			stops = append(stops, BatchStop{
+				Type:      StopDropoff,
+				Name:      name,
+				Address:   o.DeliveryAddress,
+				Latitude:  o.DeliveryLat,
+				Longitude: o.DeliveryLng,
+				Done:      true,
+			})
+		}
+		stops[i].OrderIDs = append(stops[i].OrderIDs, o.ID)
+		if o.Status != database.OrderStatusDelivered {
+			stops[i].Done = false
+		}
+	}
+	return stops
+}
+
+func pendingStops(stops []BatchStop) []BatchStop {
+	var pending []BatchStop
+	for _, stop := range stops {
+		if !stop.Done {
+			pending = append(pending, stop)
+		}
+	}
+	return pending
+}
+
+// nearestFirst orders stops by always going to the closest one next
+func nearestFirst(lat, lng float64, stops []BatchStop) []BatchStop {
+	left := append([]BatchStop{}, stops...)
+	route := make([]BatchStop, 0, len(stops))
+	for len(left) > 0 {
+		best, bestKm := 0, math.MaxFloat64
+		for i, stop := range left {
+			if km := pkg.CalculateDistance(lat, lng, stop.Latitude, stop.Longitude); km < bestKm {
+				best, bestKm = i, km
+			}
+		}
+		next := left[best]
+		route = append(route, next)
+		lat, lng = next.Latitude, next.Longitude
+		left = append(left[:best], left[best+1:]...)
+	}
+	return route
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*100) / 100
+}
@@ -180,6 +180,72 @@ func (h *Handler) ClaimOrder(c *gin.Context) {
 	pkg.SendSuccess(c, http.StatusOK, "Order claimed successfully", nil)
 }
 
+// GetBatch returns the rider's current delivery batch and its ordered stops
+// @Summary Get current delivery batch
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=BatchResponse}
+// @Router /riders/batch [get]
+func (h *Handler) GetBatch(c *gin.Context) {
+	riderID := c.GetUint("user_id")
+
+	batch, err := h.service.GetBatch(riderID)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get batch", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Batch retrieved", batch)
+}
+
+// GetBatchSuggestions returns ready orders the rider could deliver together
+// @Summary Get batch suggestions
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]BatchSuggestion}
+// @Router /riders/batch/suggestions [get]
+func (h *Handler) GetBatchSuggestions(c *gin.Context) {
+	riderID := c.GetUint("user_id")
+
+	suggestions, err := h.service.GetBatchSuggestions(riderID)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get suggestions", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Batch suggestions retrieved", suggestions)
+}
+
+// ClaimBatch claims several ready orders into the rider's batch
+// @Summary Claim orders as a batch
+// @Tags Riders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ClaimBatchRequest true "Orders to claim"
+// @Success 200 {object} pkg.Response{data=ClaimBatchResponse}
+// @Router /riders/batch/claim [post]
+func (h *Handler) ClaimBatch(c *gin.Context) {
+	riderID := c.GetUint("user_id")
+
+	var req ClaimBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	result := h.service.ClaimBatch(riderID, req.OrderIDs)
+	if len(result.Claimed) == 0 {
+		// Nothing was claimed; the first order's reason is usually why
+		pkg.SendError(c, http.StatusBadRequest, "No orders could be claimed", result.Failed[req.OrderIDs[0]])
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Orders claimed", result)
+}
+
 // GetOrder returns a specific order
 // @Summary Get order details
 // @Tags Riders
@@ -209,8 +275,12 @@ func (h *Handler) GetOrder(c *gin.Context) {
 // @Summary Mark order as picked up
 // @Tags Riders
 // @Security BearerAuth
+// @Accept json,mpfd
 // @Param id path int true "Order ID"
+// @Param request body HandoffRequest false "Pickup code and position"
+// @Param photo formData file false "Photo of the collected order"
 // @Success 200 {object} pkg.Response
+// @Failure 429 {object} pkg.Response
 // @Router /riders/orders/{id}/pickup [post]
 func (h *Handler) PickUpOrder(c *gin.Context) {
 	riderID := c.GetUint("user_id")
@@ -220,20 +290,31 @@ func (h *Handler) PickUpOrder(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.PickUpOrder(riderID, uint(orderID)); err != nil {
-		pkg.SendError(c, http.StatusBadRequest, "Failed to mark as picked up", err.Error())
+	handoff, ok := h.readHandoff(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.PickUpOrder(riderID, uint(orderID), handoff); err != nil {
+		h.discardPhoto(handoff)
+		pkg.SendError(c, handoffErrorStatus(err), "Failed to mark as picked up", err.Error())
 		return
 	}
 
 	pkg.SendSuccess(c, http.StatusOK, "Order marked as picked up", nil)
 }
 
-// DeliverOrder marks order as delivered
+// DeliverOrder marks order as delivered. The rider must enter the student's
+// delivery code when codes are required.
 // @Summary Mark order as delivered
 // @Tags Riders
 // @Security BearerAuth
+// @Accept json,mpfd
 // @Param id path int true "Order ID"
+// @Param request body HandoffRequest false "Delivery code and position"
+// @Param photo formData file false "Photo of the handed over order"
 // @Success 200 {object} pkg.Response
+// @Failure 429 {object} pkg.Response
 // @Router /riders/orders/{id}/deliver [post]
 func (h *Handler) DeliverOrder(c *gin.Context) {
 	riderID := c.GetUint("user_id")
@@ -243,14 +324,37 @@ func (h *Handler) DeliverOrder(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeliverOrder(riderID, uint(orderID)); err != nil {
-		pkg.SendError(c, http.StatusBadRequest, "Failed to mark as delivered", err.Error())
+	handoff, ok := h.readHandoff(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeliverOrder(riderID, uint(orderID), handoff); err != nil {
+		h.discardPhoto(handoff)
+		pkg.SendError(c, handoffErrorStatus(err), "Failed to mark as delivered", err.Error())
 		return
 	}
 
 	pkg.SendSuccess(c, http.StatusOK, "Order marked as delivered", nil)
 }
 
+// GetPresence returns live counts of riders online, available and busy
+// @Summary Get rider presence
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=PresenceResponse}
+// @Router /admin/riders/online [get]
+func (h *Handler) GetPresence(c *gin.Context) {
+	presence, err := h.service.GetPresence()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get rider presence", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Rider presence retrieved", presence)
+}
+
 // GetEarnings returns rider earnings
 // @Summary Get earnings
 // @Tags Riders
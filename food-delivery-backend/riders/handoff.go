@@ -0,0 +1,111 @@
+package riders
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/orders"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func handoffErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, orders.ErrHandoffLocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, orders.ErrHandoffCodeInvalid), errors.Is(err, orders.ErrHandoffCodeRequired):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// readHandoff binds the handoff code and position from a JSON or multipart
+// body and saves the photo if one was sent. It writes the error response and
+// returns false when the request is unusable. Callers discard the photo if
+// the handoff is then refused.
+func (h *Handler) readHandoff(c *gin.Context) (*orders.Handoff, bool) {
+	var req HandoffRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBind(&req); err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return nil, false
+		}
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", "latitude and longitude must be sent together")
+		return nil, false
+	}
+
+	handoff := &orders.Handoff{
+		Code:      strings.TrimSpace(req.Code),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
+
+	if c.ContentType() == "multipart/form-data" {
+		if _, err := c.FormFile("photo"); err == nil {
+			url, status, err := h.savePhoto(c)
+			if err != nil {
+				pkg.SendError(c, status, "Failed to save photo", err.Error())
+				return nil, false
+			}
+			handoff.PhotoURL = url
+		}
+	}
+	return handoff, true
+}
+
+// discardPhoto removes the proof photo of a handoff that was refused, so
+// failed attempts don't leave files behind
+func (h *Handler) discardPhoto(handoff *orders.Handoff) {
+	if handoff == nil || handoff.PhotoURL == "" {
+		return
+	}
+	path := filepath.Join(h.service.cfg.UploadPath, "handoffs", filepath.Base(handoff.PhotoURL))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		h.logger.Warn("Failed to remove handoff photo", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// savePhoto stores the uploaded proof photo and returns its URL
+func (h *Handler) savePhoto(c *gin.Context) (string, int, error) {
+	cfg := h.service.cfg
+	file, err := c.FormFile("photo")
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	if file.Size > cfg.MaxUploadSize {
+		return "", http.StatusBadRequest, fmt.Errorf("maximum size is %dMB", cfg.MaxUploadSize/(1024*1024))
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	valid := false
+	for _, allowed := range cfg.AllowedImageTypes {
+		if ext == "."+allowed {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", http.StatusBadRequest, fmt.Errorf("only %s allowed", strings.Join(cfg.AllowedImageTypes, ", "))
+	}
+
+	dir := filepath.Join(cfg.UploadPath, "handoffs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		h.logger.Error("Failed to create upload directory", zap.Error(err))
+		return "", http.StatusInternalServerError, errors.New("failed to save file")
+	}
+
+	filename := fmt.Sprintf("%d_%d%s", time.Now().UnixNano(), c.GetUint("user_id"), ext)
+	if err := c.SaveUploadedFile(file, filepath.Join(dir, filename)); err != nil {
+		h.logger.Error("Failed to save file", zap.Error(err))
+		return "", http.StatusInternalServerError, errors.New("failed to save file")
+	}
+	return fmt.Sprintf("/uploads/handoffs/%s", filename), http.StatusOK, nil
+}
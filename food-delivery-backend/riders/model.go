@@ -1,5 +1,10 @@
 package riders
 
+import (
+    "food-delivery-backend/database"
+    "food-delivery-backend/pkg"
+)
+
 type UpdateRiderRequest struct {
     VehicleNumber string `json:"vehicle_number"`
     VehicleType   string `json:"vehicle_type"`
@@ -11,22 +16,85 @@ type UpdateLocationRequest struct {
     Longitude float64 `json:"longitude" binding:"required"`
 }
 
+// PresenceResponse counts the riders around right now
+type PresenceResponse struct {
+    Online            int64 `json:"online"`       // heard from within the staleness threshold
+    Available         int64 `json:"available"`    // waiting for orders
+    Dispatchable      int   `json:"dispatchable"` // in the index dispatch searches
+    OnDelivery        int64 `json:"on_delivery"`  // holding orders that are under way
+    StaleAfterSeconds int   `json:"stale_after_seconds"`
+}
+
+// HandoffRequest is what the rider submits at pickup or delivery, as JSON or
+// as a multipart form with an optional "photo" file
+type HandoffRequest struct {
+    Code      string   `json:"code" form:"code"`
+    Latitude  *float64 `json:"latitude" form:"latitude"`
+    Longitude *float64 `json:"longitude" form:"longitude"`
+}
+
 type RiderEarningsResponse struct {
     Period struct {
         StartDate string `json:"start_date"`
         EndDate   string `json:"end_date"`
     } `json:"period"`
     Summary struct {
-        TotalDeliveries int     `json:"total_deliveries"`
-        TotalEarnings   float64 `json:"total_earnings"`
-        AveragePerDelivery float64 `json:"average_per_delivery"`
+        TotalDeliveries    int       `json:"total_deliveries"`
+        TotalEarnings      pkg.Money `json:"total_earnings"`
+        AveragePerDelivery pkg.Money `json:"average_per_delivery"`
     } `json:"summary"`
     DailyBreakdown []DailyEarnings `json:"daily_breakdown"`
-    CurrentBalance float64         `json:"current_balance"`
+    CurrentBalance pkg.Money       `json:"current_balance"`
 }
 
 type DailyEarnings struct {
-    Date       string  `json:"date"`
-    Deliveries int     `json:"deliveries"`
-    Earnings   float64 `json:"earnings"`
-}
\ No newline at end of file
+    Date       string    `json:"date"`
+    Deliveries int       `json:"deliveries"`
+    Earnings   pkg.Money `json:"earnings"`
+}
+// Stop types on a batch route
+const (
+    StopPickup  = "pickup"
+    StopDropoff = "dropoff"
+)
+
+// BatchStop is one place on the rider's route: a vendor to collect from or a
+// dorm to hand orders over at
+type BatchStop struct {
+    Sequence  int     `json:"sequence"`
+    Type      string  `json:"type"` // pickup or dropoff
+    Name      string  `json:"name"`
+    Address   string  `json:"address"`
+    Latitude  float64 `json:"latitude"`
+    Longitude float64 `json:"longitude"`
+    OrderIDs  []uint  `json:"order_ids"`
+    Done      bool    `json:"done"`
+}
+
+// BatchResponse is the rider's current batch with the route through it.
+// Batch is nil when the rider is carrying nothing.
+type BatchResponse struct {
+    Batch   *database.DeliveryBatch `json:"batch"`
+    MaxSize int                     `json:"max_size"`
+    Stops   []BatchStop             `json:"stops"`
+}
+
+// BatchSuggestion is a group of ready orders that can be delivered together,
+// or added to the rider's batch when they have one
+type BatchSuggestion struct {
+    Orders        []database.Order `json:"orders"`
+    Vendors       []string         `json:"vendors"`
+    DeliveryBlock string           `json:"delivery_block"`
+    DistanceKm    float64          `json:"distance_km"` // rider to the first pickup
+    RiderEarnings pkg.Money        `json:"rider_earnings"`
+}
+
+type ClaimBatchRequest struct {
+    OrderIDs []uint `json:"order_ids" binding:"required,min=1"`
+}
+
+// ClaimBatchResponse reports which orders were claimed; the others say why not
+type ClaimBatchResponse struct {
+    Claimed []uint          `json:"claimed"`
+    Failed  map[uint]string `json:"failed,omitempty"`
+}
@@ -0,0 +1,239 @@
+package riders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const presenceLockName = "rider-presence"
+
+func staleAfter(cfg *config.Config) time.Duration {
+	if cfg.RiderStaleSeconds <= 0 {
+		return 2 * time.Minute
+	}
+	return time.Duration(cfg.RiderStaleSeconds) * time.Second
+}
+
+// Heartbeat records that the rider is still around. It takes the rider id,
+// not the user id, so the WebSocket hub can call it directly.
+func (s *Service) Heartbeat(riderID uint) {
+	if err := s.redisClient.TouchRiderPresence(context.Background(), riderID, time.Now()); err != nil {
+		s.logger.Warn("Failed to record rider heartbeat", zap.Uint("rider_id", riderID), zap.Error(err))
+	}
+}
+
+// GetPresence counts the riders online, available and on a delivery
+func (s *Service) GetPresence() (*PresenceResponse, error) {
+	ctx := context.Background()
+	stale := staleAfter(s.cfg)
+
+	online, err := s.redisClient.CountOnlineRiders(ctx, time.Now().Add(-stale))
+	if err != nil {
+		s.logger.Error("Failed to count online riders", zap.Error(err))
+		return nil, errors.New("failed to get rider presence")
+	}
+	indexed, err := s.redisClient.GetIndexedRiders(ctx)
+	if err != nil {
+		s.logger.Error("Failed to read rider index", zap.Error(err))
+		return nil, errors.New("failed to get rider presence")
+	}
+	available, err := s.repo.CountAvailableRiders()
+	if err != nil {
+		s.logger.Error("Failed to count available riders", zap.Error(err))
+		return nil, errors.New("failed to get rider presence")
+	}
+	busy, err := s.repo.CountBusyRiders()
+	if err != nil {
+		s.logger.Error("Failed to count busy riders", zap.Error(err))
+		return nil, errors.New("failed to get rider presence")
+	}
+
+	return &PresenceResponse{
+		Online:            online,
+		Available:         available,
+		Dispatchable:      len(indexed),
+		OnDelivery:        busy,
+		StaleAfterSeconds: int(stale / time.Second),
+	}, nil
+}
+
+// takeOffline makes a rider who stopped sending heartbeats unavailable, in
+// the database first and then in Redis. A heartbeat that arrived since the
+// sweep read presence keeps the rider online.
+func (s *Service) takeOffline(ctx context.Context, rider *database.Rider, cutoff time.Time) {
+	seen, err := s.redisClient.GetRiderPresence(ctx, []uint{rider.ID})
+	if err != nil {
+		s.logger.Error("Failed to read rider presence", zap.Uint("rider_id", rider.ID), zap.Error(err))
+		return
+	}
+	if last, ok := seen[rider.ID]; ok && !last.Before(cutoff) {
+		return
+	}
+
+	changed, err := s.repo.MarkOffline(rider.ID)
+	if err != nil {
+		s.logger.Error("Failed to mark rider offline", zap.Uint("rider_id", rider.ID), zap.Error(err))
+		return
+	}
+	if changed {
+		if err := s.redisClient.SetRiderUnavailable(ctx, rider.ID); err != nil {
+			s.logger.Error("Failed to remove rider from index", zap.Uint("rider_id", rider.ID), zap.Error(err))
+		}
+		s.notifier.NotifyRider(rider.UserID, "You're Offline",
+			"We haven't heard from your app for a while, so you won't get new deliveries. Go available again when you're back.",
+			"rider_offline", "")
+		s.logger.Info("Took stale rider offline", zap.Uint("rider_id", rider.ID))
+	}
+	if err := s.redisClient.RemoveRiderPresence(ctx, rider.ID); err != nil {
+		s.logger.Warn("Failed to clear rider presence", zap.Uint("rider_id", rider.ID), zap.Error(err))
+	}
+}
+
+// PresenceWorker takes riders offline once they stop sending heartbeats and
+// keeps the geo index in line with the riders the database has available.
+type PresenceWorker struct {
+	service     *Service
+	repo        *Repository
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewPresenceWorker(service *Service, repo *Repository, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *PresenceWorker {
+	return &PresenceWorker{
+		service:     service,
+		repo:        repo,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run sweeps on every interval until ctx is cancelled
+func (w *PresenceWorker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.PresenceSweepInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx, interval)
+		}
+	}
+}
+
+func (w *PresenceWorker) sweep(ctx context.Context, lockTTL time.Duration) {
+	token, ok, err := w.redisClient.AcquireLock(ctx, presenceLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire presence lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), presenceLockName, token); err != nil {
+			w.logger.Warn("Failed to release presence lock", zap.Error(err))
+		}
+	}()
+
+	// The index is read before the database so a rider who goes available
+	// in between isn't taken out of it
+	indexed, err := w.redisClient.GetIndexedRiders(ctx)
+	if err != nil {
+		w.logger.Error("Failed to read rider index", zap.Error(err))
+		return
+	}
+	available, err := w.repo.GetAvailableRiders()
+	if err != nil {
+		w.logger.Error("Failed to load available riders", zap.Error(err))
+		return
+	}
+	ids := make([]uint, len(available))
+	for i := range available {
+		ids[i] = available[i].ID
+	}
+	seen, err := w.redisClient.GetRiderPresence(ctx, ids)
+	if err != nil {
+		w.logger.Error("Failed to read rider presence", zap.Error(err))
+		return
+	}
+
+	cutoff := time.Now().Add(-staleAfter(w.cfg))
+	inIndex := make(map[uint]bool, len(indexed))
+	for _, id := range indexed {
+		inIndex[id] = true
+	}
+	handled := make(map[uint]bool, len(available))
+
+	for i := range available {
+		if ctx.Err() != nil {
+			return
+		}
+		rider := &available[i]
+		handled[rider.ID] = true
+
+		// Riders not heard from since presence was tracked fall back to
+		// their last location post
+		last, ok := seen[rider.ID]
+		if !ok && rider.LastLocationUpdate != nil {
+			last = *rider.LastLocationUpdate
+		}
+		if last.Before(cutoff) {
+			w.service.takeOffline(ctx, rider, cutoff)
+			continue
+		}
+		if !inIndex[rider.ID] && (rider.CurrentLatitude != 0 || rider.CurrentLongitude != 0) {
+			if err := w.redisClient.SetRiderAvailable(ctx, rider.ID, rider.CurrentLatitude, rider.CurrentLongitude); err != nil {
+				w.logger.Error("Failed to restore rider to index", zap.Uint("rider_id", rider.ID), zap.Error(err))
+			}
+		}
+	}
+
+	// Riders the database doesn't have available don't belong in the index
+	for _, id := range indexed {
+		if handled[id] {
+			continue
+		}
+		if err := w.redisClient.SetRiderUnavailable(ctx, id); err != nil {
+			w.logger.Error("Failed to remove rider from index", zap.Uint("rider_id", id), zap.Error(err))
+		}
+	}
+
+	// Busy riders who went quiet stay on their orders; admin hears about it
+	stale, err := w.redisClient.GetStaleRiders(ctx, cutoff)
+	if err != nil {
+		w.logger.Error("Failed to read stale riders", zap.Error(err))
+		return
+	}
+	for _, id := range stale {
+		if handled[id] {
+			continue
+		}
+		underWay, err := w.repo.GetOrdersUnderWay(id)
+		if err != nil {
+			w.logger.Error("Failed to load rider's orders", zap.Uint("rider_id", id), zap.Error(err))
+			continue
+		}
+		if len(underWay) > 0 {
+			w.service.notifier.NotifyAdmin("Rider Offline",
+				fmt.Sprintf("Rider #%d stopped responding with %d orders under way", id, len(underWay)))
+		}
+		if err := w.redisClient.RemoveRiderPresence(ctx, id); err != nil {
+			w.logger.Warn("Failed to clear rider presence", zap.Uint("rider_id", id), zap.Error(err))
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package riders
 
 import (
+	"errors"
 	"food-delivery-backend/database"
+	"food-delivery-backend/orders"
+	"food-delivery-backend/pkg"
 	"time"
 
 	"gorm.io/gorm"
@@ -21,8 +24,36 @@ func (r *Repository) GetRiderByUserID(userID uint) (*database.Rider, error) {
 	return &rider, err
 }
 
-func (r *Repository) UpdateRider(rider *database.Rider) error {
-	return r.db.Save(rider).Error
+func (r *Repository) GetRiderByID(riderID uint) (*database.Rider, error) {
+	var rider database.Rider
+	err := r.db.First(&rider, riderID).Error
+	return &rider, err
+}
+
+// UpdateProfile saves the rider's vehicle and phone only; balances and
+// counters on the row are kept by the ledger and other jobs
+func (r *Repository) UpdateProfile(rider *database.Rider) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.Rider{}).Where("id = ?", rider.ID).
+			Updates(map[string]interface{}{
+				"vehicle_number": rider.VehicleNumber,
+				"vehicle_type":   rider.VehicleType,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&database.User{}).Where("id = ?", rider.UserID).
+			Update("phone", rider.User.Phone).Error
+	})
+}
+
+// SetAvailability turns the rider on or off by hand. Going on also puts
+// them back on shift.
+func (r *Repository) SetAvailability(riderID uint, available bool) error {
+	updates := map[string]interface{}{"is_available": available}
+	if available {
+		updates["off_shift"] = false
+	}
+	return r.db.Model(&database.Rider{}).Where("id = ?", riderID).Updates(updates).Error
 }
 
 func (r *Repository) UpdateLocation(riderID uint, lat, lng float64) error {
@@ -34,12 +65,91 @@ func (r *Repository) UpdateLocation(riderID uint, lat, lng float64) error {
 	}).Error
 }
 
+// GetOrdersUnderWay returns the rider's orders that are waiting to be picked
+// up or on their way
+func (r *Repository) GetOrdersUnderWay(riderID uint) ([]database.Order, error) {
+	var orders []database.Order
+	err := r.db.Where("assigned_rider_id = ? AND status IN ?", riderID,
+		[]database.OrderStatus{database.OrderStatusReady, database.OrderStatusPickedUp}).
+		Preload("Student").
+		Preload("Vendor").
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetLastLocationSample returns when the rider's position was last stored,
+// or the zero time if it never was
+func (r *Repository) GetLastLocationSample(riderID uint) (time.Time, error) {
+	var sample database.RiderLocation
+	err := r.db.Where("rider_id = ?", riderID).Order("id DESC").Limit(1).Find(&sample).Error
+	return sample.CreatedAt, err
+}
+
+func (r *Repository) CreateLocationSamples(samples []database.RiderLocation) error {
+	return r.db.Create(&samples).Error
+}
+
+// DeleteLocationsBefore removes location samples taken before cutoff
+func (r *Repository) DeleteLocationsBefore(cutoff time.Time) (int64, error) {
+	res := r.db.Where("created_at < ?", cutoff).Delete(&database.RiderLocation{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *Repository) GetAvailableRiders() ([]database.Rider, error) {
+	var riders []database.Rider
+	err := r.db.Where("is_available = ?", true).Find(&riders).Error
+	return riders, err
+}
+
+// MarkOffline makes an available rider unavailable and reports whether it
+// did; riders who were just given an order are left alone
+func (r *Repository) MarkOffline(riderID uint) (bool, error) {
+	res := r.db.Model(&database.Rider{}).
+		Where("id = ? AND is_available = ?", riderID, true).
+		Update("is_available", false)
+	return res.RowsAffected > 0, res.Error
+}
+
+// SetOffShift records whether the rider has checked out of their shift
+func (r *Repository) SetOffShift(riderID uint, offShift bool) error {
+	return r.db.Model(&database.Rider{}).Where("id = ?", riderID).
+		Update("off_shift", offShift).Error
+}
+
+// MarkOnline makes an unavailable rider available and reports whether it
+// did; riders with orders under way are left for delivery to free
+func (r *Repository) MarkOnline(riderID uint) (bool, error) {
+	res := r.db.Model(&database.Rider{}).
+		Where("id = ? AND is_available = ?", riderID, false).
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.assigned_rider_id = riders.id AND orders.status NOT IN ? AND orders.deleted_at IS NULL)",
+			orders.TerminalStatuses()).
+		Update("is_available", true)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *Repository) CountAvailableRiders() (int64, error) {
+	var count int64
+	err := r.db.Model(&database.Rider{}).Where("is_available = ?", true).Count(&count).Error
+	return count, err
+}
+
+// CountBusyRiders counts riders holding orders that are still under way
+func (r *Repository) CountBusyRiders() (int64, error) {
+	var count int64
+	err := r.db.Model(&database.Order{}).
+		Where("assigned_rider_id IS NOT NULL AND status NOT IN ?", orders.TerminalStatuses()).
+		Distinct("assigned_rider_id").
+		Count(&count).Error
+	return count, err
+}
+
 func (r *Repository) GetAssignedOrders(riderID uint, status string) ([]database.Order, error) {
 	var orders []database.Order
 	query := r.db.Where("assigned_rider_id = ?", riderID).
 		Preload("Student.User").
 		Preload("Vendor").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Order("created_at DESC")
 
 	if status != "" {
@@ -55,23 +165,40 @@ func (r *Repository) GetOrderByID(orderID uint) (*database.Order, error) {
 	err := r.db.Preload("Student.User").
 		Preload("Vendor").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		First(&order, orderID).Error
 	return &order, err
 }
 
-func (r *Repository) UpdateOrderStatus(orderID uint, status database.OrderStatus, timestamp *time.Time) error {
-	updates := map[string]interface{}{
-		"status": status,
+// GetActiveBatch returns the rider's active batch with all of its orders, or
+// nil when the rider has none
+func (r *Repository) GetActiveBatch(riderID uint) (*database.DeliveryBatch, error) {
+	var batch database.DeliveryBatch
+	err := r.db.Where("rider_id = ? AND status = ?", riderID, database.DeliveryBatchActive).
+		Preload("Orders", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Orders.Vendor").
+		Preload("Orders.Student.User").
+		Order("created_at DESC").
+		First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
 	}
-	if timestamp != nil {
-		switch status {
-		case database.OrderStatusPickedUp:
-			updates["picked_up_at"] = timestamp
-		case database.OrderStatusDelivered:
-			updates["delivered_at"] = timestamp
-		}
+	if err != nil {
+		return nil, err
 	}
-	return r.db.Model(&database.Order{}).Where("id = ?", orderID).Updates(updates).Error
+	return &batch, nil
+}
+
+// GetUnassignedReadyOrders returns ready orders waiting for a rider, oldest
+// first
+func (r *Repository) GetUnassignedReadyOrders(limit int) ([]database.Order, error) {
+	var orders []database.Order
+	err := r.db.Where("status = ? AND assigned_rider_id IS NULL", database.OrderStatusReady).
+		Preload("Vendor").
+		Order("ready_at ASC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
 }
 
 func (r *Repository) GetEarnings(riderID uint, startDate, endDate time.Time) ([]database.Order, error) {
@@ -101,7 +228,7 @@ func (r *Repository) GetDeliveryHistory(riderID uint, offset, limit int) ([]data
 	return orders, total, err
 }
 
-func (r *Repository) GetRiderBalance(riderID uint) (float64, error) {
+func (r *Repository) GetRiderBalance(riderID uint) (pkg.Money, error) {
 	var rider database.Rider
 	err := r.db.Select("current_balance").First(&rider, riderID).Error
 	return rider.CurrentBalance, err
@@ -117,6 +244,7 @@ func (r *Repository) GetAvailableOrders(offset, limit int) ([]database.Order, in
 	err := query.Preload("Student.User").
 		Preload("Vendor").
 		Preload("OrderItems.MenuItem").
+		Preload("OrderItems.Options").
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(limit).
@@ -124,9 +252,3 @@ func (r *Repository) GetAvailableOrders(offset, limit int) ([]database.Order, in
 
 	return orders, total, err
 }
-
-func (r *Repository) AssignOrderToRider(orderID uint, riderID uint) error {
-	// Only assign if currently unassigned
-	return r.db.Model(&database.Order{}).Where("id = ? AND assigned_rider_id IS NULL", orderID).
-		Updates(map[string]interface{}{"assigned_rider_id": riderID}).Error
-}
@@ -3,7 +3,12 @@ package riders
 import (
 	"context"
 	"errors"
+	"food-delivery-backend/cash"
+	"food-delivery-backend/config"
 	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/orders"
+	"food-delivery-backend/pkg"
 	"food-delivery-backend/redis"
 	"time"
 
@@ -12,14 +17,20 @@ import (
 
 type Service struct {
 	repo        *Repository
+	orderFlow   *orders.StateMachine
+	notifier    *notifications.Service
 	redisClient *redis.RedisClient
+	cfg         *config.Config
 	logger      *zap.Logger
 }
 
-func NewService(repo *Repository, redisClient *redis.RedisClient, logger *zap.Logger) *Service {
+func NewService(repo *Repository, orderFlow *orders.StateMachine, notifier *notifications.Service, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *Service {
 	return &Service{
 		repo:        repo,
+		orderFlow:   orderFlow,
+		notifier:    notifier,
 		redisClient: redisClient,
+		cfg:         cfg,
 		logger:      logger,
 	}
 }
@@ -49,7 +60,7 @@ func (s *Service) UpdateRiderProfile(riderID uint, req *UpdateRiderRequest) (*da
 		rider.User.Phone = req.Phone
 	}
 
-	if err := s.repo.UpdateRider(rider); err != nil {
+	if err := s.repo.UpdateProfile(rider); err != nil {
 		s.logger.Error("Failed to update rider", zap.Error(err))
 		return nil, errors.New("failed to update profile")
 	}
@@ -68,6 +79,7 @@ func (s *Service) UpdateLocation(riderID uint, lat, lng float64) error {
 		s.logger.Error("Failed to update rider location", zap.Error(err))
 		return errors.New("failed to update location")
 	}
+	s.Heartbeat(rider.ID)
 
 	// Update in Redis if rider is available
 	if rider.IsAvailable {
@@ -75,9 +87,57 @@ func (s *Service) UpdateLocation(riderID uint, lat, lng float64) error {
 		s.redisClient.SetRiderAvailable(ctx, rider.ID, lat, lng)
 	}
 
+	s.trackOrders(rider.ID, lat, lng, time.Now())
 	return nil
 }
 
+// trackOrders stores the position against each order the rider has under
+// way and streams it to the student and vendor of orders already picked up.
+// Samples are stored at most every LocationSampleSeconds; pushes go out on
+// every update. Failures are logged, the location update itself stands.
+func (s *Service) trackOrders(riderID uint, lat, lng float64, now time.Time) {
+	active, err := s.repo.GetOrdersUnderWay(riderID)
+	if err != nil {
+		s.logger.Error("Failed to load rider's orders", zap.Uint("rider_id", riderID), zap.Error(err))
+		return
+	}
+	if len(active) == 0 {
+		return
+	}
+
+	for _, order := range active {
+		if order.Status != database.OrderStatusPickedUp {
+			continue
+		}
+		s.notifier.PushRiderLocation(order.Student.UserID, order.ID, lat, lng, now)
+		s.notifier.PushRiderLocation(order.Vendor.UserID, order.ID, lat, lng, now)
+	}
+
+	last, err := s.repo.GetLastLocationSample(riderID)
+	if err != nil {
+		s.logger.Error("Failed to load last location sample", zap.Uint("rider_id", riderID), zap.Error(err))
+		return
+	}
+	if now.Sub(last) < time.Duration(s.cfg.LocationSampleSeconds)*time.Second {
+		return
+	}
+
+	samples := make([]database.RiderLocation, 0, len(active))
+	for _, order := range active {
+		samples = append(samples, database.RiderLocation{
+			CreatedAt: now,
+			RiderID:   riderID,
+			OrderID:   order.ID,
+			Status:    order.Status,
+			Latitude:  lat,
+			Longitude: lng,
+		})
+	}
+	if err := s.repo.CreateLocationSamples(samples); err != nil {
+		s.logger.Error("Failed to store location samples", zap.Uint("rider_id", riderID), zap.Error(err))
+	}
+}
+
 func (s *Service) ToggleAvailability(riderID uint) (bool, error) {
 	rider, err := s.repo.GetRiderByUserID(riderID)
 	if err != nil {
@@ -85,8 +145,11 @@ func (s *Service) ToggleAvailability(riderID uint) (bool, error) {
 	}
 
 	rider.IsAvailable = !rider.IsAvailable
+	if rider.IsAvailable {
+		rider.OffShift = false
+	}
 
-	if err := s.repo.UpdateRider(rider); err != nil {
+	if err := s.repo.SetAvailability(rider.ID, rider.IsAvailable); err != nil {
 		s.logger.Error("Failed to toggle rider availability", zap.Error(err))
 		return false, errors.New("failed to update availability")
 	}
@@ -94,6 +157,7 @@ func (s *Service) ToggleAvailability(riderID uint) (bool, error) {
 	// Update Redis
 	ctx := context.Background()
 	if rider.IsAvailable {
+		s.Heartbeat(rider.ID)
 		s.redisClient.SetRiderAvailable(ctx, rider.ID, rider.CurrentLatitude, rider.CurrentLongitude)
 	} else {
 		s.redisClient.SetRiderUnavailable(ctx, rider.ID)
@@ -102,6 +166,41 @@ func (s *Service) ToggleAvailability(riderID uint) (bool, error) {
 	return rider.IsAvailable, nil
 }
 
+// SetOnShift puts the rider on or off shift. Going on makes them available
+// now, or once their deliveries are done; going off takes them off new
+// orders and keeps finishing deliveries from bringing them back. Both use
+// conditional updates, so an assignment or presence sweep racing with them
+// isn't undone.
+func (s *Service) SetOnShift(riderID uint, onShift bool) error {
+	if err := s.repo.SetOffShift(riderID, !onShift); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if !onShift {
+		changed, err := s.repo.MarkOffline(riderID)
+		if err != nil {
+			return err
+		}
+		if changed {
+			s.redisClient.SetRiderUnavailable(ctx, riderID)
+		}
+		return nil
+	}
+
+	changed, err := s.repo.MarkOnline(riderID)
+	if err != nil || !changed {
+		return err
+	}
+	rider, err := s.repo.GetRiderByID(riderID)
+	if err != nil {
+		return err
+	}
+	s.Heartbeat(rider.ID)
+	s.redisClient.SetRiderAvailable(ctx, rider.ID, rider.CurrentLatitude, rider.CurrentLongitude)
+	return nil
+}
+
 func (s *Service) GetAssignedOrders(riderID uint, status string) ([]database.Order, error) {
 	rider, err := s.repo.GetRiderByUserID(riderID)
 	if err != nil {
@@ -141,20 +240,25 @@ func (s *Service) ClaimOrder(riderID uint, orderID uint) error {
 		return errors.New("order already assigned")
 	}
 
-	// Attempt to atomically set assigned rider
-	if err := s.repo.AssignOrderToRider(orderID, rider.ID); err != nil {
+	// The state machine assigns atomically, adds the order to the rider's
+	// batch, marks the rider unavailable in DB and Redis, and records the
+	// claim in the order's event log
+	if _, err := s.orderFlow.AssignRider(orders.Assignment{
+		OrderID:          orderID,
+		RiderID:          rider.ID,
+		ActorID:          riderID,
+		ActorRole:        "rider",
+		Reason:           "claimed by rider",
+		OnlyIfUnassigned: true,
+	}); err != nil {
+		if errors.Is(err, cash.ErrLimitExceeded) || errors.Is(err, orders.ErrBatchFull) ||
+			errors.Is(err, orders.ErrNotBatchable) {
+			return err
+		}
 		s.logger.Error("Failed to assign order to rider", zap.Error(err))
 		return errors.New("failed to claim order")
 	}
 
-	// Mark rider unavailable in DB and Redis
-	rider.IsAvailable = false
-	if err := s.repo.UpdateRider(rider); err != nil {
-		s.logger.Error("Failed to update rider availability after claim", zap.Error(err))
-	}
-	ctx := context.Background()
-	s.redisClient.SetRiderUnavailable(ctx, rider.ID)
-
 	return nil
 }
 
@@ -176,70 +280,29 @@ func (s *Service) GetOrder(riderID uint, orderID uint) (*database.Order, error)
 	return order, nil
 }
 
-func (s *Service) PickUpOrder(riderID uint, orderID uint) error {
-	rider, err := s.repo.GetRiderByUserID(riderID)
-	if err != nil {
-		return errors.New("rider not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.AssignedRiderID == nil || *order.AssignedRiderID != rider.ID {
-		return errors.New("unauthorized to pick up this order")
-	}
-
-	if order.Status != database.OrderStatusReady {
-		return errors.New("order must be ready to pick up")
-	}
-
-	now := time.Now()
-	return s.repo.UpdateOrderStatus(orderID, database.OrderStatusPickedUp, &now)
+func (s *Service) PickUpOrder(riderID uint, orderID uint, handoff *orders.Handoff) error {
+	return s.transition(riderID, orderID, database.OrderStatusPickedUp, handoff)
 }
 
-func (s *Service) DeliverOrder(riderID uint, orderID uint) error {
-	rider, err := s.repo.GetRiderByUserID(riderID)
-	if err != nil {
-		return errors.New("rider not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.AssignedRiderID == nil || *order.AssignedRiderID != rider.ID {
-		return errors.New("unauthorized to deliver this order")
-	}
-
-	if order.Status != database.OrderStatusPickedUp {
-		return errors.New("order must be picked up first")
-	}
-
-	now := time.Now()
-
-	// Update order status
-	if err := s.repo.UpdateOrderStatus(orderID, database.OrderStatusDelivered, &now); err != nil {
-		return err
-	}
-
-	// Update rider availability and earnings
-	rider.IsAvailable = true
-	rider.TotalDeliveries++
-	rider.TotalEarnings += order.RiderEarnings
-	rider.CurrentBalance += order.RiderEarnings
-
-	if err := s.repo.UpdateRider(rider); err != nil {
-		s.logger.Error("Failed to update rider after delivery", zap.Error(err))
-	}
-
-	// Update Redis availability
-	ctx := context.Background()
-	s.redisClient.SetRiderAvailable(ctx, rider.ID, rider.CurrentLatitude, rider.CurrentLongitude)
+// DeliverOrder marks the order delivered. Earnings, vendor balance,
+// availability and the cash the rider collected on cash orders are recorded
+// by the state machine's delivery hooks.
+func (s *Service) DeliverOrder(riderID uint, orderID uint, handoff *orders.Handoff) error {
+	return s.transition(riderID, orderID, database.OrderStatusDelivered, handoff)
+}
 
-	return nil
+// transition routes a rider action through the order state machine, which
+// checks the rider is assigned and the transition is allowed. riderID is the
+// user id. The handoff code, when one is required, is checked there too.
+func (s *Service) transition(riderID uint, orderID uint, to database.OrderStatus, handoff *orders.Handoff) error {
+	_, err := s.orderFlow.Apply(orders.TransitionRequest{
+		OrderID:   orderID,
+		To:        to,
+		ActorID:   riderID,
+		ActorRole: "rider",
+		Handoff:   handoff,
+	})
+	return err
 }
 
 func (s *Service) GetEarnings(riderID uint, startDateStr, endDateStr string) (*RiderEarningsResponse, error) {
@@ -282,7 +345,7 @@ func (s *Service) GetEarnings(riderID uint, startDateStr, endDateStr string) (*R
 
 	dailyMap := make(map[string]*DailyEarnings)
 	var totalDeliveries int
-	var totalEarnings float64
+	var totalEarnings pkg.Money
 
 	for _, order := range orders {
 		date := order.DeliveredAt.Format("2006-01-02")
@@ -307,7 +370,7 @@ func (s *Service) GetEarnings(riderID uint, startDateStr, endDateStr string) (*R
 	response.Summary.TotalDeliveries = totalDeliveries
 	response.Summary.TotalEarnings = totalEarnings
 	if totalDeliveries > 0 {
-		response.Summary.AveragePerDelivery = totalEarnings / float64(totalDeliveries)
+		response.Summary.AveragePerDelivery = totalEarnings.Div(int64(totalDeliveries))
 	}
 
 	balance, _ := s.repo.GetRiderBalance(rider.ID)
@@ -0,0 +1,79 @@
+package riders
+
+import (
+	"context"
+	"food-delivery-backend/config"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const retentionLockName = "rider-location-retention"
+
+// RetentionWorker deletes rider location samples once they are older than
+// LocationRetentionDays. Routes stay available for disputes until then.
+type RetentionWorker struct {
+	repo        *Repository
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewRetentionWorker(repo *Repository, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *RetentionWorker {
+	return &RetentionWorker{
+		repo:        repo,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run prunes on every interval until ctx is cancelled
+func (w *RetentionWorker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.LocationPruneInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.prune(ctx, interval)
+		}
+	}
+}
+
+func (w *RetentionWorker) prune(ctx context.Context, lockTTL time.Duration) {
+	if w.cfg.LocationRetentionDays <= 0 {
+		return
+	}
+
+	token, ok, err := w.redisClient.AcquireLock(ctx, retentionLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire location retention lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), retentionLockName, token); err != nil {
+			w.logger.Warn("Failed to release location retention lock", zap.Error(err))
+		}
+	}()
+
+	cutoff := time.Now().AddDate(0, 0, -w.cfg.LocationRetentionDays)
+	deleted, err := w.repo.DeleteLocationsBefore(cutoff)
+	if err != nil {
+		w.logger.Error("Failed to prune rider locations", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Pruned rider locations", zap.Int64("deleted", deleted), zap.Time("before", cutoff))
+	}
+}
@@ -3,13 +3,23 @@ package routes
 import (
 	"food-delivery-backend/admin"
 	"food-delivery-backend/auth"
+	"food-delivery-backend/cash"
+	"food-delivery-backend/coupons"
+	"food-delivery-backend/dispatch"
+	"food-delivery-backend/hours"
+	"food-delivery-backend/ledger"
 	"food-delivery-backend/middleware"
 	"food-delivery-backend/notifications"
 	"food-delivery-backend/orders"
+	"food-delivery-backend/payments"
 	"food-delivery-backend/pkg"
+	"food-delivery-backend/refunds"
 	"food-delivery-backend/riders"
+	"food-delivery-backend/shifts"
 	"food-delivery-backend/users"
 	"food-delivery-backend/vendors"
+	"food-delivery-backend/wallet"
+	"food-delivery-backend/withdrawals"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -23,6 +33,16 @@ func SetupRoutes(
 	ridersHandler *riders.Handler,
 	ordersHandler *orders.Handler,
 	adminHandler *admin.Handler,
+	couponsHandler *coupons.Handler,
+	ledgerHandler *ledger.Handler,
+	withdrawalsHandler *withdrawals.Handler,
+	paymentsHandler *payments.Handler,
+	walletHandler *wallet.Handler,
+	refundsHandler *refunds.Handler,
+	cashHandler *cash.Handler,
+	hoursHandler *hours.Handler,
+	dispatchHandler *dispatch.Handler,
+	shiftsHandler *shifts.Handler,
 	notificationsHandler *notifications.Handler,
 	wsHub *notifications.Hub,
 	jwtMaker *pkg.JWTMaker,
@@ -44,6 +64,9 @@ func SetupRoutes(
 			public.POST("/refresh", authHandler.RefreshToken)
 		}
 
+		// Payment provider webhooks are authenticated by signature
+		v1.POST("/payments/webhook", paymentsHandler.Webhook)
+
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(middleware.AuthMiddleware(jwtMaker))
@@ -82,8 +105,12 @@ func SetupRoutes(
 			{
 				orderRoutes.POST("", ordersHandler.CreateOrder)
 				orderRoutes.POST("/", ordersHandler.CreateOrder)
+				orderRoutes.POST("/quote", ordersHandler.QuoteOrder)
 				orderRoutes.GET("/:id", ordersHandler.GetOrder)
 				orderRoutes.GET("/:id/track", ordersHandler.TrackOrder)
+				orderRoutes.GET("/:id/events", ordersHandler.GetOrderEvents)
+				orderRoutes.GET("/:id/route", ordersHandler.GetRoute)
+				orderRoutes.GET("/:id/handoff-code", ordersHandler.GetHandoffCode)
 				orderRoutes.POST("/:id/cancel", ordersHandler.CancelOrder)
 				orderRoutes.POST("/:id/rate", ordersHandler.RateOrder)
 			}
@@ -93,6 +120,12 @@ func SetupRoutes(
 			studentRoutes.Use(middleware.RequireRole("student"))
 			{
 				studentRoutes.GET("/orders", ordersHandler.GetStudentOrders)
+
+				// Wallet
+				studentRoutes.GET("/wallet", walletHandler.GetWallet)
+				studentRoutes.GET("/wallet/statement", walletHandler.GetStatement)
+				studentRoutes.GET("/wallet/top-ups", walletHandler.GetTopUps)
+				studentRoutes.POST("/wallet/top-ups", walletHandler.TopUp)
 			}
 
 			// Vendor specific routes
@@ -103,6 +136,18 @@ func SetupRoutes(
 				vendorRoutes.GET("/profile", vendorsHandler.GetVendorProfile)
 				vendorRoutes.PUT("/profile", vendorsHandler.UpdateVendorProfile)
 				vendorRoutes.POST("/toggle-status", vendorsHandler.ToggleOpenStatus)
+
+				// Kitchen capacity and busy mode
+				vendorRoutes.GET("/kitchen", vendorsHandler.GetKitchen)
+				vendorRoutes.PUT("/capacity", vendorsHandler.UpdateCapacity)
+				vendorRoutes.POST("/busy", vendorsHandler.SetBusy)
+				vendorRoutes.DELETE("/busy", vendorsHandler.ClearBusy)
+
+				// Opening hours
+				vendorRoutes.GET("/hours", hoursHandler.GetHours)
+				vendorRoutes.PUT("/hours", hoursHandler.UpdateHours)
+				vendorRoutes.POST("/holidays", hoursHandler.AddHoliday)
+				vendorRoutes.DELETE("/holidays/:id", hoursHandler.DeleteHoliday)
 				// Add this route in the vendor routes section
 
 				vendorRoutes.POST("/upload-image", vendorsHandler.UploadImage)
@@ -113,6 +158,13 @@ func SetupRoutes(
 				vendorRoutes.PUT("/menu/:id", vendorsHandler.UpdateMenuItem)
 				vendorRoutes.DELETE("/menu/:id", vendorsHandler.DeleteMenuItem)
 				vendorRoutes.POST("/menu/:id/toggle", vendorsHandler.ToggleMenuItemAvailability)
+				vendorRoutes.GET("/menu/:id/options", vendorsHandler.GetOptionGroups)
+				vendorRoutes.POST("/menu/:id/options", vendorsHandler.AddOptionGroup)
+				vendorRoutes.PUT("/menu/:id/options/:group_id", vendorsHandler.UpdateOptionGroup)
+				vendorRoutes.DELETE("/menu/:id/options/:group_id", vendorsHandler.DeleteOptionGroup)
+				vendorRoutes.POST("/menu/:id/options/:group_id/choices", vendorsHandler.AddOption)
+				vendorRoutes.PUT("/menu/:id/options/:group_id/choices/:option_id", vendorsHandler.UpdateOption)
+				vendorRoutes.DELETE("/menu/:id/options/:group_id/choices/:option_id", vendorsHandler.DeleteOption)
 
 				// Order management
 				vendorRoutes.GET("/orders", vendorsHandler.GetOrders)
@@ -125,6 +177,14 @@ func SetupRoutes(
 
 				// Earnings
 				vendorRoutes.GET("/earnings", vendorsHandler.GetEarnings)
+				vendorRoutes.GET("/statement", ledgerHandler.GetMyStatement)
+				vendorRoutes.GET("/withdrawals", withdrawalsHandler.GetMyWithdrawals)
+				vendorRoutes.POST("/withdrawals", withdrawalsHandler.RequestWithdrawal)
+				vendorRoutes.POST("/withdrawals/:id/cancel", withdrawalsHandler.CancelWithdrawal)
+
+				// Cash collected by riders
+				vendorRoutes.GET("/cash-handovers", cashHandler.GetVendorHandovers)
+				vendorRoutes.POST("/cash-handovers", cashHandler.RecordHandover)
 			}
 
 			// Rider specific routes
@@ -143,10 +203,35 @@ func SetupRoutes(
 				riderRoutes.GET("/orders/:id", ridersHandler.GetOrder)
 				riderRoutes.POST("/orders/:id/pickup", ridersHandler.PickUpOrder)
 				riderRoutes.POST("/orders/:id/deliver", ridersHandler.DeliverOrder)
+				riderRoutes.POST("/orders/:id/release", dispatchHandler.ReleaseOrder)
+
+				// Delivery batches
+				riderRoutes.GET("/batch", ridersHandler.GetBatch)
+				riderRoutes.GET("/batch/suggestions", ridersHandler.GetBatchSuggestions)
+				riderRoutes.POST("/batch/claim", ridersHandler.ClaimBatch)
+
+				// Delivery offers
+				riderRoutes.GET("/offers", dispatchHandler.GetMyOffers)
+				riderRoutes.POST("/offers/:id/accept", dispatchHandler.AcceptOffer)
+				riderRoutes.POST("/offers/:id/decline", dispatchHandler.DeclineOffer)
+
+				// Shifts
+				riderRoutes.GET("/zones", shiftsHandler.GetZones)
+				riderRoutes.GET("/shifts", shiftsHandler.GetMyShifts)
+				riderRoutes.GET("/shifts/slots", shiftsHandler.GetOpenSlots)
+				riderRoutes.POST("/shifts/slots/:id/book", shiftsHandler.BookSlot)
+				riderRoutes.POST("/shifts/:id/cancel", shiftsHandler.CancelBooking)
+				riderRoutes.POST("/shifts/:id/check-in", shiftsHandler.CheckIn)
+				riderRoutes.POST("/shifts/:id/check-out", shiftsHandler.CheckOut)
 
 				// Earnings
 				riderRoutes.GET("/earnings", ridersHandler.GetEarnings)
 				riderRoutes.GET("/deliveries", ridersHandler.GetDeliveryHistory)
+				riderRoutes.GET("/statement", ledgerHandler.GetMyStatement)
+				riderRoutes.GET("/withdrawals", withdrawalsHandler.GetMyWithdrawals)
+				riderRoutes.POST("/withdrawals", withdrawalsHandler.RequestWithdrawal)
+				riderRoutes.POST("/withdrawals/:id/cancel", withdrawalsHandler.CancelWithdrawal)
+				riderRoutes.GET("/cash", cashHandler.GetMyCash)
 			}
 
 			// Admin specific routes
@@ -168,16 +253,80 @@ func SetupRoutes(
 
 				// Rider management
 				adminRoutes.GET("/riders", adminHandler.GetRiders)
+				adminRoutes.GET("/riders/online", ridersHandler.GetPresence)
 				adminRoutes.GET("/riders/:id/performance", adminHandler.GetRiderPerformance)
+				adminRoutes.PUT("/riders/:id/cash-limit", cashHandler.SetLimit)
+
+				// Delivery zones and rider shifts
+				adminRoutes.GET("/zones", shiftsHandler.GetZones)
+				adminRoutes.POST("/zones", shiftsHandler.CreateZone)
+				adminRoutes.PUT("/zones/:id", shiftsHandler.UpdateZone)
+				adminRoutes.GET("/shifts/slots", shiftsHandler.GetSlots)
+				adminRoutes.POST("/shifts/slots", shiftsHandler.CreateSlot)
+				adminRoutes.PUT("/shifts/slots/:id", shiftsHandler.UpdateSlot)
+				adminRoutes.DELETE("/shifts/slots/:id", shiftsHandler.DeleteSlot)
+				adminRoutes.GET("/shifts/bookings", shiftsHandler.GetBookings)
+				adminRoutes.GET("/shifts/coverage", shiftsHandler.GetCoverage)
 
 				// Order management
 				adminRoutes.GET("/orders", adminHandler.GetOrders)
 				adminRoutes.GET("/orders/:id", adminHandler.GetOrder)
 				adminRoutes.POST("/orders/:id/assign-rider", adminHandler.AssignRider)
+				adminRoutes.POST("/orders/:id/redispatch", dispatchHandler.Redispatch)
+				adminRoutes.POST("/orders/:id/handoff-override", ordersHandler.OverrideHandoff)
+				adminRoutes.GET("/orders/:id/handoffs", ordersHandler.GetHandoffProofs)
+				adminRoutes.GET("/dispatch/offers", dispatchHandler.GetOffers)
+				adminRoutes.POST("/orders/:id/refunds", refundsHandler.IssueRefund)
+
+				// Pricing
+				adminRoutes.GET("/pricing", adminHandler.GetPricing)
+				adminRoutes.PUT("/pricing", adminHandler.UpdatePricing)
+				adminRoutes.POST("/pricing/peak-hours", adminHandler.AddPeakHour)
+				adminRoutes.DELETE("/pricing/peak-hours/:id", adminHandler.DeletePeakHour)
+
+				// Coupons
+				adminRoutes.GET("/coupons", couponsHandler.GetCoupons)
+				adminRoutes.POST("/coupons", couponsHandler.CreateCoupon)
+				adminRoutes.GET("/coupons/:id", couponsHandler.GetCoupon)
+				adminRoutes.PUT("/coupons/:id", couponsHandler.UpdateCoupon)
+				adminRoutes.DELETE("/coupons/:id", couponsHandler.DeleteCoupon)
+				adminRoutes.GET("/coupons/:id/redemptions", couponsHandler.GetCouponRedemptions)
+
+				// Ledger
+				adminRoutes.GET("/ledger/balances", ledgerHandler.GetBalances)
+				adminRoutes.GET("/ledger/entries", ledgerHandler.GetEntries)
+				adminRoutes.GET("/ledger/entries/:id", ledgerHandler.GetEntry)
+				adminRoutes.GET("/ledger/reconcile", ledgerHandler.Reconcile)
+				adminRoutes.POST("/ledger/reconcile", ledgerHandler.FixBalances)
+				adminRoutes.POST("/ledger/adjustments", ledgerHandler.CreateAdjustment)
+				adminRoutes.POST("/ledger/payouts", ledgerHandler.RecordPayout)
+				adminRoutes.GET("/ledger/statements", ledgerHandler.GetStatement)
+
+				// Withdrawals
+				adminRoutes.GET("/withdrawals", withdrawalsHandler.GetWithdrawals)
+				adminRoutes.GET("/withdrawals/:id", withdrawalsHandler.GetWithdrawal)
+				adminRoutes.POST("/withdrawals/:id/approve", withdrawalsHandler.ApproveWithdrawal)
+				adminRoutes.POST("/withdrawals/:id/reject", withdrawalsHandler.RejectWithdrawal)
+				adminRoutes.POST("/withdrawals/:id/mark-paid", withdrawalsHandler.MarkPaid)
+
+				// Wallets
+				adminRoutes.POST("/wallets/credits", walletHandler.Credit)
+
+				// Refunds
+				adminRoutes.GET("/refunds", refundsHandler.GetRefunds)
+				adminRoutes.GET("/refunds/:id", refundsHandler.GetRefund)
+				adminRoutes.POST("/refunds/:id/retry", refundsHandler.RetryRefund)
+
+				// Cash on delivery
+				adminRoutes.GET("/cash-handovers", cashHandler.GetHandovers)
+				adminRoutes.POST("/cash-handovers", cashHandler.RecordHandover)
 
 				// Reports
 				adminRoutes.GET("/reports/revenue", adminHandler.GetRevenueReport)
+				adminRoutes.GET("/reports/coupons", couponsHandler.GetRedemptionReport)
 				adminRoutes.GET("/reports/status-summary", adminHandler.GetStatusSummaryReport)
+				adminRoutes.GET("/reports/cash", cashHandler.GetDailyReport)
+				adminRoutes.GET("/reports/dispatch", dispatchHandler.GetReport)
 			}
 		}
 
@@ -186,6 +335,7 @@ func SetupRoutes(
 		{
 			publicVendor.GET("/vendors", vendorsHandler.GetPublicVendors)
 			publicVendor.GET("/vendors/:id/menu", vendorsHandler.GetPublicMenu)
+			publicVendor.GET("/vendors/:id/slots", ordersHandler.GetSlots)
 			publicVendor.GET("/menu/:id", vendorsHandler.GetPublicMenuItem)
 		}
 	}
@@ -0,0 +1,200 @@
+package shifts
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// GetCoverage lays out a day hour by hour for each active zone, or just the
+// given one, comparing the riders booked with the riders the forecast needs.
+// The forecast averages the orders placed in the same hour on the same
+// weekday over the last ForecastWeeks weeks.
+func (s *Service) GetCoverage(dateStr string, zoneID *uint) (*Coverage, error) {
+	day, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return nil, errors.New("invalid date format")
+	}
+
+	zones, err := s.repo.GetZones(true)
+	if err != nil {
+		s.logger.Error("Failed to get zones", zap.Error(err))
+		return nil, errors.New("failed to get coverage")
+	}
+	if zoneID != nil {
+		found := false
+		for _, z := range zones {
+			if z.ID == *zoneID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrZoneNotFound
+		}
+	}
+
+	weeks := s.cfg.ForecastWeeks
+	if weeks <= 0 {
+		weeks = 4
+	}
+	perRider := s.cfg.RiderOrdersPerHour
+	if perRider <= 0 {
+		perRider = 1
+	}
+
+	coverage := &Coverage{
+		Date:          dateStr,
+		ForecastWeeks: weeks,
+		OrdersPerHour: perRider,
+		Zones:         []ZoneCoverage{},
+	}
+	for i := range zones {
+		zone := &zones[i]
+		if zoneID != nil && zone.ID != *zoneID {
+			continue
+		}
+		zc, err := s.zoneCoverage(zone, zones, day, weeks, perRider)
+		if err != nil {
+			s.logger.Error("Failed to build zone coverage", zap.Uint("zone_id", zone.ID), zap.Error(err))
+			return nil, errors.New("failed to get coverage")
+		}
+		coverage.Zones = append(coverage.Zones, *zc)
+	}
+	return coverage, nil
+}
+
+func (s *Service) zoneCoverage(zone *database.DeliveryZone, zones []database.DeliveryZone, day time.Time, weeks int, perRider float64) (*ZoneCoverage, error) {
+	loc := zoneLocation(zone)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+
+	zc := &ZoneCoverage{Zone: *zone, Date: start.Format("2006-01-02"), Hours: make([]CoverageHour, 24)}
+	for h := range zc.Hours {
+		zc.Hours[h].Hour = h
+		zc.Hours[h].StartsAt = time.Date(start.Year(), start.Month(), start.Day(), h, 0, 0, 0, loc)
+	}
+
+	if err := s.addBookings(zc, zone.ID, start, end); err != nil {
+		return nil, err
+	}
+	if err := s.addForecast(zc, zone, zones, start, weeks); err != nil {
+		return nil, err
+	}
+
+	for h := range zc.Hours {
+		hour := &zc.Hours[h]
+		hour.ForecastOrders = math.Round(hour.ForecastOrders*10) / 10
+		hour.RidersNeeded = int(math.Ceil(hour.ForecastOrders / perRider))
+		hour.Gap = hour.Booked - hour.RidersNeeded
+		if hour.Gap < 0 {
+			zc.ShortHours++
+		}
+	}
+	return zc, nil
+}
+
+// addBookings counts the places and riders on the zone's slots in each hour
+// they overlap
+func (s *Service) addBookings(zc *ZoneCoverage, zoneID uint, start, end time.Time) error {
+	all, err := s.repo.GetSlotsOverlapping(start, end)
+	if err != nil {
+		return err
+	}
+	var slots []database.ShiftSlot
+	ids := make([]uint, 0, len(all))
+	for _, slot := range all {
+		if slot.ZoneID == zoneID {
+			slots = append(slots, slot)
+			ids = append(ids, slot.ID)
+		}
+	}
+	bookings, err := s.repo.GetSlotBookings(ids)
+	if err != nil {
+		return err
+	}
+	bySlot := make(map[uint][]database.ShiftBooking)
+	for _, b := range bookings {
+		bySlot[b.SlotID] = append(bySlot[b.SlotID], b)
+	}
+
+	for h := range zc.Hours {
+		hour := &zc.Hours[h]
+		hourEnd := hour.StartsAt.Add(time.Hour)
+		if h < len(zc.Hours)-1 {
+			hourEnd = zc.Hours[h+1].StartsAt
+		}
+		for _, slot := range slots {
+			if !slot.StartsAt.Before(hourEnd) || !slot.EndsAt.After(hour.StartsAt) {
+				continue
+			}
+			hour.Capacity += slot.Capacity
+			for _, b := range bySlot[slot.ID] {
+				switch b.Status {
+				case database.ShiftBookingNoShow:
+					hour.NoShows++
+				case database.ShiftBookingCheckedIn, database.ShiftBookingCompleted:
+					hour.Booked++
+					hour.CheckedIn++
+				default:
+					hour.Booked++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addForecast averages the orders placed in the zone in each hour of the
+// same weekday over past weeks. Orders count towards the nearest active zone
+// their vendor is in.
+func (s *Service) addForecast(zc *ZoneCoverage, zone *database.DeliveryZone, zones []database.DeliveryZone, start time.Time, weeks int) error {
+	loc := start.Location()
+	for w := 1; w <= weeks; w++ {
+		from := start.AddDate(0, 0, -7*w)
+		placements, err := s.repo.GetOrderPlacements(from, from.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+
+		var vendorIDs []uint
+		seen := make(map[uint]bool)
+		for _, p := range placements {
+			if !seen[p.VendorID] {
+				seen[p.VendorID] = true
+				vendorIDs = append(vendorIDs, p.VendorID)
+			}
+		}
+		vendors, err := s.repo.GetVendorLocations(vendorIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range placements {
+			vendor, ok := vendors[p.VendorID]
+			if !ok || zoneOf(&vendor, zones) != zone.ID {
+				continue
+			}
+			hour := p.CreatedAt.In(loc).Hour()
+			zc.Hours[hour].ForecastOrders += 1 / float64(weeks)
+		}
+	}
+	return nil
+}
+
+// zoneOf returns the nearest zone whose radius takes in the vendor, or 0
+func zoneOf(vendor *database.Vendor, zones []database.DeliveryZone) uint {
+	var best uint
+	bestKm := math.MaxFloat64
+	for _, z := range zones {
+		km := pkg.CalculateDistance(vendor.Latitude, vendor.Longitude, z.Latitude, z.Longitude)
+		if km <= z.RadiusKm && km < bestKm {
+			best, bestKm = z.ID, km
+		}
+	}
+	return best
+}
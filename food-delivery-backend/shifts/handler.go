@@ -0,0 +1,451 @@
+package shifts
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRiderNotFound), errors.Is(err, ErrZoneNotFound),
+		errors.Is(err, ErrSlotNotFound), errors.Is(err, ErrBookingNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrZoneExists), errors.Is(err, ErrSlotFull), errors.Is(err, ErrAlreadyBooked),
+		errors.Is(err, ErrShiftOverlap), errors.Is(err, ErrSlotBooked), errors.Is(err, ErrBelowBooked),
+		errors.Is(err, ErrNotBooked), errors.Is(err, ErrNotCheckedIn):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// slotFilters reads zone_id and the from/to dates (YYYY-MM-DD, to inclusive)
+func slotFilters(c *gin.Context) (*SlotFilters, error) {
+	filters := &SlotFilters{}
+	if zoneID, err := strconv.ParseUint(c.Query("zone_id"), 10, 32); err == nil {
+		id := uint(zoneID)
+		filters.ZoneID = &id
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, errors.New("invalid from date format")
+		}
+		filters.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, errors.New("invalid to date format")
+		}
+		filters.To = t.AddDate(0, 0, 1)
+	}
+	return filters, nil
+}
+
+// GetZones lists delivery zones, only active ones for riders
+// @Summary List delivery zones
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]database.DeliveryZone}
+// @Router /admin/zones [get]
+func (h *Handler) GetZones(c *gin.Context) {
+	zones, err := h.service.GetZones(c.GetString("user_role") != "admin")
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get zones", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Zones retrieved successfully", zones)
+}
+
+// GetOpenSlots lists shift slots the rider can book
+// @Summary List shift slots
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Param zone_id query int false "Zone ID"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Success 200 {object} pkg.Response{data=[]Slot}
+// @Router /riders/shifts/slots [get]
+func (h *Handler) GetOpenSlots(c *gin.Context) {
+	filters, err := slotFilters(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	slots, err := h.service.GetOpenSlots(c.GetUint("user_id"), filters)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get shift slots", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Shift slots retrieved successfully", slots)
+}
+
+// BookSlot books the rider onto a shift slot
+// @Summary Book a shift
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Slot ID"
+// @Success 201 {object} pkg.Response{data=database.ShiftBooking}
+// @Router /riders/shifts/slots/{id}/book [post]
+func (h *Handler) BookSlot(c *gin.Context) {
+	slotID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid slot ID", nil)
+		return
+	}
+
+	booking, err := h.service.BookSlot(c.GetUint("user_id"), slotID)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to book shift", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Shift booked", booking)
+}
+
+// GetMyShifts lists the rider's current and upcoming shifts
+// @Summary Get my shifts
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]database.ShiftBooking}
+// @Router /riders/shifts [get]
+func (h *Handler) GetMyShifts(c *gin.Context) {
+	bookings, err := h.service.GetMyShifts(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get shifts", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Shifts retrieved successfully", bookings)
+}
+
+// CancelBooking gives up a booked shift
+// @Summary Cancel a shift
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Success 200 {object} pkg.Response
+// @Router /riders/shifts/{id}/cancel [post]
+func (h *Handler) CancelBooking(c *gin.Context) {
+	bookingID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid booking ID", nil)
+		return
+	}
+
+	if err := h.service.CancelBooking(c.GetUint("user_id"), bookingID); err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to cancel shift", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Shift cancelled", nil)
+}
+
+// CheckIn starts the shift and makes the rider available
+// @Summary Check in to a shift
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Success 200 {object} pkg.Response{data=database.ShiftBooking}
+// @Router /riders/shifts/{id}/check-in [post]
+func (h *Handler) CheckIn(c *gin.Context) {
+	bookingID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid booking ID", nil)
+		return
+	}
+
+	booking, err := h.service.CheckIn(c.GetUint("user_id"), bookingID)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to check in", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Checked in", booking)
+}
+
+// CheckOut ends the shift and takes the rider off new orders
+// @Summary Check out of a shift
+// @Tags Riders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Success 200 {object} pkg.Response{data=database.ShiftBooking}
+// @Router /riders/shifts/{id}/check-out [post]
+func (h *Handler) CheckOut(c *gin.Context) {
+	bookingID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid booking ID", nil)
+		return
+	}
+
+	booking, err := h.service.CheckOut(c.GetUint("user_id"), bookingID)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to check out", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Checked out", booking)
+}
+
+// CreateZone adds a delivery zone
+// @Summary Create delivery zone
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ZoneRequest true "Zone"
+// @Success 201 {object} pkg.Response{data=database.DeliveryZone}
+// @Router /admin/zones [post]
+func (h *Handler) CreateZone(c *gin.Context) {
+	var req ZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	zone, err := h.service.CreateZone(&req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to create zone", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Zone created", zone)
+}
+
+// UpdateZone edits a delivery zone
+// @Summary Update delivery zone
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Param request body ZoneRequest true "Zone"
+// @Success 200 {object} pkg.Response{data=database.DeliveryZone}
+// @Router /admin/zones/{id} [put]
+func (h *Handler) UpdateZone(c *gin.Context) {
+	zoneID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid zone ID", nil)
+		return
+	}
+
+	var req ZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	zone, err := h.service.UpdateZone(zoneID, &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to update zone", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Zone updated", zone)
+}
+
+// GetSlots lists shift slots with how many riders are booked
+// @Summary List shift slots
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param zone_id query int false "Zone ID"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Success 200 {object} pkg.Response{data=[]database.ShiftSlot}
+// @Router /admin/shifts/slots [get]
+func (h *Handler) GetSlots(c *gin.Context) {
+	filters, err := slotFilters(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	slots, err := h.service.GetSlots(filters)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get shift slots", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Shift slots retrieved successfully", slots)
+}
+
+// CreateSlot opens a shift slot for riders to book
+// @Summary Create shift slot
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SlotRequest true "Slot"
+// @Success 201 {object} pkg.Response{data=database.ShiftSlot}
+// @Router /admin/shifts/slots [post]
+func (h *Handler) CreateSlot(c *gin.Context) {
+	var req SlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	slot, err := h.service.CreateSlot(&req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to create shift slot", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Shift slot created", slot)
+}
+
+// UpdateSlot changes a shift slot's times or capacity
+// @Summary Update shift slot
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Slot ID"
+// @Param request body UpdateSlotRequest true "Changes"
+// @Success 200 {object} pkg.Response{data=database.ShiftSlot}
+// @Router /admin/shifts/slots/{id} [put]
+func (h *Handler) UpdateSlot(c *gin.Context) {
+	slotID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid slot ID", nil)
+		return
+	}
+
+	var req UpdateSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	slot, err := h.service.UpdateSlot(slotID, &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to update shift slot", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Shift slot updated", slot)
+}
+
+// DeleteSlot removes a shift slot nobody has booked
+// @Summary Delete shift slot
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Slot ID"
+// @Success 200 {object} pkg.Response
+// @Router /admin/shifts/slots/{id} [delete]
+func (h *Handler) DeleteSlot(c *gin.Context) {
+	slotID, ok := parseID(c)
+	if !ok {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid slot ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteSlot(slotID); err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to delete shift slot", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Shift slot deleted", nil)
+}
+
+// GetBookings lists shift bookings, such as a rider's no-shows
+// @Summary List shift bookings
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param slot_id query int false "Slot ID"
+// @Param rider_id query int false "Rider ID"
+// @Param status query string false "booked, checked_in, completed, cancelled or no_show"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/shifts/bookings [get]
+func (h *Handler) GetBookings(c *gin.Context) {
+	filters := BookingFilters{Status: database.ShiftBookingStatus(c.Query("status"))}
+	if slotID, err := strconv.ParseUint(c.Query("slot_id"), 10, 32); err == nil {
+		id := uint(slotID)
+		filters.SlotID = &id
+	}
+	if riderID, err := strconv.ParseUint(c.Query("rider_id"), 10, 32); err == nil {
+		id := uint(riderID)
+		filters.RiderID = &id
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	bookings, total, err := h.service.GetBookings(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get bookings", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Bookings retrieved successfully", bookings, page, limit, total)
+}
+
+// GetCoverage compares riders booked with forecast demand, hour by hour
+// @Summary Get shift coverage
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param date query string true "Date (YYYY-MM-DD)"
+// @Param zone_id query int false "Zone ID"
+// @Success 200 {object} pkg.Response{data=Coverage}
+// @Router /admin/shifts/coverage [get]
+func (h *Handler) GetCoverage(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		pkg.SendError(c, http.StatusBadRequest, "Date is required", nil)
+		return
+	}
+	var zoneID *uint
+	if id, err := strconv.ParseUint(c.Query("zone_id"), 10, 32); err == nil {
+		zone := uint(id)
+		zoneID = &zone
+	}
+
+	coverage, err := h.service.GetCoverage(date, zoneID)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to get coverage", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Coverage retrieved successfully", coverage)
+}
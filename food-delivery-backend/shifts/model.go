@@ -0,0 +1,78 @@
+package shifts
+
+import (
+	"food-delivery-backend/database"
+	"time"
+)
+
+type ZoneRequest struct {
+	Name      string  `json:"name" binding:"required"`
+	Latitude  float64 `json:"latitude" binding:"required"`
+	Longitude float64 `json:"longitude" binding:"required"`
+	RadiusKm  float64 `json:"radius_km" binding:"required,gt=0"`
+	Timezone  string  `json:"timezone"` // IANA name, UTC if empty
+	IsActive  *bool   `json:"is_active"`
+}
+
+type SlotRequest struct {
+	ZoneID   uint      `json:"zone_id" binding:"required"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Capacity int       `json:"capacity" binding:"required,min=1"`
+}
+
+// UpdateSlotRequest changes a slot. Times can only move while nobody is
+// booked; capacity can't drop below the riders already booked.
+type UpdateSlotRequest struct {
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Capacity *int       `json:"capacity" binding:"omitempty,min=1"`
+}
+
+type SlotFilters struct {
+	ZoneID *uint
+	From   time.Time
+	To     time.Time
+}
+
+type BookingFilters struct {
+	SlotID  *uint
+	RiderID *uint
+	Status  database.ShiftBookingStatus
+}
+
+// Slot is a shift slot as riders see it when booking
+type Slot struct {
+	database.ShiftSlot
+	Remaining int                          `json:"remaining"`
+	MyBooking *database.ShiftBookingStatus `json:"my_booking,omitempty"` // the rider's booking on it, if any
+}
+
+// CoverageHour compares the riders booked for an hour with the riders the
+// forecast says are needed
+type CoverageHour struct {
+	Hour           int       `json:"hour"` // 0-23 in the zone's timezone
+	StartsAt       time.Time `json:"starts_at"`
+	Capacity       int       `json:"capacity"`   // places on slots covering the hour
+	Booked         int       `json:"booked"`     // riders booked, checked in or done
+	CheckedIn      int       `json:"checked_in"` // riders who checked in, so far
+	NoShows        int       `json:"no_shows"`
+	ForecastOrders float64   `json:"forecast_orders"`
+	RidersNeeded   int       `json:"riders_needed"`
+	Gap            int       `json:"gap"` // booked minus needed, negative when short
+}
+
+type ZoneCoverage struct {
+	Zone       database.DeliveryZone `json:"zone"`
+	Date       string                `json:"date"`
+	ShortHours int                   `json:"short_hours"` // hours with fewer riders booked than needed
+	Hours      []CoverageHour        `json:"hours"`
+}
+
+// Coverage is the plan for one day across zones
+type Coverage struct {
+	Date          string         `json:"date"`
+	ForecastWeeks int            `json:"forecast_weeks"`
+	OrdersPerHour float64        `json:"orders_per_rider_hour"`
+	Zones         []ZoneCoverage `json:"zones"`
+}
@@ -0,0 +1,273 @@
+package shifts
+
+import (
+	"food-delivery-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetRiderByUserID(userID uint) (*database.Rider, error) {
+	var rider database.Rider
+	err := r.db.Where("user_id = ?", userID).First(&rider).Error
+	return &rider, err
+}
+
+// LockRider takes a row lock on the rider for the rest of tx
+func (r *Repository) LockRider(tx *gorm.DB, riderID uint) error {
+	var rider database.Rider
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&rider, riderID).Error
+}
+
+func (r *Repository) GetZones(activeOnly bool) ([]database.DeliveryZone, error) {
+	var zones []database.DeliveryZone
+	query := r.db.Order("name")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Find(&zones).Error
+	return zones, err
+}
+
+func (r *Repository) GetZone(zoneID uint) (*database.DeliveryZone, error) {
+	var zone database.DeliveryZone
+	err := r.db.First(&zone, zoneID).Error
+	return &zone, err
+}
+
+func (r *Repository) CreateZone(zone *database.DeliveryZone) error {
+	return r.db.Create(zone).Error
+}
+
+func (r *Repository) UpdateZone(zone *database.DeliveryZone) error {
+	return r.db.Save(zone).Error
+}
+
+func (r *Repository) GetSlot(slotID uint) (*database.ShiftSlot, error) {
+	var slot database.ShiftSlot
+	err := r.db.Preload("Zone").First(&slot, slotID).Error
+	return &slot, err
+}
+
+// LockSlot takes a row lock on the slot for the rest of tx
+func (r *Repository) LockSlot(tx *gorm.DB, slotID uint) (*database.ShiftSlot, error) {
+	var slot database.ShiftSlot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Zone").First(&slot, slotID).Error
+	return &slot, err
+}
+
+// GetSlots returns slots starting between two times, soonest first
+func (r *Repository) GetSlots(filters *SlotFilters) ([]database.ShiftSlot, error) {
+	var slots []database.ShiftSlot
+	query := r.db.Preload("Zone").
+		Where("starts_at >= ? AND starts_at < ?", filters.From, filters.To)
+	if filters.ZoneID != nil {
+		query = query.Where("zone_id = ?", *filters.ZoneID)
+	}
+	err := query.Order("starts_at ASC, id ASC").Find(&slots).Error
+	return slots, err
+}
+
+// GetSlotsOverlapping returns the slots that run at some point between two times
+func (r *Repository) GetSlotsOverlapping(start, end time.Time) ([]database.ShiftSlot, error) {
+	var slots []database.ShiftSlot
+	err := r.db.Where("starts_at < ? AND ends_at > ?", end, start).Find(&slots).Error
+	return slots, err
+}
+
+func (r *Repository) CreateSlot(slot *database.ShiftSlot) error {
+	return r.db.Create(slot).Error
+}
+
+func (r *Repository) UpdateSlot(tx *gorm.DB, slot *database.ShiftSlot) error {
+	return tx.Model(slot).Select("starts_at", "ends_at", "capacity").Updates(slot).Error
+}
+
+func (r *Repository) DeleteSlot(tx *gorm.DB, slotID uint) error {
+	if err := tx.Where("slot_id = ?", slotID).Delete(&database.ShiftBooking{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&database.ShiftSlot{}, slotID).Error
+}
+
+func (r *Repository) GetBooking(bookingID uint) (*database.ShiftBooking, error) {
+	var booking database.ShiftBooking
+	err := r.db.Preload("Slot.Zone").First(&booking, bookingID).Error
+	return &booking, err
+}
+
+// GetRiderBookings returns the rider's bookings on slots ending after since
+func (r *Repository) GetRiderBookings(riderID uint, since time.Time) ([]database.ShiftBooking, error) {
+	var bookings []database.ShiftBooking
+	err := r.db.Joins("JOIN shift_slots ON shift_slots.id = shift_bookings.slot_id").
+		Where("shift_bookings.rider_id = ? AND shift_slots.ends_at > ?", riderID, since).
+		Preload("Slot.Zone").
+		Order("shift_slots.starts_at ASC").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// GetRiderBookingsOn returns the rider's live bookings on the given slots,
+// keyed by slot
+func (r *Repository) GetRiderBookingsOn(riderID uint, slotIDs []uint) (map[uint]database.ShiftBookingStatus, error) {
+	var bookings []database.ShiftBooking
+	err := r.db.Where("rider_id = ? AND slot_id IN ? AND status <> ?", riderID, slotIDs, database.ShiftBookingCancelled).
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+	statuses := make(map[uint]database.ShiftBookingStatus, len(bookings))
+	for _, b := range bookings {
+		statuses[b.SlotID] = b.Status
+	}
+	return statuses, nil
+}
+
+// CountOverlappingBookings counts the rider's live bookings on slots that
+// overlap the given times
+func (r *Repository) CountOverlappingBookings(tx *gorm.DB, riderID uint, start, end time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&database.ShiftBooking{}).
+		Joins("JOIN shift_slots ON shift_slots.id = shift_bookings.slot_id").
+		Where("shift_bookings.rider_id = ? AND shift_bookings.status IN ?", riderID, []database.ShiftBookingStatus{
+			database.ShiftBookingBooked, database.ShiftBookingCheckedIn,
+		}).
+		Where("shift_slots.starts_at < ? AND shift_slots.ends_at > ?", end, start).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *Repository) CreateBooking(tx *gorm.DB, booking *database.ShiftBooking) error {
+	return tx.Create(booking).Error
+}
+
+// AdjustBooked changes the slot's booked count by delta
+func (r *Repository) AdjustBooked(tx *gorm.DB, slotID uint, delta int) error {
+	return tx.Model(&database.ShiftSlot{}).Where("id = ?", slotID).
+		Update("booked", gorm.Expr("booked + ?", delta)).Error
+}
+
+// MoveBooking changes a booking's status if it is still in from, and
+// reports whether it did
+func (r *Repository) MoveBooking(tx *gorm.DB, bookingID uint, from, to database.ShiftBookingStatus, updates map[string]interface{}) (bool, error) {
+	if updates == nil {
+		updates = make(map[string]interface{})
+	}
+	updates["status"] = to
+	res := tx.Model(&database.ShiftBooking{}).
+		Where("id = ? AND status = ?", bookingID, from).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *Repository) IncrementNoShows(tx *gorm.DB, riderID uint) error {
+	return tx.Model(&database.Rider{}).Where("id = ?", riderID).
+		Update("no_show_count", gorm.Expr("no_show_count + 1")).Error
+}
+
+// HasOtherCheckIn reports whether the rider is checked in to another shift
+func (r *Repository) HasOtherCheckIn(riderID, bookingID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&database.ShiftBooking{}).
+		Where("rider_id = ? AND id <> ? AND status = ?", riderID, bookingID, database.ShiftBookingCheckedIn).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetMissedBookings returns bookings nobody checked in to by cutoff
+func (r *Repository) GetMissedBookings(cutoff time.Time) ([]database.ShiftBooking, error) {
+	var bookings []database.ShiftBooking
+	err := r.db.Joins("JOIN shift_slots ON shift_slots.id = shift_bookings.slot_id").
+		Where("shift_bookings.status = ? AND shift_slots.starts_at < ?", database.ShiftBookingBooked, cutoff).
+		Preload("Slot.Zone").
+		Preload("Rider").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// GetFinishedCheckIns returns checked in bookings whose slot has ended
+func (r *Repository) GetFinishedCheckIns(now time.Time) ([]database.ShiftBooking, error) {
+	var bookings []database.ShiftBooking
+	err := r.db.Joins("JOIN shift_slots ON shift_slots.id = shift_bookings.slot_id").
+		Where("shift_bookings.status = ? AND shift_slots.ends_at <= ?", database.ShiftBookingCheckedIn, now).
+		Preload("Slot").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+func (r *Repository) GetBookings(filters *BookingFilters, offset, limit int) ([]database.ShiftBooking, int64, error) {
+	var bookings []database.ShiftBooking
+	var total int64
+
+	query := r.db.Model(&database.ShiftBooking{})
+	if filters.SlotID != nil {
+		query = query.Where("slot_id = ?", *filters.SlotID)
+	}
+	if filters.RiderID != nil {
+		query = query.Where("rider_id = ?", *filters.RiderID)
+	}
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+
+	query.Count(&total)
+	err := query.Preload("Slot.Zone").
+		Preload("Rider.User").
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&bookings).Error
+	return bookings, total, err
+}
+
+// GetSlotBookings returns the bookings on the given slots that weren't cancelled
+func (r *Repository) GetSlotBookings(slotIDs []uint) ([]database.ShiftBooking, error) {
+	var bookings []database.ShiftBooking
+	if len(slotIDs) == 0 {
+		return bookings, nil
+	}
+	err := r.db.Where("slot_id IN ? AND status <> ?", slotIDs, database.ShiftBookingCancelled).
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// orderPlacement is when and where an order was placed, for forecasting
+type orderPlacement struct {
+	VendorID  uint
+	CreatedAt time.Time
+}
+
+// GetOrderPlacements returns the orders placed between two times that went
+// ahead, with where their vendor is
+func (r *Repository) GetOrderPlacements(start, end time.Time) ([]orderPlacement, error) {
+	var placements []orderPlacement
+	err := r.db.Model(&database.Order{}).
+		Select("vendor_id, created_at").
+		Where("created_at >= ? AND created_at < ? AND status NOT IN ?", start, end,
+			[]database.OrderStatus{database.OrderStatusCancelled, database.OrderStatusRejected}).
+		Scan(&placements).Error
+	return placements, err
+}
+
+func (r *Repository) GetVendorLocations(vendorIDs []uint) (map[uint]database.Vendor, error) {
+	vendors := make(map[uint]database.Vendor)
+	if len(vendorIDs) == 0 {
+		return vendors, nil
+	}
+	var list []database.Vendor
+	if err := r.db.Select("id, latitude, longitude").Where("id IN ?", vendorIDs).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range list {
+		vendors[v.ID] = v
+	}
+	return vendors, nil
+}
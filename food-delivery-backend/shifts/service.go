@@ -0,0 +1,499 @@
+package shifts
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/riders"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRiderNotFound   = errors.New("rider not found")
+	ErrZoneNotFound    = errors.New("zone not found")
+	ErrSlotNotFound    = errors.New("shift slot not found")
+	ErrBookingNotFound = errors.New("shift booking not found")
+	ErrZoneExists      = errors.New("a zone with this name already exists")
+	ErrInvalidTimezone = errors.New("invalid timezone")
+	ErrInvalidSlot     = errors.New("a shift must end after it starts")
+	ErrZoneInactive    = errors.New("zone is not taking bookings")
+	ErrSlotFull        = errors.New("shift is fully booked")
+	ErrSlotEnded       = errors.New("shift has already ended")
+	ErrAlreadyBooked   = errors.New("you are already booked on this shift")
+	ErrShiftOverlap    = errors.New("you are booked on another shift at the same time")
+	ErrSlotBooked      = errors.New("shift has bookings, only its capacity can be raised")
+	ErrBelowBooked     = errors.New("capacity can't be lower than the riders already booked")
+	ErrCancelTooLate   = errors.New("shift starts too soon to cancel")
+	ErrCheckInTooEarly = errors.New("too early to check in to this shift")
+	ErrCheckInClosed   = errors.New("check-in for this shift has closed")
+	ErrNotBooked       = errors.New("booking is not waiting for check-in")
+	ErrNotCheckedIn    = errors.New("you are not checked in to this shift")
+)
+
+// slotWindow is how far ahead riders see open slots by default
+const slotWindow = 7 * 24 * time.Hour
+
+type Service struct {
+	repo     *Repository
+	riders   *riders.Service
+	notifier *notifications.Service
+	db       *gorm.DB
+	cfg      *config.Config
+	logger   *zap.Logger
+}
+
+func NewService(repo *Repository, ridersService *riders.Service, notifier *notifications.Service, db *gorm.DB, cfg *config.Config, logger *zap.Logger) *Service {
+	return &Service{
+		repo:     repo,
+		riders:   ridersService,
+		notifier: notifier,
+		db:       db,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+func zoneLocation(zone *database.DeliveryZone) *time.Location {
+	if zone != nil && zone.Timezone != "" {
+		if loc, err := time.LoadLocation(zone.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+func (s *Service) GetZones(activeOnly bool) ([]database.DeliveryZone, error) {
+	return s.repo.GetZones(activeOnly)
+}
+
+func (s *Service) CreateZone(req *ZoneRequest) (*database.DeliveryZone, error) {
+	zone := &database.DeliveryZone{IsActive: true}
+	if err := applyZone(zone, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateZone(zone); err != nil {
+		if isDuplicate(err) {
+			return nil, ErrZoneExists
+		}
+		s.logger.Error("Failed to create zone", zap.Error(err))
+		return nil, errors.New("failed to create zone")
+	}
+	return zone, nil
+}
+
+func (s *Service) UpdateZone(zoneID uint, req *ZoneRequest) (*database.DeliveryZone, error) {
+	zone, err := s.repo.GetZone(zoneID)
+	if err != nil {
+		return nil, ErrZoneNotFound
+	}
+	if err := applyZone(zone, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateZone(zone); err != nil {
+		if isDuplicate(err) {
+			return nil, ErrZoneExists
+		}
+		s.logger.Error("Failed to update zone", zap.Error(err))
+		return nil, errors.New("failed to update zone")
+	}
+	return zone, nil
+}
+
+func applyZone(zone *database.DeliveryZone, req *ZoneRequest) error {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+	zone.Name = strings.TrimSpace(req.Name)
+	zone.Latitude = req.Latitude
+	zone.Longitude = req.Longitude
+	zone.RadiusKm = req.RadiusKm
+	zone.Timezone = timezone
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func isDuplicate(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key")
+}
+
+func (s *Service) CreateSlot(req *SlotRequest) (*database.ShiftSlot, error) {
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, ErrInvalidSlot
+	}
+	zone, err := s.repo.GetZone(req.ZoneID)
+	if err != nil {
+		return nil, ErrZoneNotFound
+	}
+
+	slot := &database.ShiftSlot{
+		ZoneID:   zone.ID,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Capacity: req.Capacity,
+	}
+	if err := s.repo.CreateSlot(slot); err != nil {
+		s.logger.Error("Failed to create shift slot", zap.Error(err))
+		return nil, errors.New("failed to create shift slot")
+	}
+	slot.Zone = zone
+	return slot, nil
+}
+
+func (s *Service) UpdateSlot(slotID uint, req *UpdateSlotRequest) (*database.ShiftSlot, error) {
+	var slot *database.ShiftSlot
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		slot, err = s.repo.LockSlot(tx, slotID)
+		if err != nil {
+			return ErrSlotNotFound
+		}
+
+		if req.StartsAt != nil || req.EndsAt != nil {
+			if slot.Booked > 0 {
+				return ErrSlotBooked
+			}
+			if req.StartsAt != nil {
+				slot.StartsAt = *req.StartsAt
+			}
+			if req.EndsAt != nil {
+				slot.EndsAt = *req.EndsAt
+			}
+			if !slot.EndsAt.After(slot.StartsAt) {
+				return ErrInvalidSlot
+			}
+		}
+		if req.Capacity != nil {
+			if *req.Capacity < slot.Booked {
+				return ErrBelowBooked
+			}
+			slot.Capacity = *req.Capacity
+		}
+		return s.repo.UpdateSlot(tx, slot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// DeleteSlot removes a slot nobody is booked on
+func (s *Service) DeleteSlot(slotID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		slot, err := s.repo.LockSlot(tx, slotID)
+		if err != nil {
+			return ErrSlotNotFound
+		}
+		if slot.Booked > 0 {
+			return ErrSlotBooked
+		}
+		return s.repo.DeleteSlot(tx, slotID)
+	})
+}
+
+// GetSlots returns slots for admins, from today until a week ahead unless
+// filters say otherwise
+func (s *Service) GetSlots(filters *SlotFilters) ([]database.ShiftSlot, error) {
+	if filters.From.IsZero() {
+		y, m, d := time.Now().Date()
+		filters.From = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	if filters.To.IsZero() {
+		filters.To = filters.From.Add(slotWindow)
+	}
+	return s.repo.GetSlots(filters)
+}
+
+// GetOpenSlots returns the slots a rider can still book or has booked, from
+// now until a week ahead unless filters say otherwise
+func (s *Service) GetOpenSlots(userID uint, filters *SlotFilters) ([]Slot, error) {
+	rider, err := s.repo.GetRiderByUserID(userID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+
+	now := time.Now()
+	if filters.From.IsZero() || filters.From.Before(now) {
+		filters.From = now
+	}
+	if filters.To.IsZero() {
+		filters.To = filters.From.Add(slotWindow)
+	}
+
+	slots, err := s.repo.GetSlots(filters)
+	if err != nil {
+		s.logger.Error("Failed to get shift slots", zap.Error(err))
+		return nil, errors.New("failed to get shift slots")
+	}
+
+	ids := make([]uint, 0, len(slots))
+	for _, slot := range slots {
+		ids = append(ids, slot.ID)
+	}
+	mine := map[uint]database.ShiftBookingStatus{}
+	if len(ids) > 0 {
+		if mine, err = s.repo.GetRiderBookingsOn(rider.ID, ids); err != nil {
+			s.logger.Error("Failed to get rider's bookings", zap.Error(err))
+			return nil, errors.New("failed to get shift slots")
+		}
+	}
+
+	open := make([]Slot, 0, len(slots))
+	for _, slot := range slots {
+		if slot.Zone != nil && !slot.Zone.IsActive {
+			continue
+		}
+		view := Slot{ShiftSlot: slot, Remaining: slot.Capacity - slot.Booked}
+		if view.Remaining < 0 {
+			view.Remaining = 0
+		}
+		if status, ok := mine[slot.ID]; ok {
+			view.MyBooking = &status
+		}
+		open = append(open, view)
+	}
+	return open, nil
+}
+
+// BookSlot gives the rider a place on the slot
+func (s *Service) BookSlot(userID, slotID uint) (*database.ShiftBooking, error) {
+	rider, err := s.repo.GetRiderByUserID(userID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+
+	var booking *database.ShiftBooking
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// The rider lock keeps two bookings of overlapping slots apart
+		if err := s.repo.LockRider(tx, rider.ID); err != nil {
+			return ErrRiderNotFound
+		}
+		slot, err := s.repo.LockSlot(tx, slotID)
+		if err != nil {
+			return ErrSlotNotFound
+		}
+		if slot.Zone == nil || !slot.Zone.IsActive {
+			return ErrZoneInactive
+		}
+		if !time.Now().Before(slot.EndsAt) {
+			return ErrSlotEnded
+		}
+
+		mine, err := s.repo.GetRiderBookingsOn(rider.ID, []uint{slot.ID})
+		if err != nil {
+			return err
+		}
+		if _, ok := mine[slot.ID]; ok {
+			return ErrAlreadyBooked
+		}
+		overlapping, err := s.repo.CountOverlappingBookings(tx, rider.ID, slot.StartsAt, slot.EndsAt)
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrShiftOverlap
+		}
+		if slot.Booked >= slot.Capacity {
+			return ErrSlotFull
+		}
+
+		booking = &database.ShiftBooking{
+			SlotID:  slot.ID,
+			RiderID: rider.ID,
+			Status:  database.ShiftBookingBooked,
+		}
+		if err := s.repo.CreateBooking(tx, booking); err != nil {
+			return err
+		}
+		booking.Slot = slot
+		return s.repo.AdjustBooked(tx, slot.ID, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+func (s *Service) GetMyShifts(userID uint) ([]database.ShiftBooking, error) {
+	rider, err := s.repo.GetRiderByUserID(userID)
+	if err != nil {
+		return nil, ErrRiderNotFound
+	}
+	// Include shifts that ended today so riders can see how they went
+	since := time.Now().Add(-24 * time.Hour)
+	return s.repo.GetRiderBookings(rider.ID, since)
+}
+
+// riderBooking loads one of the rider's bookings
+func (s *Service) riderBooking(userID, bookingID uint) (*database.Rider, *database.ShiftBooking, error) {
+	rider, err := s.repo.GetRiderByUserID(userID)
+	if err != nil {
+		return nil, nil, ErrRiderNotFound
+	}
+	booking, err := s.repo.GetBooking(bookingID)
+	if err != nil || booking.RiderID != rider.ID {
+		return nil, nil, ErrBookingNotFound
+	}
+	return rider, booking, nil
+}
+
+// CancelBooking frees the rider's place on a shift that doesn't start soon
+func (s *Service) CancelBooking(userID, bookingID uint) error {
+	_, booking, err := s.riderBooking(userID, bookingID)
+	if err != nil {
+		return err
+	}
+	if booking.Status != database.ShiftBookingBooked {
+		return ErrNotBooked
+	}
+	cutoff := booking.Slot.StartsAt.Add(-time.Duration(s.cfg.ShiftCancelCutoffMinutes) * time.Minute)
+	now := time.Now()
+	if now.After(cutoff) {
+		return ErrCancelTooLate
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.repo.LockSlot(tx, booking.SlotID); err != nil {
+			return ErrSlotNotFound
+		}
+		moved, err := s.repo.MoveBooking(tx, booking.ID, database.ShiftBookingBooked, database.ShiftBookingCancelled,
+			map[string]interface{}{"cancelled_at": now})
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return ErrNotBooked
+		}
+		return s.repo.AdjustBooked(tx, booking.SlotID, -1)
+	})
+}
+
+// CheckIn starts the rider's shift and makes them available for orders.
+// Riders still finishing a delivery become available once it is done.
+func (s *Service) CheckIn(userID, bookingID uint) (*database.ShiftBooking, error) {
+	rider, booking, err := s.riderBooking(userID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != database.ShiftBookingBooked {
+		return nil, ErrNotBooked
+	}
+
+	now := time.Now()
+	opens := booking.Slot.StartsAt.Add(-time.Duration(s.cfg.ShiftCheckInEarlyMinutes) * time.Minute)
+	closes := booking.Slot.StartsAt.Add(time.Duration(s.cfg.ShiftNoShowMinutes) * time.Minute)
+	if now.Before(opens) {
+		return nil, ErrCheckInTooEarly
+	}
+	if now.After(closes) || !now.Before(booking.Slot.EndsAt) {
+		return nil, ErrCheckInClosed
+	}
+
+	moved, err := s.repo.MoveBooking(s.db, booking.ID, database.ShiftBookingBooked, database.ShiftBookingCheckedIn,
+		map[string]interface{}{"checked_in_at": now})
+	if err != nil {
+		s.logger.Error("Failed to check in", zap.Uint("booking_id", booking.ID), zap.Error(err))
+		return nil, errors.New("failed to check in")
+	}
+	if !moved {
+		return nil, ErrNotBooked
+	}
+	booking.Status = database.ShiftBookingCheckedIn
+	booking.CheckedInAt = &now
+
+	s.setOnShift(rider.ID, true)
+	return booking, nil
+}
+
+// CheckOut ends the rider's shift and takes them off new orders, unless
+// they are checked in to another shift
+func (s *Service) CheckOut(userID, bookingID uint) (*database.ShiftBooking, error) {
+	rider, booking, err := s.riderBooking(userID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != database.ShiftBookingCheckedIn {
+		return nil, ErrNotCheckedIn
+	}
+	if err := s.finish(booking, rider.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// finish completes a checked in booking
+func (s *Service) finish(booking *database.ShiftBooking, riderID uint, at time.Time) error {
+	moved, err := s.repo.MoveBooking(s.db, booking.ID, database.ShiftBookingCheckedIn, database.ShiftBookingCompleted,
+		map[string]interface{}{"checked_out_at": at})
+	if err != nil {
+		s.logger.Error("Failed to check out", zap.Uint("booking_id", booking.ID), zap.Error(err))
+		return errors.New("failed to check out")
+	}
+	if !moved {
+		return ErrNotCheckedIn
+	}
+	booking.Status = database.ShiftBookingCompleted
+	booking.CheckedOutAt = &at
+
+	other, err := s.repo.HasOtherCheckIn(riderID, booking.ID)
+	if err != nil {
+		s.logger.Error("Failed to check rider's other shifts", zap.Uint("rider_id", riderID), zap.Error(err))
+		return nil
+	}
+	if !other {
+		s.setOnShift(riderID, false)
+	}
+	return nil
+}
+
+// setOnShift puts the rider on or off shift. Riders still finishing a
+// delivery when they check out aren't made available again by it.
+func (s *Service) setOnShift(riderID uint, onShift bool) {
+	if err := s.riders.SetOnShift(riderID, onShift); err != nil {
+		s.logger.Error("Failed to change rider availability", zap.Uint("rider_id", riderID), zap.Error(err))
+	}
+}
+
+// markNoShow records that the rider never checked in to a booked shift
+func (s *Service) markNoShow(booking *database.ShiftBooking) error {
+	var moved bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		moved, err = s.repo.MoveBooking(tx, booking.ID, database.ShiftBookingBooked, database.ShiftBookingNoShow, nil)
+		if err != nil || !moved {
+			return err
+		}
+		return s.repo.IncrementNoShows(tx, booking.RiderID)
+	})
+	if err != nil || !moved {
+		return err
+	}
+
+	if booking.Rider != nil && booking.Slot != nil {
+		zone := ""
+		if booking.Slot.Zone != nil {
+			zone = " in " + booking.Slot.Zone.Name
+		}
+		s.notifier.NotifyRider(booking.Rider.UserID, "Missed Shift",
+			fmt.Sprintf("You didn't check in to your shift%s at %s, so it was recorded as a no-show",
+				zone, booking.Slot.StartsAt.In(zoneLocation(booking.Slot.Zone)).Format("Mon 15:04")),
+			"shift_no_show", fmt.Sprintf("%d", booking.ID))
+	}
+	return nil
+}
+
+func (s *Service) GetBookings(filters *BookingFilters, page, limit int) ([]database.ShiftBooking, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetBookings(filters, offset, limit)
+}
@@ -0,0 +1,102 @@
+package shifts
+
+import (
+	"context"
+	"errors"
+	"food-delivery-backend/config"
+	"food-delivery-backend/redis"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const workerLockName = "rider-shifts"
+
+// Worker records no-shows for riders who never checked in to a booked shift
+// and checks riders out once their shift has ended
+type Worker struct {
+	service     *Service
+	repo        *Repository
+	redisClient *redis.RedisClient
+	cfg         *config.Config
+	logger      *zap.Logger
+}
+
+func NewWorker(service *Service, repo *Repository, redisClient *redis.RedisClient, cfg *config.Config, logger *zap.Logger) *Worker {
+	return &Worker{
+		service:     service,
+		repo:        repo,
+		redisClient: redisClient,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run sweeps on every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.ShiftInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx, interval)
+		}
+	}
+}
+
+func (w *Worker) sweep(ctx context.Context, lockTTL time.Duration) {
+	token, ok, err := w.redisClient.AcquireLock(ctx, workerLockName, lockTTL)
+	if err != nil {
+		w.logger.Error("Failed to acquire shifts lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := w.redisClient.ReleaseLock(context.Background(), workerLockName, token); err != nil {
+			w.logger.Warn("Failed to release shifts lock", zap.Error(err))
+		}
+	}()
+
+	now := time.Now()
+	missed, err := w.repo.GetMissedBookings(now.Add(-time.Duration(w.cfg.ShiftNoShowMinutes) * time.Minute))
+	if err != nil {
+		w.logger.Error("Failed to load missed shifts", zap.Error(err))
+		return
+	}
+	for i := range missed {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.service.markNoShow(&missed[i]); err != nil {
+			w.logger.Error("Failed to record no-show", zap.Uint("booking_id", missed[i].ID), zap.Error(err))
+		}
+	}
+
+	finished, err := w.repo.GetFinishedCheckIns(now)
+	if err != nil {
+		w.logger.Error("Failed to load finished shifts", zap.Error(err))
+		return
+	}
+	for i := range finished {
+		if ctx.Err() != nil {
+			return
+		}
+		booking := &finished[i]
+		if booking.Slot == nil {
+			continue
+		}
+		err := w.service.finish(booking, booking.RiderID, booking.Slot.EndsAt)
+		if err != nil && !errors.Is(err, ErrNotCheckedIn) {
+			w.logger.Error("Failed to check rider out", zap.Uint("booking_id", booking.ID), zap.Error(err))
+		}
+	}
+}
@@ -59,6 +59,7 @@ func (r *Repository) GetOrderHistory(userID uint, offset, limit int) ([]database
 
     err := r.db.Where("student_id = ?", userID).
         Preload("OrderItems.MenuItem").
+        Preload("OrderItems.Options").
         Preload("Vendor").
         Preload("AssignedRider.User").
         Order("created_at DESC").
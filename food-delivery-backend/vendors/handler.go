@@ -86,6 +86,90 @@ func (h *Handler) ToggleOpenStatus(c *gin.Context) {
 	pkg.SendSuccess(c, http.StatusOK, "Status updated", gin.H{"is_open": isOpen})
 }
 
+// GetKitchen returns the kitchen's live queue and the wait a new order would get
+// @Summary Get kitchen load
+// @Tags Vendors
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=orders.Kitchen}
+// @Router /vendors/kitchen [get]
+func (h *Handler) GetKitchen(c *gin.Context) {
+	kitchen, err := h.service.GetKitchen(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get kitchen load", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Kitchen load retrieved", kitchen)
+}
+
+// UpdateCapacity limits how many orders the kitchen works on at once
+// @Summary Update kitchen capacity
+// @Tags Vendors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CapacityRequest true "Capacity"
+// @Success 200 {object} pkg.Response{data=database.Vendor}
+// @Router /vendors/capacity [put]
+func (h *Handler) UpdateCapacity(c *gin.Context) {
+	var req CapacityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	vendor, err := h.service.UpdateCapacity(c.GetUint("user_id"), &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to update capacity", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Capacity updated", vendor)
+}
+
+// SetBusy turns busy mode on, adding minutes to every expected wait
+// @Summary Turn busy mode on
+// @Tags Vendors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body BusyRequest true "Busy mode"
+// @Success 200 {object} pkg.Response{data=database.Vendor}
+// @Router /vendors/busy [post]
+func (h *Handler) SetBusy(c *gin.Context) {
+	var req BusyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	vendor, err := h.service.SetBusy(c.GetUint("user_id"), &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to set busy mode", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Busy mode on", vendor)
+}
+
+// ClearBusy turns busy mode off
+// @Summary Turn busy mode off
+// @Tags Vendors
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.Vendor}
+// @Router /vendors/busy [delete]
+func (h *Handler) ClearBusy(c *gin.Context) {
+	vendor, err := h.service.ClearBusy(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to clear busy mode", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Busy mode off", vendor)
+}
+
 // GetMenuItems returns vendor's menu items
 // @Summary Get menu items
 // @Tags Vendors
@@ -212,6 +296,240 @@ func (h *Handler) ToggleMenuItemAvailability(c *gin.Context) {
 	pkg.SendSuccess(c, http.StatusOK, "Availability toggled", gin.H{"is_available": isAvailable})
 }
 
+// pathID parses a numeric path parameter, replying 400 when it is invalid
+func pathID(c *gin.Context, param, label string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid "+label+" ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetOptionGroups returns a menu item's option groups
+// @Summary Get menu item options
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=[]database.MenuOptionGroup}
+// @Router /vendors/menu/{id}/options [get]
+func (h *Handler) GetOptionGroups(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+
+	groups, err := h.service.GetOptionGroups(c.GetUint("user_id"), itemID)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to get options", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Options retrieved", groups)
+}
+
+// AddOptionGroup adds an option group, such as sizes or add-ons, to a menu item
+// @Summary Add option group
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Accept json
+// @Produce json
+// @Param request body OptionGroupRequest true "Option group"
+// @Success 201 {object} pkg.Response{data=database.MenuOptionGroup}
+// @Router /vendors/menu/{id}/options [post]
+func (h *Handler) AddOptionGroup(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+
+	var req OptionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	group, err := h.service.AddOptionGroup(c.GetUint("user_id"), itemID, &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to add option group", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Option group added successfully", group)
+}
+
+// UpdateOptionGroup updates an option group
+// @Summary Update option group
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Param group_id path int true "Option Group ID"
+// @Accept json
+// @Produce json
+// @Param request body UpdateOptionGroupRequest true "Option group update"
+// @Success 200 {object} pkg.Response{data=database.MenuOptionGroup}
+// @Router /vendors/menu/{id}/options/{group_id} [put]
+func (h *Handler) UpdateOptionGroup(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+	groupID, ok := pathID(c, "group_id", "option group")
+	if !ok {
+		return
+	}
+
+	var req UpdateOptionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	group, err := h.service.UpdateOptionGroup(c.GetUint("user_id"), itemID, groupID, &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to update option group", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Option group updated successfully", group)
+}
+
+// DeleteOptionGroup deletes an option group and its options
+// @Summary Delete option group
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Param group_id path int true "Option Group ID"
+// @Success 200 {object} pkg.Response
+// @Router /vendors/menu/{id}/options/{group_id} [delete]
+func (h *Handler) DeleteOptionGroup(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+	groupID, ok := pathID(c, "group_id", "option group")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteOptionGroup(c.GetUint("user_id"), itemID, groupID); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to delete option group", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Option group deleted successfully", nil)
+}
+
+// AddOption adds an option to an option group
+// @Summary Add option
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Param group_id path int true "Option Group ID"
+// @Accept json
+// @Produce json
+// @Param request body OptionRequest true "Option"
+// @Success 201 {object} pkg.Response{data=database.MenuOption}
+// @Router /vendors/menu/{id}/options/{group_id}/choices [post]
+func (h *Handler) AddOption(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+	groupID, ok := pathID(c, "group_id", "option group")
+	if !ok {
+		return
+	}
+
+	var req OptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	option, err := h.service.AddOption(c.GetUint("user_id"), itemID, groupID, &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to add option", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Option added successfully", option)
+}
+
+// UpdateOption updates an option
+// @Summary Update option
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Param group_id path int true "Option Group ID"
+// @Param option_id path int true "Option ID"
+// @Accept json
+// @Produce json
+// @Param request body UpdateOptionRequest true "Option update"
+// @Success 200 {object} pkg.Response{data=database.MenuOption}
+// @Router /vendors/menu/{id}/options/{group_id}/choices/{option_id} [put]
+func (h *Handler) UpdateOption(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+	groupID, ok := pathID(c, "group_id", "option group")
+	if !ok {
+		return
+	}
+	optionID, ok := pathID(c, "option_id", "option")
+	if !ok {
+		return
+	}
+
+	var req UpdateOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	option, err := h.service.UpdateOption(c.GetUint("user_id"), itemID, groupID, optionID, &req)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to update option", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Option updated successfully", option)
+}
+
+// DeleteOption deletes an option
+// @Summary Delete option
+// @Tags Vendors
+// @Security BearerAuth
+// @Param id path int true "Menu Item ID"
+// @Param group_id path int true "Option Group ID"
+// @Param option_id path int true "Option ID"
+// @Success 200 {object} pkg.Response
+// @Router /vendors/menu/{id}/options/{group_id}/choices/{option_id} [delete]
+func (h *Handler) DeleteOption(c *gin.Context) {
+	itemID, ok := pathID(c, "id", "item")
+	if !ok {
+		return
+	}
+	groupID, ok := pathID(c, "group_id", "option group")
+	if !ok {
+		return
+	}
+	optionID, ok := pathID(c, "option_id", "option")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteOption(c.GetUint("user_id"), itemID, groupID, optionID); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to delete option", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Option deleted successfully", nil)
+}
+
 // GetOrders returns vendor's orders
 // @Summary Get vendor orders
 // @Tags Vendors
@@ -371,13 +689,32 @@ func (h *Handler) GetEarnings(c *gin.Context) {
 // @Produce json
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
+// @Param lat query number false "Delivery latitude"
+// @Param lng query number false "Delivery longitude"
 // @Success 200 {object} pkg.PaginatedResponse
 // @Router /public/vendors [get]
 func (h *Handler) GetPublicVendors(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	var near *GeoPoint
+	if c.Query("lat") != "" || c.Query("lng") != "" {
+		lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+		if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid coordinates", "lat and lng must both be valid coordinates")
+			return
+		}
+		near = &GeoPoint{Lat: lat, Lng: lng}
+	}
 
-	vendors, total, err := h.service.GetPublicVendors(page, limit)
+	vendors, total, err := h.service.GetPublicVendors(page, limit, near)
 	if err != nil {
 		h.logger.Error("Failed to get public vendors", zap.Error(err))
 		pkg.SendError(c, http.StatusInternalServerError, "Failed to get vendors", err.Error())
@@ -0,0 +1,113 @@
+package vendors
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/orders"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// GetKitchen returns the vendor's live queue and the wait a new order would
+// get. vendorID is the authenticated user id.
+func (s *Service) GetKitchen(vendorID uint) (*orders.Kitchen, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorID)
+	if err != nil {
+		return nil, errors.New("vendor not found")
+	}
+	counts, err := s.repo.CountKitchenOrders([]uint{vendor.ID})
+	if err != nil {
+		return nil, err
+	}
+	kitchen := orders.KitchenLoad(vendor, counts[vendor.ID], time.Now())
+	return &kitchen, nil
+}
+
+// UpdateCapacity sets how many orders the kitchen takes at once
+func (s *Service) UpdateCapacity(vendorID uint, req *CapacityRequest) (*database.Vendor, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorID)
+	if err != nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	vendor.MaxActiveOrders = req.MaxActiveOrders
+	if req.CapacityAction != "" {
+		vendor.CapacityAction = req.CapacityAction
+	}
+	if err := s.repo.UpdateVendorColumns(vendor.ID, map[string]interface{}{
+		"max_active_orders": vendor.MaxActiveOrders,
+		"capacity_action":   vendor.CapacityAction,
+	}); err != nil {
+		s.logger.Error("Failed to update kitchen capacity", zap.Error(err))
+		return nil, errors.New("failed to update capacity")
+	}
+	return vendor, nil
+}
+
+// SetBusy turns busy mode on
+func (s *Service) SetBusy(vendorID uint, req *BusyRequest) (*database.Vendor, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorID)
+	if err != nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	vendor.BusyMinutes = req.Minutes
+	vendor.BusyUntil = nil
+	if req.DurationMinutes > 0 {
+		until := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		vendor.BusyUntil = &until
+	}
+	if err := s.repo.UpdateVendorColumns(vendor.ID, map[string]interface{}{
+		"busy_minutes": vendor.BusyMinutes,
+		"busy_until":   vendor.BusyUntil,
+	}); err != nil {
+		s.logger.Error("Failed to set busy mode", zap.Error(err))
+		return nil, errors.New("failed to set busy mode")
+	}
+
+	s.logger.Info("Vendor busy mode on",
+		zap.Uint("vendor_id", vendor.ID),
+		zap.Int("minutes", req.Minutes))
+	return vendor, nil
+}
+
+// ClearBusy turns busy mode off
+func (s *Service) ClearBusy(vendorID uint) (*database.Vendor, error) {
+	vendor, err := s.repo.GetVendorByUserID(vendorID)
+	if err != nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	vendor.BusyMinutes = 0
+	vendor.BusyUntil = nil
+	if err := s.repo.UpdateVendorColumns(vendor.ID, map[string]interface{}{
+		"busy_minutes": 0,
+		"busy_until":   nil,
+	}); err != nil {
+		s.logger.Error("Failed to clear busy mode", zap.Error(err))
+		return nil, errors.New("failed to clear busy mode")
+	}
+	return vendor, nil
+}
+
+// withKitchens fills in the live queue of each listed vendor
+func (s *Service) withKitchens(list []PublicVendor) []PublicVendor {
+	if len(list) == 0 {
+		return list
+	}
+	ids := make([]uint, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	counts, err := s.repo.CountKitchenOrders(ids)
+	if err != nil {
+		s.logger.Warn("Failed to load kitchen queues", zap.Error(err))
+	}
+
+	now := time.Now()
+	for i := range list {
+		list[i].Kitchen = orders.KitchenLoad(&list[i].Vendor, counts[list[i].ID], now)
+	}
+	return list
+}
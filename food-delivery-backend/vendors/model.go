@@ -1,41 +1,80 @@
 package vendors
 
+import (
+    "food-delivery-backend/database"
+    "food-delivery-backend/orders"
+    "food-delivery-backend/pkg"
+)
+
 type UpdateVendorRequest struct {
-    BusinessName    string  `json:"business_name"`
-    BusinessAddress string  `json:"business_address"`
-    Phone           string  `json:"phone"`
-    Description     string  `json:"description"`
-    LogoURL         string  `json:"logo_url"`
-    CoverImageURL   string  `json:"cover_image_url"`
-    DeliveryRadius  float64 `json:"delivery_radius"`
-    MinimumOrder    float64 `json:"minimum_order"`
+    BusinessName    string    `json:"business_name"`
+    BusinessAddress string    `json:"business_address"`
+    Phone           string    `json:"phone"`
+    Description     string    `json:"description"`
+    LogoURL         string    `json:"logo_url"`
+    CoverImageURL   string    `json:"cover_image_url"`
+    DeliveryRadius  float64   `json:"delivery_radius"`
+    MinimumOrder    pkg.Money `json:"minimum_order"`
+    SlotCapacity    *int      `json:"slot_capacity" binding:"omitempty,min=0"` // scheduled orders per delivery slot, 0 for the platform default
 }
 
 type AddMenuItemRequest struct {
-    Name            string   `json:"name" binding:"required"`
-    Description     string   `json:"description"`
-    Category        string   `json:"category" binding:"required"`
-    Price           float64  `json:"price" binding:"required,min=0"`
-    DiscountPrice   *float64 `json:"discount_price"`
-    ImageURL        string   `json:"image_url"`
-    PreparationTime int      `json:"preparation_time"`
-    Calories        int      `json:"calories"`
-    IsVegetarian    bool     `json:"is_vegetarian"`
-    IsSpicy         bool     `json:"is_spicy"`
+    Name            string     `json:"name" binding:"required"`
+    Description     string     `json:"description"`
+    Category        string     `json:"category" binding:"required"`
+    Price           pkg.Money  `json:"price" binding:"required,min=0"`
+    DiscountPrice   *pkg.Money `json:"discount_price"`
+    ImageURL        string     `json:"image_url"`
+    PreparationTime int        `json:"preparation_time"`
+    Calories        int        `json:"calories"`
+    IsVegetarian    bool       `json:"is_vegetarian"`
+    IsSpicy         bool       `json:"is_spicy"`
 }
 
 type UpdateMenuItemRequest struct {
-    Name            string   `json:"name"`
-    Description     string   `json:"description"`
-    Category        string   `json:"category"`
-    Price           float64  `json:"price"`
-    DiscountPrice   *float64 `json:"discount_price"`
-    ImageURL        string   `json:"image_url"`
-    PreparationTime int      `json:"preparation_time"`
-    Calories        int      `json:"calories"`
-    IsVegetarian    bool     `json:"is_vegetarian"`
-    IsSpicy         bool     `json:"is_spicy"`
-    IsAvailable     *bool    `json:"is_available"`
+    Name            string     `json:"name"`
+    Description     string     `json:"description"`
+    Category        string     `json:"category"`
+    Price           pkg.Money  `json:"price"`
+    DiscountPrice   *pkg.Money `json:"discount_price"`
+    ImageURL        string     `json:"image_url"`
+    PreparationTime int        `json:"preparation_time"`
+    Calories        int        `json:"calories"`
+    IsVegetarian    bool       `json:"is_vegetarian"`
+    IsSpicy         bool       `json:"is_spicy"`
+    IsAvailable     *bool      `json:"is_available"`
+}
+
+// OptionGroupRequest creates an option group on a menu item, optionally with
+// its options. MaxSelections 0 means no maximum.
+type OptionGroupRequest struct {
+    Name          string          `json:"name" binding:"required"`
+    MinSelections int             `json:"min_selections" binding:"min=0"`
+    MaxSelections int             `json:"max_selections" binding:"min=0"`
+    Required      bool            `json:"required"`
+    SortOrder     int             `json:"sort_order"`
+    Options       []OptionRequest `json:"options" binding:"omitempty,dive"`
+}
+
+type UpdateOptionGroupRequest struct {
+    Name          string `json:"name"`
+    MinSelections *int   `json:"min_selections" binding:"omitempty,min=0"`
+    MaxSelections *int   `json:"max_selections" binding:"omitempty,min=0"`
+    Required      *bool  `json:"required"`
+    SortOrder     *int   `json:"sort_order"`
+}
+
+type OptionRequest struct {
+    Name       string    `json:"name" binding:"required"`
+    PriceDelta pkg.Money `json:"price_delta"`
+    SortOrder  int       `json:"sort_order"`
+}
+
+type UpdateOptionRequest struct {
+    Name        string     `json:"name"`
+    PriceDelta  *pkg.Money `json:"price_delta"`
+    IsAvailable *bool      `json:"is_available"`
+    SortOrder   *int       `json:"sort_order"`
 }
 
 type RejectOrderRequest struct {
@@ -48,20 +87,48 @@ type EarningsResponse struct {
         EndDate   string `json:"end_date"`
     } `json:"period"`
     Summary struct {
-        TotalOrders     int     `json:"total_orders"`
-        TotalRevenue    float64 `json:"total_revenue"`
-        TotalCommission float64 `json:"total_commission"`
-        TotalEarnings   float64 `json:"total_earnings"`
-        AverageOrderValue float64 `json:"average_order_value"`
+        TotalOrders       int       `json:"total_orders"`
+        TotalRevenue      pkg.Money `json:"total_revenue"`
+        TotalCommission   pkg.Money `json:"total_commission"`
+        TotalEarnings     pkg.Money `json:"total_earnings"`
+        AverageOrderValue pkg.Money `json:"average_order_value"`
     } `json:"summary"`
     DailyBreakdown []DailyEarnings `json:"daily_breakdown"`
-    CurrentBalance float64         `json:"current_balance"`
+    CurrentBalance pkg.Money       `json:"current_balance"`
 }
 
 type DailyEarnings struct {
-    Date        string  `json:"date"`
-    Orders      int     `json:"orders"`
-    Revenue     float64 `json:"revenue"`
-    Commission  float64 `json:"commission"`
-    Earnings    float64 `json:"earnings"`
-}
\ No newline at end of file
+    Date       string    `json:"date"`
+    Orders     int       `json:"orders"`
+    Revenue    pkg.Money `json:"revenue"`
+    Commission pkg.Money `json:"commission"`
+    Earnings   pkg.Money `json:"earnings"`
+}
+
+// PublicVendor is a vendor as listed to students. DistanceKm is only set when
+// the listing was filtered by a delivery point.
+type PublicVendor struct {
+    database.Vendor
+    DistanceKm *float64       `json:"distance_km,omitempty"`
+    Kitchen    orders.Kitchen `json:"kitchen"` // current queue and expected wait
+}
+
+// CapacityRequest limits how many confirmed and preparing orders the kitchen
+// works on at once, and what happens to new orders past that
+type CapacityRequest struct {
+    MaxActiveOrders int    `json:"max_active_orders" binding:"min=0"`
+    CapacityAction  string `json:"capacity_action" binding:"omitempty,oneof=reject extend"`
+}
+
+// BusyRequest turns busy mode on, adding Minutes to every wait. Without
+// DurationMinutes it stays on until turned off.
+type BusyRequest struct {
+    Minutes         int `json:"minutes" binding:"required,min=1,max=180"`
+    DurationMinutes int `json:"duration_minutes" binding:"min=0"`
+}
+
+// GeoPoint is a delivery location used to filter vendors
+type GeoPoint struct {
+    Lat float64
+    Lng float64
+}
@@ -0,0 +1,199 @@
+package vendors
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+
+	"go.uber.org/zap"
+)
+
+// ownedMenuItem loads a menu item belonging to the vendor. vendorID is the
+// authenticated user id.
+func (s *Service) ownedMenuItem(vendorID uint, itemID uint) (*database.MenuItem, error) {
+	item, err := s.repo.GetMenuItemByID(itemID)
+	if err != nil {
+		return nil, errors.New("menu item not found")
+	}
+	vendor, err := s.repo.GetVendorByUserID(vendorID)
+	if err != nil || item.VendorID != vendor.ID {
+		return nil, errors.New("unauthorized to modify this item")
+	}
+	return item, nil
+}
+
+// normalizeSelections checks the limits are consistent. A required group
+// needs at least one choice, but that minimum is left for ordering to derive
+// so the group can be made optional again later.
+func normalizeSelections(group *database.MenuOptionGroup) error {
+	minimum := group.MinSelections
+	if group.Required && minimum == 0 {
+		minimum = 1
+	}
+	if group.MaxSelections != 0 && group.MaxSelections < minimum {
+		return errors.New("max_selections cannot be less than min_selections")
+	}
+	return nil
+}
+
+func (s *Service) GetOptionGroups(vendorID uint, itemID uint) ([]database.MenuOptionGroup, error) {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetOptionGroups(item.ID)
+}
+
+func (s *Service) AddOptionGroup(vendorID uint, itemID uint, req *OptionGroupRequest) (*database.MenuOptionGroup, error) {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &database.MenuOptionGroup{
+		MenuItemID:    item.ID,
+		Name:          req.Name,
+		MinSelections: req.MinSelections,
+		MaxSelections: req.MaxSelections,
+		Required:      req.Required,
+		SortOrder:     req.SortOrder,
+	}
+	if err := normalizeSelections(group); err != nil {
+		return nil, err
+	}
+	for _, opt := range req.Options {
+		group.Options = append(group.Options, database.MenuOption{
+			Name:        opt.Name,
+			PriceDelta:  opt.PriceDelta,
+			IsAvailable: true,
+			SortOrder:   opt.SortOrder,
+		})
+	}
+
+	if err := s.repo.CreateOptionGroup(group); err != nil {
+		s.logger.Error("Failed to create option group", zap.Error(err))
+		return nil, errors.New("failed to add option group")
+	}
+	return group, nil
+}
+
+func (s *Service) UpdateOptionGroup(vendorID uint, itemID uint, groupID uint, req *UpdateOptionGroupRequest) (*database.MenuOptionGroup, error) {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.repo.GetOptionGroup(item.ID, groupID)
+	if err != nil {
+		return nil, errors.New("option group not found")
+	}
+
+	if req.Name != "" {
+		group.Name = req.Name
+	}
+	if req.MinSelections != nil {
+		group.MinSelections = *req.MinSelections
+	}
+	if req.MaxSelections != nil {
+		group.MaxSelections = *req.MaxSelections
+	}
+	if req.Required != nil {
+		group.Required = *req.Required
+		// An optional group has no minimum unless one was sent with it
+		if !group.Required && req.MinSelections == nil {
+			group.MinSelections = 0
+		}
+	}
+	if req.SortOrder != nil {
+		group.SortOrder = *req.SortOrder
+	}
+	if err := normalizeSelections(group); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateOptionGroup(group); err != nil {
+		s.logger.Error("Failed to update option group", zap.Error(err))
+		return nil, errors.New("failed to update option group")
+	}
+	return group, nil
+}
+
+func (s *Service) DeleteOptionGroup(vendorID uint, itemID uint, groupID uint) error {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.GetOptionGroup(item.ID, groupID); err != nil {
+		return errors.New("option group not found")
+	}
+	return s.repo.DeleteOptionGroup(groupID)
+}
+
+func (s *Service) AddOption(vendorID uint, itemID uint, groupID uint, req *OptionRequest) (*database.MenuOption, error) {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.repo.GetOptionGroup(item.ID, groupID)
+	if err != nil {
+		return nil, errors.New("option group not found")
+	}
+
+	option := &database.MenuOption{
+		GroupID:     group.ID,
+		Name:        req.Name,
+		PriceDelta:  req.PriceDelta,
+		IsAvailable: true,
+		SortOrder:   req.SortOrder,
+	}
+	if err := s.repo.CreateOption(option); err != nil {
+		s.logger.Error("Failed to create option", zap.Error(err))
+		return nil, errors.New("failed to add option")
+	}
+	return option, nil
+}
+
+func (s *Service) UpdateOption(vendorID uint, itemID uint, groupID uint, optionID uint, req *UpdateOptionRequest) (*database.MenuOption, error) {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetOptionGroup(item.ID, groupID); err != nil {
+		return nil, errors.New("option group not found")
+	}
+	option, err := s.repo.GetOption(groupID, optionID)
+	if err != nil {
+		return nil, errors.New("option not found")
+	}
+
+	if req.Name != "" {
+		option.Name = req.Name
+	}
+	if req.PriceDelta != nil {
+		option.PriceDelta = *req.PriceDelta
+	}
+	if req.IsAvailable != nil {
+		option.IsAvailable = *req.IsAvailable
+	}
+	if req.SortOrder != nil {
+		option.SortOrder = *req.SortOrder
+	}
+
+	if err := s.repo.UpdateOption(option); err != nil {
+		s.logger.Error("Failed to update option", zap.Error(err))
+		return nil, errors.New("failed to update option")
+	}
+	return option, nil
+}
+
+func (s *Service) DeleteOption(vendorID uint, itemID uint, groupID uint, optionID uint) error {
+	item, err := s.ownedMenuItem(vendorID, itemID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.GetOptionGroup(item.ID, groupID); err != nil {
+		return errors.New("option group not found")
+	}
+	if _, err := s.repo.GetOption(groupID, optionID); err != nil {
+		return errors.New("option not found")
+	}
+	return s.repo.DeleteOption(optionID)
+}
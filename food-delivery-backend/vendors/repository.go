@@ -3,6 +3,8 @@ package vendors
 import (
     "time"
     "food-delivery-backend/database"
+    "food-delivery-backend/orders"
+    "food-delivery-backend/pkg"
     "gorm.io/gorm"
 )
 
@@ -24,12 +26,103 @@ func (r *Repository) UpdateVendor(vendor *database.Vendor) error {
     return r.db.Save(vendor).Error
 }
 
+// UpdateVendorColumns writes only the given columns, leaving the balances
+// and learned prep time other jobs keep on the row untouched
+func (r *Repository) UpdateVendorColumns(vendorID uint, updates map[string]interface{}) error {
+    return r.db.Model(&database.Vendor{}).Where("id = ?", vendorID).Updates(updates).Error
+}
+
+// CountKitchenOrders returns the confirmed and preparing orders of each vendor
+func (r *Repository) CountKitchenOrders(vendorIDs []uint) (map[uint]int, error) {
+    var rows []struct {
+        VendorID uint
+        Count    int
+    }
+    err := r.db.Model(&database.Order{}).
+        Select("vendor_id, COUNT(*) AS count").
+        Where("vendor_id IN ? AND status IN ?", vendorIDs, orders.KitchenStatuses).
+        Group("vendor_id").
+        Scan(&rows).Error
+
+    counts := make(map[uint]int, len(rows))
+    for _, row := range rows {
+        counts[row.VendorID] = row.Count
+    }
+    return counts, err
+}
+
 func (r *Repository) GetMenuItems(vendorID uint) ([]database.MenuItem, error) {
     var items []database.MenuItem
-    err := r.db.Where("vendor_id = ?", vendorID).Order("sort_order, category, name").Find(&items).Error
+    err := r.db.Where("vendor_id = ?", vendorID).
+        Preload("OptionGroups", orderOptions).
+        Preload("OptionGroups.Options", orderOptions).
+        Order("sort_order, category, name").Find(&items).Error
     return items, err
 }
 
+// orderOptions sorts option groups and options the way the vendor arranged them
+func orderOptions(db *gorm.DB) *gorm.DB {
+    return db.Order("sort_order, id")
+}
+
+// availableOptions sorts options and hides the ones the vendor switched off
+func availableOptions(db *gorm.DB) *gorm.DB {
+    return db.Where("is_available = ?", true).Order("sort_order, id")
+}
+
+func (r *Repository) GetOptionGroups(menuItemID uint) ([]database.MenuOptionGroup, error) {
+    var groups []database.MenuOptionGroup
+    err := r.db.Where("menu_item_id = ?", menuItemID).
+        Preload("Options", orderOptions).
+        Scopes(orderOptions).
+        Find(&groups).Error
+    return groups, err
+}
+
+func (r *Repository) GetOptionGroup(menuItemID, groupID uint) (*database.MenuOptionGroup, error) {
+    var group database.MenuOptionGroup
+    err := r.db.Where("id = ? AND menu_item_id = ?", groupID, menuItemID).
+        Preload("Options", orderOptions).
+        First(&group).Error
+    return &group, err
+}
+
+func (r *Repository) CreateOptionGroup(group *database.MenuOptionGroup) error {
+    return r.db.Create(group).Error
+}
+
+func (r *Repository) UpdateOptionGroup(group *database.MenuOptionGroup) error {
+    return r.db.Omit("Options").Save(group).Error
+}
+
+// DeleteOptionGroup removes a group together with its options
+func (r *Repository) DeleteOptionGroup(groupID uint) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("group_id = ?", groupID).Delete(&database.MenuOption{}).Error; err != nil {
+            return err
+        }
+        return tx.Delete(&database.MenuOptionGroup{}, groupID).Error
+    })
+}
+
+func (r *Repository) GetOption(groupID, optionID uint) (*database.MenuOption, error) {
+    var option database.MenuOption
+    err := r.db.Where("id = ? AND group_id = ?", optionID, groupID).First(&option).Error
+    return &option, err
+}
+
+func (r *Repository) CreateOption(option *database.MenuOption) error {
+    return r.db.Create(option).Error
+}
+
+func (r *Repository) UpdateOption(option *database.MenuOption) error {
+    return r.db.Save(option).Error
+}
+
+func (r *Repository) DeleteOption(optionID uint) error {
+    return r.db.Delete(&database.MenuOption{}, optionID).Error
+}
+
 func (r *Repository) CreateMenuItem(item *database.MenuItem) error {
     return r.db.Create(item).Error
 }
@@ -52,7 +145,8 @@ func (r *Repository) GetOrders(vendorID uint, status string, offset, limit int)
     var orders []database.Order
     var total int64
 
-    query := r.db.Model(&database.Order{}).Where("vendor_id = ?", vendorID)
+    // Orders awaiting payment authorization are not shown to the vendor
+    query := r.db.Model(&database.Order{}).Where("vendor_id = ? AND released_at IS NOT NULL", vendorID)
     if status != "" {
         query = query.Where("status = ?", status)
     }
@@ -60,6 +154,7 @@ func (r *Repository) GetOrders(vendorID uint, status string, offset, limit int)
 
     err := query.Preload("Student.User").
         Preload("OrderItems.MenuItem").
+        Preload("OrderItems.Options").
         Preload("AssignedRider.User").
         Order("created_at DESC").
         Offset(offset).
@@ -69,23 +164,6 @@ func (r *Repository) GetOrders(vendorID uint, status string, offset, limit int)
     return orders, total, err
 }
 
-func (r *Repository) UpdateOrderStatus(orderID uint, status database.OrderStatus, timestamp *time.Time) error {
-    updates := map[string]interface{}{
-        "status": status,
-    }
-    if timestamp != nil {
-        switch status {
-        case database.OrderStatusConfirmed:
-            updates["confirmed_at"] = timestamp
-        case database.OrderStatusPreparing:
-            updates["prepared_at"] = timestamp
-        case database.OrderStatusReady:
-            updates["ready_at"] = timestamp
-        }
-    }
-    return r.db.Model(&database.Order{}).Where("id = ?", orderID).Updates(updates).Error
-}
-
 func (r *Repository) GetEarnings(vendorID uint, startDate, endDate time.Time) ([]database.Order, error) {
     var orders []database.Order
     err := r.db.Where("vendor_id = ? AND status = ? AND created_at BETWEEN ? AND ?",
@@ -95,7 +173,7 @@ func (r *Repository) GetEarnings(vendorID uint, startDate, endDate time.Time) ([
     return orders, err
 }
 
-func (r *Repository) GetVendorBalance(vendorID uint) (float64, error) {
+func (r *Repository) GetVendorBalance(vendorID uint) (pkg.Money, error) {
     var vendor database.Vendor
     err := r.db.Select("current_balance").First(&vendor, vendorID).Error
     return vendor.CurrentBalance, err
@@ -120,6 +198,16 @@ func (r *Repository) GetPublicVendors(offset, limit int) ([]database.Vendor, int
     return vendors, total, err
 }
 
+// GetOpenVendors returns every open vendor, best rated first
+func (r *Repository) GetOpenVendors() ([]database.Vendor, error) {
+    var vendors []database.Vendor
+    err := r.db.Where("is_open = ?", true).
+        Preload("User").
+        Order("rating DESC, total_orders DESC").
+        Find(&vendors).Error
+    return vendors, err
+}
+
 // GetVendorByID gets a vendor by ID
 func (r *Repository) GetVendorByID(vendorID uint) (*database.Vendor, error) {
     var vendor database.Vendor
@@ -131,6 +219,8 @@ func (r *Repository) GetVendorByID(vendorID uint) (*database.Vendor, error) {
 func (r *Repository) GetPublicMenuItems(vendorID uint) ([]database.MenuItem, error) {
     var items []database.MenuItem
     err := r.db.Where("vendor_id = ? AND is_available = ?", vendorID, true).
+        Preload("OptionGroups", orderOptions).
+        Preload("OptionGroups.Options", availableOptions).
         Order("category, sort_order, name").
         Find(&items).Error
     return items, err
@@ -141,6 +231,8 @@ func (r *Repository) GetPublicMenuItemByID(itemID uint) (*database.MenuItem, err
     var item database.MenuItem
     err := r.db.Where("id = ? AND is_available = ?", itemID, true).
         Preload("Vendor").
+        Preload("OptionGroups", orderOptions).
+        Preload("OptionGroups.Options", availableOptions).
         First(&item).Error
     return &item, err
 }
@@ -149,7 +241,7 @@ func (r *Repository) GetPublicMenuItemByID(itemID uint) (*database.MenuItem, err
 
 func (r *Repository) GetOrderByID(orderID uint) (*database.Order, error) {
     var order database.Order
-    err := r.db.Preload("Vendor").Preload("Student").Preload("OrderItems.MenuItem").
+    err := r.db.Preload("Vendor").Preload("Student").Preload("OrderItems.MenuItem").Preload("OrderItems.Options").
         Where("id = ?", orderID).First(&order).Error
     if err != nil {
         return nil, err
@@ -3,10 +3,13 @@ package vendors
 import (
 	"context"
 	"errors"
-	"fmt"
 	"food-delivery-backend/database"
 	"food-delivery-backend/notifications"
+	"food-delivery-backend/orders"
+	"food-delivery-backend/pkg"
 	"food-delivery-backend/redis"
+	"math"
+	"sort"
 	"time"
 
 	"go.uber.org/zap"
@@ -14,14 +17,16 @@ import (
 
 type Service struct {
 	repo        *Repository
+	orderFlow   *orders.StateMachine
 	notifier    *notifications.Service
 	redisClient *redis.RedisClient
 	logger      *zap.Logger
 }
 
-func NewService(repo *Repository, notifier *notifications.Service, redisClient *redis.RedisClient, logger *zap.Logger) *Service {
+func NewService(repo *Repository, orderFlow *orders.StateMachine, notifier *notifications.Service, redisClient *redis.RedisClient, logger *zap.Logger) *Service {
 	return &Service{
 		repo:        repo,
+		orderFlow:   orderFlow,
 		notifier:    notifier,
 		redisClient: redisClient,
 		logger:      logger,
@@ -68,6 +73,9 @@ func (s *Service) UpdateVendorProfile(vendorID uint, req *UpdateVendorRequest) (
 	if req.MinimumOrder != 0 {
 		vendor.MinimumOrder = req.MinimumOrder
 	}
+	if req.SlotCapacity != nil {
+		vendor.SlotCapacity = *req.SlotCapacity
+	}
 
 	if err := s.repo.UpdateVendor(vendor); err != nil {
 		s.logger.Error("Failed to update vendor", zap.Error(err))
@@ -251,91 +259,36 @@ func (s *Service) GetOrder(vendorID uint, orderID uint) (*database.Order, error)
 	if order.VendorID != vendor.ID {
 		return nil, errors.New("unauthorized to view this order")
 	}
+	if order.ReleasedAt == nil {
+		return nil, errors.New("order not found")
+	}
 
 	return order, nil
 }
 
 func (s *Service) AcceptOrder(vendorID uint, orderID uint) error {
-	vendor, err := s.repo.GetVendorByUserID(vendorID)
-	if err != nil {
-		return errors.New("vendor not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.VendorID != vendor.ID {
-		return errors.New("unauthorized to accept this order")
-	}
-
-	if order.Status != database.OrderStatusPending {
-		return errors.New("order cannot be accepted in current status")
-	}
-
-	now := time.Now()
-	if err := s.repo.UpdateOrderStatus(orderID, database.OrderStatusConfirmed, &now); err != nil {
-		return err
-	}
-
-	// Reload and notify
-	if s.notifier != nil {
-		updated, err := s.repo.GetOrderByID(orderID)
-		if err == nil {
-			s.notifier.NotifyOrderUpdate(updated, database.OrderStatusConfirmed, "")
-			s.notifier.NotifyAdmin("Order Accepted",
-				fmt.Sprintf("Order #%s was accepted by the vendor", updated.OrderNumber))
-		}
-	}
-
-	return nil
+	return s.transition(vendorID, orderID, database.OrderStatusConfirmed, "")
 }
 
 func (s *Service) RejectOrder(vendorID uint, orderID uint, reason string) error {
-	vendor, err := s.repo.GetVendorByUserID(vendorID)
-	if err != nil {
-		return errors.New("vendor not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.VendorID != vendor.ID {
-		return errors.New("unauthorized to reject this order")
-	}
-
-	if order.Status != database.OrderStatusPending {
-		return errors.New("order cannot be rejected in current status")
-	}
-
-	now := time.Now()
-	return s.repo.UpdateOrderStatus(orderID, database.OrderStatusRejected, &now)
+	return s.transition(vendorID, orderID, database.OrderStatusRejected, reason)
 }
 
 func (s *Service) MarkOrderReady(vendorID uint, orderID uint) error {
-	vendor, err := s.repo.GetVendorByUserID(vendorID)
-	if err != nil {
-		return errors.New("vendor not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		return errors.New("order not found")
-	}
-
-	if order.VendorID != vendor.ID {
-		return errors.New("unauthorized to update this order")
-	}
-
-	if order.Status != database.OrderStatusPreparing {
-		return errors.New("order must be in preparing status to mark as ready")
-	}
+	return s.transition(vendorID, orderID, database.OrderStatusReady, "")
+}
 
-	now := time.Now()
-	return s.repo.UpdateOrderStatus(orderID, database.OrderStatusReady, &now)
+// transition routes a vendor action through the order state machine, which
+// checks ownership and the allowed transitions. vendorID is the user id.
+func (s *Service) transition(vendorID uint, orderID uint, to database.OrderStatus, reason string) error {
+	_, err := s.orderFlow.Apply(orders.TransitionRequest{
+		OrderID:   orderID,
+		To:        to,
+		ActorID:   vendorID,
+		ActorRole: "vendor",
+		Reason:    reason,
+	})
+	return err
 }
 
 func (s *Service) GetEarnings(vendorID uint, startDateStr, endDateStr string) (*EarningsResponse, error) {
@@ -381,7 +334,7 @@ func (s *Service) GetEarnings(vendorID uint, startDateStr, endDateStr string) (*
 
 	dailyMap := make(map[string]*DailyEarnings)
 	var totalOrders int
-	var totalRevenue, totalCommission, totalEarnings float64
+	var totalRevenue, totalCommission, totalEarnings pkg.Money
 
 	for _, order := range orders {
 		date := order.CreatedAt.Format("2006-01-02")
@@ -413,7 +366,7 @@ func (s *Service) GetEarnings(vendorID uint, startDateStr, endDateStr string) (*
 	response.Summary.TotalCommission = totalCommission
 	response.Summary.TotalEarnings = totalEarnings
 	if totalOrders > 0 {
-		response.Summary.AverageOrderValue = totalRevenue / float64(totalOrders)
+		response.Summary.AverageOrderValue = totalRevenue.Div(int64(totalOrders))
 	}
 
 	// Get current balance
@@ -423,10 +376,60 @@ func (s *Service) GetEarnings(vendorID uint, startDateStr, endDateStr string) (*
 	return response, nil
 }
 
-// GetPublicVendors returns all active vendors for public viewing
-func (s *Service) GetPublicVendors(page, limit int) ([]database.Vendor, int64, error) {
+// GetPublicVendors returns all active vendors for public viewing. When near
+// is given only vendors that deliver to that point are returned, closest first.
+func (s *Service) GetPublicVendors(page, limit int, near *GeoPoint) ([]PublicVendor, int64, error) {
 	offset := (page - 1) * limit
-	return s.repo.GetPublicVendors(offset, limit)
+
+	if near == nil {
+		vendors, total, err := s.repo.GetPublicVendors(offset, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		result := make([]PublicVendor, len(vendors))
+		for i := range vendors {
+			result[i] = PublicVendor{Vendor: vendors[i]}
+		}
+		return s.withKitchens(result), total, nil
+	}
+
+	// Delivery radii are per vendor, so filter in memory before paginating
+	vendors, err := s.repo.GetOpenVendors()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var inZone []PublicVendor
+	for i := range vendors {
+		if !orders.Delivers(&vendors[i], near.Lat, near.Lng) {
+			continue
+		}
+		pv := PublicVendor{Vendor: vendors[i]}
+		if orders.HasLocation(&vendors[i]) {
+			distance := math.Round(orders.DistanceToVendor(&vendors[i], near.Lat, near.Lng)*100) / 100
+			pv.DistanceKm = &distance
+		}
+		inZone = append(inZone, pv)
+	}
+
+	// Vendors without coordinates sort last
+	sort.SliceStable(inZone, func(i, j int) bool {
+		a, b := inZone[i].DistanceKm, inZone[j].DistanceKm
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+
+	total := int64(len(inZone))
+	if offset >= len(inZone) {
+		return []PublicVendor{}, total, nil
+	}
+	end := offset + limit
+	if end > len(inZone) {
+		end = len(inZone)
+	}
+	return s.withKitchens(inZone[offset:end]), total, nil
 }
 
 // GetPublicMenu returns a vendor's menu for public viewing
@@ -461,31 +464,6 @@ func (s *Service) GetPublicMenuItem(itemID uint) (*database.MenuItem, error) {
 func (s *Service) UpdateOrderStatus(vendorID uint, orderID uint, status string) error {
 	s.logger.Info("Vendor UpdateOrderStatus called", zap.Uint("vendor_user_id", vendorID), zap.Uint("order_id", orderID), zap.String("requested_status", status))
 
-	vendor, err := s.repo.GetVendorByUserID(vendorID)
-	if err != nil {
-		s.logger.Error("Vendor not found for UpdateOrderStatus", zap.Error(err), zap.Uint("vendor_user_id", vendorID))
-		return errors.New("vendor not found")
-	}
-
-	order, err := s.repo.GetOrderByID(orderID)
-	if err != nil {
-		s.logger.Error("Order not found for UpdateOrderStatus", zap.Error(err), zap.Uint("vendor_user_id", vendorID), zap.Uint("order_id", orderID))
-		return errors.New("order not found")
-	}
-
-	if order.VendorID != vendor.ID {
-		return errors.New("unauthorized to update this order")
-	}
-
-	// Validate status transition
-	if order.Status != database.OrderStatusConfirmed && status == "preparing" {
-		return errors.New("order must be confirmed before starting preparation")
-	}
-
-	if order.Status != database.OrderStatusPreparing && status == "ready" {
-		return errors.New("order must be in preparing status to mark as ready")
-	}
-
 	var newStatus database.OrderStatus
 	switch status {
 	case "preparing":
@@ -497,6 +475,5 @@ func (s *Service) UpdateOrderStatus(vendorID uint, orderID uint, status string)
 		return errors.New("invalid status")
 	}
 
-	now := time.Now()
-	return s.repo.UpdateOrderStatus(orderID, newStatus, &now)
+	return s.transition(vendorID, orderID, newStatus, "")
 }
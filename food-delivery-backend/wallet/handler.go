@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"errors"
+	"food-delivery-backend/payments"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetWallet returns the student's wallet balance
+// @Summary Get wallet
+// @Tags Students
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} pkg.Response{data=Summary}
+// @Router /student/wallet [get]
+func (h *Handler) GetWallet(c *gin.Context) {
+	summary, err := h.service.GetWallet(c.GetUint("user_id"))
+	if err != nil {
+		pkg.SendError(c, http.StatusNotFound, "Failed to get wallet", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Wallet retrieved successfully", summary)
+}
+
+// TopUp adds money to the student's wallet by card
+// @Summary Top up wallet
+// @Tags Students
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body TopUpRequest true "Top-up"
+// @Success 201 {object} pkg.Response{data=database.WalletTopUp}
+// @Failure 422 {object} pkg.Response "Payment declined"
+// @Router /student/wallet/top-ups [post]
+func (h *Handler) TopUp(c *gin.Context) {
+	var req TopUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	topUp, err := h.service.TopUp(c.GetUint("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, payments.ErrPaymentDeclined) {
+			pkg.SendErrorCode(c, http.StatusUnprocessableEntity, payments.CodePaymentDeclined, "Failed to top up wallet", err)
+			return
+		}
+		pkg.SendError(c, http.StatusBadRequest, "Failed to top up wallet", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Wallet top-up created successfully", topUp)
+}
+
+// GetTopUps lists the student's wallet top-ups
+// @Summary List wallet top-ups
+// @Tags Students
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /student/wallet/top-ups [get]
+func (h *Handler) GetTopUps(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	topUps, total, err := h.service.GetTopUps(c.GetUint("user_id"), page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get top-ups", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Top-ups retrieved successfully", topUps, page, limit, total)
+}
+
+// GetStatement returns the student's wallet statement
+// @Summary Get wallet statement
+// @Tags Students
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "week (default) or month"
+// @Param date query string false "Any date in the period (YYYY-MM-DD), default today"
+// @Success 200 {object} pkg.Response{data=Statement}
+// @Router /student/wallet/statement [get]
+func (h *Handler) GetStatement(c *gin.Context) {
+	statement, err := h.service.GetStatement(c.GetUint("user_id"), c.Query("period"), c.Query("date"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Failed to get statement", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Statement retrieved successfully", statement)
+}
+
+// Credit adds money to a student's wallet
+// @Summary Credit student wallet
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreditRequest true "Credit"
+// @Success 201 {object} pkg.Response{data=database.LedgerEntry}
+// @Router /admin/wallets/credits [post]
+func (h *Handler) Credit(c *gin.Context) {
+	var req CreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	entry, err := h.service.Credit(c.GetUint("user_id"), &req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrStudentNotFound) {
+			status = http.StatusNotFound
+		}
+		pkg.SendError(c, status, "Failed to credit wallet", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Wallet credited successfully", entry)
+}
@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"food-delivery-backend/pkg"
+	"time"
+)
+
+type TopUpRequest struct {
+	Amount       pkg.Money `json:"amount" binding:"required,gt=0"`
+	PaymentToken string    `json:"payment_token" binding:"required"` // card token from the payment provider
+}
+
+// CreditRequest adds money to a student's wallet, e.g. as a goodwill gesture
+type CreditRequest struct {
+	UserID uint      `json:"user_id" binding:"required"`
+	Amount pkg.Money `json:"amount" binding:"required,gt=0"`
+	Reason string    `json:"reason" binding:"required"`
+}
+
+type Summary struct {
+	Balance       pkg.Money `json:"balance"`
+	PendingTopUps pkg.Money `json:"pending_top_ups"`
+}
+
+// Statement lists a student's wallet movements for one period
+type Statement struct {
+	UserID         uint            `json:"user_id"`
+	PeriodStart    string          `json:"period_start"`
+	PeriodEnd      string          `json:"period_end"`
+	OpeningBalance pkg.Money       `json:"opening_balance"`
+	TopUps         pkg.Money       `json:"top_ups"`
+	Spent          pkg.Money       `json:"spent"`
+	Refunds        pkg.Money       `json:"refunds"`
+	Credits        pkg.Money       `json:"credits"`
+	ClosingBalance pkg.Money       `json:"closing_balance"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+type StatementLine struct {
+	Date        time.Time `json:"date"`
+	EntryID     uint      `json:"entry_id"`
+	OrderID     *uint     `json:"order_id,omitempty"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Amount      pkg.Money `json:"amount"`
+	Balance     pkg.Money `json:"balance"`
+}
@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetBalance(userID uint) (pkg.Money, error) {
+	var student database.Student
+	err := r.db.Select("wallet_balance").Where("user_id = ?", userID).First(&student).Error
+	return student.WalletBalance, err
+}
+
+func (r *Repository) GetPendingTopUps(userID uint) (pkg.Money, error) {
+	var total pkg.Money
+	err := r.db.Model(&database.WalletTopUp{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND status = ?", userID, database.WalletTopUpStatusPending).
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *Repository) GetTopUps(userID uint, offset, limit int) ([]database.WalletTopUp, int64, error) {
+	var topUps []database.WalletTopUp
+	var total int64
+
+	query := r.db.Model(&database.WalletTopUp{}).Where("user_id = ?", userID)
+	query.Count(&total)
+	err := query.Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&topUps).Error
+	return topUps, total, err
+}
+
+// LockTopUpByIntent loads a top-up with a row lock for the rest of tx
+func (r *Repository) LockTopUpByIntent(tx *gorm.DB, intentID string) (*database.WalletTopUp, error) {
+	var topUp database.WalletTopUp
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("intent_id = ?", intentID).First(&topUp).Error
+	return &topUp, err
+}
+
+func (r *Repository) StudentExists(userID uint) bool {
+	var count int64
+	r.db.Model(&database.Student{}).Where("user_id = ?", userID).Count(&count)
+	return count > 0
+}
+
+// GetBalanceBefore sums a wallet's lines posted before at
+func (r *Repository) GetBalanceBefore(userID uint, at time.Time) (pkg.Money, error) {
+	var balance pkg.Money
+	err := r.db.Model(&database.Transaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("account = ? AND user_id = ? AND created_at < ?", database.LedgerAccountWallet, userID, at).
+		Scan(&balance).Error
+	return balance, err
+}
+
+func (r *Repository) GetTransactions(userID uint, start, end time.Time) ([]database.Transaction, error) {
+	var txns []database.Transaction
+	err := r.db.Where("account = ? AND user_id = ? AND created_at >= ? AND created_at < ?",
+		database.LedgerAccountWallet, userID, start, end).
+		Order("created_at ASC, id ASC").
+		Find(&txns).Error
+	return txns, err
+}
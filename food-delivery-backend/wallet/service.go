@@ -0,0 +1,267 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/payments"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrStudentNotFound = errors.New("student profile not found")
+
+type Service struct {
+	repo     *Repository
+	db       *gorm.DB
+	payments *payments.Service
+	notifier *notifications.Service
+	cfg      *config.Config
+	logger   *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, paymentService *payments.Service, notifier *notifications.Service, cfg *config.Config, logger *zap.Logger) *Service {
+	s := &Service{
+		repo:     repo,
+		db:       db,
+		payments: paymentService,
+		notifier: notifier,
+		cfg:      cfg,
+		logger:   logger,
+	}
+	paymentService.HandleIntents(payments.IntentHandler{
+		Name:    "wallet_top_up",
+		Apply:   s.applyTopUpEvent,
+		Applied: s.topUpEventApplied,
+	})
+	return s
+}
+
+func (s *Service) GetWallet(userID uint) (*Summary, error) {
+	balance, err := s.repo.GetBalance(userID)
+	if err != nil {
+		return nil, ErrStudentNotFound
+	}
+	pending, err := s.repo.GetPendingTopUps(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Summary{Balance: balance, PendingTopUps: pending}, nil
+}
+
+// TopUp charges the student's card and credits the wallet once the payment
+// is captured. Top-ups the provider has not authorized yet stay pending
+// until its webhook arrives.
+func (s *Service) TopUp(userID uint, req *TopUpRequest) (*database.WalletTopUp, error) {
+	minimum, maximum := pkg.NewMoney(s.cfg.MinWalletTopUp), pkg.NewMoney(s.cfg.MaxWalletTopUp)
+	if req.Amount < minimum || req.Amount > maximum {
+		return nil, fmt.Errorf("top-ups must be between %s and %s", minimum, maximum)
+	}
+	if !s.repo.StudentExists(userID) {
+		return nil, ErrStudentNotFound
+	}
+
+	intent, err := s.payments.Authorize("topup_"+pkg.GenerateTransactionID(), req.Amount, req.PaymentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	topUp := &database.WalletTopUp{
+		UserID:   userID,
+		Amount:   req.Amount,
+		Status:   database.WalletTopUpStatusPending,
+		Provider: s.payments.ProviderName(),
+		IntentID: intent.ID,
+	}
+	if intent.Status != payments.IntentAuthorized {
+		if err := s.db.Create(topUp).Error; err != nil {
+			s.payments.Void(intent)
+			s.logger.Error("Failed to create wallet top-up", zap.Uint("user_id", userID), zap.Error(err))
+			return nil, errors.New("failed to top up wallet")
+		}
+		return topUp, nil
+	}
+
+	captured, err := s.payments.CaptureIntent(intent)
+	if err != nil {
+		s.payments.Void(intent)
+		return nil, err
+	}
+
+	markCompleted(topUp)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(topUp).Error; err != nil {
+			return err
+		}
+		return ledger.PostWalletTopUp(tx, topUp)
+	})
+	if err != nil {
+		s.payments.Void(captured)
+		s.logger.Error("Failed to credit wallet top-up", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, errors.New("failed to top up wallet")
+	}
+
+	s.notifyTopUp(topUp)
+	return topUp, nil
+}
+
+func (s *Service) GetTopUps(userID uint, page, limit int) ([]database.WalletTopUp, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetTopUps(userID, offset, limit)
+}
+
+// GetStatement lists the wallet's movements for the week or month
+// containing dateStr (YYYY-MM-DD, default today)
+func (s *Service) GetStatement(userID uint, period, dateStr string) (*Statement, error) {
+	date := time.Now()
+	if dateStr != "" {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", dateStr, time.Local); err != nil {
+			return nil, errors.New("invalid date format")
+		}
+	}
+	start, end, err := ledger.SettlementPeriod(period, date)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := s.repo.GetBalanceBefore(userID, start)
+	if err != nil {
+		s.logger.Error("Failed to get wallet opening balance", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, errors.New("failed to generate statement")
+	}
+	txns, err := s.repo.GetTransactions(userID, start, end)
+	if err != nil {
+		s.logger.Error("Failed to get wallet statement lines", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, errors.New("failed to generate statement")
+	}
+
+	statement := &Statement{
+		UserID:         userID,
+		PeriodStart:    start.Format("2006-01-02"),
+		PeriodEnd:      end.AddDate(0, 0, -1).Format("2006-01-02"),
+		OpeningBalance: opening,
+		Lines:          []StatementLine{},
+	}
+	balance := opening
+	for _, txn := range txns {
+		balance += txn.Amount
+		switch txn.Type {
+		case ledger.TypeTopUp:
+			statement.TopUps += txn.Amount
+		case ledger.TypePayment:
+			statement.Spent += txn.Amount
+		case ledger.TypeRefund:
+			statement.Refunds += txn.Amount
+		default:
+			statement.Credits += txn.Amount
+		}
+		statement.Lines = append(statement.Lines, StatementLine{
+			Date:        txn.CreatedAt,
+			EntryID:     txn.EntryID,
+			OrderID:     txn.OrderID,
+			Type:        txn.Type,
+			Description: txn.Description,
+			Amount:      txn.Amount,
+			Balance:     balance,
+		})
+	}
+	statement.ClosingBalance = balance
+	return statement, nil
+}
+
+// ================ ADMIN ================
+
+// Credit adds money to a student's wallet at the platform's expense
+func (s *Service) Credit(adminID uint, req *CreditRequest) (*database.LedgerEntry, error) {
+	if !s.repo.StudentExists(req.UserID) {
+		return nil, ErrStudentNotFound
+	}
+
+	var entry *database.LedgerEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = ledger.PostAdjustment(tx, ledger.Adjustment{
+			Account:     database.LedgerAccountWallet,
+			UserID:      req.UserID,
+			Amount:      req.Amount,
+			Reason:      req.Reason,
+			ReferenceID: "wallet_credit:" + pkg.GenerateTransactionID(),
+			CreatedByID: &adminID,
+		})
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to credit wallet", zap.Uint("user_id", req.UserID), zap.Error(err))
+		return nil, errors.New("failed to credit wallet")
+	}
+
+	s.notifier.NotifyStudent(req.UserID, "Wallet Credited",
+		fmt.Sprintf("%s was added to your wallet: %s", req.Amount, req.Reason),
+		"wallet_credit", fmt.Sprintf("%d", entry.ID))
+	return entry, nil
+}
+
+// applyTopUpEvent settles a pending top-up from a provider webhook
+func (s *Service) applyTopUpEvent(tx *gorm.DB, event *payments.WebhookEvent) (bool, error) {
+	topUp, err := s.repo.LockTopUpByIntent(tx, event.IntentID)
+	if err != nil {
+		return false, nil
+	}
+	if topUp.Status != database.WalletTopUpStatusPending {
+		return true, nil
+	}
+
+	switch event.Type {
+	case payments.EventAuthorized:
+		intent := &payments.Intent{ID: topUp.IntentID, Amount: topUp.Amount, Status: payments.IntentAuthorized}
+		if _, err := s.payments.CaptureIntent(intent); err != nil {
+			return true, err
+		}
+	case payments.EventCaptured:
+	case payments.EventFailed:
+		topUp.Status = database.WalletTopUpStatusFailed
+		topUp.FailureReason = event.FailureReason
+		return true, tx.Save(topUp).Error
+	default:
+		return true, nil
+	}
+
+	markCompleted(topUp)
+	if err := tx.Save(topUp).Error; err != nil {
+		return true, err
+	}
+	return true, ledger.PostWalletTopUp(tx, topUp)
+}
+
+func (s *Service) topUpEventApplied(event *payments.WebhookEvent) {
+	var topUp database.WalletTopUp
+	if err := s.db.Where("intent_id = ?", event.IntentID).First(&topUp).Error; err != nil {
+		return
+	}
+	s.notifyTopUp(&topUp)
+}
+
+func markCompleted(topUp *database.WalletTopUp) {
+	now := time.Now()
+	topUp.Status = database.WalletTopUpStatusCompleted
+	topUp.CompletedAt = &now
+}
+
+func (s *Service) notifyTopUp(topUp *database.WalletTopUp) {
+	reference := fmt.Sprintf("%d", topUp.ID)
+	switch topUp.Status {
+	case database.WalletTopUpStatusCompleted:
+		s.notifier.NotifyStudent(topUp.UserID, "Wallet Topped Up",
+			fmt.Sprintf("%s was added to your wallet", topUp.Amount), "wallet_top_up", reference)
+	case database.WalletTopUpStatusFailed:
+		s.notifier.NotifyStudent(topUp.UserID, "Top-up Failed",
+			fmt.Sprintf("Your wallet top-up of %s failed", topUp.Amount), "wallet_top_up", reference)
+	}
+}
@@ -0,0 +1,257 @@
+package withdrawals
+
+import (
+	"errors"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrWithdrawalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrOpenWithdrawal), errors.Is(err, ErrInvalidStatus):
+		return http.StatusConflict
+	case errors.Is(err, ledger.ErrInsufficientBalance):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+func withdrawalID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid withdrawal ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// RequestWithdrawal asks for part of the caller's balance to be paid out
+// @Summary Request withdrawal
+// @Tags Vendors, Riders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateWithdrawalRequest true "Withdrawal"
+// @Success 201 {object} pkg.Response{data=database.Withdrawal}
+// @Router /vendors/withdrawals [post]
+// @Router /riders/withdrawals [post]
+func (h *Handler) RequestWithdrawal(c *gin.Context) {
+	var req CreateWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	account := database.LedgerAccount(c.GetString("user_role"))
+	withdrawal, err := h.service.RequestWithdrawal(c.GetUint("user_id"), account, &req)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to request withdrawal", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusCreated, "Withdrawal requested successfully", withdrawal)
+}
+
+// GetMyWithdrawals lists the caller's withdrawals
+// @Summary List my withdrawals
+// @Tags Vendors, Riders
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /vendors/withdrawals [get]
+// @Router /riders/withdrawals [get]
+func (h *Handler) GetMyWithdrawals(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	withdrawals, total, err := h.service.GetMyWithdrawals(c.GetUint("user_id"), page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get withdrawals", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Withdrawals retrieved successfully", withdrawals, page, limit, total)
+}
+
+// CancelWithdrawal cancels a withdrawal that has not been reviewed yet
+// @Summary Cancel withdrawal
+// @Tags Vendors, Riders
+// @Security BearerAuth
+// @Param id path int true "Withdrawal ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.Withdrawal}
+// @Router /vendors/withdrawals/{id}/cancel [post]
+// @Router /riders/withdrawals/{id}/cancel [post]
+func (h *Handler) CancelWithdrawal(c *gin.Context) {
+	id, ok := withdrawalID(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := h.service.CancelWithdrawal(c.GetUint("user_id"), id)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to cancel withdrawal", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Withdrawal cancelled successfully", withdrawal)
+}
+
+// GetWithdrawals lists withdrawals for review
+// @Summary List withdrawals
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, approved, rejected, paid or cancelled"
+// @Param account query string false "vendor or rider"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} pkg.PaginatedResponse
+// @Router /admin/withdrawals [get]
+func (h *Handler) GetWithdrawals(c *gin.Context) {
+	filters := WithdrawalFilters{
+		Status:  database.WithdrawalStatus(c.Query("status")),
+		Account: database.LedgerAccount(c.Query("account")),
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	withdrawals, total, err := h.service.GetWithdrawals(&filters, page, limit)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, "Failed to get withdrawals", err.Error())
+		return
+	}
+
+	pkg.SendPaginated(c, http.StatusOK, "Withdrawals retrieved successfully", withdrawals, page, limit, total)
+}
+
+// GetWithdrawal returns a withdrawal
+// @Summary Get withdrawal
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Withdrawal ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.Withdrawal}
+// @Router /admin/withdrawals/{id} [get]
+func (h *Handler) GetWithdrawal(c *gin.Context) {
+	id, ok := withdrawalID(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := h.service.GetWithdrawal(id)
+	if err != nil {
+		pkg.SendError(c, http.StatusNotFound, "Withdrawal not found", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Withdrawal retrieved successfully", withdrawal)
+}
+
+// ApproveWithdrawal approves a pending withdrawal for payment
+// @Summary Approve withdrawal
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Withdrawal ID"
+// @Produce json
+// @Success 200 {object} pkg.Response{data=database.Withdrawal}
+// @Router /admin/withdrawals/{id}/approve [post]
+func (h *Handler) ApproveWithdrawal(c *gin.Context) {
+	id, ok := withdrawalID(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := h.service.ApproveWithdrawal(c.GetUint("user_id"), id)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to approve withdrawal", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Withdrawal approved successfully", withdrawal)
+}
+
+// RejectWithdrawal rejects a withdrawal and releases the held amount
+// @Summary Reject withdrawal
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Withdrawal ID"
+// @Accept json
+// @Produce json
+// @Param request body RejectWithdrawalRequest true "Rejection reason"
+// @Success 200 {object} pkg.Response{data=database.Withdrawal}
+// @Router /admin/withdrawals/{id}/reject [post]
+func (h *Handler) RejectWithdrawal(c *gin.Context) {
+	id, ok := withdrawalID(c)
+	if !ok {
+		return
+	}
+
+	var req RejectWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	withdrawal, err := h.service.RejectWithdrawal(c.GetUint("user_id"), id, req.Reason)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to reject withdrawal", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Withdrawal rejected successfully", withdrawal)
+}
+
+// MarkPaid records that an approved withdrawal has been paid
+// @Summary Mark withdrawal paid
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Withdrawal ID"
+// @Accept json
+// @Produce json
+// @Param request body MarkPaidRequest true "Payment reference"
+// @Success 200 {object} pkg.Response{data=database.Withdrawal}
+// @Router /admin/withdrawals/{id}/mark-paid [post]
+func (h *Handler) MarkPaid(c *gin.Context) {
+	id, ok := withdrawalID(c)
+	if !ok {
+		return
+	}
+
+	var req MarkPaidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	withdrawal, err := h.service.MarkPaid(c.GetUint("user_id"), id, req.PaymentReference)
+	if err != nil {
+		pkg.SendError(c, errorStatus(err), "Failed to mark withdrawal paid", err.Error())
+		return
+	}
+
+	pkg.SendSuccess(c, http.StatusOK, "Withdrawal marked as paid", withdrawal)
+}
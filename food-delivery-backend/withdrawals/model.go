@@ -0,0 +1,26 @@
+package withdrawals
+
+import (
+	"food-delivery-backend/database"
+	"food-delivery-backend/pkg"
+)
+
+type CreateWithdrawalRequest struct {
+	Amount        pkg.Money `json:"amount" binding:"required,gt=0"`
+	PayoutDetails string    `json:"payout_details" binding:"required"` // bank or mobile money account
+	Note          string    `json:"note"`
+}
+
+type RejectWithdrawalRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type MarkPaidRequest struct {
+	PaymentReference string `json:"payment_reference" binding:"required"`
+}
+
+type WithdrawalFilters struct {
+	Status  database.WithdrawalStatus
+	Account database.LedgerAccount
+	UserID  *uint
+}
@@ -0,0 +1,62 @@
+package withdrawals
+
+import (
+	"food-delivery-backend/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetWithdrawals(filters *WithdrawalFilters, offset, limit int) ([]database.Withdrawal, int64, error) {
+	var withdrawals []database.Withdrawal
+	var total int64
+
+	query := r.db.Model(&database.Withdrawal{})
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	if filters.Account != "" {
+		query = query.Where("account = ?", filters.Account)
+	}
+	if filters.UserID != nil {
+		query = query.Where("user_id = ?", *filters.UserID)
+	}
+
+	query.Count(&total)
+	err := query.Preload("User").
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&withdrawals).Error
+	return withdrawals, total, err
+}
+
+func (r *Repository) GetWithdrawal(withdrawalID uint) (*database.Withdrawal, error) {
+	var withdrawal database.Withdrawal
+	err := r.db.Preload("User").First(&withdrawal, withdrawalID).Error
+	return &withdrawal, err
+}
+
+// LockWithdrawal loads a withdrawal with a row lock for the rest of tx
+func (r *Repository) LockWithdrawal(tx *gorm.DB, withdrawalID uint) (*database.Withdrawal, error) {
+	var withdrawal database.Withdrawal
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&withdrawal, withdrawalID).Error
+	return &withdrawal, err
+}
+
+// HasOpenWithdrawal reports whether the user has a withdrawal awaiting review or payment
+func (r *Repository) HasOpenWithdrawal(tx *gorm.DB, userID uint) bool {
+	var count int64
+	tx.Model(&database.Withdrawal{}).
+		Where("user_id = ? AND status IN ?", userID,
+			[]database.WithdrawalStatus{database.WithdrawalStatusPending, database.WithdrawalStatusApproved}).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,235 @@
+package withdrawals
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery-backend/config"
+	"food-delivery-backend/database"
+	"food-delivery-backend/ledger"
+	"food-delivery-backend/notifications"
+	"food-delivery-backend/pkg"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+	ErrOpenWithdrawal     = errors.New("you already have a withdrawal awaiting payment")
+	ErrInvalidStatus      = errors.New("withdrawal cannot be changed in its current status")
+)
+
+type Service struct {
+	repo     *Repository
+	db       *gorm.DB
+	notifier *notifications.Service
+	cfg      *config.Config
+	logger   *zap.Logger
+}
+
+func NewService(repo *Repository, db *gorm.DB, notifier *notifications.Service, cfg *config.Config, logger *zap.Logger) *Service {
+	return &Service{
+		repo:     repo,
+		db:       db,
+		notifier: notifier,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+// MinimumAmount is the smallest withdrawal the account holder can request
+func (s *Service) MinimumAmount(account database.LedgerAccount) pkg.Money {
+	if account == database.LedgerAccountRider {
+		return pkg.NewMoney(s.cfg.MinRiderWithdrawal)
+	}
+	return pkg.NewMoney(s.cfg.MinVendorWithdrawal)
+}
+
+// RequestWithdrawal creates a withdrawal and holds the amount out of the
+// holder's available balance until it is paid or released
+func (s *Service) RequestWithdrawal(userID uint, account database.LedgerAccount, req *CreateWithdrawalRequest) (*database.Withdrawal, error) {
+	if !ledger.IsHolderAccount(account) {
+		return nil, errors.New("only vendors and riders can withdraw")
+	}
+	if minimum := s.MinimumAmount(account); req.Amount < minimum {
+		return nil, fmt.Errorf("the minimum withdrawal is %s", minimum)
+	}
+
+	withdrawal := &database.Withdrawal{
+		UserID:        userID,
+		Account:       account,
+		Amount:        req.Amount,
+		Status:        database.WithdrawalStatusPending,
+		PayoutDetails: req.PayoutDetails,
+		Note:          req.Note,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		balance, err := ledger.LockBalance(tx, account, userID)
+		if err != nil {
+			return err
+		}
+		if s.repo.HasOpenWithdrawal(tx, userID) {
+			return ErrOpenWithdrawal
+		}
+		if balance < req.Amount {
+			return fmt.Errorf("%w: %s available", ledger.ErrInsufficientBalance, balance)
+		}
+
+		if err := tx.Create(withdrawal).Error; err != nil {
+			return err
+		}
+		return ledger.PostWithdrawalHold(tx, withdrawal)
+	})
+	if err != nil {
+		s.logger.Error("Failed to request withdrawal", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	s.notifyHolder(withdrawal, "Withdrawal Requested",
+		fmt.Sprintf("Your withdrawal of %s is awaiting review", withdrawal.Amount))
+	s.notifier.NotifyAdmin("Withdrawal Requested",
+		fmt.Sprintf("A %s requested a withdrawal of %s (#%d)", account, withdrawal.Amount, withdrawal.ID))
+	return withdrawal, nil
+}
+
+// CancelWithdrawal lets the holder withdraw a request that has not been
+// reviewed yet
+func (s *Service) CancelWithdrawal(userID, withdrawalID uint) (*database.Withdrawal, error) {
+	withdrawal, err := s.transition(withdrawalID, func(tx *gorm.DB, w *database.Withdrawal) error {
+		if w.UserID != userID {
+			return ErrWithdrawalNotFound
+		}
+		if w.Status != database.WithdrawalStatusPending {
+			return ErrInvalidStatus
+		}
+		w.Status = database.WithdrawalStatusCancelled
+		return ledger.PostWithdrawalRelease(tx, w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyHolder(withdrawal, "Withdrawal Cancelled",
+		fmt.Sprintf("Your withdrawal of %s was cancelled and returned to your balance", withdrawal.Amount))
+	return withdrawal, nil
+}
+
+func (s *Service) GetMyWithdrawals(userID uint, page, limit int) ([]database.Withdrawal, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetWithdrawals(&WithdrawalFilters{UserID: &userID}, offset, limit)
+}
+
+// ================ ADMIN ================
+
+func (s *Service) GetWithdrawals(filters *WithdrawalFilters, page, limit int) ([]database.Withdrawal, int64, error) {
+	offset := (page - 1) * limit
+	return s.repo.GetWithdrawals(filters, offset, limit)
+}
+
+func (s *Service) GetWithdrawal(withdrawalID uint) (*database.Withdrawal, error) {
+	withdrawal, err := s.repo.GetWithdrawal(withdrawalID)
+	if err != nil {
+		return nil, ErrWithdrawalNotFound
+	}
+	return withdrawal, nil
+}
+
+func (s *Service) ApproveWithdrawal(adminID, withdrawalID uint) (*database.Withdrawal, error) {
+	withdrawal, err := s.transition(withdrawalID, func(tx *gorm.DB, w *database.Withdrawal) error {
+		if w.Status != database.WithdrawalStatusPending {
+			return ErrInvalidStatus
+		}
+		w.Status = database.WithdrawalStatusApproved
+		reviewed(w, adminID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyHolder(withdrawal, "Withdrawal Approved",
+		fmt.Sprintf("Your withdrawal of %s was approved and will be paid shortly", withdrawal.Amount))
+	return withdrawal, nil
+}
+
+// RejectWithdrawal declines a pending or approved withdrawal and releases
+// the held amount back to the holder's balance
+func (s *Service) RejectWithdrawal(adminID, withdrawalID uint, reason string) (*database.Withdrawal, error) {
+	withdrawal, err := s.transition(withdrawalID, func(tx *gorm.DB, w *database.Withdrawal) error {
+		if w.Status != database.WithdrawalStatusPending && w.Status != database.WithdrawalStatusApproved {
+			return ErrInvalidStatus
+		}
+		w.Status = database.WithdrawalStatusRejected
+		w.RejectionReason = reason
+		reviewed(w, adminID)
+		return ledger.PostWithdrawalRelease(tx, w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyHolder(withdrawal, "Withdrawal Rejected",
+		fmt.Sprintf("Your withdrawal of %s was rejected (%s). The amount is back in your balance", withdrawal.Amount, reason))
+	return withdrawal, nil
+}
+
+// MarkPaid records that an approved withdrawal has been sent to the holder
+func (s *Service) MarkPaid(adminID, withdrawalID uint, reference string) (*database.Withdrawal, error) {
+	withdrawal, err := s.transition(withdrawalID, func(tx *gorm.DB, w *database.Withdrawal) error {
+		if w.Status != database.WithdrawalStatusApproved {
+			return ErrInvalidStatus
+		}
+		now := time.Now()
+		w.Status = database.WithdrawalStatusPaid
+		w.PaymentReference = reference
+		w.PaidAt = &now
+		return ledger.PostWithdrawalPaid(tx, w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyHolder(withdrawal, "Withdrawal Paid",
+		fmt.Sprintf("Your withdrawal of %s has been paid (ref %s)", withdrawal.Amount, reference))
+	return withdrawal, nil
+}
+
+// transition locks a withdrawal, lets change update it and post to the
+// ledger, then saves it in the same transaction
+func (s *Service) transition(withdrawalID uint, change func(tx *gorm.DB, w *database.Withdrawal) error) (*database.Withdrawal, error) {
+	var withdrawal *database.Withdrawal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		withdrawal, err = s.repo.LockWithdrawal(tx, withdrawalID)
+		if err != nil {
+			return ErrWithdrawalNotFound
+		}
+		if err := change(tx, withdrawal); err != nil {
+			return err
+		}
+		return tx.Save(withdrawal).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrWithdrawalNotFound) && !errors.Is(err, ErrInvalidStatus) {
+			s.logger.Error("Failed to update withdrawal", zap.Uint("withdrawal_id", withdrawalID), zap.Error(err))
+		}
+		return nil, err
+	}
+	return withdrawal, nil
+}
+
+func reviewed(w *database.Withdrawal, adminID uint) {
+	now := time.Now()
+	w.ReviewedByID = &adminID
+	w.ReviewedAt = &now
+}
+
+func (s *Service) notifyHolder(w *database.Withdrawal, title, message string) {
+	reference := fmt.Sprintf("%d", w.ID)
+	if w.Account == database.LedgerAccountRider {
+		s.notifier.NotifyRider(w.UserID, title, message, "withdrawal", reference)
+		return
+	}
+	s.notifier.NotifyVendor(w.UserID, title, message, "withdrawal", reference)
+}